@@ -10,6 +10,7 @@ import (
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	"github.com/pkg/errors"
 
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/storagedeal"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
@@ -53,21 +54,27 @@ deals, active deals, finished deals and cancelled deals.
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		minerAddress, _ := GetPorcelainAPI(env).ConfigGet("mining.minerAddress")
-		dealsCh, err := GetPorcelainAPI(env).DealsLs(req.Context)
-		if err != nil {
-			return err
-		}
 
 		filterForMiner, _ := req.Options[minerOnly].(bool)
 		filterForClient, _ := req.Options[clientOnly].(bool)
 
+		var filter porcelain.DealFilter
+		if filterForMiner {
+			// An unconfigured miner address matches no deals rather than all of them.
+			filter.Miner, _ = minerAddress.(address.Address)
+			if filter.Miner.Empty() {
+				return nil
+			}
+		}
+		dealsCh, err := GetPorcelainAPI(env).DealsLsFiltered(req.Context, filter)
+		if err != nil {
+			return err
+		}
+
 		for deal := range dealsCh {
 			if deal.Err != nil {
 				return deal.Err
 			}
-			if filterForMiner && deal.Deal.Miner != minerAddress {
-				continue
-			}
 			if filterForClient && deal.Deal.Miner == minerAddress {
 				continue
 			}
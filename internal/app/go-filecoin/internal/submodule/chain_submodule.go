@@ -17,6 +17,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/cst"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/net"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/net/pubsub"
@@ -34,6 +35,7 @@ type ChainSubmodule struct {
 	ChainSelector nodeChainSelector
 	ChainReader   nodeChainReader
 	MessageStore  *chain.MessageStore
+	MessageIndex  *chain.MessageIndex
 	Syncer        nodeChainSyncer
 	SyncDispatch  nodeSyncDispatcher
 	ActorState    *consensus.ActorStateStore
@@ -51,8 +53,8 @@ type ChainSubmodule struct {
 	Fetcher net.Fetcher
 	State   *cst.ChainStateReadWriter
 
-	validator consensus.BlockValidator
-	Processor *consensus.DefaultProcessor
+	BlockValidator consensus.BlockValidator
+	Processor      *consensus.DefaultProcessor
 }
 
 type nodeChainSelector interface {
@@ -82,10 +84,14 @@ type nodeSyncDispatcher interface {
 	SendOwnBlock(*block.ChainInfo) error
 	SendGossipBlock(*block.ChainInfo) error
 	Start(context.Context)
+	Stopped() <-chan struct{}
+	CurrentTarget() (*syncer.Target, bool)
+	HighestTarget() uint64
 }
 
 type chainRepo interface {
 	ChainDatastore() repo.Datastore
+	Config() *config.Config
 }
 
 type chainConfig interface {
@@ -111,7 +117,11 @@ func NewChainSubmodule(ctx context.Context, config chainConfig, repo chainRepo,
 
 	// setup block validation
 	// TODO when #2961 is resolved do the needful here.
-	blkValid := consensus.NewDefaultBlockValidator(config.BlockTime(), config.Clock(), pvt)
+	futureWindow, err := time.ParseDuration(repo.Config().Chain.BlockFutureWindow)
+	if err != nil {
+		return ChainSubmodule{}, errors.Wrap(err, "invalid chain.blockFutureWindow")
+	}
+	blkValid := consensus.NewDefaultBlockValidatorWithFutureWindow(config.BlockTime(), config.Clock(), pvt, nil, futureWindow)
 
 	// register block validation on floodsub
 	btv := net.NewBlockTopicValidator(blkValid)
@@ -134,9 +144,39 @@ func NewChainSubmodule(ctx context.Context, config chainConfig, repo chainRepo,
 
 	messageStore := chain.NewMessageStore(blockstore.Blockstore)
 
+	// index messages as heads are set so they can be found without traversal
+	messageIndex, err := chain.NewMessageIndexWithDatastore(repo.ChainDatastore())
+	if err != nil {
+		return ChainSubmodule{}, errors.Wrap(err, "failed to load message index")
+	}
+	messageIndex.SetRetention(repo.Config().Chain.MessageIndexRetention)
+	chainStore.UseMessageIndex(messageIndex, messageStore)
+
 	// only the syncer gets the storage which is online connected
-	chainSyncer := chain.NewSyncer(nodeConsensus, nodeChainSelector, chainStore, messageStore, fetcher, chainStatusReporter, config.Clock())
-	syncerDispatcher := syncer.NewDispatcher(chainSyncer)
+	chainSyncer := chain.NewSyncer(nodeConsensus, nodeChainSelector, chainStore, messageStore, fetcher, chainStatusReporter, config.Clock(), pvt)
+	syncerDispatcher := syncer.NewDispatcher(chainSyncer, nil)
+	chainSyncer.SetBadTipSetHandler(syncerDispatcher.MarkBad)
+	chainSyncer.SetReorgLimit(repo.Config().Chain.MaxReorgDepth, repo.Config().Chain.ReorgWeightMargin)
+	syncerDispatcher.UseHeadHeight(func() (uint64, error) {
+		head, err := chainStore.GetTipSet(chainStore.GetHead())
+		if err != nil {
+			return 0, err
+		}
+		return head.Height()
+	})
+	// resume syncing the targets outstanding when the node last stopped
+	syncerDispatcher.UseTargetStore(syncer.NewTargetStore(repo.ChainDatastore()))
+	syncerDispatcher.UseCompletedWindow(syncer.DefaultCompletedWindow, config.Clock())
+	syncerDispatcher.UseMaxHeight(func() (uint64, error) {
+		var genesis block.Block
+		if err := blockstore.CborStore.Get(ctx, config.GenesisCid(), &genesis); err != nil {
+			return 0, err
+		}
+		genesisTime := time.Unix(int64(genesis.Timestamp), 0)
+		// Blocks up to the future window ahead of the clock are synced once
+		// their time comes, so chain infos announcing them are plausible.
+		return consensus.MaxPlausibleHeight(genesisTime, config.BlockTime(), config.Clock().Now().Add(futureWindow)), nil
+	})
 
 	chainState := cst.NewChainStateReadWriter(chainStore, messageStore, blockstore.CborStore, builtin.DefaultActors)
 
@@ -146,14 +186,15 @@ func NewChainSubmodule(ctx context.Context, config chainConfig, repo chainRepo,
 		ChainSelector: nodeChainSelector,
 		ChainReader:   chainStore,
 		MessageStore:  messageStore,
+		MessageIndex:  messageIndex,
 		SyncDispatch:  syncerDispatcher,
 		ActorState:    actorState,
 		// HeaviestTipSetCh: nil,
 		// cancelChainSync: nil,
-		ChainSynced: moresync.NewLatch(1),
-		Fetcher:     fetcher,
-		State:       chainState,
-		validator:   blkValid,
-		Processor:   processor,
+		ChainSynced:    moresync.NewLatch(1),
+		Fetcher:        fetcher,
+		State:          chainState,
+		BlockValidator: blkValid,
+		Processor:      processor,
 	}, nil
 }
@@ -3,6 +3,9 @@ package submodule
 import (
 	"context"
 
+	ds "github.com/ipfs/go-datastore"
+	"github.com/pkg/errors"
+
 	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/journal"
@@ -23,6 +26,14 @@ type MessagingSubmodule struct {
 	MessageSub pubsub.Subscription
 
 	MsgPool *message.Pool
+
+	// MessageValidator validates messages on the message pubsub topic and
+	// counts, per peer, the messages received and forwarded.
+	MessageValidator *net.MessageTopicValidator
+
+	// poolStore persists pending messages across restarts. It is nil unless
+	// enabled in the message pool config.
+	poolStore *message.PoolStore
 }
 
 type messagingConfig interface {
@@ -31,21 +42,60 @@ type messagingConfig interface {
 
 type messagingRepo interface {
 	Config() *config.Config
+	Datastore() ds.Batching
 }
 
 // NewMessagingSubmodule creates a new discovery submodule.
 func NewMessagingSubmodule(ctx context.Context, config messagingConfig, repo messagingRepo, network *NetworkSubmodule, chain *ChainSubmodule, wallet *WalletSubmodule) (MessagingSubmodule, error) {
-	msgPool := message.NewPool(repo.Config().Mpool, consensus.NewIngestionValidator(chain.State, repo.Config().Mpool))
-	inbox := message.NewInbox(msgPool, message.InboxMaxAgeTipsets, chain.ChainReader, chain.MessageStore)
+	ingestionValidator := consensus.NewIngestionValidator(chain.State, repo.Config().Mpool)
+	msgPool := message.NewPool(repo.Config().Mpool, ingestionValidator)
+	inbox := message.NewInbox(msgPool, repo.Config().Mpool.MaxAgeTipsets, chain.ChainReader, chain.MessageStore, config.Journal().Topic("inbox"))
 
 	msgQueue := message.NewQueue()
 	outboxPolicy := message.NewMessageQueuePolicy(chain.MessageStore, message.OutboxMaxAgeRounds)
 	msgPublisher := message.NewDefaultPublisher(pubsub.NewPublisher(network.fsub), net.MessageTopic(network.NetworkName), msgPool)
 	outbox := message.NewOutbox(wallet.Wallet, consensus.NewOutboundMessageValidator(), msgQueue, msgPublisher, outboxPolicy, chain.ChainReader, chain.State, config.Journal().Topic("outbox"))
 
+	// register message validation on floodsub so that invalid messages are not forwarded
+	mtv := net.NewMessageTopicValidator(ingestionValidator)
+	if err := network.fsub.RegisterTopicValidator(mtv.Topic(network.NetworkName), mtv.Validator(), mtv.Opts()...); err != nil {
+		return MessagingSubmodule{}, errors.Wrap(err, "failed to register message validator")
+	}
+
+	var poolStore *message.PoolStore
+	if repo.Config().Mpool.PersistPending {
+		poolStore = message.NewPoolStore(repo.Datastore())
+	}
+
 	return MessagingSubmodule{
-		Inbox:   inbox,
-		Outbox:  outbox,
-		MsgPool: msgPool,
+		Inbox:            inbox,
+		Outbox:           outbox,
+		MsgPool:          msgPool,
+		MessageValidator: mtv,
+		poolStore:        poolStore,
 	}, nil
 }
+
+// RestorePending re-admits messages saved by PersistPending to the message
+// pool, tagged with the chain height `height`. It does nothing unless pending
+// message persistence is enabled.
+func (m MessagingSubmodule) RestorePending(ctx context.Context, height uint64) error {
+	if m.poolStore == nil {
+		return nil
+	}
+	msgs, err := m.poolStore.Load()
+	if err != nil {
+		return err
+	}
+	return m.MsgPool.Restore(ctx, msgs, height)
+}
+
+// PersistPending saves the messages pending in the message pool so that they
+// can be restored by RestorePending. It does nothing unless pending message
+// persistence is enabled.
+func (m MessagingSubmodule) PersistPending() error {
+	if m.poolStore == nil {
+		return nil
+	}
+	return m.poolStore.Save(m.MsgPool.Pending())
+}
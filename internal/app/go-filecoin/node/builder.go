@@ -229,22 +229,32 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		return nil, errors.Wrap(err, "failed to build node.FaultSlasher")
 	}
 
+	deals := strgdls.New(b.repo.DealsDatastore())
+	if err := deals.Migrate(strgdls.DefaultMigrations); err != nil {
+		return nil, errors.Wrap(err, "failed to migrate deal store")
+	}
+
 	nd.PorcelainAPI = porcelain.New(plumbing.New(&plumbing.APIDeps{
-		Bitswap:       nd.network.Bitswap,
-		Chain:         nd.chain.State,
-		Sync:          cst.NewChainSyncProvider(nd.chain.Syncer),
-		Config:        cfg.NewConfig(b.repo),
-		DAG:           dag.NewDAG(merkledag.NewDAGService(nd.Blockservice.Blockservice)),
-		Deals:         strgdls.New(b.repo.DealsDatastore()),
-		Expected:      nd.chain.Consensus,
-		MsgPool:       nd.Messaging.MsgPool,
-		MsgPreviewer:  msg.NewPreviewer(nd.chain.ChainReader, nd.Blockstore.CborStore, nd.Blockstore.Blockstore, nd.chain.Processor),
-		ActState:      nd.chain.ActorState,
-		MsgWaiter:     msg.NewWaiter(nd.chain.ChainReader, nd.chain.MessageStore, nd.Blockstore.Blockstore, nd.Blockstore.CborStore),
-		Network:       nd.network.Network,
-		Outbox:        nd.Messaging.Outbox,
-		SectorBuilder: nd.SectorBuilder,
-		Wallet:        nd.Wallet.Wallet,
+		Bitswap:        nd.network.Bitswap,
+		BlockValidator: nd.chain.BlockValidator,
+		Chain:          nd.chain.State,
+		Sync:           cst.NewChainSyncProvider(nd.chain.Syncer, nd.chain.SyncDispatch),
+		Config:         cfg.NewConfig(b.repo),
+		DAG:            dag.NewDAG(merkledag.NewDAGService(nd.Blockservice.Blockservice)),
+		Deals:          deals,
+		Expected:       nd.chain.Consensus,
+		MsgIndex:       nd.chain.MessageIndex,
+		MsgPool:        nd.Messaging.MsgPool,
+		MsgPreviewer:   msg.NewPreviewer(nd.chain.ChainReader, nd.Blockstore.CborStore, nd.Blockstore.Blockstore, nd.chain.Processor),
+		ActState:       nd.chain.ActorState,
+		MsgWaiter:      msg.NewWaiterWithIndex(nd.chain.ChainReader, nd.chain.MessageStore, nd.Blockstore.Blockstore, nd.Blockstore.CborStore, nd.chain.MessageIndex),
+		Network:        nd.network.Network,
+		Outbox:         nd.Messaging.Outbox,
+		ParentWeight:   nd.getWeight,
+		Rewarder:       nd.chain.Processor.BlockRewarder(),
+		SectorBuilder:  nd.SectorBuilder,
+		VersionTable:   nd.VersionTable,
+		Wallet:         nd.Wallet.Wallet,
 	}))
 
 	return nd, nil
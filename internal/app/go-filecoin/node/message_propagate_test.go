@@ -5,6 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/peer"
+	libp2pps "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -12,9 +15,11 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net"
 	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/wallet"
 )
 
 // TestMessagePropagation is a high level check that messages are propagated between message
@@ -85,3 +90,104 @@ func TestMessagePropagation(t *testing.T) {
 		assert.True(t, nodes[2].Messaging.Inbox.Pool().Pending()[0].Message.Method == "foo")
 	})
 }
+
+// TestMessagePropagationCluster checks the same propagation as TestMessagePropagation
+// using a cluster of nodes connected in series.
+func TestMessagePropagationCluster(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ki := types.MustGenerateKeyInfo(1, 42)[0]
+	senderAddress, err := ki.Address()
+	require.NoError(t, err)
+	genesis := consensus.MakeGenesisFunc(
+		consensus.ActorAccount(senderAddress, types.NewAttoFILFromFIL(100)),
+		consensus.Network(version.TEST),
+	)
+
+	nodes, stop := NewCluster(t, 3, genesis, SeriesTopology)
+	defer stop()
+
+	// Give the first node the key that can send messages.
+	backends := nodes[0].Wallet.Wallet.Backends(wallet.DSBackendType)
+	require.Len(t, backends, 1)
+	require.NoError(t, backends[0].(*wallet.DSBackend).ImportKey(&ki))
+
+	_, err = nodes[0].PorcelainAPI.MessageSend(
+		ctx,
+		senderAddress,
+		address.NetworkAddress,
+		types.NewAttoFILFromFIL(1),
+		types.NewGasPrice(1),
+		types.NewGasUnits(0),
+		"foo",
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, th.WaitForIt(50, 100*time.Millisecond, func() (bool, error) {
+		for _, nd := range nodes {
+			if len(nd.Messaging.Inbox.Pool().Pending()) != 1 {
+				return false, nil
+			}
+		}
+		return true, nil
+	}), "failed to propagate messages")
+
+	for _, nd := range nodes {
+		assert.Equal(t, "foo", nd.Messaging.Inbox.Pool().Pending()[0].Message.Method)
+	}
+}
+
+// TestInvalidMessageNotForwarded checks that a node does not forward a message
+// that fails validation to its other peers.
+func TestInvalidMessageNotForwarded(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ki := types.MustGenerateKeyInfo(1, 42)[0]
+	senderAddress, err := ki.Address()
+	require.NoError(t, err)
+	genesis := consensus.MakeGenesisFunc(
+		consensus.ActorAccount(senderAddress, types.NewAttoFILFromFIL(100)),
+		consensus.Network(version.TEST),
+	)
+
+	nodes, stop := NewCluster(t, 2, genesis, SeriesTopology)
+	defer stop()
+	topic := net.MessageTopic(nodes[0].Network().NetworkName)
+
+	// The sender is a bare pubsub peer that does not validate what it publishes.
+	senderHost, err := libp2p.New(ctx, libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, senderHost.Close()) }()
+	senderSub, err := libp2pps.NewFloodSub(ctx, senderHost, libp2pps.WithMessageSigning(false))
+	require.NoError(t, err)
+	require.NoError(t, senderHost.Connect(ctx, peer.AddrInfo{ID: nodes[0].Host().ID(), Addrs: nodes[0].Host().Addrs()}))
+	require.NoError(t, th.WaitForIt(50, 10*time.Millisecond, func() (bool, error) {
+		return len(senderSub.ListPeers(topic)) == 1, nil
+	}), "sender did not learn of the node's subscription")
+
+	// Publish a message whose signature does not verify.
+	msg, err := types.NewSignedMessage(*types.NewMeteredMessage(senderAddress, address.NetworkAddress, 0, types.NewAttoFILFromFIL(1), "foo", nil, types.NewGasPrice(1), types.NewGasUnits(0)), types.NewMockSigner([]types.KeyInfo{ki}))
+	require.NoError(t, err)
+	msg.Signature = []byte("not a signature")
+	data, err := msg.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, senderSub.Publish(topic, data))
+
+	validator := nodes[0].Messaging.MessageValidator
+	require.NoError(t, th.WaitForIt(50, 10*time.Millisecond, func() (bool, error) {
+		return validator.Received(senderHost.ID()) == 1, nil
+	}), "message did not reach the first node")
+	assert.Equal(t, uint64(0), validator.Forwarded(senderHost.ID()))
+
+	// Give a forwarded message time to arrive before checking it did not.
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, uint64(0), nodes[1].Messaging.MessageValidator.Received(nodes[0].Host().ID()))
+	assert.Empty(t, nodes[0].Messaging.Inbox.Pool().Pending())
+	assert.Empty(t, nodes[1].Messaging.Inbox.Pool().Pending())
+}
@@ -117,6 +117,10 @@ func (node *Node) Start(ctx context.Context) error {
 		return err
 	}
 
+	if err := node.restorePendingMessages(ctx); err != nil {
+		log.Warnf("failed to restore pending messages: %s", err)
+	}
+
 	// Only set these up if there is a miner configured.
 	if _, err := node.MiningAddress(); err == nil {
 		if err := node.setupSectorBuilder(ctx); err != nil {
@@ -146,7 +150,7 @@ func (node *Node) Start(ctx context.Context) error {
 	if !node.OfflineMode {
 
 		// Start syncing dispatch
-		node.chain.SyncDispatch.Start(context.Background())
+		node.chain.SyncDispatch.Start(syncCtx)
 
 		// Start node discovery
 		if err := node.Discovery.Start(node); err != nil {
@@ -338,8 +342,17 @@ func (node *Node) Stop(ctx context.Context) {
 	node.StopMining(ctx)
 
 	node.cancelSubscriptions()
+	// Wait for the dispatcher to save its outstanding targets.
+	select {
+	case <-node.chain.SyncDispatch.Stopped():
+	case <-ctx.Done():
+	}
 	node.chain.ChainReader.Stop()
 
+	if err := node.Messaging.PersistPending(); err != nil {
+		fmt.Printf("error persisting pending messages: %s\n", err)
+	}
+
 	if node.SectorBuilder() != nil {
 		if err := node.SectorBuilder().Close(); err != nil {
 			fmt.Printf("error closing sector builder: %s\n", err)
@@ -360,6 +373,20 @@ func (node *Node) Stop(ctx context.Context) {
 	fmt.Println("stopping filecoin :(")
 }
 
+// restorePendingMessages re-admits messages pending when the node last stopped
+// to the message pool, at the height of the loaded chain head.
+func (node *Node) restorePendingMessages(ctx context.Context) error {
+	head, err := node.chain.ChainReader.GetTipSet(node.chain.ChainReader.GetHead())
+	if err != nil {
+		return err
+	}
+	height, err := head.Height()
+	if err != nil {
+		return err
+	}
+	return node.Messaging.RestorePending(ctx, height)
+}
+
 func (node *Node) addNewlyMinedBlock(ctx context.Context, b *block.Block) {
 	log.Debugf("Got a newly mined block from the mining worker: %s", b)
 	if err := node.AddNewBlock(ctx, b); err != nil {
@@ -452,7 +479,7 @@ func (node *Node) StartMining(ctx context.Context) error {
 	_, mineDelay := node.MiningTimes()
 
 	if node.BlockMining.MiningScheduler == nil {
-		node.BlockMining.MiningScheduler = mining.NewScheduler(node.BlockMining.MiningWorker, mineDelay, node.PorcelainAPI.ChainHead)
+		node.BlockMining.MiningScheduler = mining.NewScheduler(node.BlockMining.MiningWorker, mineDelay, node.PorcelainAPI.ChainHead, node.Clock)
 	} else if node.BlockMining.MiningScheduler.IsStarted() {
 		return fmt.Errorf("miner scheduler already started")
 	}
@@ -694,6 +721,7 @@ func (node *Node) setupProtocols() error {
 		node.chain.ChainReader,
 		node.IsMining,
 		mineDelay,
+		node.Clock,
 		node.SetupMining,
 		node.StartMining,
 		node.StopMining,
@@ -747,6 +775,9 @@ func (node *Node) CreateMiningWorker(ctx context.Context) (mining.Worker, error)
 		GetAncestors: node.getAncestors,
 		Election:     consensus.ElectionMachine{},
 		TicketGen:    consensus.TicketMachine{},
+		MessageLimit: func(height uint64) (int, bool, error) {
+			return consensus.MaxMessagesAt(node.VersionTable, consensus.DefaultMaxMessagesPerBlock, height)
+		},
 
 		MessageSource: node.Messaging.Inbox.Pool(),
 		MessageStore:  node.chain.MessageStore,
@@ -763,34 +794,19 @@ func (node *Node) getStateTree(ctx context.Context, ts block.TipSet) (state.Tree
 
 // getWeight is the default GetWeight function for the mining worker.
 func (node *Node) getWeight(ctx context.Context, ts block.TipSet) (uint64, error) {
-	h, err := ts.Height()
-	if err != nil {
-		return 0, err
-	}
-	var wFun func(context.Context, block.TipSet, cid.Cid) (uint64, error)
-	v, err := node.VersionTable.VersionAt(types.NewBlockHeight(h))
-	if err != nil {
-		return 0, err
-	}
-	if v >= version.Protocol1 {
-		wFun = node.chain.ChainSelector.NewWeight
-	} else {
-		wFun = node.chain.ChainSelector.Weight
-	}
-
 	parent, err := ts.Parents()
 	if err != nil {
 		return uint64(0), err
 	}
 	// TODO handle genesis cid more gracefully
-	if parent.Len() == 0 {
-		return wFun(ctx, ts, cid.Undef)
-	}
-	root, err := node.chain.ChainReader.GetTipSetStateRoot(parent)
-	if err != nil {
-		return uint64(0), err
+	root := cid.Undef
+	if parent.Len() != 0 {
+		root, err = node.chain.ChainReader.GetTipSetStateRoot(parent)
+		if err != nil {
+			return uint64(0), err
+		}
 	}
-	return wFun(ctx, ts, root)
+	return consensus.ComputeParentWeight(ctx, ts, root, node.chain.ChainSelector, node.VersionTable)
 }
 
 // getAncestors is the default GetAncestors function for the mining worker.
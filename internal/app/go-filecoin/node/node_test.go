@@ -12,7 +12,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/node"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/mining"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/proofs/verification"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage"
@@ -193,6 +195,46 @@ func TestOptionWithError(t *testing.T) {
 
 }
 
+func TestPendingMessagesRestoredAfterRestart(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	signer, kis := types.NewMockSignersAndKeyInfo(1)
+	sender, err := kis[0].Address()
+	require.NoError(t, err)
+
+	r := repo.NewInMemoryRepo()
+	genesis := consensus.MakeGenesisFunc(consensus.ActorAccount(sender, types.NewAttoFILFromFIL(100)))
+	require.NoError(t, node.Init(ctx, r, genesis))
+	r.Config().Swarm.Address = "/ip4/127.0.0.1/tcp/0"
+	r.Config().Mpool.PersistPending = true
+
+	startNode := func() *node.Node {
+		opts, err := node.OptionsFromRepo(r)
+		require.NoError(t, err)
+		opts = append(opts, node.OfflineMode(true))
+		opts = append(opts, node.DefaultTestingConfig()...)
+		nd, err := node.New(ctx, opts...)
+		require.NoError(t, err)
+		require.NoError(t, nd.Start(ctx))
+		return nd
+	}
+
+	msg, err := types.NewSignedMessage(*types.NewMeteredMessage(sender, address.NetworkAddress, 0, types.NewAttoFILFromFIL(1), "", nil, types.NewGasPrice(1), types.NewGasUnits(0)), signer)
+	require.NoError(t, err)
+
+	nd := startNode()
+	c, err := nd.Messaging.MsgPool.Add(ctx, msg, 0)
+	require.NoError(t, err)
+	nd.Stop(ctx)
+
+	restarted := startNode()
+	defer restarted.Stop(ctx)
+	restored, ok := restarted.Messaging.MsgPool.Get(c)
+	require.True(t, ok)
+	assert.True(t, msg.Equals(restored))
+}
+
 func TestNodeConfig(t *testing.T) {
 	tf.UnitTest(t)
 
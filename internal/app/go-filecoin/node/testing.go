@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	bserv "github.com/ipfs/go-blockservice"
@@ -173,6 +174,51 @@ func MakeNodesUnstartedWithGif(t *testing.T, numNodes int, offlineMode bool, gif
 	return out
 }
 
+// Topology describes how the nodes of a test cluster are connected to each other.
+type Topology int
+
+const (
+	// SeriesTopology connects each node to the next one, forming a line.
+	SeriesTopology Topology = iota
+	// StarTopology connects every node to the first node.
+	StarTopology
+	// MeshTopology connects every node to every other node.
+	MeshTopology
+)
+
+// NewCluster creates numNodes online nodes with the supplied genesis init function,
+// starts them and connects them in the requested topology. The returned func
+// stops all of the nodes.
+func NewCluster(t *testing.T, numNodes int, gif consensus.GenesisInitFunc, topology Topology) ([]*Node, func()) {
+	t.Helper()
+	nodes := MakeNodesUnstartedWithGif(t, numNodes, false, gif)
+	StartNodes(t, nodes)
+
+	switch topology {
+	case SeriesTopology:
+		for i := 1; i < len(nodes); i++ {
+			ConnectNodes(t, nodes[i-1], nodes[i])
+		}
+	case StarTopology:
+		for i := 1; i < len(nodes); i++ {
+			ConnectNodes(t, nodes[0], nodes[i])
+		}
+	case MeshTopology:
+		for i := 0; i < len(nodes); i++ {
+			for j := i + 1; j < len(nodes); j++ {
+				ConnectNodes(t, nodes[i], nodes[j])
+			}
+		}
+	default:
+		StopNodes(nodes)
+		t.Fatalf("unknown topology %d", topology)
+	}
+	// Wait for network connection notifications to propagate
+	time.Sleep(time.Millisecond * 50)
+
+	return nodes, func() { StopNodes(nodes) }
+}
+
 // MakeNodesUnstarted creates some new nodes with an InMemoryRepo, fake proof verifier, and default genesis block.
 // Call StartNodes to start them.
 func MakeNodesUnstarted(t *testing.T, numNodes int, offlineMode bool) []*Node {
@@ -33,7 +33,9 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/storagedeal"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/sectorbuilder"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/syncer"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/wallet"
 )
 
@@ -45,40 +47,50 @@ import (
 type API struct {
 	logger logging.EventLogger
 
-	bitswap       exchange.Interface
-	chain         *cst.ChainStateReadWriter
-	syncer        *cst.ChainSyncProvider
-	config        *cfg.Config
-	dag           *dag.DAG
-	expected      consensus.Protocol
-	msgPool       *message.Pool
-	msgPreviewer  *msg.Previewer
-	actorState    *consensus.ActorStateStore
-	msgWaiter     *msg.Waiter
-	network       *net.Network
-	outbox        *message.Outbox
-	sectorBuilder func() sectorbuilder.SectorBuilder
-	storagedeals  *strgdls.Store
-	wallet        *wallet.Wallet
+	bitswap        exchange.Interface
+	blockValidator consensus.BlockValidator
+	chain          *cst.ChainStateReadWriter
+	syncer         *cst.ChainSyncProvider
+	config         *cfg.Config
+	dag            *dag.DAG
+	expected       consensus.Protocol
+	msgPool        *message.Pool
+	msgPreviewer   *msg.Previewer
+	actorState     *consensus.ActorStateStore
+	msgIndex       *chain.MessageIndex
+	msgWaiter      *msg.Waiter
+	network        *net.Network
+	outbox         *message.Outbox
+	parentWeight   func(context.Context, block.TipSet) (uint64, error)
+	rewarder       consensus.BlockRewarder
+	sectorBuilder  func() sectorbuilder.SectorBuilder
+	storagedeals   *strgdls.Store
+	versionTable   *version.ProtocolVersionTable
+	wallet         *wallet.Wallet
 }
 
 // APIDeps contains all the API's dependencies
 type APIDeps struct {
-	Bitswap       exchange.Interface
-	Chain         *cst.ChainStateReadWriter
-	ActState      *consensus.ActorStateStore
-	Sync          *cst.ChainSyncProvider
-	Config        *cfg.Config
-	DAG           *dag.DAG
-	Deals         *strgdls.Store
-	Expected      consensus.Protocol
-	MsgPool       *message.Pool
-	MsgPreviewer  *msg.Previewer
-	MsgWaiter     *msg.Waiter
-	Network       *net.Network
-	Outbox        *message.Outbox
-	SectorBuilder func() sectorbuilder.SectorBuilder
-	Wallet        *wallet.Wallet
+	Bitswap        exchange.Interface
+	BlockValidator consensus.BlockValidator
+	Chain          *cst.ChainStateReadWriter
+	ActState       *consensus.ActorStateStore
+	Sync           *cst.ChainSyncProvider
+	Config         *cfg.Config
+	DAG            *dag.DAG
+	Deals          *strgdls.Store
+	Expected       consensus.Protocol
+	MsgPool        *message.Pool
+	MsgIndex       *chain.MessageIndex
+	MsgPreviewer   *msg.Previewer
+	MsgWaiter      *msg.Waiter
+	Network        *net.Network
+	Outbox         *message.Outbox
+	ParentWeight   func(context.Context, block.TipSet) (uint64, error)
+	Rewarder       consensus.BlockRewarder
+	SectorBuilder  func() sectorbuilder.SectorBuilder
+	VersionTable   *version.ProtocolVersionTable
+	Wallet         *wallet.Wallet
 }
 
 // New constructs a new instance of the API.
@@ -86,21 +98,26 @@ func New(deps *APIDeps) *API {
 	return &API{
 		logger: logging.Logger("porcelain"),
 
-		bitswap:       deps.Bitswap,
-		chain:         deps.Chain,
-		actorState:    deps.ActState,
-		syncer:        deps.Sync,
-		config:        deps.Config,
-		dag:           deps.DAG,
-		expected:      deps.Expected,
-		msgPool:       deps.MsgPool,
-		msgPreviewer:  deps.MsgPreviewer,
-		msgWaiter:     deps.MsgWaiter,
-		network:       deps.Network,
-		outbox:        deps.Outbox,
-		sectorBuilder: deps.SectorBuilder,
-		storagedeals:  deps.Deals,
-		wallet:        deps.Wallet,
+		bitswap:        deps.Bitswap,
+		blockValidator: deps.BlockValidator,
+		chain:          deps.Chain,
+		actorState:     deps.ActState,
+		syncer:         deps.Sync,
+		config:         deps.Config,
+		dag:            deps.DAG,
+		expected:       deps.Expected,
+		msgPool:        deps.MsgPool,
+		msgIndex:       deps.MsgIndex,
+		msgPreviewer:   deps.MsgPreviewer,
+		msgWaiter:      deps.MsgWaiter,
+		network:        deps.Network,
+		outbox:         deps.Outbox,
+		parentWeight:   deps.ParentWeight,
+		rewarder:       deps.Rewarder,
+		sectorBuilder:  deps.SectorBuilder,
+		storagedeals:   deps.Deals,
+		versionTable:   deps.VersionTable,
+		wallet:         deps.Wallet,
 	}
 }
 
@@ -109,6 +126,11 @@ func (api *API) ActorGet(ctx context.Context, addr address.Address) (*actor.Acto
 	return api.chain.GetActor(ctx, addr)
 }
 
+// ActorGetAt returns an actor from the state of the tipset with the given key
+func (api *API) ActorGetAt(ctx context.Context, addr address.Address, key block.TipSetKey) (*actor.Actor, error) {
+	return api.chain.GetActorAt(ctx, key, addr)
+}
+
 // ActorGetSignature returns the signature of the given actor's given method.
 // The function signature is typically used to enable a caller to decode the
 // output of an actor method call (message).
@@ -121,11 +143,35 @@ func (api *API) ActorLs(ctx context.Context) (<-chan state.GetAllActorsResult, e
 	return api.chain.LsActors(ctx)
 }
 
+// ActorLsByCode returns a channel with the actors having any of the given
+// codes from the latest state on the chain
+func (api *API) ActorLsByCode(ctx context.Context, codes ...cid.Cid) (<-chan state.GetAllActorsResult, error) {
+	return api.chain.LsActorsByCode(ctx, codes...)
+}
+
+// ActorLsPaged returns a page of up to `limit` actors from the latest state on
+// the chain, listed from `cursor`, and the cursor of the next page. Pages are
+// listed from an empty cursor until the next cursor is empty.
+func (api *API) ActorLsPaged(ctx context.Context, cursor string, limit int) ([]state.GetAllActorsResult, string, error) {
+	return api.chain.LsActorsPaged(ctx, cursor, limit)
+}
+
+// BlockRewardAt returns the block reward paid for a block at the given height.
+func (api *API) BlockRewardAt(height uint64) types.AttoFIL {
+	return api.rewarder.RewardAt(height)
+}
+
 // BlockTime returns the block time used by the consensus protocol.
 func (api *API) BlockTime() time.Duration {
 	return api.expected.BlockTime()
 }
 
+// ProtocolVersions returns the protocol versions scheduled for the node's
+// network, ordered by the height at which they take effect.
+func (api *API) ProtocolVersions() []version.ProtocolVersion {
+	return api.versionTable.Versions()
+}
+
 // ConfigSet sets the given parameters at the given path in the local config.
 // The given path may be either a single field name, or a dotted path to a field.
 // The JSON value may be either a single value or a whole data structure to be replace.
@@ -147,16 +193,41 @@ func (api *API) ChainGetBlock(ctx context.Context, id cid.Cid) (*block.Block, er
 	return api.chain.GetBlock(ctx, id)
 }
 
+// ChainGetBlocks gets blocks by CID, in the order of `ids`
+func (api *API) ChainGetBlocks(ctx context.Context, ids []cid.Cid) ([]*block.Block, error) {
+	return api.chain.GetBlocks(ctx, ids)
+}
+
 // ChainGetMessages gets a message collection by CID
 func (api *API) ChainGetMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, error) {
 	return api.chain.GetMessages(ctx, meta)
 }
 
+// ChainLoadMessages gets both the secp and bls message collections referenced by `meta`
+func (api *API) ChainLoadMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error) {
+	return api.chain.LoadMessages(ctx, meta)
+}
+
+// ChainMessageLocate returns the location of the message with CID `c` on the
+// current chain as recorded by the message index, and false if the message
+// is not indexed or there is no index.
+func (api *API) ChainMessageLocate(c cid.Cid) (chain.MessageLocation, bool) {
+	if api.msgIndex == nil {
+		return chain.MessageLocation{}, false
+	}
+	return api.msgIndex.Locate(c)
+}
+
 // ChainGetReceipts gets a receipt collection by CID
 func (api *API) ChainGetReceipts(ctx context.Context, id cid.Cid) ([]*types.MessageReceipt, error) {
 	return api.chain.GetReceipts(ctx, id)
 }
 
+// ChainGenesisCid returns the CID of the genesis block
+func (api *API) ChainGenesisCid() cid.Cid {
+	return api.chain.GenesisCid()
+}
+
 // ChainHeadKey returns the head tipset key
 func (api *API) ChainHeadKey() block.TipSetKey {
 	return api.chain.Head()
@@ -172,6 +243,23 @@ func (api *API) ChainTipSet(key block.TipSetKey) (block.TipSet, error) {
 	return api.chain.GetTipSet(key)
 }
 
+// ChainParentWeight returns the weight a block mined on `parents` must
+// claim as its parent weight
+func (api *API) ChainParentWeight(ctx context.Context, parents block.TipSet) (uint64, error) {
+	return api.parentWeight(ctx, parents)
+}
+
+// ChainValidateBlockSyntax validates that a block is correctly formed
+func (api *API) ChainValidateBlockSyntax(ctx context.Context, blk *block.Block) error {
+	return api.blockValidator.ValidateSyntax(ctx, blk)
+}
+
+// ChainValidateBlockSemantic validates that a block is correctly derived from
+// its parents, which have weight `parentWeight`
+func (api *API) ChainValidateBlockSemantic(ctx context.Context, blk *block.Block, parents *block.TipSet, parentWeight uint64) error {
+	return api.blockValidator.ValidateSemantic(ctx, blk, parents, parentWeight)
+}
+
 // ChainLs returns an iterator of tipsets from head to genesis
 func (api *API) ChainLs(ctx context.Context) (*chain.TipsetIterator, error) {
 	return api.chain.Ls(ctx)
@@ -189,6 +277,18 @@ func (api *API) ChainStatus() chain.Status {
 	return api.syncer.Status()
 }
 
+// SyncCurrentTarget returns the target currently being synced. The second
+// return value is false if no sync is in progress.
+func (api *API) SyncCurrentTarget() (*syncer.Target, bool) {
+	return api.syncer.CurrentTarget()
+}
+
+// SyncHighestTarget returns the greatest chain height claimed by a sync
+// target that has not failed or been found bad.
+func (api *API) SyncHighestTarget() uint64 {
+	return api.syncer.HighestTarget()
+}
+
 // ChainSyncHandleNewTipSet submits a chain head to the syncer for processing. If the head is trusted
 // the syncer will attempt to sync the new head regardless of length.
 func (api *API) ChainSyncHandleNewTipSet(ctx context.Context, ci *block.ChainInfo, trusted bool) error {
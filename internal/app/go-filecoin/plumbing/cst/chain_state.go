@@ -25,6 +25,7 @@ import (
 var logStore = logging.Logger("plumbing/chain_store")
 
 type chainReadWriter interface {
+	GenesisCid() cid.Cid
 	GetHead() block.TipSetKey
 	GetTipSet(block.TipSetKey) (block.TipSet, error)
 	GetTipSetState(context.Context, block.TipSetKey) (state.Tree, error)
@@ -78,6 +79,11 @@ func (chn *ChainStateReadWriter) Head() block.TipSetKey {
 	return chn.readWriter.GetHead()
 }
 
+// GenesisCid returns the CID of the genesis block
+func (chn *ChainStateReadWriter) GenesisCid() cid.Cid {
+	return chn.readWriter.GenesisCid()
+}
+
 // GetTipSet returns the tipset at the given key
 func (chn *ChainStateReadWriter) GetTipSet(key block.TipSetKey) (block.TipSet, error) {
 	return chn.readWriter.GetTipSet(key)
@@ -99,6 +105,19 @@ func (chn *ChainStateReadWriter) GetBlock(ctx context.Context, id cid.Cid) (*blo
 	return &out, err
 }
 
+// GetBlocks gets blocks by CID, in the order of `ids`.
+func (chn *ChainStateReadWriter) GetBlocks(ctx context.Context, ids []cid.Cid) ([]*block.Block, error) {
+	out := make([]*block.Block, len(ids))
+	for i, id := range ids {
+		var blk block.Block
+		if err := chn.cst.Get(ctx, id, &blk); err != nil {
+			return nil, errors.Wrapf(err, "failed to get block %s", id)
+		}
+		out[i] = &blk
+	}
+	return out, nil
+}
+
 // GetMessages gets a message collection by CID.
 func (chn *ChainStateReadWriter) GetMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, error) {
 	secp, _, err := chn.messageProvider.LoadMessages(ctx, meta)
@@ -108,6 +127,11 @@ func (chn *ChainStateReadWriter) GetMessages(ctx context.Context, meta types.TxM
 	return secp, nil
 }
 
+// LoadMessages gets the secp and bls message collections referenced by `meta`.
+func (chn *ChainStateReadWriter) LoadMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error) {
+	return chn.messageProvider.LoadMessages(ctx, meta)
+}
+
 // GetReceipts gets a receipt collection by CID.
 func (chn *ChainStateReadWriter) GetReceipts(ctx context.Context, id cid.Cid) ([]*types.MessageReceipt, error) {
 	return chn.messageProvider.LoadReceipts(ctx, id)
@@ -156,6 +180,27 @@ func (chn *ChainStateReadWriter) LsActors(ctx context.Context) (<-chan state.Get
 	return state.GetAllActors(ctx, st), nil
 }
 
+// LsActorsByCode returns a channel with the actors having any of the given
+// codes from the latest state on the chain
+func (chn *ChainStateReadWriter) LsActorsByCode(ctx context.Context, codes ...cid.Cid) (<-chan state.GetAllActorsResult, error) {
+	st, err := chn.readWriter.GetTipSetState(ctx, chn.readWriter.GetHead())
+	if err != nil {
+		return nil, err
+	}
+	return state.GetAllActorsWithCode(ctx, st, codes...), nil
+}
+
+// LsActorsPaged returns a page of up to `limit` actors following `cursor` in
+// address order from the latest state on the chain, and the cursor of the
+// next page, which is empty after the last page.
+func (chn *ChainStateReadWriter) LsActorsPaged(ctx context.Context, cursor string, limit int) ([]state.GetAllActorsResult, string, error) {
+	st, err := chn.readWriter.GetTipSetState(ctx, chn.readWriter.GetHead())
+	if err != nil {
+		return nil, "", err
+	}
+	return state.GetActorsPage(ctx, st, cursor, limit)
+}
+
 // GetActorSignature returns the signature of the given actor's given method.
 // The function signature is typically used to enable a caller to decode the
 // output of an actor method call (message).
@@ -5,6 +5,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/syncer"
 )
 
 type chainSync interface {
@@ -12,15 +13,22 @@ type chainSync interface {
 	Status() chain.Status
 }
 
+type syncDispatcher interface {
+	CurrentTarget() (*syncer.Target, bool)
+	HighestTarget() uint64
+}
+
 // ChainSyncProvider provides access to chain sync operations and their status.
 type ChainSyncProvider struct {
-	sync chainSync
+	sync       chainSync
+	dispatcher syncDispatcher
 }
 
 // NewChainSyncProvider returns a new ChainSyncProvider.
-func NewChainSyncProvider(chainSyncer chainSync) *ChainSyncProvider {
+func NewChainSyncProvider(chainSyncer chainSync, dispatcher syncDispatcher) *ChainSyncProvider {
 	return &ChainSyncProvider{
-		sync: chainSyncer,
+		sync:       chainSyncer,
+		dispatcher: dispatcher,
 	}
 }
 
@@ -37,3 +45,15 @@ func (chs *ChainSyncProvider) Status() chain.Status {
 func (chs *ChainSyncProvider) HandleNewTipSet(ctx context.Context, ci *block.ChainInfo, trusted bool) error {
 	return chs.sync.HandleNewTipSet(ctx, ci, trusted)
 }
+
+// CurrentTarget returns the target the sync dispatcher is currently syncing.
+// The second return value is false if the dispatcher is idle.
+func (chs *ChainSyncProvider) CurrentTarget() (*syncer.Target, bool) {
+	return chs.dispatcher.CurrentTarget()
+}
+
+// HighestTarget returns the greatest height claimed by a target of the sync
+// dispatcher that has not failed or been found bad.
+func (chs *ChainSyncProvider) HighestTarget() uint64 {
+	return chs.dispatcher.HighestTarget()
+}
@@ -35,13 +35,22 @@ type Waiter struct {
 	messageProvider chain.MessageProvider
 	cst             *hamt.CborIpldStore
 	bs              bstore.Blockstore
+	// index, if set, is consulted before traversing the chain.
+	index *chain.MessageIndex
 }
 
 // ChainMessage is an on-chain message with its block and receipt.
+// A message that failed to apply in its tipset has no receipt; FailureReason
+// then explains why, and its errors.Cause() is a consensus validation error
+// such as consensus.ErrNonceTooLow.
+// A BLS message is stored unsigned, its signature aggregated into its block's.
+// UnsignedMessage is then set, and Message wraps it with an empty signature.
 type ChainMessage struct {
-	Message *types.SignedMessage
-	Block   *block.Block
-	Receipt *types.MessageReceipt
+	Message         *types.SignedMessage
+	UnsignedMessage *types.UnsignedMessage
+	Block           *block.Block
+	Receipt         *types.MessageReceipt
+	FailureReason   error
 }
 
 // NewWaiter returns a new Waiter.
@@ -54,6 +63,15 @@ func NewWaiter(chainStore waiterChainReader, messages chain.MessageProvider, bs
 	}
 }
 
+// NewWaiterWithIndex returns a new Waiter that looks messages up in `index`
+// before falling back to traversing the chain. The index should be kept
+// current with the head of `chainStore`.
+func NewWaiterWithIndex(chainStore waiterChainReader, messages chain.MessageProvider, bs bstore.Blockstore, cst *hamt.CborIpldStore, index *chain.MessageIndex) *Waiter {
+	w := NewWaiter(chainStore, messages, bs, cst)
+	w.index = index
+	return w
+}
+
 // Find searches the blockchain history for a message (but doesn't wait).
 func (w *Waiter) Find(ctx context.Context, msgCid cid.Cid) (*ChainMessage, bool, error) {
 	headTipSet, err := w.chainReader.GetTipSet(w.chainReader.GetHead())
@@ -72,9 +90,8 @@ func (w *Waiter) Find(ctx context.Context, msgCid cid.Cid) (*ChainMessage, bool,
 // Something like receiptFromTipset is necessary because not every message in
 // a block will have a receipt in the tipset: it might be a duplicate message.
 //
-// TODO: This implementation will become prohibitively expensive since it
-// traverses the entire chain. We should use an index instead.
-// https://github.com/filecoin-project/go-filecoin/issues/1518
+// Without a message index this traverses the entire chain, which becomes
+// prohibitively expensive as the chain grows; see NewWaiterWithIndex.
 func (w *Waiter) Wait(ctx context.Context, msgCid cid.Cid, cb func(*block.Block, *types.SignedMessage, *types.MessageReceipt) error) error {
 	log.Infof("Calling Waiter.Wait CID: %s", msgCid.String())
 
@@ -96,36 +113,216 @@ func (w *Waiter) Wait(ctx context.Context, msgCid cid.Cid, cb func(*block.Block,
 	return err
 }
 
+// WaitForN invokes the callback as each message with one of the given cids
+// appears on chain, and returns once all have appeared, the callback returns
+// an error, or the context is done. The messages already on chain are found
+// in a single traversal of it, and the rest share a single subscription to
+// new heads.
+func (w *Waiter) WaitForN(ctx context.Context, msgCids []cid.Cid, cb func(cid.Cid, *ChainMessage) error) error {
+	ch := w.chainReader.HeadEvents().Sub(chain.NewHeadTopic)
+	defer w.chainReader.HeadEvents().Unsub(ch, chain.NewHeadTopic)
+
+	pending := make(map[cid.Cid]struct{})
+	for _, msgCid := range msgCids {
+		pending[msgCid] = struct{}{}
+	}
+	headTipSet, err := w.chainReader.GetTipSet(w.chainReader.GetHead())
+	if err != nil {
+		return err
+	}
+	if err := w.findPending(ctx, headTipSet, pending, cb); err != nil {
+		return err
+	}
+
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case raw, more := <-ch:
+			if !more {
+				return fmt.Errorf("head events closed with %d messages unconfirmed", len(pending))
+			}
+			switch raw := raw.(type) {
+			case error:
+				log.Errorf("Waiter.WaitForN: %s", raw)
+				return raw
+			case block.TipSet:
+				if err := w.confirmPending(ctx, raw, pending, cb); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unexpected type in channel: %T", raw)
+			}
+		}
+	}
+	return nil
+}
+
+// findPending invokes the callback for each message on the chain ending at
+// `head` with a cid in `pending`, removing it from `pending`. The index, if
+// any, is consulted first, and the chain is then traversed once for the
+// messages it misses, as findMessage does for a single message.
+func (w *Waiter) findPending(ctx context.Context, head block.TipSet, pending map[cid.Cid]struct{}, cb func(cid.Cid, *ChainMessage) error) error {
+	floor := uint64(0)
+	if w.index != nil {
+		authoritative := w.index.Head().Equals(head.Key())
+		for msgCid := range pending {
+			chainMsg, found, err := w.findIndexedMessage(ctx, msgCid)
+			if err != nil {
+				return err
+			}
+			if !found {
+				if _, indexed := w.index.Locate(msgCid); indexed {
+					authoritative = false
+				}
+				continue
+			}
+			delete(pending, msgCid)
+			if err := cb(msgCid, chainMsg); err != nil {
+				return err
+			}
+		}
+		if authoritative {
+			floor = w.index.Floor()
+			if floor == 0 {
+				return nil
+			}
+		}
+	}
+
+	var err error
+	for iterator := chain.IterAncestors(ctx, w.chainReader, head); !iterator.Complete() && len(pending) > 0; err = iterator.Next() {
+		if err != nil {
+			log.Errorf("Waiter.WaitForN: %s", err)
+			return err
+		}
+		if floor > 0 {
+			h, err := iterator.Value().Height()
+			if err != nil {
+				return err
+			}
+			if h >= floor {
+				continue
+			}
+		}
+		if err := w.confirmPending(ctx, iterator.Value(), pending, cb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// confirmPending invokes the callback for each message of `ts` with a cid in
+// `pending`, removing it from `pending`.
+func (w *Waiter) confirmPending(ctx context.Context, ts block.TipSet, pending map[cid.Cid]struct{}, cb func(cid.Cid, *ChainMessage) error) error {
+	for i := 0; i < ts.Len(); i++ {
+		blk := ts.At(i)
+		secpMsgs, blsMsgs, err := w.messageProvider.LoadMessages(ctx, blk.Messages)
+		if err != nil {
+			return err
+		}
+		var included []cid.Cid
+		for _, msg := range secpMsgs {
+			c, err := msg.Cid()
+			if err != nil {
+				return err
+			}
+			included = append(included, c)
+		}
+		for _, msg := range blsMsgs {
+			c, err := msg.Cid()
+			if err != nil {
+				return err
+			}
+			included = append(included, c)
+		}
+
+		for _, c := range included {
+			if _, ok := pending[c]; !ok {
+				continue
+			}
+			chainMsg, found, err := w.findInBlock(ctx, ts, blk, c)
+			if err != nil {
+				return err
+			}
+			if !found {
+				continue
+			}
+			delete(pending, c)
+			if err := cb(c, chainMsg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // findMessage looks for a message CID in the chain and returns the message,
 // block and receipt, when it is found. Returns the found message/block or nil
-// if now block with the given CID exists in the chain.
+// if now block with the given CID exists in the chain. The index, if any, is
+// consulted first. If it was last updated to `ts`, a message it misses is not
+// in any tipset it covers, so only the tipsets below its floor are traversed.
 func (w *Waiter) findMessage(ctx context.Context, ts block.TipSet, msgCid cid.Cid) (*ChainMessage, bool, error) {
+	floor := uint64(0)
+	if w.index != nil {
+		chainMsg, found, err := w.findIndexedMessage(ctx, msgCid)
+		if err != nil {
+			return nil, false, err
+		}
+		if found {
+			return chainMsg, true, nil
+		}
+		if _, indexed := w.index.Locate(msgCid); !indexed && w.index.Head().Equals(ts.Key()) {
+			floor = w.index.Floor()
+			if floor == 0 {
+				return nil, false, nil
+			}
+		}
+	}
+
 	var err error
 	for iterator := chain.IterAncestors(ctx, w.chainReader, ts); !iterator.Complete(); err = iterator.Next() {
 		if err != nil {
 			log.Errorf("Waiter.Wait: %s", err)
 			return nil, false, err
 		}
-		for i := 0; i < iterator.Value().Len(); i++ {
-			blk := iterator.Value().At(i)
-			secpMsgs, _, err := w.messageProvider.LoadMessages(ctx, blk.Messages)
+		if floor > 0 {
+			h, err := iterator.Value().Height()
 			if err != nil {
 				return nil, false, err
 			}
-			for _, msg := range secpMsgs {
-				c, err := msg.Cid()
-				if err != nil {
-					return nil, false, err
-				}
-				if c.Equals(msgCid) {
-					recpt, err := w.receiptFromTipSet(ctx, msgCid, iterator.Value())
-					if err != nil {
-						return nil, false, errors.Wrap(err, "error retrieving receipt from tipset")
-					}
-					return &ChainMessage{msg, blk, recpt}, true, nil
-				}
+			if h >= floor {
+				continue
 			}
 		}
+		chainMsg, found, err := w.findInTipSet(ctx, iterator.Value(), msgCid)
+		if err != nil || found {
+			return chainMsg, found, err
+		}
+	}
+	return nil, false, nil
+}
+
+// findIndexedMessage looks for a message CID in the index and returns the
+// message, block and receipt when it is indexed. A message missing from the
+// index, or indexed at a tipset or block that cannot be loaded, is reported
+// as not found so that the caller falls back to traversal.
+func (w *Waiter) findIndexedMessage(ctx context.Context, msgCid cid.Cid) (*ChainMessage, bool, error) {
+	loc, ok := w.index.Locate(msgCid)
+	if !ok {
+		return nil, false, nil
+	}
+	ts, err := w.chainReader.GetTipSet(loc.TipSet)
+	if err != nil {
+		log.Warnf("failed to load tipset %s of indexed message %s: %s", loc.TipSet, msgCid, err)
+		return nil, false, nil
+	}
+	for i := 0; i < ts.Len(); i++ {
+		blk := ts.At(i)
+		if !blk.Cid().Equals(loc.Block) {
+			continue
+		}
+		return w.findInBlock(ctx, ts, blk, msgCid)
 	}
 	return nil, false, nil
 }
@@ -149,25 +346,9 @@ func (w *Waiter) waitForMessage(ctx context.Context, ch <-chan interface{}, msgC
 				log.Errorf("Waiter.Wait: %s", e)
 				return nil, false, e
 			case block.TipSet:
-				for i := 0; i < raw.Len(); i++ {
-					blk := raw.At(i)
-					secpMsgs, _, err := w.messageProvider.LoadMessages(ctx, blk.Messages)
-					if err != nil {
-						return nil, false, err
-					}
-					for _, msg := range secpMsgs {
-						c, err := msg.Cid()
-						if err != nil {
-							return nil, false, err
-						}
-						if c.Equals(msgCid) {
-							recpt, err := w.receiptFromTipSet(ctx, msgCid, raw)
-							if err != nil {
-								return nil, false, errors.Wrap(err, "error retrieving receipt from tipset")
-							}
-							return &ChainMessage{msg, blk, recpt}, true, nil
-						}
-					}
+				chainMsg, found, err := w.findInTipSet(ctx, raw, msgCid)
+				if err != nil || found {
+					return chainMsg, found, err
 				}
 			default:
 				return nil, false, fmt.Errorf("unexpected type in channel: %T", raw)
@@ -176,11 +357,75 @@ func (w *Waiter) waitForMessage(ctx context.Context, ch <-chan interface{}, msgC
 	}
 }
 
+// findInTipSet looks for a message CID, secp or BLS, in the blocks of `ts`
+// and returns the message, block and receipt when it is found.
+func (w *Waiter) findInTipSet(ctx context.Context, ts block.TipSet, msgCid cid.Cid) (*ChainMessage, bool, error) {
+	for i := 0; i < ts.Len(); i++ {
+		chainMsg, found, err := w.findInBlock(ctx, ts, ts.At(i), msgCid)
+		if err != nil || found {
+			return chainMsg, found, err
+		}
+	}
+	return nil, false, nil
+}
+
+// findInBlock looks for a message CID, secp or BLS, in `blk`, a block of
+// `ts`, and returns the message, block and receipt when it is found.
+func (w *Waiter) findInBlock(ctx context.Context, ts block.TipSet, blk *block.Block, msgCid cid.Cid) (*ChainMessage, bool, error) {
+	secpMsgs, blsMsgs, err := w.messageProvider.LoadMessages(ctx, blk.Messages)
+	if err != nil {
+		return nil, false, err
+	}
+	chainMsg := &ChainMessage{Block: blk}
+	for _, msg := range secpMsgs {
+		c, err := msg.Cid()
+		if err != nil {
+			return nil, false, err
+		}
+		if c.Equals(msgCid) {
+			chainMsg.Message = msg
+			break
+		}
+	}
+	if chainMsg.Message == nil {
+		for _, msg := range blsMsgs {
+			c, err := msg.Cid()
+			if err != nil {
+				return nil, false, err
+			}
+			if c.Equals(msgCid) {
+				chainMsg.Message = &types.SignedMessage{Message: *msg}
+				chainMsg.UnsignedMessage = msg
+				break
+			}
+		}
+	}
+	if chainMsg.Message == nil {
+		return nil, false, nil
+	}
+
+	chainMsg.Receipt, chainMsg.FailureReason, err = w.receiptFromTipSet(ctx, msgCid, ts)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "error retrieving receipt from tipset")
+	}
+	return chainMsg, true, nil
+}
+
 // receiptFromTipSet finds the receipt for the message with msgCid in the
 // input tipset.  This can differ from the message's receipt as stored in its
 // parent block in the case that the message is in conflict with another
-// message of the tipset.
-func (w *Waiter) receiptFromTipSet(ctx context.Context, msgCid cid.Cid, ts block.TipSet) (*types.MessageReceipt, error) {
+// message of the tipset. If the message failed to apply there is no receipt
+// and the apply error is returned as the failure reason.
+func (w *Waiter) receiptFromTipSet(ctx context.Context, msgCid cid.Cid, ts block.TipSet) (_ *types.MessageReceipt, failureReason error, err error) {
+	tsMessages, blsCids, err := w.canonicalMessages(ctx, ts)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The processor knows a BLS message by the CID of its wrapped form.
+	if wrapped, ok := blsCids[msgCid]; ok {
+		msgCid = wrapped
+	}
+
 	// Receipts always match block if tipset has only 1 member.
 	var rcpt *types.MessageReceipt
 	if ts.Len() == 1 {
@@ -189,90 +434,110 @@ func (w *Waiter) receiptFromTipSet(ctx context.Context, msgCid cid.Cid, ts block
 		// Right now doing so breaks tests because our test helpers
 		// don't correctly apply messages when making test chains.
 		//
-		j, err := w.msgIndexOfTipSet(ctx, msgCid, ts, make(map[cid.Cid]struct{}))
+		j, err := msgIndexOfTipSet(msgCid, tsMessages, make(map[cid.Cid]struct{}))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		receipts, err := w.messageProvider.LoadReceipts(ctx, b.MessageReceipts)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if j < len(receipts) {
 			rcpt = receipts[j]
 		}
-		return rcpt, nil
+		return rcpt, nil, nil
 	}
 
 	// Apply all the tipset's messages to determine the correct receipts.
 	ids, err := ts.Parents()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	st, err := w.chainReader.GetTipSetState(ctx, ids)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	tsHeight, err := ts.Height()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	ancestorHeight := types.NewBlockHeight(tsHeight).Sub(types.NewBlockHeight(consensus.AncestorRoundsNeeded))
 	parentTs, err := w.chainReader.GetTipSet(ids)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	ancestors, err := chain.GetRecentAncestors(ctx, parentTs, w.chainReader, ancestorHeight)
 	if err != nil {
-		return nil, err
-	}
-
-	var tsMessages [][]*types.SignedMessage
-	for i := 0; i < ts.Len(); i++ {
-		blk := ts.At(i)
-		secpMsgs, _, err := w.messageProvider.LoadMessages(ctx, blk.Messages)
-		if err != nil {
-			return nil, err
-		}
-		tsMessages = append(tsMessages, secpMsgs)
+		return nil, nil, err
 	}
 
+	// Rewards are paid as on chain, since a message may spend a block reward
+	// paid earlier in the tipset.
 	res, err := consensus.NewDefaultProcessor().ProcessTipSet(ctx, st, vm.NewStorageMap(w.bs), ts, tsMessages, ancestors)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// If this is a failing conflict message there is no application receipt.
 	_, failed := res.Failures[msgCid]
 	if failed {
-		return nil, nil
+		reason := res.FailureReasons[msgCid]
+		log.Infof("message %s failed to apply: %s", msgCid, reason)
+		return nil, reason, nil
 	}
 
-	j, err := w.msgIndexOfTipSet(ctx, msgCid, ts, res.Failures)
+	j, err := msgIndexOfTipSet(msgCid, tsMessages, res.Failures)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	// TODO #3194: out of bounds receipt index should return an error.
 	if j < len(res.Results) {
 		rcpt = res.Results[j].Receipt
 	}
-	return rcpt, nil
+	return rcpt, nil, nil
+}
+
+// canonicalMessages returns the messages of `ts` in the order the processor
+// applies them: one slice per block, holding the block's BLS messages,
+// wrapped with empty signatures, followed by its secp messages. It also maps
+// the CID of each BLS message to that of its wrapped form.
+func (w *Waiter) canonicalMessages(ctx context.Context, ts block.TipSet) ([][]*types.SignedMessage, map[cid.Cid]cid.Cid, error) {
+	var tsMessages [][]*types.SignedMessage
+	blsCids := make(map[cid.Cid]cid.Cid)
+	for i := 0; i < ts.Len(); i++ {
+		secpMsgs, blsMsgs, err := w.messageProvider.LoadMessages(ctx, ts.At(i).Messages)
+		if err != nil {
+			return nil, nil, err
+		}
+		var msgs []*types.SignedMessage
+		for _, msg := range blsMsgs {
+			smsg := &types.SignedMessage{Message: *msg}
+			c, err := msg.Cid()
+			if err != nil {
+				return nil, nil, err
+			}
+			wc, err := smsg.Cid()
+			if err != nil {
+				return nil, nil, err
+			}
+			blsCids[c] = wc
+			msgs = append(msgs, smsg)
+		}
+		tsMessages = append(tsMessages, append(msgs, secpMsgs...))
+	}
+	return tsMessages, blsCids, nil
 }
 
 // msgIndexOfTipSet returns the order in which msgCid appears in the canonical
-// message ordering of the given tipset, or an error if it is not in the
+// message ordering `tsMessages` of a tipset, or an error if it is not in the
 // tipset.
-// TODO: find a better home for this method
-func (w *Waiter) msgIndexOfTipSet(ctx context.Context, msgCid cid.Cid, ts block.TipSet, fails map[cid.Cid]struct{}) (int, error) {
+func msgIndexOfTipSet(msgCid cid.Cid, tsMessages [][]*types.SignedMessage, fails map[cid.Cid]struct{}) (int, error) {
 	duplicates := make(map[cid.Cid]struct{})
 	var msgCnt int
-	for i := 0; i < ts.Len(); i++ {
-		secpMsgs, _, err := w.messageProvider.LoadMessages(ctx, ts.At(i).Messages)
-		if err != nil {
-			return -1, err
-		}
-		for _, msg := range secpMsgs {
+	for _, msgs := range tsMessages {
+		for _, msg := range msgs {
 			c, err := msg.Cid()
 			if err != nil {
 				return -1, err
@@ -2,6 +2,7 @@ package msg
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -12,7 +13,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
@@ -103,6 +106,261 @@ func testWaitNew(ctx context.Context, t *testing.T, cst *hamt.CborIpldStore, cha
 	wg.Wait()
 }
 
+func TestWaitWithIndex(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	d := requiredCommonDeps(t, th.DefaultGenesis)
+	idx := chain.NewMessageIndex()
+	d.chainStore.UseMessageIndex(idx, d.messages)
+	waiter := NewWaiterWithIndex(d.chainStore, d.messages, d.blockstore, d.cst, idx)
+
+	m1, m2 := newSignedMessage(), newSignedMessage()
+	headTipSet, err := d.chainStore.GetTipSet(d.chainStore.GetHead())
+	require.NoError(t, err)
+	chainWithMsgs := newChainWithMessages(d.cst, d.messages, headTipSet, smsgsSet{smsgs{m1}}, smsgsSet{smsgs{m2}})
+	for _, ts := range chainWithMsgs[1:] {
+		require.NoError(t, d.chainStore.PutTipSetAndState(ctx, &chain.TipSetAndState{
+			TipSet:          ts,
+			TipSetStateRoot: ts.ToSlice()[0].StateRoot,
+		}))
+	}
+	head := chainWithMsgs[len(chainWithMsgs)-1]
+	require.NoError(t, d.chainStore.SetHead(ctx, head))
+	<-d.chainStore.MessageIndexBuilt()
+
+	c1, err := m1.Cid()
+	require.NoError(t, err)
+	_, indexed := idx.Get(c1)
+	require.True(t, indexed)
+
+	chainMsg, found, err := waiter.Find(ctx, c1)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.True(t, types.SmsgCidsEqual(m1, chainMsg.Message))
+	assert.Equal(t, chainWithMsgs[1].At(0).Cid(), chainMsg.Block.Cid())
+
+	testWaitHelp(nil, t, waiter, m2, false, nil)
+
+	t.Run("falls back to traversal on a miss", func(t *testing.T) {
+		empty := NewWaiterWithIndex(d.chainStore, d.messages, d.blockstore, d.cst, chain.NewMessageIndex())
+		chainMsg, found, err := empty.Find(ctx, c1)
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.True(t, types.SmsgCidsEqual(m1, chainMsg.Message))
+	})
+
+	t.Run("trusts a miss from a complete and current index", func(t *testing.T) {
+		counting := &countingMessageProvider{MessageProvider: d.messages}
+		trusting := NewWaiterWithIndex(d.chainStore, counting, d.blockstore, d.cst, idx)
+		missing, err := newSignedMessage().Cid()
+		require.NoError(t, err)
+		_, found, err := trusting.Find(ctx, missing)
+		require.NoError(t, err)
+		assert.False(t, found)
+		assert.Equal(t, 0, counting.loads)
+	})
+}
+
+// countingMessageProvider counts the calls to LoadMessages.
+type countingMessageProvider struct {
+	chain.MessageProvider
+	loads int
+}
+
+func (p *countingMessageProvider) LoadMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error) {
+	p.loads++
+	return p.MessageProvider.LoadMessages(ctx, meta)
+}
+
+func TestWaitBLSMessage(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	cst, chainStore, msgStore, waiter := setupTest(t)
+
+	addrs := address.NewForTestGetter()
+	secp := newSignedMessage()
+	bls := types.NewUnsignedMessage(addrs(), addrs(), 0, types.ZeroAttoFIL, "", nil)
+	blsCid, err := bls.Cid()
+	require.NoError(t, err)
+	secpCid, err := secp.Cid()
+	require.NoError(t, err)
+
+	// BLS messages precede secp ones in the block's receipts.
+	txMeta, err := msgStore.StoreMessages(ctx, []*types.SignedMessage{secp}, []*types.UnsignedMessage{bls})
+	require.NoError(t, err)
+	rcptsCid, err := msgStore.StoreReceipts(ctx, []*types.MessageReceipt{{ExitCode: 1}, {ExitCode: 2}})
+	require.NoError(t, err)
+	headTipSet, err := chainStore.GetTipSet(chainStore.GetHead())
+	require.NoError(t, err)
+	height, err := headTipSet.Height()
+	require.NoError(t, err)
+	child := &block.Block{
+		Messages:        txMeta,
+		MessageReceipts: rcptsCid,
+		Parents:         headTipSet.Key(),
+		Height:          types.Uint64(height + 1),
+		StateRoot:       types.CidFromString(t, "bls-state"),
+	}
+	mustPut(cst, child)
+	ts, err := block.NewTipSet(child)
+	require.NoError(t, err)
+	require.NoError(t, chainStore.PutTipSetAndState(ctx, &chain.TipSetAndState{TipSet: ts, TipSetStateRoot: child.StateRoot}))
+	require.NoError(t, chainStore.SetHead(ctx, ts))
+
+	chainMsg, found, err := waiter.Find(ctx, blsCid)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, bls, chainMsg.UnsignedMessage)
+	assert.Equal(t, *bls, chainMsg.Message.Message)
+	assert.Equal(t, uint8(1), chainMsg.Receipt.ExitCode)
+
+	chainMsg, found, err = waiter.Find(ctx, secpCid)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Nil(t, chainMsg.UnsignedMessage)
+	assert.Equal(t, uint8(2), chainMsg.Receipt.ExitCode)
+
+	err = waiter.Wait(ctx, blsCid, func(b *block.Block, msg *types.SignedMessage, rcpt *types.MessageReceipt) error {
+		assert.Equal(t, child.Cid(), b.Cid())
+		assert.Equal(t, *bls, msg.Message)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestWaitMultiBlockTipSet(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	signer, _ := types.NewMockSignersAndKeyInfo(4)
+	addrs := address.NewForTestGetter()
+	owner, recipient := addrs(), addrs()
+	minerAddr, err := consensus.MiningNodeAddress(owner)
+	require.NoError(t, err)
+	funded := types.NewAttoFILFromFIL(100)
+	d := requiredCommonDeps(t, consensus.MakeGenesisFunc(
+		consensus.ActorAccount(signer.Addresses[0], funded),
+		consensus.ActorAccount(signer.Addresses[1], funded),
+		consensus.ActorAccount(signer.Addresses[2], funded),
+		consensus.ActorAccount(signer.Addresses[3], funded),
+		consensus.MiningNode(owner, owner, funded, types.NewBytesAmount(1024)),
+	))
+	waiter := NewWaiter(d.chainStore, d.messages, d.blockstore, d.cst)
+
+	transfer := func(from address.Address) *types.UnsignedMessage {
+		return types.NewMeteredMessage(from, recipient, 0, types.NewAttoFILFromFIL(1), "", nil, types.NewGasPrice(0), types.NewGasUnits(0))
+	}
+	secpMsg := func(from address.Address) *types.SignedMessage {
+		smsg, err := types.NewSignedMessage(*transfer(from), &signer)
+		require.NoError(t, err)
+		return smsg
+	}
+
+	// Each block carries a secp and a BLS message from its own senders, so
+	// the tipset's messages all apply whatever the order of its blocks.
+	headTipSet, err := d.chainStore.GetTipSet(d.chainStore.GetHead())
+	require.NoError(t, err)
+	emptyReceipts, err := d.messages.StoreReceipts(ctx, []*types.MessageReceipt{})
+	require.NoError(t, err)
+	var blocks []*block.Block
+	var secpCids, blsCids []cid.Cid
+	for i := 0; i < 2; i++ {
+		secp := secpMsg(signer.Addresses[i])
+		bls := transfer(signer.Addresses[i+2])
+		txMeta, err := d.messages.StoreMessages(ctx, []*types.SignedMessage{secp}, []*types.UnsignedMessage{bls})
+		require.NoError(t, err)
+		blk := &block.Block{
+			Miner:           minerAddr,
+			Messages:        txMeta,
+			MessageReceipts: emptyReceipts,
+			Parents:         headTipSet.Key(),
+			Height:          types.Uint64(1),
+			StateRoot:       types.CidFromString(t, fmt.Sprintf("multi-block-state-%d", i)),
+		}
+		mustPut(d.cst, blk)
+		blocks = append(blocks, blk)
+
+		secpCid, err := secp.Cid()
+		require.NoError(t, err)
+		blsCid, err := bls.Cid()
+		require.NoError(t, err)
+		secpCids = append(secpCids, secpCid)
+		blsCids = append(blsCids, blsCid)
+	}
+	ts, err := block.NewTipSet(blocks...)
+	require.NoError(t, err)
+	require.NoError(t, d.chainStore.PutTipSetAndState(ctx, &chain.TipSetAndState{TipSet: ts, TipSetStateRoot: blocks[0].StateRoot}))
+	require.NoError(t, d.chainStore.SetHead(ctx, ts))
+
+	for _, c := range append(secpCids, blsCids...) {
+		chainMsg, found, err := waiter.Find(ctx, c)
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.NoError(t, chainMsg.FailureReason)
+		require.NotNil(t, chainMsg.Receipt, "no receipt for %s", c)
+		assert.Equal(t, uint8(0), chainMsg.Receipt.ExitCode)
+	}
+}
+
+func TestWaitForN(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	cst, chainStore, msgStore, waiter := setupTest(t)
+
+	// m1 is on chain before waiting, m2 and m3 arrive after.
+	m1, m2, m3 := newSignedMessage(), newSignedMessage(), newSignedMessage()
+	headTipSet, err := chainStore.GetTipSet(chainStore.GetHead())
+	require.NoError(t, err)
+	chainWithMsgs := newChainWithMessages(cst, msgStore, headTipSet, smsgsSet{smsgs{m1}}, smsgsSet{smsgs{m2, m3}})
+	for _, ts := range chainWithMsgs[1:] {
+		require.NoError(t, chainStore.PutTipSetAndState(ctx, &chain.TipSetAndState{
+			TipSet:          ts,
+			TipSetStateRoot: ts.ToSlice()[0].StateRoot,
+		}))
+	}
+	require.NoError(t, chainStore.SetHead(ctx, chainWithMsgs[1]))
+
+	var cids []cid.Cid
+	for _, msg := range []*types.SignedMessage{m1, m2, m3} {
+		c, err := msg.Cid()
+		require.NoError(t, err)
+		cids = append(cids, c)
+	}
+
+	done := make(chan error)
+	var mu sync.Mutex
+	confirmed := make(map[cid.Cid]*ChainMessage)
+	go func() {
+		done <- waiter.WaitForN(ctx, cids, func(c cid.Cid, chainMsg *ChainMessage) error {
+			mu.Lock()
+			defer mu.Unlock()
+			confirmed[c] = chainMsg
+			return nil
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, chainStore.SetHead(ctx, chainWithMsgs[2]))
+
+	require.NoError(t, <-done)
+	require.Equal(t, 3, len(confirmed))
+	assert.True(t, types.SmsgCidsEqual(m1, confirmed[cids[0]].Message))
+	assert.True(t, types.SmsgCidsEqual(m2, confirmed[cids[1]].Message))
+	assert.True(t, types.SmsgCidsEqual(m3, confirmed[cids[2]].Message))
+
+	t.Run("returns when context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		missing := newSignedMessage()
+		c, err := missing.Cid()
+		require.NoError(t, err)
+		err = waiter.WaitForN(ctx, []cid.Cid{c}, func(cid.Cid, *ChainMessage) error { return nil })
+		assert.Equal(t, context.Canceled, err)
+	})
+}
+
 func TestWaitError(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -154,6 +412,32 @@ func TestWaitRespectsContextCancel(t *testing.T) {
 	}
 }
 
+func TestWaitOnFakeStore(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	builder := chain.NewBuilder(t, address.Undef)
+	genesis := builder.NewGenesis()
+	store := chain.NewFakeStore(builder)
+	require.NoError(t, store.SetHead(ctx, genesis))
+
+	// Receipts of single-block tipsets are read from the block, so no blockstore or state
+	// store is needed.
+	waiter := NewWaiter(store, builder, nil, nil)
+
+	msg := newSignedMessage()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go testWaitHelp(&wg, t, waiter, msg, false, nil)
+
+	ts := builder.BuildOneOn(genesis, func(b *chain.BlockBuilder) {
+		b.AddMessages([]*types.SignedMessage{msg}, []*types.UnsignedMessage{}, types.EmptyReceipts(1))
+	})
+	require.NoError(t, store.SetHead(ctx, ts))
+
+	wg.Wait()
+}
+
 // NewChainWithMessages creates a chain of tipsets containing the given messages
 // and stores them in the given store.  Note the msg arguments are slices of
 // slices of messages -- each slice of slices goes into a successive tipset,
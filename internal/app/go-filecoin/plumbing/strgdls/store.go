@@ -1,23 +1,67 @@
 package strgdls
 
 import (
+	"strconv"
+	"sync"
+
+	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/query"
 	"github.com/pkg/errors"
 
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/storagedeal"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
 )
 
+var (
+	// ErrDealNotFound is returned when no deal is stored for a proposal cid.
+	ErrDealNotFound = errors.New("deal not found")
+	// ErrDealCorrupt is returned when a stored deal cannot be decoded.
+	ErrDealCorrupt = errors.New("stored deal is corrupt")
+	// ErrDealStoreUnavailable is returned when the underlying datastore fails.
+	ErrDealStoreUnavailable = errors.New("deal datastore unavailable")
+)
+
 // Store is plumbing implementation querying deals
+//
+// A Store is safe for concurrent use. Each operation holds the store's lock
+// for its duration, so a write of a deal together with its version and index
+// entries is never observed in part by a read. Iterators are snapshots taken
+// under the lock and do not observe later writes.
 type Store struct {
+	lk      sync.RWMutex
 	dealsDs repo.Datastore
 }
 
 // StorageDealPrefix is the datastore prefix for storage deals
 const StorageDealPrefix = "storagedeals"
 
+// DealVersionPrefix is the datastore prefix for the schema versions of stored
+// deals. Versions are kept apart from the deals so that deal records remain
+// plain encodings of storagedeal.Deal.
+const DealVersionPrefix = "dealversions"
+
+// MinerIndexPrefix is the datastore prefix for the index of deals by miner
+const MinerIndexPrefix = "dealsbyminer"
+
+// ClientIndexPrefix is the datastore prefix for the index of deals by client
+const ClientIndexPrefix = "dealsbyclient"
+
+// CurrentDealVersion is the schema version of deals written by Put. Deals
+// stored without a version are version 0.
+const CurrentDealVersion = 2
+
+// DefaultMigrations upgrade stored deals to CurrentDealVersion. Version 1
+// introduced version records and version 2 the counterparty indexes, which
+// are written along with every migrated deal. Neither changed the shape of
+// storagedeal.Deal.
+var DefaultMigrations = map[int]func([]byte) ([]byte, error){
+	0: func(raw []byte) ([]byte, error) { return raw, nil },
+	1: func(raw []byte) ([]byte, error) { return raw, nil },
+}
+
 // New returns a new Store.
 func New(dealsDatastore repo.Datastore) *Store {
 	return &Store{dealsDs: dealsDatastore}
@@ -25,13 +69,53 @@ func New(dealsDatastore repo.Datastore) *Store {
 
 // Iterator returns an iterator with deals matching the given query
 func (store *Store) Iterator() (*query.Results, error) {
-	results, err := store.dealsDs.Query(query.Query{Prefix: "/" + StorageDealPrefix})
+	store.lk.RLock()
+	defer store.lk.RUnlock()
+
+	entries, err := store.entries()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to query deals from datastore")
+		return nil, err
 	}
+	results := query.ResultsWithEntries(query.Query{Prefix: "/" + StorageDealPrefix}, entries)
 	return &results, nil
 }
 
+// entries reads all stored deals.
+func (store *Store) entries() ([]query.Entry, error) {
+	results, err := store.dealsDs.Query(query.Query{Prefix: "/" + StorageDealPrefix})
+	if err != nil {
+		return nil, unavailable(err, "failed to query deals from datastore")
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, unavailable(err, "failed to read deals from datastore")
+	}
+	return entries, nil
+}
+
+// Get returns the deal for the proposal cid. It returns ErrDealNotFound if
+// there is no such deal and ErrDealCorrupt if the deal cannot be decoded.
+func (store *Store) Get(proposalCid cid.Cid) (*storagedeal.Deal, error) {
+	store.lk.RLock()
+	defer store.lk.RUnlock()
+	return store.get(proposalCid.String())
+}
+
+func (store *Store) get(proposalCid string) (*storagedeal.Deal, error) {
+	datum, err := store.dealsDs.Get(dealKey(proposalCid))
+	if err == datastore.ErrNotFound {
+		return nil, errors.Wrapf(ErrDealNotFound, "no deal for proposal %s", proposalCid)
+	}
+	if err != nil {
+		return nil, unavailable(err, "could not read stored deal")
+	}
+	var storageDeal storagedeal.Deal
+	if err := encoding.Decode(datum, &storageDeal); err != nil {
+		return nil, errors.Wrapf(ErrDealCorrupt, "could not unmarshal deal for proposal %s: %s", proposalCid, err)
+	}
+	return &storageDeal, nil
+}
+
 // Put puts the deal into the datastore
 func (store *Store) Put(storageDeal *storagedeal.Deal) error {
 	proposalCid := storageDeal.Response.ProposalCid
@@ -40,11 +124,234 @@ func (store *Store) Put(storageDeal *storagedeal.Deal) error {
 		return errors.Wrap(err, "could not marshal storageDeal")
 	}
 
-	key := datastore.KeyWithNamespaces([]string{StorageDealPrefix, proposalCid.String()})
-	err = store.dealsDs.Put(key, datum)
+	store.lk.Lock()
+	defer store.lk.Unlock()
+
+	// Re-putting a deal whose counterparties changed must not leave it indexed
+	// under the old ones. A corrupt prior deal is overwritten, though its
+	// index entries cannot be found to be removed.
+	prior, err := store.get(proposalCid.String())
+	switch errors.Cause(err) {
+	case nil:
+	case ErrDealNotFound, ErrDealCorrupt:
+		prior = nil
+	default:
+		return err
+	}
+	return store.write(proposalCid.String(), datum, storageDeal, prior)
+}
+
+// Delete removes the deal for the proposal cid along with its version and
+// index entries. It returns ErrDealNotFound if there is no such deal.
+func (store *Store) Delete(proposalCid cid.Cid) error {
+	store.lk.Lock()
+	defer store.lk.Unlock()
+
+	key := proposalCid.String()
+	prior, err := store.get(key)
+	switch errors.Cause(err) {
+	case nil:
+	case ErrDealCorrupt:
+		prior = nil
+	default:
+		return err
+	}
+
+	batch, err := store.dealsDs.Batch()
 	if err != nil {
-		return errors.Wrap(err, "could not save storage deal to disk")
+		return unavailable(err, "could not create batch")
+	}
+	keys := []datastore.Key{dealKey(key), versionKey(key)}
+	if prior != nil {
+		keys = append(keys, indexKeys(key, prior)...)
 	}
+	for _, k := range keys {
+		if err := batch.Delete(k); err != nil {
+			return unavailable(err, "could not delete storage deal")
+		}
+	}
+	if err := batch.Commit(); err != nil {
+		return unavailable(err, "could not delete storage deal")
+	}
+	return nil
+}
+
+// IterateByMiner returns an iterator over the deals made with the miner.
+func (store *Store) IterateByMiner(miner address.Address) (*query.Results, error) {
+	return store.iterateIndex(indexKey(MinerIndexPrefix, miner))
+}
 
+// IterateByClient returns an iterator over the deals paid for by the client.
+func (store *Store) IterateByClient(client address.Address) (*query.Results, error) {
+	return store.iterateIndex(indexKey(ClientIndexPrefix, client))
+}
+
+func (store *Store) iterateIndex(prefix datastore.Key) (*query.Results, error) {
+	store.lk.RLock()
+	defer store.lk.RUnlock()
+
+	q := query.Query{Prefix: prefix.String(), KeysOnly: true}
+	results, err := store.dealsDs.Query(q)
+	if err != nil {
+		return nil, unavailable(err, "failed to query deal index from datastore")
+	}
+	indexed, err := results.Rest()
+	if err != nil {
+		return nil, unavailable(err, "failed to read deal index from datastore")
+	}
+
+	var entries []query.Entry
+	for _, entry := range indexed {
+		// The prefix also matches addresses extending this one.
+		indexKey := datastore.NewKey(entry.Key)
+		if !indexKey.Parent().Equal(prefix) {
+			continue
+		}
+		key := dealKey(indexKey.BaseNamespace())
+		datum, err := store.dealsDs.Get(key)
+		if err != nil {
+			return nil, unavailable(err, "failed to read indexed deal "+key.String())
+		}
+		entries = append(entries, query.Entry{Key: key.String(), Value: datum})
+	}
+	deals := query.ResultsWithEntries(query.Query{Prefix: "/" + StorageDealPrefix}, entries)
+	return &deals, nil
+}
+
+// write saves the encoded deal, its version and its index entries in one
+// batch, removing the index entries of the prior deal it replaces, if any.
+func (store *Store) write(proposalCid string, datum []byte, storageDeal *storagedeal.Deal, prior *storagedeal.Deal) error {
+	batch, err := store.dealsDs.Batch()
+	if err != nil {
+		return unavailable(err, "could not create batch")
+	}
+
+	keys := indexKeys(proposalCid, storageDeal)
+	if prior != nil {
+		current := make(map[datastore.Key]struct{}, len(keys))
+		for _, key := range keys {
+			current[key] = struct{}{}
+		}
+		for _, key := range indexKeys(proposalCid, prior) {
+			if _, ok := current[key]; ok {
+				continue
+			}
+			if err := batch.Delete(key); err != nil {
+				return unavailable(err, "could not remove deal index entry")
+			}
+		}
+	}
+	if err := batch.Put(dealKey(proposalCid), datum); err != nil {
+		return unavailable(err, "could not save storage deal to disk")
+	}
+	if err := batch.Put(versionKey(proposalCid), encodeVersion(CurrentDealVersion)); err != nil {
+		return unavailable(err, "could not save storage deal version to disk")
+	}
+	for _, key := range keys {
+		if err := batch.Put(key, []byte{}); err != nil {
+			return unavailable(err, "could not save deal index entry")
+		}
+	}
+	if err := batch.Commit(); err != nil {
+		return unavailable(err, "could not commit storage deal")
+	}
+	return nil
+}
+
+// Version returns the schema version of the stored deal with the given
+// proposal cid key. A deal stored without a version is version 0.
+func (store *Store) Version(proposalCid string) (int, error) {
+	store.lk.RLock()
+	defer store.lk.RUnlock()
+	return store.version(proposalCid)
+}
+
+func (store *Store) version(proposalCid string) (int, error) {
+	raw, err := store.dealsDs.Get(versionKey(proposalCid))
+	if err == datastore.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, unavailable(err, "could not read storage deal version")
+	}
+	return strconv.Atoi(string(raw))
+}
+
+// Migrate upgrades every stored deal older than CurrentDealVersion by applying
+// the migrator for each version in turn, where migrators[v] upgrades a record
+// from version v to v+1. Migrated deals are indexed by counterparty. It fails
+// without writing the deal if a migrator is missing.
+func (store *Store) Migrate(migrators map[int]func([]byte) ([]byte, error)) error {
+	store.lk.Lock()
+	defer store.lk.Unlock()
+
+	entries, err := store.entries()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		proposalCid := datastore.NewKey(entry.Key).BaseNamespace()
+		version, err := store.version(proposalCid)
+		if err != nil {
+			return err
+		}
+		if version >= CurrentDealVersion {
+			continue
+		}
+
+		datum := entry.Value
+		for v := version; v < CurrentDealVersion; v++ {
+			migrate, ok := migrators[v]
+			if !ok {
+				return errors.Errorf("no migration from deal version %d for deal %s", v, proposalCid)
+			}
+			if datum, err = migrate(datum); err != nil {
+				return errors.Wrapf(err, "failed to migrate deal %s from version %d", proposalCid, v)
+			}
+		}
+
+		var migratedDeal storagedeal.Deal
+		if err := encoding.Decode(datum, &migratedDeal); err != nil {
+			return errors.Wrapf(ErrDealCorrupt, "could not unmarshal migrated deal %s: %s", proposalCid, err)
+		}
+		// The record being replaced may not decode in the current shape, so
+		// its index entries are not removed. Migrations must preserve a
+		// deal's counterparties.
+		if err := store.write(proposalCid, datum, &migratedDeal, nil); err != nil {
+			return errors.Wrapf(err, "could not save migrated deal %s", proposalCid)
+		}
+	}
 	return nil
 }
+
+func dealKey(proposalCid string) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{StorageDealPrefix, proposalCid})
+}
+
+func versionKey(proposalCid string) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{DealVersionPrefix, proposalCid})
+}
+
+func indexKey(prefix string, addr address.Address) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{prefix, addr.String()})
+}
+
+// indexKeys returns the index entries of a deal. Deals without a client are
+// not indexed by client.
+func indexKeys(proposalCid string, storageDeal *storagedeal.Deal) []datastore.Key {
+	keys := []datastore.Key{indexKey(MinerIndexPrefix, storageDeal.Miner).ChildString(proposalCid)}
+	if storageDeal.Proposal != nil && !storageDeal.Proposal.Payment.Payer.Empty() {
+		keys = append(keys, indexKey(ClientIndexPrefix, storageDeal.Proposal.Payment.Payer).ChildString(proposalCid))
+	}
+	return keys
+}
+
+// unavailable wraps a datastore failure as ErrDealStoreUnavailable.
+func unavailable(err error, msg string) error {
+	return errors.Wrapf(ErrDealStoreUnavailable, "%s: %s", msg, err)
+}
+
+func encodeVersion(v int) []byte {
+	return []byte(strconv.Itoa(v))
+}
@@ -1,8 +1,13 @@
 package strgdls_test
 
 import (
+	"sync"
 	"testing"
 
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -101,3 +106,289 @@ func TestDealStoreRoundTrip(t *testing.T) {
 	assert.Equal(t, totalPrice, retrievedDeal.Proposal.Payment.Vouchers[0].Amount)
 	assert.Equal(t, *validAt, retrievedDeal.Proposal.Payment.Vouchers[0].ValidAt)
 }
+
+func TestDealStoreMigrate(t *testing.T) {
+	tf.UnitTest(t)
+
+	cidGetter := types.NewCidForTestGetter()
+	proposalCid := cidGetter()
+	v0Deal := &storagedeal.Deal{
+		Miner: address.NewForTestGetter()(),
+		Response: &storagedeal.SignedResponse{
+			Response: storagedeal.Response{
+				State:       storagedeal.Accepted,
+				ProposalCid: proposalCid,
+			},
+		},
+	}
+
+	// writeV0 stores a deal as it was written before deals carried a version.
+	writeV0 := func(ds repo.Datastore) {
+		datum, err := encoding.Encode(v0Deal)
+		require.NoError(t, err)
+		require.NoError(t, ds.Put(datastore.KeyWithNamespaces([]string{strgdls.StorageDealPrefix, proposalCid.String()}), datum))
+	}
+
+	t.Run("upgrades v0 records", func(t *testing.T) {
+		ds := repo.NewInMemoryRepo().DealsDs
+		writeV0(ds)
+		store := strgdls.New(ds)
+
+		version, err := store.Version(proposalCid.String())
+		require.NoError(t, err)
+		assert.Equal(t, 0, version)
+
+		migrated := 0
+		require.NoError(t, store.Migrate(map[int]func([]byte) ([]byte, error){
+			0: func(raw []byte) ([]byte, error) {
+				migrated++
+				var deal storagedeal.Deal
+				if err := encoding.Decode(raw, &deal); err != nil {
+					return nil, err
+				}
+				deal.Response.Message = "migrated"
+				return encoding.Encode(deal)
+			},
+			1: func(raw []byte) ([]byte, error) { return raw, nil },
+		}))
+		assert.Equal(t, 1, migrated)
+
+		version, err = store.Version(proposalCid.String())
+		require.NoError(t, err)
+		assert.Equal(t, strgdls.CurrentDealVersion, version)
+
+		dealIterator, err := store.Iterator()
+		require.NoError(t, err)
+		var retrievedDeal storagedeal.Deal
+		require.NoError(t, encoding.Decode((<-(*dealIterator).Next()).Value, &retrievedDeal))
+		assert.Equal(t, "migrated", retrievedDeal.Response.Message)
+
+		// Current records are left alone.
+		require.NoError(t, store.Migrate(map[int]func([]byte) ([]byte, error){}))
+	})
+
+	t.Run("fails without a migrator", func(t *testing.T) {
+		ds := repo.NewInMemoryRepo().DealsDs
+		writeV0(ds)
+		store := strgdls.New(ds)
+
+		assert.Error(t, store.Migrate(map[int]func([]byte) ([]byte, error){}))
+		version, err := store.Version(proposalCid.String())
+		require.NoError(t, err)
+		assert.Equal(t, 0, version)
+	})
+
+	t.Run("put writes the current version", func(t *testing.T) {
+		store := strgdls.New(repo.NewInMemoryRepo().DealsDs)
+		require.NoError(t, store.Put(v0Deal))
+		version, err := store.Version(proposalCid.String())
+		require.NoError(t, err)
+		assert.Equal(t, strgdls.CurrentDealVersion, version)
+	})
+}
+
+func TestDealStoreIndexes(t *testing.T) {
+	tf.UnitTest(t)
+
+	addressMaker := address.NewForTestGetter()
+	cidGetter := types.NewCidForTestGetter()
+	minerA, minerB := addressMaker(), addressMaker()
+	clientA, clientB := addressMaker(), addressMaker()
+
+	store := strgdls.New(repo.NewInMemoryRepo().DealsDs)
+	newDeal := func(miner, client address.Address) *storagedeal.Deal {
+		return &storagedeal.Deal{
+			Miner: miner,
+			Proposal: &storagedeal.SignedProposal{
+				Proposal: storagedeal.Proposal{
+					PieceRef:     cidGetter(),
+					Size:         types.NewBytesAmount(1),
+					TotalPrice:   types.NewAttoFILFromFIL(1),
+					MinerAddress: miner,
+					Payment:      storagedeal.PaymentInfo{Payer: client},
+				},
+			},
+			Response: &storagedeal.SignedResponse{
+				Response: storagedeal.Response{
+					State:       storagedeal.Accepted,
+					ProposalCid: cidGetter(),
+				},
+			},
+		}
+	}
+	d1, d2, d3 := newDeal(minerA, clientA), newDeal(minerA, clientB), newDeal(minerB, clientA)
+	for _, d := range []*storagedeal.Deal{d1, d2, d3} {
+		require.NoError(t, store.Put(d))
+	}
+
+	proposalCids := func(results *query.Results) []cid.Cid {
+		entries, err := (*results).Rest()
+		require.NoError(t, err)
+		var cids []cid.Cid
+		for _, entry := range entries {
+			var deal storagedeal.Deal
+			require.NoError(t, encoding.Decode(entry.Value, &deal))
+			cids = append(cids, deal.Response.ProposalCid)
+		}
+		return cids
+	}
+	byMiner := func(miner address.Address) []cid.Cid {
+		results, err := store.IterateByMiner(miner)
+		require.NoError(t, err)
+		return proposalCids(results)
+	}
+	byClient := func(client address.Address) []cid.Cid {
+		results, err := store.IterateByClient(client)
+		require.NoError(t, err)
+		return proposalCids(results)
+	}
+
+	assert.ElementsMatch(t, []cid.Cid{d1.Response.ProposalCid, d2.Response.ProposalCid}, byMiner(minerA))
+	assert.ElementsMatch(t, []cid.Cid{d3.Response.ProposalCid}, byMiner(minerB))
+	assert.ElementsMatch(t, []cid.Cid{d1.Response.ProposalCid, d3.Response.ProposalCid}, byClient(clientA))
+	assert.ElementsMatch(t, []cid.Cid{d2.Response.ProposalCid}, byClient(clientB))
+
+	// Re-putting a deal does not duplicate its index entries.
+	d1.Response.State = storagedeal.Complete
+	require.NoError(t, store.Put(d1))
+	assert.ElementsMatch(t, []cid.Cid{d1.Response.ProposalCid, d2.Response.ProposalCid}, byMiner(minerA))
+	assert.ElementsMatch(t, []cid.Cid{d1.Response.ProposalCid, d3.Response.ProposalCid}, byClient(clientA))
+
+	// Re-putting a deal with a new miner moves it in the miner index.
+	d2.Miner = minerB
+	require.NoError(t, store.Put(d2))
+	assert.ElementsMatch(t, []cid.Cid{d1.Response.ProposalCid}, byMiner(minerA))
+	assert.ElementsMatch(t, []cid.Cid{d2.Response.ProposalCid, d3.Response.ProposalCid}, byMiner(minerB))
+}
+
+func TestDealStoreErrors(t *testing.T) {
+	tf.UnitTest(t)
+
+	cidGetter := types.NewCidForTestGetter()
+
+	t.Run("missing deal", func(t *testing.T) {
+		store := strgdls.New(repo.NewInMemoryRepo().DealsDs)
+		_, err := store.Get(cidGetter())
+		assert.Equal(t, strgdls.ErrDealNotFound, errors.Cause(err))
+		assert.Equal(t, strgdls.ErrDealNotFound, errors.Cause(store.Delete(cidGetter())))
+	})
+
+	t.Run("corrupt deal", func(t *testing.T) {
+		ds := repo.NewInMemoryRepo().DealsDs
+		proposalCid := cidGetter()
+		key := datastore.KeyWithNamespaces([]string{strgdls.StorageDealPrefix, proposalCid.String()})
+		require.NoError(t, ds.Put(key, []byte("not a deal")))
+
+		store := strgdls.New(ds)
+		_, err := store.Get(proposalCid)
+		assert.Equal(t, strgdls.ErrDealCorrupt, errors.Cause(err))
+
+		// A corrupt deal can still be deleted.
+		require.NoError(t, store.Delete(proposalCid))
+		_, err = store.Get(proposalCid)
+		assert.Equal(t, strgdls.ErrDealNotFound, errors.Cause(err))
+	})
+
+	t.Run("delete removes index entries", func(t *testing.T) {
+		store := strgdls.New(repo.NewInMemoryRepo().DealsDs)
+		miner := address.NewForTestGetter()()
+		proposalCid := cidGetter()
+		require.NoError(t, store.Put(&storagedeal.Deal{
+			Miner: miner,
+			Response: &storagedeal.SignedResponse{
+				Response: storagedeal.Response{ProposalCid: proposalCid},
+			},
+		}))
+
+		deal, err := store.Get(proposalCid)
+		require.NoError(t, err)
+		assert.Equal(t, miner, deal.Miner)
+
+		require.NoError(t, store.Delete(proposalCid))
+		results, err := store.IterateByMiner(miner)
+		require.NoError(t, err)
+		entries, err := (*results).Rest()
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestDealStoreConcurrentAccess(t *testing.T) {
+	tf.UnitTest(t)
+
+	const writers, dealsPerWriter, readers = 4, 25, 4
+
+	store := strgdls.New(repo.NewInMemoryRepo().DealsDs)
+	miner := address.NewForTestGetter()()
+	cidGetter := types.NewCidForTestGetter()
+	proposalCids := make([][]cid.Cid, writers)
+	for w := range proposalCids {
+		for i := 0; i < dealsPerWriter; i++ {
+			proposalCids[w] = append(proposalCids[w], cidGetter())
+		}
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for _, proposalCid := range proposalCids[w] {
+				assert.NoError(t, store.Put(&storagedeal.Deal{
+					Miner: miner,
+					Response: &storagedeal.SignedResponse{
+						Response: storagedeal.Response{ProposalCid: proposalCid},
+					},
+				}))
+			}
+		}(w)
+	}
+
+	var readersWg sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		readersWg.Add(1)
+		go func() {
+			defer readersWg.Done()
+			last := 0
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				results, err := store.Iterator()
+				if !assert.NoError(t, err) {
+					return
+				}
+				entries, err := (*results).Rest()
+				if !assert.NoError(t, err) {
+					return
+				}
+				for _, entry := range entries {
+					var deal storagedeal.Deal
+					assert.NoError(t, encoding.Decode(entry.Value, &deal))
+				}
+				// Deals are only added, so no snapshot is smaller than an earlier one.
+				assert.True(t, len(entries) >= last)
+				last = len(entries)
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readersWg.Wait()
+
+	results, err := store.Iterator()
+	require.NoError(t, err)
+	entries, err := (*results).Rest()
+	require.NoError(t, err)
+	assert.Len(t, entries, writers*dealsPerWriter)
+
+	results, err = store.IterateByMiner(miner)
+	require.NoError(t, err)
+	entries, err = (*results).Rest()
+	require.NoError(t, err)
+	assert.Len(t, entries, writers*dealsPerWriter)
+}
@@ -0,0 +1,38 @@
+package porcelain
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/actor"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+)
+
+// ErrActorNotFound is returned when there is no actor at the requested address
+var ErrActorNotFound = errors.New("actor not found")
+
+type actorGetPlumbing interface {
+	ChainHeadKey() block.TipSetKey
+	ActorGetAt(ctx context.Context, addr address.Address, key block.TipSetKey) (*actor.Actor, error)
+}
+
+// ActorGet returns the actor at the given address in the state of the head tipset.
+func ActorGet(ctx context.Context, plumbing actorGetPlumbing, addr address.Address) (*actor.Actor, error) {
+	return ActorGetAt(ctx, plumbing, addr, plumbing.ChainHeadKey())
+}
+
+// ActorGetAt returns the actor at the given address in the state of the tipset
+// with the given key. ErrActorNotFound is returned if there is no such actor.
+func ActorGetAt(ctx context.Context, plumbing actorGetPlumbing, addr address.Address, key block.TipSetKey) (*actor.Actor, error) {
+	act, err := plumbing.ActorGetAt(ctx, addr, key)
+	if err != nil {
+		if state.IsActorNotFoundError(err) {
+			return nil, ErrActorNotFound
+		}
+		return nil, err
+	}
+	return act, nil
+}
@@ -0,0 +1,79 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-hamt-ipld"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/actor"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/actor/builtin/account"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+type testActorGetPlumbing struct {
+	head   block.TipSetKey
+	states map[string]state.Tree
+}
+
+func (tagp *testActorGetPlumbing) ChainHeadKey() block.TipSetKey {
+	return tagp.head
+}
+
+func (tagp *testActorGetPlumbing) ActorGetAt(ctx context.Context, addr address.Address, key block.TipSetKey) (*actor.Actor, error) {
+	return tagp.states[key.String()].GetActor(ctx, addr)
+}
+
+func TestActorGet(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	addrGetter := address.NewForTestGetter()
+	addr := addrGetter()
+	cidGetter := types.NewCidForTestGetter()
+	earlyKey := block.NewTipSetKey(cidGetter())
+	headKey := block.NewTipSetKey(cidGetter())
+
+	cst := hamt.NewCborStore()
+	earlyState := state.NewEmptyStateTree(cst)
+	earlyActor, err := account.NewActor(types.NewAttoFILFromFIL(10))
+	require.NoError(t, err)
+	require.NoError(t, earlyState.SetActor(ctx, addr, earlyActor))
+
+	headState := state.NewEmptyStateTree(cst)
+	headActor, err := account.NewActor(types.NewAttoFILFromFIL(25))
+	require.NoError(t, err)
+	require.NoError(t, headState.SetActor(ctx, addr, headActor))
+
+	plumbing := &testActorGetPlumbing{
+		head: headKey,
+		states: map[string]state.Tree{
+			earlyKey.String(): earlyState,
+			headKey.String():  headState,
+		},
+	}
+
+	t.Run("reads the actor at head", func(t *testing.T) {
+		act, err := porcelain.ActorGet(ctx, plumbing, addr)
+		require.NoError(t, err)
+		assert.Equal(t, types.NewAttoFILFromFIL(25), act.Balance)
+	})
+
+	t.Run("reads the actor at an earlier tipset", func(t *testing.T) {
+		act, err := porcelain.ActorGetAt(ctx, plumbing, addr, earlyKey)
+		require.NoError(t, err)
+		assert.Equal(t, types.NewAttoFILFromFIL(10), act.Balance)
+	})
+
+	t.Run("returns ErrActorNotFound for a missing actor", func(t *testing.T) {
+		_, err := porcelain.ActorGet(ctx, plumbing, addrGetter())
+		assert.Equal(t, porcelain.ErrActorNotFound, err)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
@@ -40,11 +41,17 @@ import (
 // take this implementation as a dependency.
 type API struct {
 	*plumbing.API
+
+	// genesisMu protects genesis.
+	genesisMu sync.Mutex
+	// genesis caches the genesis tipset once resolved, since it never
+	// changes.
+	genesis block.TipSet
 }
 
 // New returns a new porcelain.API.
 func New(plumbing *plumbing.API) *API {
-	return &API{plumbing}
+	return &API{API: plumbing}
 }
 
 // ChainHead returns the current head tipset
@@ -52,11 +59,78 @@ func (a *API) ChainHead() (block.TipSet, error) {
 	return ChainHead(a)
 }
 
+// ChainValidateBlock validates a block against its parents and the current chain rules
+func (a *API) ChainValidateBlock(ctx context.Context, blk *block.Block) error {
+	return ChainValidateBlock(ctx, a, blk)
+}
+
+// ChainValidateStore checks the messages and receipts of the chain ending at `head` are present and consistent
+func (a *API) ChainValidateStore(ctx context.Context, head block.TipSetKey, depth int) (ValidateReport, error) {
+	return ChainValidateStore(ctx, a, head, depth)
+}
+
+// ChainHeadBlocks returns the block headers of the current head tipset
+func (a *API) ChainHeadBlocks(ctx context.Context) ([]*block.Block, error) {
+	return ChainHeadBlocks(ctx, a)
+}
+
+// ChainHeadKeyStrings returns the CID strings of the current head tipset's blocks
+func (a *API) ChainHeadKeyStrings() []string {
+	return ChainHeadKeyStrings(a)
+}
+
 // ChainGetFullBlock returns the full block given the header cid
 func (a *API) ChainGetFullBlock(ctx context.Context, id cid.Cid) (*block.FullBlock, error) {
 	return GetFullBlock(ctx, a, id)
 }
 
+// ChainGetFullBlocks returns the full blocks given their header cids, in order
+func (a *API) ChainGetFullBlocks(ctx context.Context, ids []cid.Cid, mode FetchMode) ([]*block.FullBlock, error) {
+	return GetFullBlocks(ctx, a, ids, mode)
+}
+
+// ChainMessageLocation returns the key and height of the tipset containing a message.
+func (a *API) ChainMessageLocation(ctx context.Context, msgCid cid.Cid) (block.TipSetKey, uint64, bool, error) {
+	return ChainMessageLocation(ctx, a, msgCid)
+}
+
+// ChainGetFullTipSet returns the full blocks and deduplicated messages of a tipset
+func (a *API) ChainGetFullTipSet(ctx context.Context, key block.TipSetKey) (*block.FullTipSet, error) {
+	return ChainGetFullTipSet(ctx, a, key)
+}
+
+// ChainTipSetAtHeight returns the tipset at the given height in the chain ending at head,
+// choosing a neighbour by mode if the height was a null round.
+func (a *API) ChainTipSetAtHeight(ctx context.Context, head block.TipSetKey, height uint64, mode RoundingMode) (block.TipSet, error) {
+	return ChainTipSetAtHeight(ctx, a, head, height, mode)
+}
+
+// ChainGetGenesis returns the genesis tipset, resolving it on first use
+func (a *API) ChainGetGenesis(ctx context.Context) (block.TipSet, error) {
+	a.genesisMu.Lock()
+	defer a.genesisMu.Unlock()
+	if a.genesis.Defined() {
+		return a.genesis, nil
+	}
+	genesis, err := ChainGetGenesis(ctx, a)
+	if err != nil {
+		return block.UndefTipSet, err
+	}
+	a.genesis = genesis
+	return genesis, nil
+}
+
+// ChainGenesisTime returns the genesis time recorded in chain state
+func (a *API) ChainGenesisTime(ctx context.Context) (time.Time, error) {
+	return ChainGenesisTime(ctx, a)
+}
+
+// SyncStatus reports how far the chain head is behind the chains announced
+// to the node and whether a sync is in progress
+func (a *API) SyncStatus(ctx context.Context) (SyncState, error) {
+	return SyncStatus(ctx, a)
+}
+
 // CreatePayments establishes a payment channel and create multiple payments against it
 func (a *API) CreatePayments(ctx context.Context, config CreatePaymentsParams) (*CreatePaymentsReturn, error) {
 	return CreatePayments(ctx, a, config)
@@ -89,6 +163,11 @@ func (a *API) DealsLs(ctx context.Context) (<-chan *StorageDealLsResult, error)
 	return DealsLs(ctx, a)
 }
 
+// DealsLsFiltered returns a channel with the deals matching the filter
+func (a *API) DealsLsFiltered(ctx context.Context, filter DealFilter) (<-chan *StorageDealLsResult, error) {
+	return DealsLsFiltered(ctx, a, filter)
+}
+
 // MessagePoolWait waits for the message pool to have at least messageCount unmined messages.
 // It's useful for integration testing.
 func (a *API) MessagePoolWait(ctx context.Context, messageCount uint) ([]*types.SignedMessage, error) {
@@ -185,16 +264,53 @@ func (a *API) MinerPreviewSetPrice(
 	return MinerPreviewSetPrice(ctx, a, from, miner, price, expiry)
 }
 
+// ProtocolBlockReward returns the block reward at the height of the current
+// head
+func (a *API) ProtocolBlockReward(ctx context.Context) (types.AttoFIL, error) {
+	return ProtocolBlockReward(ctx, a)
+}
+
+// ProtocolVersionHistory returns the protocol versions scheduled for the
+// network and whether each has activated at the current head.
+func (a *API) ProtocolVersionHistory(ctx context.Context) ([]ActivatedUpgrade, error) {
+	return ProtocolVersionHistory(ctx, a)
+}
+
 // ProtocolParameters fetches the current protocol configuration parameters.
 func (a *API) ProtocolParameters(ctx context.Context) (*ProtocolParams, error) {
 	return ProtocolParameters(ctx, a)
 }
 
+// ProtocolStoragePower returns the total storage power of the network at the head.
+func (a *API) ProtocolStoragePower(ctx context.Context) (*types.BytesAmount, error) {
+	return ProtocolStoragePower(ctx, a)
+}
+
+// MinerPower returns the storage power of a miner and the network total at the head.
+func (a *API) MinerPower(ctx context.Context, minerAddr address.Address) (miner, total *types.BytesAmount, err error) {
+	return MinerStoragePower(ctx, a, minerAddr)
+}
+
 // WalletBalance returns the current balance of the given wallet address.
 func (a *API) WalletBalance(ctx context.Context, address address.Address) (types.AttoFIL, error) {
 	return WalletBalance(ctx, a, address)
 }
 
+// WalletSignMessage signs a message with the wallet key for `from` without sending it.
+func (a *API) WalletSignMessage(ctx context.Context, msg *types.UnsignedMessage, from address.Address) (*types.SignedMessage, error) {
+	return WalletSignMessage(ctx, a, msg, from)
+}
+
+// WalletImportKey adds a single key to the wallet and returns its address.
+func (a *API) WalletImportKey(ctx context.Context, ki *types.KeyInfo) (address.Address, error) {
+	return WalletImportKey(ctx, a, ki)
+}
+
+// WalletExportKey returns the key the wallet holds for `addr`.
+func (a *API) WalletExportKey(ctx context.Context, addr address.Address) (*types.KeyInfo, error) {
+	return WalletExportKey(ctx, a, addr)
+}
+
 // WalletDefaultAddress returns a default wallet address from the config.
 // If none is set it picks the first address in the wallet and sets it as the default in the config.
 func (a *API) WalletDefaultAddress() (address.Address, error) {
@@ -227,6 +343,12 @@ func (a *API) ClientListAsks(ctx context.Context) <-chan Ask {
 	return ClientListAsks(ctx, a)
 }
 
+// ClientListAsksSorted returns the asks from the latest chain state ordered by
+// miner address and ask ID
+func (a *API) ClientListAsksSorted(ctx context.Context) ([]Ask, error) {
+	return ClientListAsksSorted(ctx, a)
+}
+
 // ClientValidateDeal checks to see that a storage deal is in the `Complete` state, and that its PIP is valid
 func (a *API) ClientValidateDeal(ctx context.Context, proposalCid cid.Cid, proofInfo *storagedeal.ProofInfo) error {
 	return ClientVerifyStorageDeal(ctx, a, proposalCid, proofInfo)
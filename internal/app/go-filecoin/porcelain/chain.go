@@ -2,10 +2,18 @@ package porcelain
 
 import (
 	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
 
+	"github.com/filecoin-project/go-filecoin/internal/pkg/abi"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
 
@@ -19,6 +27,164 @@ func ChainHead(plumbing chainHeadPlumbing) (block.TipSet, error) {
 	return plumbing.ChainTipSet(plumbing.ChainHeadKey())
 }
 
+// ChainHeadBlocks returns the block headers of the current head tipset.
+func ChainHeadBlocks(ctx context.Context, plumbing chainHeadPlumbing) ([]*block.Block, error) {
+	head, err := ChainHead(plumbing)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load head tipset")
+	}
+	return head.ToSlice(), nil
+}
+
+// ChainHeadKeyStrings returns the CIDs of the current head tipset's blocks as
+// strings, in key order.
+func ChainHeadKeyStrings(plumbing chainHeadPlumbing) []string {
+	ids := plumbing.ChainHeadKey().ToSlice()
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
+
+type validateBlockPlumbing interface {
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+	ChainParentWeight(ctx context.Context, parents block.TipSet) (uint64, error)
+	ChainValidateBlockSyntax(ctx context.Context, blk *block.Block) error
+	ChainValidateBlockSemantic(ctx context.Context, blk *block.Block, parents *block.TipSet, parentWeight uint64) error
+}
+
+// ChainValidateBlock validates the syntax of `blk` and its semantics against
+// its parent tipset, which must be in the chain store. It returns the first
+// failure found.
+func ChainValidateBlock(ctx context.Context, plumbing validateBlockPlumbing, blk *block.Block) error {
+	if err := plumbing.ChainValidateBlockSyntax(ctx, blk); err != nil {
+		return err
+	}
+	parents, err := plumbing.ChainTipSet(blk.Parents)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load parents %s of block %s", blk.Parents, blk.Cid())
+	}
+	parentWeight, err := plumbing.ChainParentWeight(ctx, parents)
+	if err != nil {
+		return errors.Wrapf(err, "failed to compute weight of parents %s", blk.Parents)
+	}
+	return plumbing.ChainValidateBlockSemantic(ctx, blk, &parents, parentWeight)
+}
+
+// ValidateReport describes the result of ChainValidateStore.
+type ValidateReport struct {
+	// TipSets is the number of tipsets checked.
+	TipSets int
+	// Blocks is the number of blocks checked.
+	Blocks int
+	// Problems lists the missing or inconsistent data found, in the order
+	// found.
+	Problems []StoreProblem
+}
+
+// StoreProblem describes missing or inconsistent data in the chain store.
+type StoreProblem struct {
+	// TipSet is the key of the tipset with the problem.
+	TipSet block.TipSetKey
+	// Block is the CID of the block with the problem, undefined if the
+	// tipset itself could not be loaded.
+	Block cid.Cid
+	// Reason describes the problem.
+	Reason string
+}
+
+type validateStorePlumbing interface {
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+	ChainLoadMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error)
+	ChainGetReceipts(ctx context.Context, id cid.Cid) ([]*types.MessageReceipt, error)
+}
+
+// ChainValidateStore walks up to `depth` tipsets of the chain ending at
+// `head`, or to genesis if depth is not positive, checking that each block's
+// messages and receipts are present and match the roots in its header. Missing
+// or mismatched data is reported rather than returned as an error. A missing
+// tipset ends the walk.
+func ChainValidateStore(ctx context.Context, plumbing validateStorePlumbing, head block.TipSetKey, depth int) (ValidateReport, error) {
+	var report ValidateReport
+	for key := head; depth <= 0 || report.TipSets < depth; {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		ts, err := plumbing.ChainTipSet(key)
+		if err != nil {
+			report.Problems = append(report.Problems, StoreProblem{TipSet: key, Reason: fmt.Sprintf("missing tipset: %s", err)})
+			break
+		}
+		report.TipSets++
+
+		for i := 0; i < ts.Len(); i++ {
+			blk := ts.At(i)
+			report.Blocks++
+			problem := func(format string, args ...interface{}) {
+				report.Problems = append(report.Problems, StoreProblem{TipSet: key, Block: blk.Cid(), Reason: fmt.Sprintf(format, args...)})
+			}
+
+			secpMsgs, blsMsgs, err := plumbing.ChainLoadMessages(ctx, blk.Messages)
+			if err != nil {
+				problem("missing messages: %s", err)
+			} else if err := chain.VerifyTxMeta(ctx, blk.Messages, secpMsgs, blsMsgs); err != nil {
+				problem("mismatched messages: %s", err)
+			}
+
+			rcpts, err := plumbing.ChainGetReceipts(ctx, blk.MessageReceipts)
+			if err != nil {
+				problem("missing receipts: %s", err)
+			} else if err := chain.VerifyReceiptsRoot(ctx, blk.MessageReceipts, rcpts); err != nil {
+				problem("mismatched receipts: %s", err)
+			}
+		}
+
+		key, err = ts.Parents()
+		if err != nil {
+			return report, err
+		}
+		if key.Empty() {
+			break
+		}
+	}
+	return report, nil
+}
+
+type chainGenesisPlumbing interface {
+	ChainGenesisCid() cid.Cid
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+}
+
+// ChainGetGenesis returns the genesis tipset, the single genesis block the
+// chain store was initialized with.
+func ChainGetGenesis(ctx context.Context, plumbing chainGenesisPlumbing) (block.TipSet, error) {
+	genesis, err := plumbing.ChainTipSet(block.NewTipSetKey(plumbing.ChainGenesisCid()))
+	if err != nil {
+		return block.UndefTipSet, errors.Wrap(err, "failed to load genesis tipset")
+	}
+	return genesis, nil
+}
+
+type genesisTimePlumbing interface {
+	ChainHeadKey() block.TipSetKey
+	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey block.TipSetKey, params ...interface{}) ([][]byte, error)
+}
+
+// ChainGenesisTime returns the genesis time recorded in the init actor's state
+// by the GenesisTime genesis option.
+func ChainGenesisTime(ctx context.Context, plumbing genesisTimePlumbing) (time.Time, error) {
+	rets, err := plumbing.MessageQuery(ctx, address.Undef, address.InitAddress, "getGenesisTime", plumbing.ChainHeadKey())
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "'getGenesisTime' query message failed")
+	}
+	val, err := abi.Deserialize(rets[0], abi.Integer)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "could not convert query message result to integer")
+	}
+	return time.Unix(val.Val.(*big.Int).Int64(), 0), nil
+}
+
 type fullBlockPlumbing interface {
 	ChainGetBlock(context.Context, cid.Cid) (*block.Block, error)
 	ChainGetMessages(context.Context, types.TxMeta) ([]*types.SignedMessage, error)
@@ -47,3 +213,281 @@ func GetFullBlock(ctx context.Context, plumbing fullBlockPlumbing, id cid.Cid) (
 
 	return &out, nil
 }
+
+// FetchMode determines how GetFullBlocks handles blocks that fail to load.
+type FetchMode int
+
+const (
+	// FetchAtomic fails the whole fetch if any block fails to load.
+	FetchAtomic FetchMode = iota
+	// FetchPerItem returns the blocks that loaded, with nil in place of those
+	// that failed, along with a *FullBlocksError.
+	FetchPerItem
+)
+
+// FullBlocksError reports the blocks GetFullBlocks failed to load in
+// FetchPerItem mode.
+type FullBlocksError struct {
+	// Errs holds an error for each input id, nil for ids loaded successfully.
+	Errs []error
+}
+
+func (e *FullBlocksError) Error() string {
+	var first error
+	failed := 0
+	for _, err := range e.Errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("failed to load %d of %d blocks, first error: %s", failed, len(e.Errs), first)
+}
+
+type fullBlocksPlumbing interface {
+	fullBlockPlumbing
+	ChainGetBlocks(context.Context, []cid.Cid) ([]*block.Block, error)
+}
+
+// GetFullBlocks returns the full blocks for `ids`, in order. Headers are
+// fetched in one batch, then messages and receipts are fetched concurrently.
+// `mode` determines whether a block that fails to load fails the whole fetch.
+func GetFullBlocks(ctx context.Context, plumbing fullBlocksPlumbing, ids []cid.Cid, mode FetchMode) ([]*block.FullBlock, error) {
+	out := make([]*block.FullBlock, len(ids))
+	errs := make([]error, len(ids))
+
+	headers, err := plumbing.ChainGetBlocks(ctx, ids)
+	if err != nil {
+		if mode == FetchAtomic {
+			return nil, err
+		}
+		// Load headers one at a time to find those that fail.
+		headers = make([]*block.Block, len(ids))
+		for i, id := range ids {
+			headers[i], errs[i] = plumbing.ChainGetBlock(ctx, id)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, header := range headers {
+		if errs[i] != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, header *block.Block) {
+			defer wg.Done()
+			fb := &block.FullBlock{Header: header}
+			fb.Messages, errs[i] = plumbing.ChainGetMessages(ctx, header.Messages)
+			if errs[i] != nil {
+				return
+			}
+			fb.Receipts, errs[i] = plumbing.ChainGetReceipts(ctx, header.MessageReceipts)
+			if errs[i] != nil {
+				return
+			}
+			out[i] = fb
+		}(i, header)
+	}
+	wg.Wait()
+
+	failed := false
+	for _, err := range errs {
+		if err != nil {
+			if mode == FetchAtomic {
+				return nil, err
+			}
+			failed = true
+		}
+	}
+	if failed {
+		return out, &FullBlocksError{Errs: errs}
+	}
+	return out, nil
+}
+
+type fullTipSetPlumbing interface {
+	fullBlocksPlumbing
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+}
+
+// ChainGetFullTipSet returns the full blocks of the tipset with key `key`, in
+// canonical tipset order, along with its deduplicated messages.
+func ChainGetFullTipSet(ctx context.Context, plumbing fullTipSetPlumbing, key block.TipSetKey) (*block.FullTipSet, error) {
+	ts, err := plumbing.ChainTipSet(key)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]cid.Cid, ts.Len())
+	for i := 0; i < ts.Len(); i++ {
+		ids[i] = ts.At(i).Cid()
+	}
+	blocks, err := GetFullBlocks(ctx, plumbing, ids, FetchAtomic)
+	if err != nil {
+		return nil, err
+	}
+	return block.NewFullTipSet(blocks)
+}
+
+// RoundingMode determines which tipset ChainTipSetAtHeight returns when the
+// requested height was a null round.
+type RoundingMode int
+
+const (
+	// RoundExact requires a tipset at exactly the requested height.
+	RoundExact RoundingMode = iota
+	// RoundPrev selects the nearest tipset below a null round.
+	RoundPrev
+	// RoundNext selects the nearest tipset above a null round.
+	RoundNext
+)
+
+// ErrNullRound is returned by ChainTipSetAtHeight in RoundExact mode when no
+// tipset was mined at the requested height.
+var ErrNullRound = errors.New("no tipset at height: null round")
+
+type chainTipSetPlumbing interface {
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+}
+
+// ChainTipSetAtHeight returns the tipset at `height` in the chain ending at
+// `head`. If no tipset was mined at that height the tipset returned is chosen
+// by `mode`.
+func ChainTipSetAtHeight(ctx context.Context, plumbing chainTipSetPlumbing, head block.TipSetKey, height uint64, mode RoundingMode) (block.TipSet, error) {
+	ts, err := plumbing.ChainTipSet(head)
+	if err != nil {
+		return block.UndefTipSet, err
+	}
+	h, err := ts.Height()
+	if err != nil {
+		return block.UndefTipSet, err
+	}
+	if h < height {
+		return block.UndefTipSet, errors.Errorf("height %d is above head height %d", height, h)
+	}
+
+	above := block.UndefTipSet
+	for {
+		select {
+		case <-ctx.Done():
+			return block.UndefTipSet, ctx.Err()
+		default:
+		}
+
+		h, err := ts.Height()
+		if err != nil {
+			return block.UndefTipSet, err
+		}
+		if h == height {
+			return ts, nil
+		}
+		if h < height {
+			switch mode {
+			case RoundPrev:
+				return ts, nil
+			case RoundNext:
+				return above, nil
+			default:
+				return block.UndefTipSet, ErrNullRound
+			}
+		}
+
+		parents, err := ts.Parents()
+		if err != nil {
+			return block.UndefTipSet, err
+		}
+		above = ts
+		ts, err = plumbing.ChainTipSet(parents)
+		if err != nil {
+			return block.UndefTipSet, err
+		}
+	}
+}
+
+// MessageLocationScanDepth is the number of tipsets ChainMessageLocation
+// searches back from the head for a message missing from the message index.
+const MessageLocationScanDepth = 2000
+
+type chainMessageLocationPlumbing interface {
+	ChainHeadKey() block.TipSetKey
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+	ChainLoadMessages(context.Context, types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error)
+	ChainMessageLocate(c cid.Cid) (chain.MessageLocation, bool)
+}
+
+// ChainMessageLocation returns the key and height of the tipset containing
+// the message `msgCid`, secp or BLS. The message index is consulted first,
+// and a message missing from it is searched for at most
+// MessageLocationScanDepth tipsets back from the head. `found` is false if
+// the message is in neither.
+func ChainMessageLocation(ctx context.Context, plumbing chainMessageLocationPlumbing, msgCid cid.Cid) (tipsetKey block.TipSetKey, height uint64, found bool, err error) {
+	if loc, ok := plumbing.ChainMessageLocate(msgCid); ok {
+		return loc.TipSet, loc.Height, true, nil
+	}
+
+	ts, err := plumbing.ChainTipSet(plumbing.ChainHeadKey())
+	if err != nil {
+		return block.TipSetKey{}, 0, false, err
+	}
+	for depth := 0; depth < MessageLocationScanDepth; depth++ {
+		select {
+		case <-ctx.Done():
+			return block.TipSetKey{}, 0, false, ctx.Err()
+		default:
+		}
+
+		for i := 0; i < ts.Len(); i++ {
+			included, err := blockMessageCids(ctx, plumbing, ts.At(i))
+			if err != nil {
+				return block.TipSetKey{}, 0, false, err
+			}
+			for _, c := range included {
+				if c.Equals(msgCid) {
+					h, err := ts.Height()
+					if err != nil {
+						return block.TipSetKey{}, 0, false, err
+					}
+					return ts.Key(), h, true, nil
+				}
+			}
+		}
+
+		parents, err := ts.Parents()
+		if err != nil {
+			return block.TipSetKey{}, 0, false, err
+		}
+		if parents.Empty() {
+			break
+		}
+		ts, err = plumbing.ChainTipSet(parents)
+		if err != nil {
+			return block.TipSetKey{}, 0, false, err
+		}
+	}
+	return block.TipSetKey{}, 0, false, nil
+}
+
+// blockMessageCids returns the CIDs of the secp and BLS messages of `blk`.
+func blockMessageCids(ctx context.Context, plumbing chainMessageLocationPlumbing, blk *block.Block) ([]cid.Cid, error) {
+	secpMsgs, blsMsgs, err := plumbing.ChainLoadMessages(ctx, blk.Messages)
+	if err != nil {
+		return nil, err
+	}
+	var out []cid.Cid
+	for _, msg := range secpMsgs {
+		c, err := msg.Cid()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	for _, msg := range blsMsgs {
+		c, err := msg.Cid()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
@@ -0,0 +1,514 @@
+package porcelain_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/abi"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
+)
+
+type testChainTipSetPlumbing struct {
+	builder *chain.Builder
+}
+
+func (tctp *testChainTipSetPlumbing) ChainTipSet(key block.TipSetKey) (block.TipSet, error) {
+	return tctp.builder.GetTipSet(key)
+}
+
+func TestChainHead(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	store := chain.NewFakeStore(builder)
+	plumbing := porcelain.NewFakeChainPlumbing(store)
+
+	t.Run("empty head", func(t *testing.T) {
+		_, err := porcelain.ChainHead(plumbing)
+		assert.Error(t, err)
+	})
+
+	t.Run("head", func(t *testing.T) {
+		genesis := builder.NewGenesis()
+		head := builder.AppendManyOn(3, genesis)
+		require.NoError(t, store.SetHead(ctx, head))
+
+		ts, err := porcelain.ChainHead(plumbing)
+		require.NoError(t, err)
+		assert.Equal(t, head.Key(), ts.Key())
+	})
+}
+
+func TestChainHeadBlocks(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	store := chain.NewFakeStore(builder)
+	plumbing := porcelain.NewFakeChainPlumbing(store)
+
+	genesis := builder.NewGenesis()
+	head := builder.AppendOn(genesis, 3)
+	require.NoError(t, store.SetHead(ctx, head))
+
+	blks, err := porcelain.ChainHeadBlocks(ctx, plumbing)
+	require.NoError(t, err)
+	require.Len(t, blks, 3)
+	for i, blk := range blks {
+		assert.Equal(t, head.At(i).Cid(), blk.Cid())
+	}
+
+	strs := porcelain.ChainHeadKeyStrings(plumbing)
+	require.Len(t, strs, 3)
+	for i, id := range head.Key().ToSlice() {
+		assert.Equal(t, id.String(), strs[i])
+	}
+}
+
+// genesisPlumbing adds the genesis CID to the fake chain plumbing.
+type genesisPlumbing struct {
+	*porcelain.FakeChainPlumbing
+	genesis cid.Cid
+}
+
+func (p *genesisPlumbing) ChainGenesisCid() cid.Cid {
+	return p.genesis
+}
+
+func TestChainGetGenesis(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	store := chain.NewFakeStore(builder)
+
+	genesis := builder.NewGenesis()
+	head := builder.AppendManyOn(5, genesis)
+	require.NoError(t, store.SetHead(ctx, head))
+	plumbing := &genesisPlumbing{FakeChainPlumbing: porcelain.NewFakeChainPlumbing(store), genesis: genesis.At(0).Cid()}
+
+	ts, err := porcelain.ChainGetGenesis(ctx, plumbing)
+	require.NoError(t, err)
+	assert.Equal(t, genesis.Key(), ts.Key())
+	h, err := ts.Height()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), h)
+	parents, err := ts.Parents()
+	require.NoError(t, err)
+	assert.True(t, parents.Empty())
+}
+
+func TestChainTipSetAtHeight(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	genesis := builder.NewGenesis()
+	below := builder.AppendManyOn(2, genesis)
+	// Null rounds at heights 3 and 4.
+	above := builder.BuildOneOn(below, func(b *chain.BlockBuilder) {
+		b.IncHeight(2)
+	})
+	head := builder.AppendOn(above, 1)
+	plumbing := &testChainTipSetPlumbing{builder}
+
+	t.Run("exact finds a mined height", func(t *testing.T) {
+		ts, err := porcelain.ChainTipSetAtHeight(ctx, plumbing, head.Key(), 2, porcelain.RoundExact)
+		require.NoError(t, err)
+		assert.Equal(t, below.Key(), ts.Key())
+	})
+
+	t.Run("exact errors on a null round", func(t *testing.T) {
+		_, err := porcelain.ChainTipSetAtHeight(ctx, plumbing, head.Key(), 3, porcelain.RoundExact)
+		assert.Equal(t, porcelain.ErrNullRound, err)
+	})
+
+	t.Run("prev selects the tipset below a null round", func(t *testing.T) {
+		ts, err := porcelain.ChainTipSetAtHeight(ctx, plumbing, head.Key(), 4, porcelain.RoundPrev)
+		require.NoError(t, err)
+		assert.Equal(t, below.Key(), ts.Key())
+	})
+
+	t.Run("next selects the tipset above a null round", func(t *testing.T) {
+		ts, err := porcelain.ChainTipSetAtHeight(ctx, plumbing, head.Key(), 3, porcelain.RoundNext)
+		require.NoError(t, err)
+		assert.Equal(t, above.Key(), ts.Key())
+	})
+
+	t.Run("errors above the head", func(t *testing.T) {
+		_, err := porcelain.ChainTipSetAtHeight(ctx, plumbing, head.Key(), 10, porcelain.RoundPrev)
+		assert.Error(t, err)
+	})
+}
+
+type testFullBlockPlumbing struct {
+	builder *chain.Builder
+}
+
+func (tfbp *testFullBlockPlumbing) ChainGetBlock(ctx context.Context, id cid.Cid) (*block.Block, error) {
+	return tfbp.builder.GetBlock(ctx, id)
+}
+
+func (tfbp *testFullBlockPlumbing) ChainGetBlocks(ctx context.Context, ids []cid.Cid) ([]*block.Block, error) {
+	return tfbp.builder.GetBlocks(ctx, ids)
+}
+
+func (tfbp *testFullBlockPlumbing) ChainGetMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, error) {
+	secp, _, err := tfbp.builder.LoadMessages(ctx, meta)
+	return secp, err
+}
+
+func (tfbp *testFullBlockPlumbing) ChainTipSet(key block.TipSetKey) (block.TipSet, error) {
+	return tfbp.builder.GetTipSet(key)
+}
+
+func (tfbp *testFullBlockPlumbing) ChainGetReceipts(ctx context.Context, id cid.Cid) ([]*types.MessageReceipt, error) {
+	return tfbp.builder.LoadReceipts(ctx, id)
+}
+
+func TestGetFullBlocks(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	mm := types.NewMessageMaker(t, types.MustGenerateKeyInfo(1, 42))
+	sender := mm.Addresses()[0]
+	genesis := builder.NewGenesis()
+	ts := builder.BuildOn(genesis, 3, func(b *chain.BlockBuilder, i int) {
+		msgs := []*types.SignedMessage{mm.NewSignedMessage(sender, uint64(i))}
+		b.AddMessages(msgs, []*types.UnsignedMessage{}, types.EmptyReceipts(1))
+	})
+	ids := ts.Key().ToSlice()
+	plumbing := &testFullBlockPlumbing{builder}
+
+	t.Run("matches individual fetches in order", func(t *testing.T) {
+		fbs, err := porcelain.GetFullBlocks(ctx, plumbing, ids, porcelain.FetchAtomic)
+		require.NoError(t, err)
+		require.Len(t, fbs, len(ids))
+		for i, id := range ids {
+			expected, err := porcelain.GetFullBlock(ctx, plumbing, id)
+			require.NoError(t, err)
+			assert.Equal(t, expected, fbs[i])
+		}
+	})
+
+	missing := types.CidFromString(t, "missing")
+	withMissing := []cid.Cid{ids[0], missing, ids[2]}
+
+	t.Run("atomic fails on a missing block", func(t *testing.T) {
+		fbs, err := porcelain.GetFullBlocks(ctx, plumbing, withMissing, porcelain.FetchAtomic)
+		assert.Error(t, err)
+		assert.Nil(t, fbs)
+	})
+
+	t.Run("per item returns the blocks that loaded", func(t *testing.T) {
+		fbs, err := porcelain.GetFullBlocks(ctx, plumbing, withMissing, porcelain.FetchPerItem)
+		require.Error(t, err)
+		fbErr, ok := errors.Cause(err).(*porcelain.FullBlocksError)
+		require.True(t, ok)
+
+		require.Len(t, fbs, 3)
+		assert.NotNil(t, fbs[0])
+		assert.Nil(t, fbs[1])
+		assert.NotNil(t, fbs[2])
+		assert.NoError(t, fbErr.Errs[0])
+		assert.Error(t, fbErr.Errs[1])
+		assert.NoError(t, fbErr.Errs[2])
+	})
+}
+
+func TestChainGetFullTipSet(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	mm := types.NewMessageMaker(t, types.MustGenerateKeyInfo(1, 42))
+	sender := mm.Addresses()[0]
+	shared := mm.NewSignedMessage(sender, 0)
+	unique := []*types.SignedMessage{
+		mm.NewSignedMessage(sender, 1),
+		mm.NewSignedMessage(sender, 2),
+		mm.NewSignedMessage(sender, 3),
+	}
+	genesis := builder.NewGenesis()
+	// Every block carries the shared message followed by one of its own.
+	ts := builder.BuildOn(genesis, 3, func(b *chain.BlockBuilder, i int) {
+		msgs := []*types.SignedMessage{shared, unique[i]}
+		b.AddMessages(msgs, []*types.UnsignedMessage{}, types.EmptyReceipts(2))
+	})
+
+	fts, err := porcelain.ChainGetFullTipSet(ctx, &testFullBlockPlumbing{builder}, ts.Key())
+	require.NoError(t, err)
+
+	require.Len(t, fts.Blocks, ts.Len())
+	for i, fb := range fts.Blocks {
+		assert.Equal(t, ts.At(i).Cid(), fb.Header.Cid())
+		assert.Len(t, fb.Messages, 2)
+	}
+
+	// The shared message appears once, ahead of each block's own message in
+	// block order.
+	expected := []*types.SignedMessage{shared}
+	for _, fb := range fts.Blocks {
+		expected = append(expected, fb.Messages[1])
+	}
+	require.Len(t, fts.Messages, len(expected))
+	for i := range expected {
+		assert.True(t, types.SmsgCidsEqual(expected[i], fts.Messages[i]))
+	}
+}
+
+type testValidateBlockPlumbing struct {
+	builder   *chain.Builder
+	validator *consensus.DefaultBlockValidator
+}
+
+func (tvbp *testValidateBlockPlumbing) ChainTipSet(key block.TipSetKey) (block.TipSet, error) {
+	return tvbp.builder.GetTipSet(key)
+}
+
+func (tvbp *testValidateBlockPlumbing) ChainParentWeight(ctx context.Context, parents block.TipSet) (uint64, error) {
+	grandparents, err := parents.Parents()
+	if err != nil {
+		return 0, err
+	}
+	return chain.FakeStateBuilder{}.Weigh(parents, tvbp.builder.StateForKey(grandparents))
+}
+
+func (tvbp *testValidateBlockPlumbing) ChainValidateBlockSyntax(ctx context.Context, blk *block.Block) error {
+	return tvbp.validator.ValidateSyntax(ctx, blk)
+}
+
+func (tvbp *testValidateBlockPlumbing) ChainValidateBlockSemantic(ctx context.Context, blk *block.Block, parents *block.TipSet, parentWeight uint64) error {
+	return tvbp.validator.ValidateSemantic(ctx, blk, parents, parentWeight)
+}
+
+func TestChainValidateBlock(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	pvt, err := version.ConfigureProtocolVersions(version.TEST)
+	require.NoError(t, err)
+	validator := consensus.NewDefaultBlockValidator(time.Second, th.NewFakeClock(time.Unix(1000, 0)), pvt, nil)
+
+	builder := chain.NewBuilder(t, address.NewForTestGetter()())
+	genesis := builder.NewGenesis()
+	head := builder.BuildOneOn(genesis, func(b *chain.BlockBuilder) {
+		b.SetTimestamp(types.Uint64(1))
+	})
+	plumbing := &testValidateBlockPlumbing{builder, validator}
+
+	t.Run("valid block", func(t *testing.T) {
+		assert.NoError(t, porcelain.ChainValidateBlock(ctx, plumbing, head.At(0)))
+	})
+
+	t.Run("invalid parent weight", func(t *testing.T) {
+		corrupt := *head.At(0)
+		corrupt.ParentWeight++
+		assert.Error(t, porcelain.ChainValidateBlock(ctx, plumbing, &corrupt))
+	})
+
+	t.Run("missing ticket", func(t *testing.T) {
+		corrupt := *head.At(0)
+		corrupt.Ticket = block.Ticket{}
+		assert.Error(t, porcelain.ChainValidateBlock(ctx, plumbing, &corrupt))
+	})
+}
+
+type testValidateStorePlumbing struct {
+	builder *chain.Builder
+	missing map[cid.Cid]bool
+}
+
+func (tvsp *testValidateStorePlumbing) ChainTipSet(key block.TipSetKey) (block.TipSet, error) {
+	return tvsp.builder.GetTipSet(key)
+}
+
+func (tvsp *testValidateStorePlumbing) ChainLoadMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error) {
+	if tvsp.missing[meta.SecpRoot] {
+		return nil, nil, errors.Errorf("block %s not found", meta.SecpRoot)
+	}
+	return tvsp.builder.LoadMessages(ctx, meta)
+}
+
+func (tvsp *testValidateStorePlumbing) ChainGetReceipts(ctx context.Context, id cid.Cid) ([]*types.MessageReceipt, error) {
+	return tvsp.builder.LoadReceipts(ctx, id)
+}
+
+func TestChainValidateStore(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	mm := types.NewMessageMaker(t, types.MustGenerateKeyInfo(1, 42))
+	alice := mm.Addresses()[0]
+	builder := chain.NewBuilder(t, address.Undef)
+	genesis := builder.NewGenesis()
+	withMessage := builder.BuildOneOn(builder.AppendOn(genesis, 2), func(b *chain.BlockBuilder) {
+		b.AddMessages([]*types.SignedMessage{mm.NewSignedMessage(alice, 0)}, []*types.UnsignedMessage{}, []*types.MessageReceipt{})
+	})
+	head := builder.AppendManyOn(2, withMessage)
+
+	t.Run("consistent store", func(t *testing.T) {
+		plumbing := &testValidateStorePlumbing{builder: builder}
+		report, err := porcelain.ChainValidateStore(ctx, plumbing, head.Key(), 0)
+		require.NoError(t, err)
+		assert.Empty(t, report.Problems)
+		assert.Equal(t, 5, report.TipSets)
+		assert.Equal(t, 6, report.Blocks)
+	})
+
+	t.Run("depth bounds the walk", func(t *testing.T) {
+		plumbing := &testValidateStorePlumbing{builder: builder}
+		report, err := porcelain.ChainValidateStore(ctx, plumbing, head.Key(), 2)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.TipSets)
+	})
+
+	t.Run("missing messages", func(t *testing.T) {
+		blk := withMessage.At(0)
+		plumbing := &testValidateStorePlumbing{builder: builder, missing: map[cid.Cid]bool{blk.Messages.SecpRoot: true}}
+		report, err := porcelain.ChainValidateStore(ctx, plumbing, head.Key(), 0)
+		require.NoError(t, err)
+		require.Len(t, report.Problems, 1)
+		assert.Equal(t, withMessage.Key(), report.Problems[0].TipSet)
+		assert.Equal(t, blk.Cid(), report.Problems[0].Block)
+		assert.Contains(t, report.Problems[0].Reason, "missing messages")
+	})
+}
+
+type testGenesisTimePlumbing struct {
+	testing     *testing.T
+	genesisTime time.Time
+}
+
+func (tgtp *testGenesisTimePlumbing) ChainHeadKey() block.TipSetKey {
+	return block.NewTipSetKey()
+}
+
+func (tgtp *testGenesisTimePlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, _ block.TipSetKey, params ...interface{}) ([][]byte, error) {
+	assert.Equal(tgtp.testing, address.InitAddress, to)
+	assert.Equal(tgtp.testing, "getGenesisTime", method)
+	val := &abi.Value{Type: abi.Integer, Val: big.NewInt(tgtp.genesisTime.Unix())}
+	ret, err := val.Serialize()
+	require.NoError(tgtp.testing, err)
+	return [][]byte{ret}, nil
+}
+
+func TestChainGenesisTime(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	genesisTime := time.Unix(1234567890, 0)
+	plumbing := &testGenesisTimePlumbing{testing: t, genesisTime: genesisTime}
+	actual, err := porcelain.ChainGenesisTime(ctx, plumbing)
+	require.NoError(t, err)
+	assert.Equal(t, genesisTime, actual)
+
+	// The expected timestamp at height 10 follows from the genesis time.
+	blockTime := 30 * time.Second
+	assert.Equal(t, genesisTime.Add(10*blockTime), consensus.ExpectedTimestamp(actual, blockTime, 10))
+}
+
+type testMessageLocationPlumbing struct {
+	builder *chain.Builder
+	head    block.TipSetKey
+	index   *chain.MessageIndex
+	loads   int
+}
+
+func (tmlp *testMessageLocationPlumbing) ChainHeadKey() block.TipSetKey {
+	return tmlp.head
+}
+
+func (tmlp *testMessageLocationPlumbing) ChainTipSet(key block.TipSetKey) (block.TipSet, error) {
+	return tmlp.builder.GetTipSet(key)
+}
+
+func (tmlp *testMessageLocationPlumbing) ChainLoadMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error) {
+	tmlp.loads++
+	return tmlp.builder.LoadMessages(ctx, meta)
+}
+
+func (tmlp *testMessageLocationPlumbing) ChainMessageLocate(c cid.Cid) (chain.MessageLocation, bool) {
+	if tmlp.index == nil {
+		return chain.MessageLocation{}, false
+	}
+	return tmlp.index.Locate(c)
+}
+
+func TestChainMessageLocation(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	keys := types.MustGenerateKeyInfo(1, 42)
+	mm := types.NewMessageMaker(t, keys)
+	alice := mm.Addresses()[0]
+	msg := mm.NewSignedMessage(alice, 0)
+	msgCid, err := msg.Cid()
+	require.NoError(t, err)
+	blsMsg := types.NewUnsignedMessage(alice, alice, 1, types.ZeroAttoFIL, "", nil)
+	blsCid, err := blsMsg.Cid()
+	require.NoError(t, err)
+
+	builder := chain.NewBuilder(t, address.Undef)
+	genesis := builder.NewGenesis()
+	withMsg := builder.BuildOneOn(genesis, func(b *chain.BlockBuilder) {
+		b.AddMessages([]*types.SignedMessage{msg}, []*types.UnsignedMessage{blsMsg}, []*types.MessageReceipt{{}, {}})
+	})
+	head := builder.AppendManyOn(3, withMsg)
+
+	t.Run("finds a message on chain", func(t *testing.T) {
+		plumbing := &testMessageLocationPlumbing{builder: builder, head: head.Key()}
+		key, height, found, err := porcelain.ChainMessageLocation(ctx, plumbing, msgCid)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, withMsg.Key(), key)
+		assert.Equal(t, uint64(1), height)
+	})
+
+	t.Run("finds a BLS message on chain", func(t *testing.T) {
+		plumbing := &testMessageLocationPlumbing{builder: builder, head: head.Key()}
+		key, height, found, err := porcelain.ChainMessageLocation(ctx, plumbing, blsCid)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, withMsg.Key(), key)
+		assert.Equal(t, uint64(1), height)
+	})
+
+	t.Run("finds an indexed message without scanning", func(t *testing.T) {
+		index := chain.NewMessageIndex()
+		require.NoError(t, index.Update(ctx, block.UndefTipSet, head, builder))
+		plumbing := &testMessageLocationPlumbing{builder: builder, head: head.Key(), index: index}
+		key, height, found, err := porcelain.ChainMessageLocation(ctx, plumbing, msgCid)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, withMsg.Key(), key)
+		assert.Equal(t, uint64(1), height)
+		assert.Equal(t, 0, plumbing.loads)
+	})
+
+	t.Run("not found for a message not on chain", func(t *testing.T) {
+		plumbing := &testMessageLocationPlumbing{builder: builder, head: head.Key()}
+		other, err := mm.NewSignedMessage(alice, 1).Cid()
+		require.NoError(t, err)
+		_, _, found, err := porcelain.ChainMessageLocation(ctx, plumbing, other)
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+}
@@ -1,8 +1,10 @@
 package porcelain
 
 import (
+	"bytes"
 	"context"
 	"math/big"
+	"sort"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
@@ -27,7 +29,7 @@ type Ask struct {
 }
 
 type claPlubming interface {
-	ActorLs(ctx context.Context) (<-chan state.GetAllActorsResult, error)
+	ActorLsByCode(ctx context.Context, codes ...cid.Cid) (<-chan state.GetAllActorsResult, error)
 	ChainHeadKey() block.TipSetKey
 	MessageQuery(ctx context.Context, optFrom, to address.Address, method string, baseKey block.TipSetKey, params ...interface{}) ([][]byte, error)
 }
@@ -38,7 +40,7 @@ func ClientListAsks(ctx context.Context, plumbing claPlubming) <-chan Ask {
 
 	go func() {
 		defer close(out)
-		actorCh, err := plumbing.ActorLs(ctx)
+		actorCh, err := plumbing.ActorLsByCode(ctx, types.MinerActorCodeCid, types.BootstrapMinerActorCodeCid)
 		if err != nil {
 			out <- Ask{
 				Error: err,
@@ -60,17 +62,33 @@ func ClientListAsks(ctx context.Context, plumbing claPlubming) <-chan Ask {
 	return out
 }
 
+// ClientListAsksSorted collects the asks from the latest chain state and
+// returns them ordered by miner address, then ask ID, so repeated calls against
+// the same state produce the same output.
+func ClientListAsksSorted(ctx context.Context, plumbing claPlubming) ([]Ask, error) {
+	var asks []Ask
+	for ask := range ClientListAsks(ctx, plumbing) {
+		if ask.Error != nil {
+			return nil, ask.Error
+		}
+		asks = append(asks, ask)
+	}
+
+	sort.Slice(asks, func(i, j int) bool {
+		if c := bytes.Compare(asks[i].Miner.Bytes(), asks[j].Miner.Bytes()); c != 0 {
+			return c < 0
+		}
+		return asks[i].ID < asks[j].ID
+	})
+	return asks, nil
+}
+
 func listAsksFromActorResult(ctx context.Context, plumbing claPlubming, actorResult state.GetAllActorsResult, out chan Ask) error {
 	if actorResult.Error != nil {
 		return actorResult.Error
 	}
 
 	addr, _ := address.NewFromString(actorResult.Address)
-	actor := actorResult.Actor
-
-	if !types.MinerActorCodeCid.Equals(actor.Code) && !types.BootstrapMinerActorCodeCid.Equals(actor.Code) {
-		return nil
-	}
 
 	// TODO: at some point, we will need to check that the miners are actually part of the storage market
 	// for now, its impossible for them not to be.
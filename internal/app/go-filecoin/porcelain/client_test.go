@@ -1,8 +1,10 @@
 package porcelain_test
 
 import (
+	"bytes"
 	"context"
 	"math/big"
+	"math/rand"
 	"testing"
 
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
@@ -15,8 +17,10 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/ipfs/go-cid"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type claPlumbing struct {
@@ -27,7 +31,7 @@ type claPlumbing struct {
 	MinerAddress address.Address
 }
 
-func (cla *claPlumbing) ActorLs(ctx context.Context) (<-chan state.GetAllActorsResult, error) {
+func (cla *claPlumbing) ActorLsByCode(ctx context.Context, codes ...cid.Cid) (<-chan state.GetAllActorsResult, error) {
 	out := make(chan state.GetAllActorsResult)
 
 	if cla.actorFail {
@@ -134,3 +138,74 @@ func TestClientListAsks(t *testing.T) {
 		assert.Error(t, result.Error, "MESSAGE FAILURE")
 	})
 }
+
+// shuffledAsksPlumbing emits its miners in a different random order on each
+// call, each with several asks listed out of order.
+type shuffledAsksPlumbing struct {
+	miners []address.Address
+}
+
+func (sap *shuffledAsksPlumbing) ActorLsByCode(ctx context.Context, codes ...cid.Cid) (<-chan state.GetAllActorsResult, error) {
+	out := make(chan state.GetAllActorsResult)
+	go func() {
+		defer close(out)
+		for _, i := range rand.Perm(len(sap.miners)) {
+			out <- state.GetAllActorsResult{
+				Address: sap.miners[i].String(),
+				Actor:   &actor.Actor{Code: types.MinerActorCodeCid},
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (sap *shuffledAsksPlumbing) ChainHeadKey() block.TipSetKey {
+	return block.NewTipSetKey()
+}
+
+func (sap *shuffledAsksPlumbing) MessageQuery(ctx context.Context, optFrom, to address.Address, method string, _ block.TipSetKey, params ...interface{}) ([][]byte, error) {
+	if method == "getAsks" {
+		askIDs, _ := encoding.Encode([]uint64{2, 0, 1})
+		return [][]byte{askIDs}, nil
+	}
+
+	ask := miner.Ask{
+		Expiry: types.NewBlockHeight(1),
+		ID:     params[0].(*big.Int),
+		Price:  types.NewAttoFILFromFIL(3),
+	}
+	askBytes, _ := encoding.Encode(ask)
+	return [][]byte{askBytes}, nil
+}
+
+func TestClientListAsksSorted(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	addrGetter := address.NewForTestGetter()
+	plumbing := &shuffledAsksPlumbing{}
+	for i := 0; i < 10; i++ {
+		plumbing.miners = append(plumbing.miners, addrGetter())
+	}
+
+	first, err := porcelain.ClientListAsksSorted(ctx, plumbing)
+	require.NoError(t, err)
+	require.Len(t, first, 30)
+
+	for i := 1; i < len(first); i++ {
+		prev, cur := first[i-1], first[i]
+		c := bytes.Compare(prev.Miner.Bytes(), cur.Miner.Bytes())
+		assert.True(t, c < 0 || (c == 0 && prev.ID < cur.ID), "asks out of order at %d", i)
+	}
+
+	for i := 0; i < 5; i++ {
+		again, err := porcelain.ClientListAsksSorted(ctx, plumbing)
+		require.NoError(t, err)
+		assert.Equal(t, first, again)
+	}
+
+	t.Run("error is returned", func(t *testing.T) {
+		_, err := porcelain.ClientListAsksSorted(ctx, &claPlumbing{messageFail: true})
+		assert.EqualError(t, err, "MESSAGE FAILURE")
+	})
+}
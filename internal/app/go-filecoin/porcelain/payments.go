@@ -167,9 +167,10 @@ func CreatePayments(ctx context.Context, plumbing cpPlumbing, config CreatePayme
 		Params: []interface{}{config.CommP[:], config.PieceSize},
 	}
 
-	// generate payments
+	// generate payments, signing them together once all are created
 	headKey := plumbing.ChainHeadKey()
 	response.Vouchers = []*types.PaymentVoucher{}
+	var signItems []types.SignItem
 	voucherAmount := types.ZeroAttoFIL
 	for i := 0; uint64(i+1)*config.PaymentInterval < config.Duration; i++ {
 		voucherAmount = voucherAmount.Add(valuePerPayment)
@@ -178,18 +179,28 @@ func CreatePayments(ctx context.Context, plumbing cpPlumbing, config CreatePayme
 		}
 
 		validAt := currentHeight.Add(types.NewBlockHeight(uint64(i+1) * config.PaymentInterval))
-		err = createPayment(ctx, plumbing, headKey, response, voucherAmount, validAt, condition)
+		item, err := createPayment(ctx, plumbing, headKey, response, voucherAmount, validAt, condition)
 		if err != nil {
 			return response, err
 		}
+		signItems = append(signItems, item)
 	}
 
 	// create last payment
 	validAt := currentHeight.Add(types.NewBlockHeight(config.Duration))
-	err = createPayment(ctx, plumbing, headKey, response, config.Value, validAt, nil)
+	item, err := createPayment(ctx, plumbing, headKey, response, config.Value, validAt, nil)
 	if err != nil {
 		return response, err
 	}
+	signItems = append(signItems, item)
+
+	sigs, err := types.SignBatch(plumbing, signItems)
+	if err != nil {
+		return response, err
+	}
+	for i, sig := range sigs {
+		response.Vouchers[i].Signature = sig
+	}
 
 	return response, nil
 }
@@ -240,7 +251,9 @@ func ValidatePaymentVoucherCondition(ctx context.Context, condition *types.Predi
 	return nil
 }
 
-func createPayment(ctx context.Context, plumbing cpPlumbing, baseKey block.TipSetKey, response *CreatePaymentsReturn, amount types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate) error {
+// createPayment appends an unsigned voucher to the response and returns the
+// item to sign for it.
+func createPayment(ctx context.Context, plumbing cpPlumbing, baseKey block.TipSetKey, response *CreatePaymentsReturn, amount types.AttoFIL, validAt *types.BlockHeight, condition *types.Predicate) (types.SignItem, error) {
 
 	ret, err := plumbing.MessageQuery(ctx,
 		response.From,
@@ -253,20 +266,19 @@ func createPayment(ctx context.Context, plumbing cpPlumbing, baseKey block.TipSe
 		condition,
 	)
 	if err != nil {
-		return err
+		return types.SignItem{}, err
 	}
 
 	var voucher types.PaymentVoucher
 	if err := encoding.Decode(ret[0], &voucher); err != nil {
-		return err
+		return types.SignItem{}, err
 	}
 
-	sig, err := paymentbroker.SignVoucher(&voucher.Channel, amount, validAt, voucher.Payer, condition, plumbing)
+	item, err := paymentbroker.VoucherSignItem(&voucher.Channel, amount, validAt, voucher.Payer, condition)
 	if err != nil {
-		return err
+		return types.SignItem{}, err
 	}
-	voucher.Signature = sig
 
 	response.Vouchers = append(response.Vouchers, &voucher)
-	return nil
+	return item, nil
 }
@@ -0,0 +1,56 @@
+package porcelain
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+type powerPlumbing interface {
+	ChainHeadKey() block.TipSetKey
+	Snapshot(ctx context.Context, baseKey block.TipSetKey) (consensus.ActorStateSnapshot, error)
+}
+
+// ProtocolStoragePower returns the total storage power committed to the
+// network in the state of the head tipset.
+func ProtocolStoragePower(ctx context.Context, plumbing powerPlumbing) (*types.BytesAmount, error) {
+	view, err := headPowerTableView(ctx, plumbing)
+	if err != nil {
+		return nil, err
+	}
+	return view.Total(ctx)
+}
+
+// MinerStoragePower returns the storage power of the given miner and the total
+// storage power of the network in the state of the head tipset.
+// Unlike MinerGetPower, both values are read from the same state snapshot.
+func MinerStoragePower(ctx context.Context, plumbing powerPlumbing, minerAddr address.Address) (miner, total *types.BytesAmount, err error) {
+	view, err := headPowerTableView(ctx, plumbing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	miner, err = view.Miner(ctx, minerAddr)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to get power of miner %s", minerAddr)
+	}
+
+	total, err = view.Total(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get total power")
+	}
+	return miner, total, nil
+}
+
+func headPowerTableView(ctx context.Context, plumbing powerPlumbing) (consensus.PowerTableView, error) {
+	snapshot, err := plumbing.Snapshot(ctx, plumbing.ChainHeadKey())
+	if err != nil {
+		return consensus.PowerTableView{}, errors.Wrap(err, "failed to snapshot head state")
+	}
+	return consensus.NewPowerTableView(snapshot), nil
+}
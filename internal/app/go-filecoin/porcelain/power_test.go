@@ -0,0 +1,88 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-hamt-ipld"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/tools/gengen/util"
+)
+
+type testPowerPlumbing struct {
+	snapshot consensus.ActorStateSnapshot
+}
+
+func (tpp *testPowerPlumbing) ChainHeadKey() block.TipSetKey {
+	return block.NewTipSetKey()
+}
+
+func (tpp *testPowerPlumbing) Snapshot(ctx context.Context, baseKey block.TipSetKey) (consensus.ActorStateSnapshot, error) {
+	return tpp.snapshot, nil
+}
+
+func TestStoragePower(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	r := repo.NewInMemoryRepo()
+	bs := bstore.NewBlockstore(r.Datastore())
+	cst := hamt.NewCborStore()
+
+	genCfg := &gengen.GenesisCfg{
+		ProofsMode: types.TestProofsMode,
+		Keys:       2,
+		Miners: []*gengen.CreateStorageMinerConfig{
+			{
+				NumCommittedSectors: 3,
+				SectorSize:          types.OneKiBSectorSize.Uint64(),
+			},
+			{
+				NumCommittedSectors: 5,
+				SectorSize:          types.OneKiBSectorSize.Uint64(),
+			},
+		},
+		Network: "powertest",
+	}
+	info, err := gengen.GenGen(ctx, genCfg, cst, bs, 0)
+	require.NoError(t, err)
+
+	var genesis block.Block
+	require.NoError(t, cst.Get(ctx, info.GenesisCid, &genesis))
+	st, err := state.LoadStateTree(ctx, cst, genesis.StateRoot)
+	require.NoError(t, err)
+
+	as := consensus.NewActorStateStore(nil, cst, bs, consensus.NewDefaultProcessor())
+	plumbing := &testPowerPlumbing{snapshot: as.StateTreeSnapshot(st, types.NewBlockHeight(0))}
+
+	expectedTotal := types.NewBytesAmount(types.OneKiBSectorSize.Uint64() * 8)
+
+	t.Run("returns total network power", func(t *testing.T) {
+		total, err := porcelain.ProtocolStoragePower(ctx, plumbing)
+		require.NoError(t, err)
+		assert.True(t, expectedTotal.Equal(total))
+	})
+
+	t.Run("returns miner and total power", func(t *testing.T) {
+		miner, total, err := porcelain.MinerStoragePower(ctx, plumbing, info.Miners[1].Address)
+		require.NoError(t, err)
+		assert.True(t, types.NewBytesAmount(types.OneKiBSectorSize.Uint64()*5).Equal(miner))
+		assert.True(t, expectedTotal.Equal(total))
+	})
+
+	t.Run("errors for an unknown miner", func(t *testing.T) {
+		_, _, err := porcelain.MinerStoragePower(ctx, plumbing, address.NewForTestGetter()())
+		assert.Error(t, err)
+	})
+}
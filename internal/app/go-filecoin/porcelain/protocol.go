@@ -11,6 +11,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 )
 
 // SectorInfo provides information about a sector construction
@@ -77,6 +78,68 @@ func ProtocolParameters(ctx context.Context, plumbing protocolParamsPlumbing) (*
 	}, nil
 }
 
+type blockRewardPlumbing interface {
+	ChainHeadKey() block.TipSetKey
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+	BlockRewardAt(height uint64) types.AttoFIL
+}
+
+// ProtocolBlockReward returns the block reward at the height of the current
+// head
+func ProtocolBlockReward(ctx context.Context, plumbing blockRewardPlumbing) (types.AttoFIL, error) {
+	head, err := plumbing.ChainTipSet(plumbing.ChainHeadKey())
+	if err != nil {
+		return types.ZeroAttoFIL, errors.Wrap(err, "could not load head tipset")
+	}
+	height, err := head.Height()
+	if err != nil {
+		return types.ZeroAttoFIL, err
+	}
+	return plumbing.BlockRewardAt(height), nil
+}
+
+// ActivatedUpgrade describes a protocol version scheduled for the node's
+// network.
+type ActivatedUpgrade struct {
+	// Version is the protocol version.
+	Version uint64
+	// EffectiveAt is the height at which the version takes effect.
+	EffectiveAt *types.BlockHeight
+	// Activated is true if the current head is at or above EffectiveAt.
+	Activated bool
+}
+
+type versionHistoryPlumbing interface {
+	ChainHeadKey() block.TipSetKey
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+	ProtocolVersions() []version.ProtocolVersion
+}
+
+// ProtocolVersionHistory returns each protocol version scheduled for the
+// node's network in order of the height at which it takes effect, noting
+// whether the current head has reached that height.
+func ProtocolVersionHistory(ctx context.Context, plumbing versionHistoryPlumbing) ([]ActivatedUpgrade, error) {
+	head, err := plumbing.ChainTipSet(plumbing.ChainHeadKey())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load head tipset")
+	}
+	h, err := head.Height()
+	if err != nil {
+		return nil, err
+	}
+	height := types.NewBlockHeight(h)
+
+	var history []ActivatedUpgrade
+	for _, v := range plumbing.ProtocolVersions() {
+		history = append(history, ActivatedUpgrade{
+			Version:     v.Version,
+			EffectiveAt: v.EffectiveAt,
+			Activated:   !height.LessThan(v.EffectiveAt),
+		})
+	}
+	return history, nil
+}
+
 // IsSupportedSectorSize returns true if the given sector size is supported by
 // the network.
 func (pp *ProtocolParams) IsSupportedSectorSize(sectorSize *types.BytesAmount) bool {
@@ -8,7 +8,9 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 	"github.com/filecoin-project/go-sectorbuilder"
 	"github.com/pkg/errors"
 
@@ -73,3 +75,84 @@ func TestProtocolParams(t *testing.T) {
 		assert.Equal(t, expected, out)
 	})
 }
+
+// testBlockRewardPlumbing pays a reward of one FIL per unit of height.
+type testBlockRewardPlumbing struct {
+	*porcelain.FakeChainPlumbing
+}
+
+func (brp *testBlockRewardPlumbing) BlockRewardAt(height uint64) types.AttoFIL {
+	return types.NewAttoFILFromFIL(height)
+}
+
+func TestProtocolBlockReward(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	store := chain.NewFakeStore(builder)
+	plumbing := &testBlockRewardPlumbing{porcelain.NewFakeChainPlumbing(store)}
+
+	t.Run("empty head", func(t *testing.T) {
+		_, err := porcelain.ProtocolBlockReward(ctx, plumbing)
+		assert.Error(t, err)
+	})
+
+	t.Run("reward at head height", func(t *testing.T) {
+		head := builder.NewGenesis()
+		require.NoError(t, store.SetHead(ctx, head))
+		for _, height := range []uint64{0, 1, 5} {
+			if height > 0 {
+				head = builder.AppendManyOn(int(height-mustHeight(t, head)), head)
+				require.NoError(t, store.SetHead(ctx, head))
+			}
+
+			reward, err := porcelain.ProtocolBlockReward(ctx, plumbing)
+			require.NoError(t, err)
+			assert.Equal(t, types.NewAttoFILFromFIL(height), reward)
+		}
+	})
+}
+
+type testVersionHistoryPlumbing struct {
+	*porcelain.FakeChainPlumbing
+	pvt *version.ProtocolVersionTable
+}
+
+func (vhp *testVersionHistoryPlumbing) ProtocolVersions() []version.ProtocolVersion {
+	return vhp.pvt.Versions()
+}
+
+func TestProtocolVersionHistory(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	pvt, err := version.NewProtocolVersionTableBuilder(version.TEST).
+		Add(version.TEST, version.Protocol1, types.NewBlockHeight(0)).
+		Add(version.TEST, version.Protocol2, types.NewBlockHeight(3)).
+		Add(version.TEST, version.Protocol3, types.NewBlockHeight(10)).
+		Build()
+	require.NoError(t, err)
+
+	builder := chain.NewBuilder(t, address.Undef)
+	store := chain.NewFakeStore(builder)
+	plumbing := &testVersionHistoryPlumbing{porcelain.NewFakeChainPlumbing(store), pvt}
+
+	head := builder.AppendManyOn(5, builder.NewGenesis())
+	require.NoError(t, store.SetHead(ctx, head))
+	require.Equal(t, uint64(5), mustHeight(t, head))
+
+	history, err := porcelain.ProtocolVersionHistory(ctx, plumbing)
+	require.NoError(t, err)
+	assert.Equal(t, []porcelain.ActivatedUpgrade{
+		{Version: version.Protocol1, EffectiveAt: types.NewBlockHeight(0), Activated: true},
+		{Version: version.Protocol2, EffectiveAt: types.NewBlockHeight(3), Activated: true},
+		{Version: version.Protocol3, EffectiveAt: types.NewBlockHeight(10), Activated: false},
+	}, history)
+}
+
+func mustHeight(t *testing.T, ts block.TipSet) uint64 {
+	h, err := ts.Height()
+	require.NoError(t, err)
+	return h
+}
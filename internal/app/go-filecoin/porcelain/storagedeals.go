@@ -52,8 +52,47 @@ type dealLsPlumbing interface {
 	DealsIterator() (*query.Results, error)
 }
 
+// DealFilter selects deals by counterparty and state. Zero valued fields match
+// every deal.
+type DealFilter struct {
+	// Miner matches deals made with this storage miner.
+	Miner address.Address
+	// Client matches deals paid for by this client.
+	Client address.Address
+	// States matches deals in any of these states.
+	States []storagedeal.State
+}
+
+// Match returns true if the deal satisfies every field set in the filter.
+func (f DealFilter) Match(deal *storagedeal.Deal) bool {
+	if !f.Miner.Empty() && deal.Miner != f.Miner {
+		return false
+	}
+	if !f.Client.Empty() && (deal.Proposal == nil || deal.Proposal.Payment.Payer != f.Client) {
+		return false
+	}
+	if len(f.States) > 0 {
+		if deal.Response == nil {
+			return false
+		}
+		for _, state := range f.States {
+			if deal.Response.State == state {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
 // DealsLs returns an channel with all deals or a possible error
 func DealsLs(ctx context.Context, plumbing dealLsPlumbing) (<-chan *StorageDealLsResult, error) {
+	return DealsLsFiltered(ctx, plumbing, DealFilter{})
+}
+
+// DealsLsFiltered returns a channel with the deals matching the filter or a
+// possible error
+func DealsLsFiltered(ctx context.Context, plumbing dealLsPlumbing, filter DealFilter) (<-chan *StorageDealLsResult, error) {
 	out := make(chan *StorageDealLsResult)
 	results, err := plumbing.DealsIterator()
 	if err != nil {
@@ -77,6 +116,9 @@ func DealsLs(ctx context.Context, plumbing dealLsPlumbing) (<-chan *StorageDealL
 					}
 					return
 				}
+				if !filter.Match(&storageDeal) {
+					continue
+				}
 				out <- &StorageDealLsResult{
 					Deal: storageDeal,
 				}
@@ -6,12 +6,15 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore/query"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/strgdls"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/storagedeal"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
@@ -84,6 +87,82 @@ func TestDealGetNotFound(t *testing.T) {
 	assert.Nil(t, resultDeal)
 }
 
+type testDealStorePlumbing struct {
+	store *strgdls.Store
+}
+
+func (tdsp *testDealStorePlumbing) ConfigGet(path string) (interface{}, error) {
+	return nil, nil
+}
+
+func (tdsp *testDealStorePlumbing) DealsIterator() (*query.Results, error) {
+	return tdsp.store.Iterator()
+}
+
+func TestDealsLsFiltered(t *testing.T) {
+	tf.UnitTest(t)
+
+	addressMaker := address.NewForTestGetter()
+	cidGetter := types.NewCidForTestGetter()
+	minerA, minerB := addressMaker(), addressMaker()
+	clientA, clientB := addressMaker(), addressMaker()
+
+	plumbing := &testDealStorePlumbing{store: strgdls.New(repo.NewInMemoryRepo().DealsDs)}
+	newDeal := func(miner, client address.Address, state storagedeal.State) cid.Cid {
+		proposalCid := cidGetter()
+		require.NoError(t, plumbing.store.Put(&storagedeal.Deal{
+			Miner: miner,
+			Proposal: &storagedeal.SignedProposal{
+				Proposal: storagedeal.Proposal{
+					PieceRef:     cidGetter(),
+					Size:         types.NewBytesAmount(1),
+					TotalPrice:   types.NewAttoFILFromFIL(1),
+					MinerAddress: miner,
+					Payment:      storagedeal.PaymentInfo{Payer: client},
+				},
+			},
+			Response: &storagedeal.SignedResponse{
+				Response: storagedeal.Response{
+					State:       state,
+					ProposalCid: proposalCid,
+				},
+			},
+		}))
+		return proposalCid
+	}
+
+	d1 := newDeal(minerA, clientA, storagedeal.Accepted)
+	d2 := newDeal(minerA, clientB, storagedeal.Complete)
+	d3 := newDeal(minerB, clientA, storagedeal.Complete)
+	d4 := newDeal(minerB, clientB, storagedeal.Failed)
+
+	list := func(filter porcelain.DealFilter) map[cid.Cid]struct{} {
+		dealCh, err := porcelain.DealsLsFiltered(context.Background(), plumbing, filter)
+		require.NoError(t, err)
+		found := make(map[cid.Cid]struct{})
+		for result := range dealCh {
+			require.NoError(t, result.Err)
+			found[result.Deal.Response.ProposalCid] = struct{}{}
+		}
+		return found
+	}
+	set := func(cids ...cid.Cid) map[cid.Cid]struct{} {
+		s := make(map[cid.Cid]struct{})
+		for _, c := range cids {
+			s[c] = struct{}{}
+		}
+		return s
+	}
+
+	assert.Equal(t, set(d1, d2, d3, d4), list(porcelain.DealFilter{}))
+	assert.Equal(t, set(d1, d2), list(porcelain.DealFilter{Miner: minerA}))
+	assert.Equal(t, set(d1, d3), list(porcelain.DealFilter{Client: clientA}))
+	assert.Equal(t, set(d2, d3), list(porcelain.DealFilter{States: []storagedeal.State{storagedeal.Complete}}))
+	assert.Equal(t, set(d3, d4), list(porcelain.DealFilter{States: []storagedeal.State{storagedeal.Complete, storagedeal.Failed}, Miner: minerB}))
+	assert.Equal(t, set(d4), list(porcelain.DealFilter{Miner: minerB, Client: clientB}))
+	assert.Equal(t, set(), list(porcelain.DealFilter{Miner: minerA, States: []storagedeal.State{storagedeal.Failed}}))
+}
+
 type testRedeemPlumbing struct {
 	t *testing.T
 
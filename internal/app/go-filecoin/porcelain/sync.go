@@ -0,0 +1,53 @@
+package porcelain
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/syncer"
+)
+
+type syncStatusPlumbing interface {
+	ChainHeadKey() block.TipSetKey
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+	SyncCurrentTarget() (*syncer.Target, bool)
+	SyncHighestTarget() uint64
+}
+
+// SyncState summarises how far the local chain is behind the chains
+// announced to the node.
+type SyncState struct {
+	// HeadHeight is the height of the local chain head.
+	HeadHeight uint64
+	// HighestTargetHeight is the greatest height claimed by a chain
+	// announced to the node that has not failed to sync or been found bad.
+	HighestTargetHeight uint64
+	// BlocksBehind estimates how many rounds the head is behind the highest
+	// target, zero if it is not behind.
+	BlocksBehind uint64
+	// Syncing is true while a sync target is being processed.
+	Syncing bool
+}
+
+// SyncStatus reports the node's sync state from the chain head and the sync
+// dispatcher.
+func SyncStatus(ctx context.Context, plumbing syncStatusPlumbing) (SyncState, error) {
+	head, err := plumbing.ChainTipSet(plumbing.ChainHeadKey())
+	if err != nil {
+		return SyncState{}, err
+	}
+	headHeight, err := head.Height()
+	if err != nil {
+		return SyncState{}, err
+	}
+
+	state := SyncState{
+		HeadHeight:          headHeight,
+		HighestTargetHeight: plumbing.SyncHighestTarget(),
+	}
+	if state.HighestTargetHeight > headHeight {
+		state.BlocksBehind = state.HighestTargetHeight - headHeight
+	}
+	_, state.Syncing = plumbing.SyncCurrentTarget()
+	return state, nil
+}
@@ -0,0 +1,68 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/syncer"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+type testSyncStatusPlumbing struct {
+	*porcelain.FakeChainPlumbing
+	current *syncer.Target
+	highest uint64
+}
+
+func (p *testSyncStatusPlumbing) SyncCurrentTarget() (*syncer.Target, bool) {
+	return p.current, p.current != nil
+}
+
+func (p *testSyncStatusPlumbing) SyncHighestTarget() uint64 {
+	return p.highest
+}
+
+func TestSyncStatus(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	store := chain.NewFakeStore(builder)
+	genesis := builder.NewGenesis()
+	head := builder.AppendManyOn(3, genesis)
+	require.NoError(t, store.SetHead(ctx, head))
+
+	t.Run("behind while syncing", func(t *testing.T) {
+		plumbing := &testSyncStatusPlumbing{
+			FakeChainPlumbing: porcelain.NewFakeChainPlumbing(store),
+			current:           &syncer.Target{},
+			highest:           10,
+		}
+		status, err := porcelain.SyncStatus(ctx, plumbing)
+		require.NoError(t, err)
+		assert.Equal(t, porcelain.SyncState{
+			HeadHeight:          3,
+			HighestTargetHeight: 10,
+			BlocksBehind:        7,
+			Syncing:             true,
+		}, status)
+	})
+
+	t.Run("caught up and idle", func(t *testing.T) {
+		plumbing := &testSyncStatusPlumbing{
+			FakeChainPlumbing: porcelain.NewFakeChainPlumbing(store),
+			highest:           2,
+		}
+		status, err := porcelain.SyncStatus(ctx, plumbing)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(3), status.HeadHeight)
+		assert.Equal(t, uint64(0), status.BlocksBehind)
+		assert.False(t, status.Syncing)
+	})
+}
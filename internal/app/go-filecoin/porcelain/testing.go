@@ -0,0 +1,28 @@
+package porcelain
+
+import (
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+)
+
+// FakeChainPlumbing serves chain reading plumbing from a chain.FakeStore, so
+// porcelain chain functions can be tested over chains made with a
+// chain.Builder.
+type FakeChainPlumbing struct {
+	store *chain.FakeStore
+}
+
+// NewFakeChainPlumbing creates plumbing reading the chain from `store`.
+func NewFakeChainPlumbing(store *chain.FakeStore) *FakeChainPlumbing {
+	return &FakeChainPlumbing{store: store}
+}
+
+// ChainHeadKey returns the store's head key.
+func (p *FakeChainPlumbing) ChainHeadKey() block.TipSetKey {
+	return p.store.GetHead()
+}
+
+// ChainTipSet returns the tipset with key `key` from the store.
+func (p *FakeChainPlumbing) ChainTipSet(key block.TipSetKey) (block.TipSet, error) {
+	return p.store.GetTipSet(key)
+}
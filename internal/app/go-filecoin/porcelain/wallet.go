@@ -2,12 +2,16 @@ package porcelain
 
 import (
 	"context"
+
+	bls "github.com/filecoin-project/go-bls-sigs"
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/actor"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/wallet"
 )
 
 // ErrNoDefaultFromAddress is returned when a default wallet address couldn't be determined (eg, there are zero addresses in the wallet).
@@ -61,3 +65,76 @@ func WalletDefaultAddress(plumbing wdaPlumbing) (address.Address, error) {
 
 	return address.Undef, ErrNoDefaultFromAddress
 }
+
+type wsmPlumbing interface {
+	SignBytes(data []byte, addr address.Address) (types.Signature, error)
+	WalletFind(address address.Address) (wallet.Backend, error)
+}
+
+// WalletSignMessage signs `msg` with the wallet key for `from` without sending
+// it. The message's from address is set to `from` if it is empty and must
+// otherwise match it. It errors if the wallet holds no key for `from`.
+func WalletSignMessage(ctx context.Context, plumbing wsmPlumbing, msg *types.UnsignedMessage, from address.Address) (*types.SignedMessage, error) {
+	if !msg.From.Empty() && msg.From != from {
+		return nil, errors.Errorf("message from address %s does not match signing address %s", msg.From, from)
+	}
+	if _, err := plumbing.WalletFind(from); err != nil {
+		return nil, errors.Wrapf(err, "no wallet key for %s", from)
+	}
+
+	toSign := *msg
+	toSign.From = from
+	return types.NewSignedMessage(toSign, plumbing)
+}
+
+type wiPlumbing interface {
+	WalletFind(address address.Address) (wallet.Backend, error)
+	WalletImport(kinfos ...*types.KeyInfo) ([]address.Address, error)
+}
+
+// WalletImportKey adds the key `ki` to the wallet and returns its address. It
+// errors if the key is malformed or the wallet already holds it.
+func WalletImportKey(ctx context.Context, plumbing wiPlumbing, ki *types.KeyInfo) (address.Address, error) {
+	if ki == nil {
+		return address.Undef, errors.New("no key to import")
+	}
+	switch ki.CryptSystem {
+	case types.SECP256K1:
+		if len(ki.PrivateKey) != crypto.PrivateKeyBytes {
+			return address.Undef, errors.Errorf("secp256k1 private key must be %d bytes, got %d", crypto.PrivateKeyBytes, len(ki.PrivateKey))
+		}
+	case types.BLS:
+		if len(ki.PrivateKey) != bls.PrivateKeyBytes {
+			return address.Undef, errors.Errorf("bls private key must be %d bytes, got %d", bls.PrivateKeyBytes, len(ki.PrivateKey))
+		}
+	default:
+		return address.Undef, errors.Errorf("unknown crypto system: %s", ki.CryptSystem)
+	}
+
+	addr, err := ki.Address()
+	if err != nil {
+		return address.Undef, err
+	}
+	if _, err := plumbing.WalletFind(addr); err == nil {
+		return address.Undef, errors.Errorf("wallet already holds a key for %s", addr)
+	}
+
+	if _, err := plumbing.WalletImport(ki); err != nil {
+		return address.Undef, errors.Wrap(err, "failed to import key")
+	}
+	return addr, nil
+}
+
+type wePlumbing interface {
+	WalletExport(addrs []address.Address) ([]*types.KeyInfo, error)
+}
+
+// WalletExportKey returns the key the wallet holds for `addr`. It errors if the
+// wallet holds no key for `addr`.
+func WalletExportKey(ctx context.Context, plumbing wePlumbing, addr address.Address) (*types.KeyInfo, error) {
+	kis, err := plumbing.WalletExport([]address.Address{addr})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to export key for %s", addr)
+	}
+	return kis[0], nil
+}
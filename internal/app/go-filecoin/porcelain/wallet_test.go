@@ -111,6 +111,117 @@ func TestWalletDefaultAddress(t *testing.T) {
 	})
 }
 
+type wsmTestPlumbing struct {
+	wallet *wallet.Wallet
+}
+
+func (wsmtp *wsmTestPlumbing) SignBytes(data []byte, addr address.Address) (types.Signature, error) {
+	return wsmtp.wallet.SignBytes(data, addr)
+}
+
+func (wsmtp *wsmTestPlumbing) WalletFind(addr address.Address) (wallet.Backend, error) {
+	return wsmtp.wallet.Find(addr)
+}
+
+func TestWalletSignMessage(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	backend, err := wallet.NewDSBackend(repo.NewInMemoryRepo().WalletDatastore())
+	require.NoError(t, err)
+	plumbing := &wsmTestPlumbing{wallet: wallet.New(backend)}
+	from, err := wallet.NewAddress(plumbing.wallet, address.SECP256K1)
+	require.NoError(t, err)
+	to := address.NewForTestGetter()()
+
+	t.Run("signs with the wallet key", func(t *testing.T) {
+		msg := types.NewUnsignedMessage(address.Undef, to, 0, types.NewAttoFILFromFIL(1), "", nil)
+		smsg, err := porcelain.WalletSignMessage(ctx, plumbing, msg, from)
+		require.NoError(t, err)
+		assert.Equal(t, from, smsg.Message.From)
+		assert.True(t, smsg.VerifySignature())
+	})
+
+	t.Run("errors without a wallet key", func(t *testing.T) {
+		unknown := address.NewForTestGetter()()
+		msg := types.NewUnsignedMessage(unknown, to, 0, types.NewAttoFILFromFIL(1), "", nil)
+		_, err := porcelain.WalletSignMessage(ctx, plumbing, msg, unknown)
+		assert.Error(t, err)
+	})
+
+	t.Run("errors on mismatched from address", func(t *testing.T) {
+		msg := types.NewUnsignedMessage(to, to, 0, types.NewAttoFILFromFIL(1), "", nil)
+		_, err := porcelain.WalletSignMessage(ctx, plumbing, msg, from)
+		assert.Error(t, err)
+	})
+}
+
+type wieTestPlumbing struct {
+	wallet *wallet.Wallet
+}
+
+func (wietp *wieTestPlumbing) WalletFind(addr address.Address) (wallet.Backend, error) {
+	return wietp.wallet.Find(addr)
+}
+
+func (wietp *wieTestPlumbing) WalletImport(kinfos ...*types.KeyInfo) ([]address.Address, error) {
+	return wietp.wallet.Import(kinfos...)
+}
+
+func (wietp *wieTestPlumbing) WalletExport(addrs []address.Address) ([]*types.KeyInfo, error) {
+	return wietp.wallet.Export(addrs)
+}
+
+func TestWalletImportExport(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	newPlumbing := func() *wieTestPlumbing {
+		backend, err := wallet.NewDSBackend(repo.NewInMemoryRepo().WalletDatastore())
+		require.NoError(t, err)
+		return &wieTestPlumbing{wallet: wallet.New(backend)}
+	}
+
+	t.Run("round trips a key", func(t *testing.T) {
+		plumbing := newPlumbing()
+		ki := types.MustGenerateKeyInfo(1, 42)[0]
+		expected, err := ki.Address()
+		require.NoError(t, err)
+
+		addr, err := porcelain.WalletImportKey(ctx, plumbing, &ki)
+		require.NoError(t, err)
+		assert.Equal(t, expected, addr)
+
+		exported, err := porcelain.WalletExportKey(ctx, plumbing, addr)
+		require.NoError(t, err)
+		assert.True(t, ki.Equals(exported))
+	})
+
+	t.Run("rejects a duplicate key", func(t *testing.T) {
+		plumbing := newPlumbing()
+		ki := types.MustGenerateKeyInfo(1, 42)[0]
+		_, err := porcelain.WalletImportKey(ctx, plumbing, &ki)
+		require.NoError(t, err)
+
+		_, err = porcelain.WalletImportKey(ctx, plumbing, &ki)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects malformed keys", func(t *testing.T) {
+		plumbing := newPlumbing()
+		_, err := porcelain.WalletImportKey(ctx, plumbing, &types.KeyInfo{PrivateKey: []byte{1, 2, 3}, CryptSystem: types.SECP256K1})
+		assert.Error(t, err)
+
+		_, err = porcelain.WalletImportKey(ctx, plumbing, &types.KeyInfo{PrivateKey: make([]byte, 32), CryptSystem: "rot13"})
+		assert.Error(t, err)
+	})
+
+	t.Run("export errors for an unknown address", func(t *testing.T) {
+		_, err := porcelain.WalletExportKey(ctx, newPlumbing(), address.NewForTestGetter()())
+		assert.Error(t, err)
+	})
+}
+
 func isInList(needle address.Address, haystack []address.Address) bool {
 	for _, a := range haystack {
 		if a == needle {
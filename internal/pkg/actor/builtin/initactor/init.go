@@ -1,6 +1,8 @@
 package initactor
 
 import (
+	"math/big"
+
 	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/ipfs/go-cid"
@@ -8,6 +10,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/abi"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/actor"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/exec"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/errors"
 )
 
@@ -18,6 +21,10 @@ type Actor struct{}
 // State is the init actor's storage.
 type State struct {
 	Network string
+	// ProtocolVersions is the protocol upgrade schedule recorded at genesis, if any.
+	ProtocolVersions []version.ProtocolVersion `refmt:",omitempty"`
+	// GenesisTime is the unix timestamp of the genesis block, if recorded at genesis.
+	GenesisTime uint64 `refmt:",omitempty"`
 }
 
 // Ensure InitActor is an ExecutableActor at compile time.
@@ -29,6 +36,10 @@ var initExports = exec.Exports{
 		Params: []abi.Type{},
 		Return: []abi.Type{abi.String},
 	},
+	"getGenesisTime": &exec.FunctionSignature{
+		Params: []abi.Type{},
+		Return: []abi.Type{abi.Integer},
+	},
 }
 
 // Exports makes the available methods for this contract available.
@@ -75,3 +86,18 @@ func (sma *Actor) GetNetwork(vmctx exec.VMContext) (string, uint8, error) {
 
 	return state.Network, 0, nil
 }
+
+// GetGenesisTime returns the unix timestamp of the genesis block recorded at genesis.
+func (sma *Actor) GetGenesisTime(vmctx exec.VMContext) (*big.Int, uint8, error) {
+	if err := vmctx.Charge(actor.DefaultGasCost); err != nil {
+		return nil, exec.ErrInsufficientGas, errors.RevertErrorWrap(err, "Insufficient gas")
+	}
+
+	var state State
+	err := actor.ReadState(vmctx, &state)
+	if err != nil {
+		return nil, errors.CodeError(err), err
+	}
+
+	return new(big.Int).SetUint64(state.GenesisTime), 0, nil
+}
@@ -653,11 +653,21 @@ const separator = 0x0
 // channel, amount, validAt (earliest block height for redeem) and from address.
 // It does so by signing the following bytes: (channelID | 0x0 | amount | 0x0 | validAt)
 func SignVoucher(channelID *types.ChannelID, amount types.AttoFIL, validAt *types.BlockHeight, addr address.Address, condition *types.Predicate, signer types.Signer) (types.Signature, error) {
-	data, err := createVoucherSignatureData(channelID, amount, validAt, condition)
+	item, err := VoucherSignItem(channelID, amount, validAt, addr, condition)
 	if err != nil {
 		return nil, err
 	}
-	return signer.SignBytes(data, addr)
+	return signer.SignBytes(item.Data, item.Addr)
+}
+
+// VoucherSignItem returns the payload SignVoucher signs, for signing many
+// vouchers at once with types.SignBatch.
+func VoucherSignItem(channelID *types.ChannelID, amount types.AttoFIL, validAt *types.BlockHeight, addr address.Address, condition *types.Predicate) (types.SignItem, error) {
+	data, err := createVoucherSignatureData(channelID, amount, validAt, condition)
+	if err != nil {
+		return types.SignItem{}, err
+	}
+	return types.SignItem{Data: data, Addr: addr}, nil
 }
 
 // VerifyVoucherSignature returns whether the voucher's signature is valid
@@ -92,9 +92,12 @@ func TestSecp256k1Address(t *testing.T) {
 	sk, err := crypto.GenerateKey()
 	assert.NoError(t, err)
 
-	addr, err := NewSecp256k1Address(crypto.PublicKey(sk))
+	pk := crypto.PublicKey(sk)
+	addr, err := NewSecp256k1Address(pk)
 	assert.NoError(t, err)
 	assert.Equal(t, SECP256K1, addr.Protocol())
+	assert.Equal(t, SECP256K1, addr.Bytes()[0])
+	assert.Equal(t, addressHash(pk), addr.Payload())
 
 	str, err := encode(Mainnet, addr)
 	assert.NoError(t, err)
@@ -260,6 +263,8 @@ func TestRandomBLSAddress(t *testing.T) {
 	addr, err := NewBLSAddress(pk[:])
 	assert.NoError(t, err)
 	assert.Equal(t, BLS, addr.Protocol())
+	assert.Equal(t, BLS, addr.Bytes()[0])
+	assert.Equal(t, pk[:], addr.Payload())
 
 	str, err := encode(Mainnet, addr)
 	assert.NoError(t, err)
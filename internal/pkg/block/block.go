@@ -47,6 +47,9 @@ type Block struct {
 	// an election.
 	ElectionProof VRFPi `json:"proof"`
 
+	// PoStProof is the miner's proof of spacetime over its committed sectors.
+	PoStProof types.PoStProof `json:"postProof,omitempty" refmt:",omitempty"`
+
 	// The timestamp, in seconds since the Unix epoch, at which this block was created.
 	Timestamp types.Uint64 `json:"timestamp"`
 
@@ -146,6 +149,7 @@ func (b *Block) SignatureData() []byte {
 		StateRoot:       b.StateRoot,
 		MessageReceipts: b.MessageReceipts,
 		ElectionProof:   b.ElectionProof,
+		PoStProof:       b.PoStProof,
 		Timestamp:       b.Timestamp,
 		BLSAggregateSig: b.BLSAggregateSig,
 		// BlockSig omitted
@@ -71,6 +71,7 @@ func TestTriangleEncoding(t *testing.T) {
 			Parents:         blk.NewTipSetKey(types.CidFromString(t, "somecid")),
 			ParentWeight:    types.Uint64(1000),
 			ElectionProof:   types.NewTestPoSt(),
+			PoStProof:       []byte{0x4},
 			StateRoot:       types.CidFromString(t, "somecid"),
 			Timestamp:       types.Uint64(1),
 			BlockSig:        []byte{0x3},
@@ -82,7 +83,7 @@ func TestTriangleEncoding(t *testing.T) {
 		// Also please add non zero fields to "b" and "diff" in TestSignatureData
 		// and add a new check that different values of the new field result in
 		// different output data.
-		require.Equal(t, 14, s.NumField()) // Note: this also counts private fields
+		require.Equal(t, 15, s.NumField()) // Note: this also counts private fields
 		testRoundTrip(t, b)
 	})
 }
@@ -235,6 +236,7 @@ func TestSignatureData(t *testing.T) {
 		Parents:         blk.NewTipSetKey(types.CidFromString(t, "somecid")),
 		ParentWeight:    types.Uint64(1000),
 		ElectionProof:   []byte{0x1},
+		PoStProof:       []byte{0x5},
 		StateRoot:       types.CidFromString(t, "somecid"),
 		Timestamp:       types.Uint64(1),
 		BlockSig:        []byte{0x3},
@@ -249,6 +251,7 @@ func TestSignatureData(t *testing.T) {
 		Parents:         blk.NewTipSetKey(types.CidFromString(t, "someothercid")),
 		ParentWeight:    types.Uint64(1001),
 		ElectionProof:   []byte{0x2},
+		PoStProof:       []byte{0x6},
 		StateRoot:       types.CidFromString(t, "someothercid"),
 		Timestamp:       types.Uint64(4),
 		BlockSig:        []byte{0x4},
@@ -357,6 +360,17 @@ func TestSignatureData(t *testing.T) {
 		assert.False(t, bytes.Equal(before, after))
 	}()
 
+	func() {
+		before := b.SignatureData()
+
+		cpy := b.PoStProof
+		defer func() { b.PoStProof = cpy }()
+
+		b.PoStProof = diff.PoStProof
+		after := b.SignatureData()
+		assert.False(t, bytes.Equal(before, after))
+	}()
+
 	func() {
 		before := b.SignatureData()
 
@@ -4,6 +4,26 @@ import (
 	"fmt"
 
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+)
+
+func init() {
+	encoding.RegisterIpldCborType(ChainInfoWire{})
+}
+
+// ChainInfoWireVersion is the version of the chain info wire encoding produced by this node.
+const ChainInfoWireVersion = 1
+
+var (
+	// ErrUnsupportedChainInfoVersion is returned when decoding a chain info with an unknown
+	// wire version.
+	ErrUnsupportedChainInfoVersion = errors.New("unsupported chain info wire version")
+	// ErrEmptyChainInfoHead is returned when a chain info has no head tipset key.
+	ErrEmptyChainInfoHead = errors.New("chain info head is empty")
+	// ErrUndefinedChainInfoHead is returned when a chain info head contains an undefined CID.
+	ErrUndefinedChainInfoHead = errors.New("chain info head contains an undefined cid")
 )
 
 // ChainInfo is used to track metadata about a peer and its chain.
@@ -23,6 +43,63 @@ func NewChainInfo(peer peer.ID, head TipSetKey, height uint64) *ChainInfo {
 	}
 }
 
+// ChainInfoWire is the versioned form of a ChainInfo exchanged between peers.
+type ChainInfoWire struct {
+	Version uint64
+	Peer    string
+	Head    TipSetKey
+	Height  uint64
+}
+
+// ToWire returns the wire form of the chain info at the current wire version.
+func (i *ChainInfo) ToWire() ChainInfoWire {
+	return ChainInfoWire{
+		Version: ChainInfoWireVersion,
+		Peer:    string(i.Peer),
+		Head:    i.Head,
+		Height:  i.Height,
+	}
+}
+
+// ChainInfoFromWire validates a wire chain info and converts it to a ChainInfo.
+func ChainInfoFromWire(w ChainInfoWire) (*ChainInfo, error) {
+	if w.Version != ChainInfoWireVersion {
+		return nil, errors.Wrapf(ErrUnsupportedChainInfoVersion, "version %d", w.Version)
+	}
+	ci := NewChainInfo(peer.ID(w.Peer), w.Head, w.Height)
+	if err := ci.Validate(); err != nil {
+		return nil, err
+	}
+	return ci, nil
+}
+
+// EncodeChainInfo encodes the wire form of a chain info.
+func EncodeChainInfo(ci *ChainInfo) ([]byte, error) {
+	return encoding.Encode(ci.ToWire())
+}
+
+// DecodeChainInfo decodes and validates a chain info from its wire form.
+func DecodeChainInfo(raw []byte) (*ChainInfo, error) {
+	var w ChainInfoWire
+	if err := encoding.Decode(raw, &w); err != nil {
+		return nil, errors.Wrap(err, "malformed chain info")
+	}
+	return ChainInfoFromWire(w)
+}
+
+// Validate checks that the chain info names a head that could be fetched.
+func (i *ChainInfo) Validate() error {
+	if i.Head.Empty() {
+		return ErrEmptyChainInfoHead
+	}
+	for _, c := range i.Head.ToSlice() {
+		if !c.Defined() {
+			return ErrUndefinedChainInfoHead
+		}
+	}
+	return nil
+}
+
 // Returns a human-readable string representation of a chain info
 func (i *ChainInfo) String() string {
 	return fmt.Sprintf("{peer=%s height=%d head=%s}", i.Peer, i.Height, i.Head)
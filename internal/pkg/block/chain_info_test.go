@@ -0,0 +1,65 @@
+package block_test
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func TestChainInfoWireRoundTrip(t *testing.T) {
+	tf.UnitTest(t)
+
+	head := block.NewTipSetKey(types.CidFromString(t, "a"), types.CidFromString(t, "b"))
+	ci := block.NewChainInfo(th.RequireRandomPeerID(t), head, 42)
+
+	raw, err := block.EncodeChainInfo(ci)
+	require.NoError(t, err)
+	decoded, err := block.DecodeChainInfo(raw)
+	require.NoError(t, err)
+
+	assert.Equal(t, ci.Peer, decoded.Peer)
+	assert.True(t, ci.Head.Equals(decoded.Head))
+	assert.Equal(t, ci.Height, decoded.Height)
+}
+
+func TestChainInfoWireRejectsMalformed(t *testing.T) {
+	tf.UnitTest(t)
+
+	pid := th.RequireRandomPeerID(t)
+	head := block.NewTipSetKey(types.CidFromString(t, "a"))
+
+	t.Run("empty head", func(t *testing.T) {
+		raw, err := block.EncodeChainInfo(block.NewChainInfo(pid, block.NewTipSetKey(), 1))
+		require.NoError(t, err)
+		_, err = block.DecodeChainInfo(raw)
+		assert.Equal(t, block.ErrEmptyChainInfoHead, errors.Cause(err))
+	})
+
+	t.Run("undefined cid in head", func(t *testing.T) {
+		ci := block.NewChainInfo(pid, block.NewTipSetKey(cid.Undef), 1)
+		assert.Equal(t, block.ErrUndefinedChainInfoHead, ci.Validate())
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		w := block.NewChainInfo(pid, head, 1).ToWire()
+		w.Version = block.ChainInfoWireVersion + 1
+		raw, err := encoding.Encode(w)
+		require.NoError(t, err)
+		_, err = block.DecodeChainInfo(raw)
+		assert.Equal(t, block.ErrUnsupportedChainInfoVersion, errors.Cause(err))
+	})
+
+	t.Run("garbage bytes", func(t *testing.T) {
+		_, err := block.DecodeChainInfo([]byte{0xff, 0x00, 0x13})
+		assert.Error(t, err)
+	})
+}
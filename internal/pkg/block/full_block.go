@@ -1,6 +1,13 @@
 package block
 
-import "github.com/filecoin-project/go-filecoin/internal/pkg/types"
+import (
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func init() {
+	encoding.RegisterIpldCborType(FullBlock{})
+}
 
 // FullBlock carries a block header and the message and receipt collections
 // referenced from the header.
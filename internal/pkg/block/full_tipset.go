@@ -0,0 +1,40 @@
+package block
+
+import (
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+// FullTipSet carries the full blocks of a tipset in canonical tipset order,
+// and the tipset's messages with duplicates across blocks removed.
+type FullTipSet struct {
+	Blocks []*FullBlock
+	// Messages holds each distinct message of the tipset once, in the order
+	// first encountered walking the blocks in order.
+	Messages []*types.SignedMessage
+}
+
+// NewFullTipSet constructs a full tipset from full blocks in canonical tipset
+// order.
+func NewFullTipSet(blocks []*FullBlock) (*FullTipSet, error) {
+	seen := make(map[cid.Cid]struct{})
+	var msgs []*types.SignedMessage
+	for _, blk := range blocks {
+		for _, msg := range blk.Messages {
+			c, err := msg.Cid()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := seen[c]; dup {
+				continue
+			}
+			seen[c] = struct{}{}
+			msgs = append(msgs, msg)
+		}
+	}
+	return &FullTipSet{
+		Blocks:   blocks,
+		Messages: msgs,
+	}, nil
+}
@@ -45,6 +45,7 @@ func NewTipSet(blocks ...*Block) (TipSet, error) {
 	parents := first.Parents
 	weight := first.ParentWeight
 	cids := make([]cid.Cid, len(blocks))
+	seen := make(map[cid.Cid]struct{}, len(blocks))
 
 	sorted := make([]*Block, len(blocks))
 	for i, blk := range blocks {
@@ -59,8 +60,13 @@ func NewTipSet(blocks ...*Block) (TipSet, error) {
 				return UndefTipSet, errors.Errorf("Inconsistent block parent weights %d and %d", weight, blk.ParentWeight)
 			}
 		}
+		c := blk.Cid()
+		if _, dup := seen[c]; dup {
+			return UndefTipSet, errors.Errorf("Duplicate block %s in tipset", c)
+		}
+		seen[c] = struct{}{}
 		sorted[i] = blk
-		cids[i] = blk.Cid()
+		cids[i] = c
 	}
 
 	// Sort blocks by ticket
@@ -73,7 +79,7 @@ func NewTipSet(blocks ...*Block) (TipSet, error) {
 		return cmp < 0
 	})
 
-	// Duplicate blocks (CIDs) are rejected here, pass that error through.
+	// Duplicate blocks (CIDs) are rejected above, so this should not fail.
 	key, err := NewTipSetKeyFromUnique(cids...)
 	if err != nil {
 		return UndefTipSet, err
@@ -110,12 +116,19 @@ func (ts TipSet) ToSlice() []*Block {
 	return slice
 }
 
-// MinTicket returns the smallest ticket of all blocks in the tipset.
+// MinTicket returns the smallest ticket of all blocks in the tipset, ordering
+// tickets lexicographically by their sort key.
 func (ts TipSet) MinTicket() (Ticket, error) {
 	if len(ts.blocks) == 0 {
 		return Ticket{}, errUndefTipSet
 	}
-	return ts.blocks[0].Ticket, nil
+	min := ts.blocks[0].Ticket
+	for i := 1; i < len(ts.blocks); i++ {
+		if bytes.Compare(ts.blocks[i].Ticket.SortKey(), min.SortKey()) < 0 {
+			min = ts.blocks[i].Ticket
+		}
+	}
+	return min, nil
 }
 
 // MinTimestamp returns the smallest timestamp of all blocks in the tipset.
@@ -56,6 +56,7 @@ func TestTipSetKey(t *testing.T) {
 		s2 := blk.NewTipSetKey(c3, c2, c1)
 
 		assert.True(t, s1.Equals(s2))
+		assert.Equal(t, s1.String(), s2.String())
 
 		// Sorted order is not a defined property, but an important implementation detail to
 		// verify unless the implementation is changed.
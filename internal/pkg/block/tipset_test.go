@@ -167,10 +167,27 @@ func TestTipSet(t *testing.T) {
 	t.Run("duplicate block fails new tipset", func(t *testing.T) {
 		b1, b2, b3 = makeTestBlocks(t)
 		ts, err := blk.NewTipSet(b1, b2, b1)
-		assert.Error(t, err)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), b1.Cid().String())
 		assert.False(t, ts.Defined())
 	})
 
+	t.Run("identical copies of a block fail new tipset", func(t *testing.T) {
+		b1, _, _ = makeTestBlocks(t)
+		copied := *b1
+		ts, err := blk.NewTipSet(b1, &copied)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Duplicate block")
+		assert.False(t, ts.Defined())
+	})
+
+	t.Run("distinct blocks make new tipset", func(t *testing.T) {
+		b1, b2, b3 = makeTestBlocks(t)
+		ts, err := blk.NewTipSet(b1, b2, b3)
+		require.NoError(t, err)
+		assert.Equal(t, 3, ts.Len())
+	})
+
 	t.Run("mismatched height fails new tipset", func(t *testing.T) {
 		b1, b2, b3 = makeTestBlocks(t)
 		b1.Height = 3
@@ -218,6 +235,29 @@ func TestTipSetMinTicket(t *testing.T) {
 	assert.Equal(t, ts.At(2), b3)
 }
 
+// TestTipSetMinTicketLexicographic checks that MinTicket compares tickets
+// byte-wise rather than by length or insertion order.
+func TestTipSetMinTicketLexicographic(t *testing.T) {
+	tf.UnitTest(t)
+
+	b1, b2, b3 := makeTestBlocks(t)
+	b1.Ticket = blk.Ticket{VRFProof: []byte{0x2}}
+	b2.Ticket = blk.Ticket{VRFProof: []byte{0x1, 0xff, 0xff}}
+	b3.Ticket = blk.Ticket{VRFProof: []byte{0x1, 0xff, 0x0}}
+
+	for _, ts := range []blk.TipSet{
+		RequireNewTipSet(t, b1, b2, b3),
+		RequireNewTipSet(t, b3, b1, b2),
+	} {
+		minTicket, err := ts.MinTicket()
+		require.NoError(t, err)
+		assert.Equal(t, b3.Ticket, minTicket)
+	}
+
+	_, err := blk.UndefTipSet.MinTicket()
+	assert.Error(t, err)
+}
+
 func TestUndefKey(t *testing.T) {
 	ts := blk.UndefTipSet
 	udKey := ts.Key()
@@ -13,30 +13,43 @@ import (
 // that the cache is only in-memory, so it is reset whenever the node is restarted.
 // TODO: this needs to be limited.
 type badTipSetCache struct {
-	mu  sync.Mutex
-	bad map[string]struct{}
+	mu sync.Mutex
+	// bad is keyed by tipset key string, which is canonical for a set of CIDs,
+	// and holds the reason each tipset is bad.
+	bad map[string]error
 }
 
-// AddChain adds the chain of tipsets to the badTipSetCache.  For now it just
-// does the simplest thing and adds all blocks of the chain to the cache.
+// AddChain adds the chain of tipsets to the badTipSetCache, each bad for
+// `reason`.  For now it just does the simplest thing and adds all blocks of
+// the chain to the cache.
 // TODO: might want to cache a random subset once cache size is limited.
-func (cache *badTipSetCache) AddChain(chain []block.TipSet) {
+func (cache *badTipSetCache) AddChain(chain []block.TipSet, reason error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
 	for _, ts := range chain {
-		cache.Add(ts.String())
+		cache.bad[ts.Key().String()] = reason
 	}
 }
 
-// Add adds a single tipset key to the badTipSetCache.
-func (cache *badTipSetCache) Add(tsKey string) {
+// Add adds a single tipset key to the badTipSetCache, bad for `reason`.
+func (cache *badTipSetCache) Add(key block.TipSetKey, reason error) {
 	cache.mu.Lock()
 	defer cache.mu.Unlock()
-	cache.bad[tsKey] = struct{}{}
+	cache.bad[key.String()] = reason
 }
 
 // Has checks for membership in the badTipSetCache.
-func (cache *badTipSetCache) Has(tsKey string) bool {
+func (cache *badTipSetCache) Has(key block.TipSetKey) bool {
 	cache.mu.Lock()
 	defer cache.mu.Unlock()
-	_, ok := cache.bad[tsKey]
+	_, ok := cache.bad[key.String()]
 	return ok
 }
+
+// Reason returns the reason the tipset with key `key` is bad, or nil if it is
+// not in the badTipSetCache.
+func (cache *badTipSetCache) Reason(key block.TipSetKey) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.bad[key.String()]
+}
@@ -0,0 +1,35 @@
+package chain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func TestBadTipSetCacheReorderedKey(t *testing.T) {
+	tf.UnitTest(t)
+
+	cache := &badTipSetCache{bad: make(map[string]error)}
+	c1, c2 := types.CidFromString(t, "a"), types.CidFromString(t, "b")
+
+	cache.Add(block.NewTipSetKey(c1, c2), errors.New("bad"))
+	assert.True(t, cache.Has(block.NewTipSetKey(c2, c1)))
+	assert.False(t, cache.Has(block.NewTipSetKey(c1)))
+}
+
+func TestBadTipSetCacheReason(t *testing.T) {
+	tf.UnitTest(t)
+
+	cache := &badTipSetCache{bad: make(map[string]error)}
+	c1, c2 := types.CidFromString(t, "a"), types.CidFromString(t, "b")
+	reason := errors.New("invalid")
+
+	cache.Add(block.NewTipSetKey(c1), reason)
+	assert.Equal(t, reason, cache.Reason(block.NewTipSetKey(c1)))
+	assert.NoError(t, cache.Reason(block.NewTipSetKey(c2)))
+}
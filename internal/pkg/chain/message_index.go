@@ -0,0 +1,465 @@
+package chain
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func init() {
+	encoding.RegisterIpldCborType(MessageLocation{})
+	encoding.RegisterIpldCborType(persistedIndexHead{})
+}
+
+// messageIndexPrefix is the datastore namespace under which a persistent
+// MessageIndex stores its entries, one per message CID.
+var messageIndexPrefix = datastore.NewKey("/chain/msgindex/entries")
+
+// messageIndexHeadKey is the datastore key under which a persistent
+// MessageIndex stores the head it was last updated to.
+var messageIndexHeadKey = datastore.NewKey("/chain/msgindex/head")
+
+type messageIndexReader interface {
+	TipSetProvider
+	LoadMessages(context.Context, types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error)
+}
+
+// MessageIndex maps the CIDs of the messages on a chain to the key of the
+// tipset including them. A message included more than once is indexed at the
+// lowest tipset including it. The index is kept in step with the chain by
+// calling Update with each change of head.
+//
+// An unbounded index grows with the length of the chain. With a retention
+// window set, only messages in tipsets within that many heights of the head
+// are indexed and older entries are evicted as the head advances. Find then
+// falls back to scanning the chain below the window, trading lookup time for
+// old messages against memory.
+//
+// An index constructed with a datastore writes its entries and head through to
+// it, so that a restarted node can resume indexing from where it stopped
+// rather than traversing the whole chain again. The persisted entries are
+// loaded into memory when the index is constructed, so the retention window
+// bounds the memory used at startup as well.
+type MessageIndex struct {
+	mu    sync.RWMutex
+	index map[cid.Cid]MessageLocation
+	// ds, if set, persists the index.
+	ds datastore.Datastore
+	// head is the key of the head the index was last updated to.
+	head block.TipSetKey
+
+	// retention is the number of heights below and including the head that
+	// are indexed, or zero if all heights are.
+	retention uint64
+	// floor is the lowest height indexed.
+	floor uint64
+}
+
+// MessageLocation is the position of an indexed message on chain.
+type MessageLocation struct {
+	// TipSet is the key of the tipset including the message.
+	TipSet block.TipSetKey
+	// Block is the CID of the first block of the tipset including it.
+	Block cid.Cid
+	// Height is the height of the tipset.
+	Height uint64
+}
+
+// persistedIndexHead is the stored form of the head a persistent index was
+// last updated to, with the floor of its retention window at that head.
+type persistedIndexHead struct {
+	Head  block.TipSetKey
+	Floor uint64
+}
+
+// NewMessageIndex returns an empty MessageIndex.
+func NewMessageIndex() *MessageIndex {
+	return &MessageIndex{
+		index: make(map[cid.Cid]MessageLocation),
+	}
+}
+
+// NewMessageIndexWithDatastore returns a MessageIndex persisted in `ds`,
+// loaded with any entries previously written there.
+func NewMessageIndexWithDatastore(ds datastore.Datastore) (*MessageIndex, error) {
+	mi := NewMessageIndex()
+	mi.ds = ds
+
+	val, err := ds.Get(messageIndexHeadKey)
+	if err == datastore.ErrNotFound {
+		return mi, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read message index head")
+	}
+	var head persistedIndexHead
+	if err := encoding.Decode(val, &head); err != nil {
+		return nil, errors.Wrap(err, "failed to decode message index head")
+	}
+
+	results, err := ds.Query(query.Query{Prefix: messageIndexPrefix.String()})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query message index")
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read message index")
+	}
+	for _, entry := range entries {
+		c, err := cid.Decode(datastore.NewKey(entry.Key).BaseNamespace())
+		if err != nil {
+			return nil, errors.Wrapf(err, "malformed message index key %s", entry.Key)
+		}
+		var loc MessageLocation
+		if err := encoding.Decode(entry.Value, &loc); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode message index entry %s", c)
+		}
+		mi.index[c] = loc
+	}
+	mi.head = head.Head
+	mi.floor = head.Floor
+	return mi, nil
+}
+
+// SetRetention limits the index to messages in tipsets within `heights`
+// heights of the head, the head's included. Zero indexes all heights. It
+// must be called before the index is first updated.
+func (mi *MessageIndex) SetRetention(heights uint64) {
+	mi.retention = heights
+}
+
+// Get returns the key of the tipset including the message with CID `c`, and
+// false if the message is not indexed.
+func (mi *MessageIndex) Get(c cid.Cid) (block.TipSetKey, bool) {
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+	loc, ok := mi.index[c]
+	return loc.TipSet, ok
+}
+
+// Locate returns the location of the message with CID `c`, and false if the
+// message is not indexed.
+func (mi *MessageIndex) Locate(c cid.Cid) (MessageLocation, bool) {
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+	loc, ok := mi.index[c]
+	return loc, ok
+}
+
+// Head returns the key of the head the index was last updated to, which for
+// a persistent index may have been set before a restart. It is empty if the
+// index has not been updated since it was created or reset.
+func (mi *MessageIndex) Head() block.TipSetKey {
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+	return mi.head
+}
+
+// Floor returns the lowest height indexed at the head the index was last
+// updated to. Messages in tipsets below it are not indexed.
+func (mi *MessageIndex) Floor() uint64 {
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+	return mi.floor
+}
+
+// Find returns the key of the lowest tipset on the chain ending at `head`
+// including the message with CID `c`, and false if there is none. Messages
+// below the retention window are found by scanning the chain below it. `head`
+// must be the head the index was last updated to.
+func (mi *MessageIndex) Find(ctx context.Context, c cid.Cid, head block.TipSet, reader messageIndexReader) (block.TipSetKey, bool, error) {
+	mi.mu.RLock()
+	loc, ok := mi.index[c]
+	floor := mi.floor
+	mi.mu.RUnlock()
+	if ok {
+		return loc.TipSet, true, nil
+	}
+	if floor == 0 {
+		return block.TipSetKey{}, false, nil
+	}
+
+	// The whole chain below the floor is scanned so that, as in the index,
+	// the lowest tipset including the message is found.
+	lowest := block.TipSetKey{}
+	found := false
+	for it := IterAncestors(ctx, reader, head); !it.Complete(); {
+		ts := it.Value()
+		h, err := ts.Height()
+		if err != nil {
+			return block.TipSetKey{}, false, err
+		}
+		if h < floor {
+			includes, err := tipSetIncludes(ctx, ts, c, reader)
+			if err != nil {
+				return block.TipSetKey{}, false, err
+			}
+			if includes {
+				lowest = ts.Key()
+				found = true
+			}
+		}
+		if err := it.Next(); err != nil {
+			return block.TipSetKey{}, false, err
+		}
+	}
+	return lowest, found, nil
+}
+
+// Len returns the number of messages indexed.
+func (mi *MessageIndex) Len() int {
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+	return len(mi.index)
+}
+
+// Reset empties the index, and its datastore if it is persistent.
+func (mi *MessageIndex) Reset() error {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+	if mi.ds != nil {
+		// The head goes first so that a partially cleared index is not
+		// resumed from.
+		if err := mi.ds.Delete(messageIndexHeadKey); err != nil {
+			return errors.Wrap(err, "failed to clear message index head")
+		}
+		results, err := mi.ds.Query(query.Query{Prefix: messageIndexPrefix.String(), KeysOnly: true})
+		if err != nil {
+			return errors.Wrap(err, "failed to query message index")
+		}
+		entries, err := results.Rest()
+		if err != nil {
+			return errors.Wrap(err, "failed to read message index")
+		}
+		for _, entry := range entries {
+			if err := mi.ds.Delete(datastore.NewKey(entry.Key)); err != nil {
+				return errors.Wrapf(err, "failed to clear message index entry %s", entry.Key)
+			}
+		}
+	}
+	mi.index = make(map[cid.Cid]MessageLocation)
+	mi.head = block.TipSetKey{}
+	mi.floor = 0
+	return nil
+}
+
+// Update moves the index from the chain ending at `oldHead` to the chain
+// ending at `newHead`, removing the messages of tipsets reverted from the old
+// chain and adding those of tipsets applied on the new one. An undefined
+// `oldHead` indexes the whole chain ending at `newHead`, or as much of it as
+// is within the retention window. Entries that fall below the window are
+// evicted. A persistent index writes the changes through to its datastore.
+func (mi *MessageIndex) Update(ctx context.Context, oldHead, newHead block.TipSet, reader messageIndexReader) error {
+	headHeight, err := newHead.Height()
+	if err != nil {
+		return err
+	}
+	floor := uint64(0)
+	if mi.retention > 0 && headHeight+1 > mi.retention {
+		floor = headHeight + 1 - mi.retention
+	}
+
+	stop := block.UndefTipSet
+	reverting := false
+	stopHeight := uint64(0)
+	if oldHead.Defined() {
+		stop, err = FindCommonAncestor(IterAncestors(ctx, reader, oldHead), IterAncestors(ctx, reader, newHead))
+		if err != nil {
+			return errors.Wrapf(err, "failed to find common ancestor of %s and %s", oldHead.Key(), newHead.Key())
+		}
+		stopHeight, err = stop.Height()
+		if err != nil {
+			return err
+		}
+		reverting = !stop.Equals(oldHead)
+	}
+
+	// If the floor falls, heights between the two floors that are common to
+	// both chains must be indexed too.
+	applyStop := stop
+	if floor < mi.floor {
+		applyStop = block.UndefTipSet
+	}
+	applied, err := mi.collect(ctx, newHead, applyStop, floor, reader)
+	if err != nil {
+		return err
+	}
+
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+	// Entries above the common ancestor locate messages in reverted tipsets.
+	// Since each entry is the lowest tipset including its message, such a
+	// message is included nowhere in the window on the common chain, so its
+	// entry is recomputed from the applied tipsets alone, or dropped.
+	if reverting {
+		for c, loc := range mi.index {
+			if loc.Height > stopHeight {
+				if err := mi.remove(c); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for c, loc := range applied {
+		if err := mi.put(c, loc); err != nil {
+			return err
+		}
+	}
+	if floor > mi.floor {
+		for c, loc := range mi.index {
+			if loc.Height < floor {
+				if err := mi.remove(c); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	mi.floor = floor
+	mi.head = newHead.Key()
+	if mi.ds != nil {
+		val, err := encoding.Encode(persistedIndexHead{Head: mi.head, Floor: floor})
+		if err != nil {
+			return errors.Wrap(err, "failed to encode message index head")
+		}
+		if err := mi.ds.Put(messageIndexHeadKey, val); err != nil {
+			return errors.Wrap(err, "failed to write message index head")
+		}
+	}
+	return nil
+}
+
+// put indexes the message with CID `c` at `loc`, unless it is already indexed
+// at a lower tipset. The caller must hold the write lock.
+func (mi *MessageIndex) put(c cid.Cid, loc MessageLocation) error {
+	if indexed, ok := mi.index[c]; ok && indexed.Height < loc.Height {
+		return nil
+	}
+	if mi.ds != nil {
+		val, err := encoding.Encode(loc)
+		if err != nil {
+			return errors.Wrapf(err, "failed to encode message index entry %s", c)
+		}
+		if err := mi.ds.Put(locationKey(c), val); err != nil {
+			return errors.Wrapf(err, "failed to write message index entry %s", c)
+		}
+	}
+	mi.index[c] = loc
+	return nil
+}
+
+// remove drops the message with CID `c` from the index. The caller must hold
+// the write lock.
+func (mi *MessageIndex) remove(c cid.Cid) error {
+	if mi.ds != nil {
+		if err := mi.ds.Delete(locationKey(c)); err != nil {
+			return errors.Wrapf(err, "failed to delete message index entry %s", c)
+		}
+	}
+	delete(mi.index, c)
+	return nil
+}
+
+// locationKey returns the datastore key of the entry for the message with
+// CID `c`.
+func locationKey(c cid.Cid) datastore.Key {
+	return messageIndexPrefix.ChildString(c.String())
+}
+
+// collect returns the messages of the tipsets from `head` down to, but not
+// including, `stop` or any below height `floor`, each mapped to the lowest
+// such tipset including it.
+func (mi *MessageIndex) collect(ctx context.Context, head, stop block.TipSet, floor uint64, reader messageIndexReader) (map[cid.Cid]MessageLocation, error) {
+	out := make(map[cid.Cid]MessageLocation)
+	if !head.Defined() {
+		return out, nil
+	}
+	for it := IterAncestors(ctx, reader, head); !it.Complete(); {
+		ts := it.Value()
+		if stop.Defined() && ts.Equals(stop) {
+			break
+		}
+		h, err := ts.Height()
+		if err != nil {
+			return nil, err
+		}
+		if h < floor {
+			break
+		}
+		// Blocks are visited in reverse so that a message included by several
+		// blocks of the tipset is located at the first.
+		for i := ts.Len() - 1; i >= 0; i-- {
+			blk := ts.At(i)
+			cids, err := blockMessageCids(ctx, ts, blk, reader)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range cids {
+				out[c] = MessageLocation{TipSet: ts.Key(), Block: blk.Cid(), Height: h}
+			}
+		}
+		if err := it.Next(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// tipSetIncludes returns true if a block of `ts` includes the message with
+// CID `c`.
+func tipSetIncludes(ctx context.Context, ts block.TipSet, c cid.Cid, reader messageIndexReader) (bool, error) {
+	cids, err := tipSetMessageCids(ctx, ts, reader)
+	if err != nil {
+		return false, err
+	}
+	for _, included := range cids {
+		if included.Equals(c) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tipSetMessageCids returns the CIDs of the messages of the blocks of `ts`.
+func tipSetMessageCids(ctx context.Context, ts block.TipSet, reader messageIndexReader) ([]cid.Cid, error) {
+	var out []cid.Cid
+	for i := 0; i < ts.Len(); i++ {
+		cids, err := blockMessageCids(ctx, ts, ts.At(i), reader)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, cids...)
+	}
+	return out, nil
+}
+
+// blockMessageCids returns the CIDs of the messages of `blk`, a block of `ts`.
+func blockMessageCids(ctx context.Context, ts block.TipSet, blk *block.Block, reader messageIndexReader) ([]cid.Cid, error) {
+	secpMsgs, blsMsgs, err := reader.LoadMessages(ctx, blk.Messages)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load messages %s of tipset %s", blk.Messages, ts.Key())
+	}
+	var out []cid.Cid
+	for _, msg := range secpMsgs {
+		c, err := msg.Cid()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	for _, msg := range blsMsgs {
+		c, err := msg.Cid()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
@@ -0,0 +1,278 @@
+package chain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-hamt-ipld"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+// messageIndexChain is a chain with two branches from genesis, a1 <- a2 and
+// b1 <- b2, each of whose tipsets includes messages. The message msgs[0] is
+// included on both branches.
+type messageIndexChain struct {
+	builder        *chain.Builder
+	gen            block.TipSet
+	a1, a2, b1, b2 block.TipSet
+	msgs           []*types.SignedMessage
+}
+
+func newMessageIndexChain(t *testing.T) *messageIndexChain {
+	mm := types.NewMessageMaker(t, types.MustGenerateKeyInfo(1, 42))
+	alice := mm.Addresses()[0]
+	var msgs []*types.SignedMessage
+	for i := uint64(0); i < 4; i++ {
+		msgs = append(msgs, mm.NewSignedMessage(alice, i))
+	}
+	withMessages := func(secp ...*types.SignedMessage) func(*chain.BlockBuilder) {
+		return func(b *chain.BlockBuilder) {
+			b.AddMessages(secp, []*types.UnsignedMessage{}, []*types.MessageReceipt{})
+		}
+	}
+
+	c := &messageIndexChain{builder: chain.NewBuilder(t, address.Undef), msgs: msgs}
+	c.gen = c.builder.NewGenesis()
+	c.a1 = c.builder.BuildOneOn(c.gen, withMessages(msgs[0]))
+	c.a2 = c.builder.BuildOneOn(c.a1, withMessages(msgs[1]))
+	c.b1 = c.builder.BuildOneOn(c.gen, withMessages(msgs[2], msgs[0]))
+	c.b2 = c.builder.BuildOneOn(c.b1, withMessages(msgs[3]))
+	return c
+}
+
+// requireIndexed asserts that `idx` holds exactly the given messages, each at
+// the tipset it is mapped to.
+func requireIndexed(t *testing.T, idx *chain.MessageIndex, expected map[*types.SignedMessage]block.TipSet) {
+	require.Equal(t, len(expected), idx.Len())
+	for msg, ts := range expected {
+		c, err := msg.Cid()
+		require.NoError(t, err)
+		key, ok := idx.Get(c)
+		require.True(t, ok, "message %s not indexed", c)
+		assert.Equal(t, ts.Key(), key)
+	}
+}
+
+func TestMessageIndexUpdate(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	c := newMessageIndexChain(t)
+
+	t.Run("extension", func(t *testing.T) {
+		idx := chain.NewMessageIndex()
+		require.NoError(t, idx.Update(ctx, block.UndefTipSet, c.a1, c.builder))
+		requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.a1})
+
+		require.NoError(t, idx.Update(ctx, c.a1, c.a2, c.builder))
+		requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.a1, c.msgs[1]: c.a2})
+	})
+
+	t.Run("reorg", func(t *testing.T) {
+		idx := chain.NewMessageIndex()
+		require.NoError(t, idx.Update(ctx, block.UndefTipSet, c.a2, c.builder))
+		require.NoError(t, idx.Update(ctx, c.a2, c.b2, c.builder))
+		requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.b1, c.msgs[2]: c.b1, c.msgs[3]: c.b2})
+
+		// And back again.
+		require.NoError(t, idx.Update(ctx, c.b2, c.a2, c.builder))
+		requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.a1, c.msgs[1]: c.a2})
+	})
+
+	t.Run("keeps the lowest inclusion", func(t *testing.T) {
+		a3 := c.builder.BuildOneOn(c.a2, func(b *chain.BlockBuilder) {
+			b.AddMessages([]*types.SignedMessage{c.msgs[0]}, []*types.UnsignedMessage{}, []*types.MessageReceipt{})
+		})
+		idx := chain.NewMessageIndex()
+		require.NoError(t, idx.Update(ctx, block.UndefTipSet, c.a2, c.builder))
+		require.NoError(t, idx.Update(ctx, c.a2, a3, c.builder))
+		requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.a1, c.msgs[1]: c.a2})
+
+		// Reverting the higher inclusion leaves the message on chain.
+		require.NoError(t, idx.Update(ctx, a3, c.a2, c.builder))
+		requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.a1, c.msgs[1]: c.a2})
+	})
+
+	t.Run("reorg recomputes entries of reverted tipsets", func(t *testing.T) {
+		// c1 includes msgs[0] above a1, which includes it too.
+		c1 := c.builder.BuildOneOn(c.a1, func(b *chain.BlockBuilder) {
+			b.AddMessages([]*types.SignedMessage{c.msgs[0], c.msgs[2]}, []*types.UnsignedMessage{}, []*types.MessageReceipt{})
+		})
+		idx := chain.NewMessageIndex()
+		require.NoError(t, idx.Update(ctx, block.UndefTipSet, c.b2, c.builder))
+		require.NoError(t, idx.Update(ctx, c.b2, c1, c.builder))
+		requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.a1, c.msgs[2]: c1})
+
+		require.NoError(t, idx.Update(ctx, c1, c.a2, c.builder))
+		requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.a1, c.msgs[1]: c.a2})
+	})
+}
+
+// countingMessageReader counts the message collections loaded through it.
+type countingMessageReader struct {
+	*chain.Builder
+	loads int
+}
+
+func (r *countingMessageReader) LoadMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error) {
+	r.loads++
+	return r.Builder.LoadMessages(ctx, meta)
+}
+
+func TestMessageIndexRetention(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	mm := types.NewMessageMaker(t, types.MustGenerateKeyInfo(1, 42))
+	alice := mm.Addresses()[0]
+	builder := chain.NewBuilder(t, address.Undef)
+	head := builder.NewGenesis()
+	var msgs []*types.SignedMessage
+	var tips []block.TipSet
+	for i := uint64(0); i < 5; i++ {
+		msg := mm.NewSignedMessage(alice, i)
+		head = builder.BuildOneOn(head, func(b *chain.BlockBuilder) {
+			b.AddMessages([]*types.SignedMessage{msg}, []*types.UnsignedMessage{}, []*types.MessageReceipt{})
+		})
+		msgs = append(msgs, msg)
+		tips = append(tips, head)
+	}
+
+	idx := chain.NewMessageIndex()
+	idx.SetRetention(2)
+	reader := &countingMessageReader{Builder: builder}
+	require.NoError(t, idx.Update(ctx, block.UndefTipSet, head, reader))
+	// Only the head and its parent are indexed.
+	requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{msgs[3]: tips[3], msgs[4]: tips[4]})
+	h, err := tips[3].Height()
+	require.NoError(t, err)
+	assert.Equal(t, h, idx.Floor())
+
+	t.Run("recent messages hit the index", func(t *testing.T) {
+		reader.loads = 0
+		c, err := msgs[4].Cid()
+		require.NoError(t, err)
+		key, found, err := idx.Find(ctx, c, head, reader)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, tips[4].Key(), key)
+		assert.Equal(t, 0, reader.loads)
+	})
+
+	t.Run("old messages are found by scanning", func(t *testing.T) {
+		reader.loads = 0
+		c, err := msgs[0].Cid()
+		require.NoError(t, err)
+		_, ok := idx.Get(c)
+		assert.False(t, ok)
+
+		key, found, err := idx.Find(ctx, c, head, reader)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, tips[0].Key(), key)
+		assert.True(t, reader.loads > 0)
+	})
+
+	t.Run("scanning finds the lowest inclusion", func(t *testing.T) {
+		// A fork re-including msgs[0] in window and below it.
+		fork := builder.BuildOneOn(tips[1], func(b *chain.BlockBuilder) {
+			b.AddMessages([]*types.SignedMessage{msgs[0]}, []*types.UnsignedMessage{}, []*types.MessageReceipt{})
+		})
+		forkHead := builder.AppendManyOn(2, fork)
+		forkIdx := chain.NewMessageIndex()
+		forkIdx.SetRetention(2)
+		require.NoError(t, forkIdx.Update(ctx, block.UndefTipSet, forkHead, builder))
+
+		c, err := msgs[0].Cid()
+		require.NoError(t, err)
+		key, found, err := forkIdx.Find(ctx, c, forkHead, builder)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, tips[0].Key(), key)
+	})
+
+	t.Run("entries below the window are evicted", func(t *testing.T) {
+		next := builder.AppendOn(head, 1)
+		require.NoError(t, idx.Update(ctx, head, next, reader))
+		requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{msgs[4]: tips[4]})
+	})
+}
+
+func TestStoreUpdatesMessageIndex(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	c := newMessageIndexChain(t)
+
+	store := chain.NewStore(repo.NewInMemoryRepo().Datastore(), hamt.NewCborStore(), &state.TreeStateLoader{}, chain.NewStatusReporter(), c.gen.At(0).Cid())
+	for _, ts := range []block.TipSet{c.gen, c.a1, c.a2, c.b1, c.b2} {
+		require.NoError(t, store.PutTipSetAndState(ctx, &chain.TipSetAndState{TipSet: ts, TipSetStateRoot: ts.At(0).StateRoot}))
+	}
+	idx := chain.NewMessageIndex()
+	store.UseMessageIndex(idx, c.builder)
+
+	// The index is first built in the background.
+	require.NoError(t, store.SetHead(ctx, c.a2))
+	<-store.MessageIndexBuilt()
+	requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.a1, c.msgs[1]: c.a2})
+
+	require.NoError(t, store.SetHead(ctx, c.b2))
+	requireIndexed(t, idx, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.b1, c.msgs[2]: c.b1, c.msgs[3]: c.b2})
+}
+
+func TestPersistentMessageIndex(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	c := newMessageIndexChain(t)
+	ds := repo.NewInMemoryRepo().Datastore()
+
+	idx, err := chain.NewMessageIndexWithDatastore(ds)
+	require.NoError(t, err)
+	assert.True(t, idx.Head().Empty())
+	require.NoError(t, idx.Update(ctx, block.UndefTipSet, c.a2, c.builder))
+
+	t.Run("reloads entries and head", func(t *testing.T) {
+		reloaded, err := chain.NewMessageIndexWithDatastore(ds)
+		require.NoError(t, err)
+		requireIndexed(t, reloaded, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.a1, c.msgs[1]: c.a2})
+		assert.Equal(t, c.a2.Key(), reloaded.Head())
+
+		c1, err := c.msgs[1].Cid()
+		require.NoError(t, err)
+		loc, ok := reloaded.Locate(c1)
+		require.True(t, ok)
+		assert.Equal(t, c.a2.Key(), loc.TipSet)
+		assert.Equal(t, c.a2.At(0).Cid(), loc.Block)
+		assert.Equal(t, uint64(2), loc.Height)
+	})
+
+	t.Run("store resumes from persisted head", func(t *testing.T) {
+		store := chain.NewStore(repo.NewInMemoryRepo().Datastore(), hamt.NewCborStore(), &state.TreeStateLoader{}, chain.NewStatusReporter(), c.gen.At(0).Cid())
+		for _, ts := range []block.TipSet{c.gen, c.a1, c.a2, c.b1, c.b2} {
+			require.NoError(t, store.PutTipSetAndState(ctx, &chain.TipSetAndState{TipSet: ts, TipSetStateRoot: ts.At(0).StateRoot}))
+		}
+		reloaded, err := chain.NewMessageIndexWithDatastore(ds)
+		require.NoError(t, err)
+		store.UseMessageIndex(reloaded, c.builder)
+
+		// Moving to the other branch reverts the persisted one.
+		require.NoError(t, store.SetHead(ctx, c.b2))
+		<-store.MessageIndexBuilt()
+		requireIndexed(t, reloaded, map[*types.SignedMessage]block.TipSet{c.msgs[0]: c.b1, c.msgs[2]: c.b1, c.msgs[3]: c.b2})
+	})
+
+	t.Run("reset clears datastore", func(t *testing.T) {
+		require.NoError(t, idx.Reset())
+		reloaded, err := chain.NewMessageIndexWithDatastore(ds)
+		require.NoError(t, err)
+		assert.Equal(t, 0, reloaded.Len())
+		assert.True(t, reloaded.Head().Empty())
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-ipfs-blockstore"
 	cbor "github.com/ipfs/go-ipld-cbor"
 	"github.com/multiformats/go-multihash"
@@ -152,6 +153,37 @@ func (ms *MessageStore) StoreReceipts(ctx context.Context, receipts []*types.Mes
 	return ms.storeAMTCids(ctx, cids)
 }
 
+// VerifyTxMeta checks that the secp and bls message collections hash to the
+// roots in `meta`, as computed by StoreMessages.
+func VerifyTxMeta(ctx context.Context, meta types.TxMeta, secpMessages []*types.SignedMessage, blsMessages []*types.UnsignedMessage) error {
+	ms := NewMessageStore(blockstore.NewBlockstore(datastore.NewMapDatastore()))
+	computed, err := ms.StoreMessages(ctx, secpMessages, blsMessages)
+	if err != nil {
+		return errors.Wrap(err, "could not compute message roots")
+	}
+	if !computed.SecpRoot.Equals(meta.SecpRoot) {
+		return errors.Errorf("secp messages root %s does not match expected root %s", computed.SecpRoot, meta.SecpRoot)
+	}
+	if !computed.BLSRoot.Equals(meta.BLSRoot) {
+		return errors.Errorf("bls messages root %s does not match expected root %s", computed.BLSRoot, meta.BLSRoot)
+	}
+	return nil
+}
+
+// VerifyReceiptsRoot checks that the receipts hash to `root`, as computed by
+// StoreReceipts.
+func VerifyReceiptsRoot(ctx context.Context, root cid.Cid, receipts []*types.MessageReceipt) error {
+	ms := NewMessageStore(blockstore.NewBlockstore(datastore.NewMapDatastore()))
+	computed, err := ms.StoreReceipts(ctx, receipts)
+	if err != nil {
+		return errors.Wrap(err, "could not compute receipts root")
+	}
+	if !computed.Equals(root) {
+		return errors.Errorf("receipts root %s does not match expected root %s", computed, root)
+	}
+	return nil
+}
+
 func (ms *MessageStore) loadAMTCids(ctx context.Context, c cid.Cid) ([]cid.Cid, error) {
 	as := amt.WrapBlockstore(ms.bs)
 	a, err := amt.LoadAMT(as, c)
@@ -7,8 +7,10 @@ import (
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-ipfs-blockstore"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
 
@@ -62,3 +64,52 @@ func TestMessageStoreReceiptsHappy(t *testing.T) {
 
 	assert.Equal(t, receipts, rtReceipts)
 }
+
+func TestVerifyTxMeta(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	keys := types.MustGenerateKeyInfo(2, 42)
+	mm := types.NewMessageMaker(t, keys)
+	alice := mm.Addresses()[0]
+	bob := mm.Addresses()[1]
+
+	secpMsgs := []*types.SignedMessage{mm.NewSignedMessage(alice, 0), mm.NewSignedMessage(bob, 0)}
+	blsMsgs := []*types.UnsignedMessage{&mm.NewSignedMessage(alice, 1).Message}
+
+	ms := chain.NewMessageStore(blockstore.NewBlockstore(datastore.NewMapDatastore()))
+	meta, err := ms.StoreMessages(ctx, secpMsgs, blsMsgs)
+	require.NoError(t, err)
+
+	t.Run("matching collections", func(t *testing.T) {
+		assert.NoError(t, chain.VerifyTxMeta(ctx, meta, secpMsgs, blsMsgs))
+	})
+
+	t.Run("tampered secp messages", func(t *testing.T) {
+		tampered := []*types.SignedMessage{secpMsgs[1], secpMsgs[0]}
+		err := chain.VerifyTxMeta(ctx, meta, tampered, blsMsgs)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "secp")
+	})
+
+	t.Run("tampered bls messages", func(t *testing.T) {
+		err := chain.VerifyTxMeta(ctx, meta, secpMsgs, []*types.UnsignedMessage{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "bls")
+	})
+}
+
+func TestVerifyReceiptsRoot(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	mr := types.NewReceiptMaker()
+	receipts := []*types.MessageReceipt{mr.NewReceipt(), mr.NewReceipt()}
+
+	ms := chain.NewMessageStore(blockstore.NewBlockstore(datastore.NewMapDatastore()))
+	root, err := ms.StoreReceipts(ctx, receipts)
+	require.NoError(t, err)
+
+	assert.NoError(t, chain.VerifyReceiptsRoot(ctx, root, receipts))
+	assert.Error(t, chain.VerifyReceiptsRoot(ctx, root, receipts[:1]))
+}
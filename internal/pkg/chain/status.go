@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"sync"
 
-	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	logging "github.com/ipfs/go-log"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net"
 )
 
 var logChainStatus = logging.Logger("chain/status")
@@ -68,6 +70,11 @@ type Status struct {
 	FetchingHead block.TipSetKey
 	// The height of FetchingHead
 	FetchingHeight uint64
+	// The number of tipsets of SyncingHead's chain fetched so far.
+	FetchedTipsets uint64
+	// The encoded size of the block headers of SyncingHead's chain fetched
+	// so far.
+	FetchedBytes uint64
 }
 
 // NewDefaultChainStatus returns a ChainStaus with the default empty values.
@@ -83,15 +90,17 @@ func newDefaultChainStatus() *Status {
 		SyncingFetchComplete: true,
 		FetchingHead:         block.UndefTipSet.Key(),
 		FetchingHeight:       0,
+		FetchedTipsets:       0,
+		FetchedBytes:         0,
 	}
 }
 
 // String returns the Status as a string
 func (s Status) String() string {
-	return fmt.Sprintf("validatedHead=%s, validatedHeight=%d, syncingStarted=%d, syncingHead=%s, syncingHeight=%d, syncingTrusted=%t, syncingComplete=%t syncingFetchComplete=%t fetchingHead=%s, fetchingHeight=%d",
+	return fmt.Sprintf("validatedHead=%s, validatedHeight=%d, syncingStarted=%d, syncingHead=%s, syncingHeight=%d, syncingTrusted=%t, syncingComplete=%t syncingFetchComplete=%t fetchingHead=%s, fetchingHeight=%d, fetchedTipsets=%d, fetchedBytes=%d",
 		s.ValidatedHead, s.ValidatedHeadHeight, s.SyncingStarted,
 		s.SyncingHead, s.SyncingHeight, s.SyncingTrusted, s.SyncingComplete, s.SyncingFetchComplete,
-		s.FetchingHead, s.FetchingHeight)
+		s.FetchingHead, s.FetchingHeight, s.FetchedTipsets, s.FetchedBytes)
 }
 
 // StatusUpdates defines a type for ipdating syncer status.
@@ -166,3 +175,10 @@ func fetchHeight(u uint64) StatusUpdates {
 		s.FetchingHeight = u
 	}
 }
+
+func fetchProgress(p net.FetchProgress) StatusUpdates {
+	return func(s *Status) {
+		s.FetchedTipsets = p.TipsetsFetched
+		s.FetchedBytes = p.BytesFetched
+	}
+}
@@ -88,6 +88,25 @@ type Store struct {
 
 	// Reporter is used by the store to update the current status of the chain.
 	reporter Reporter
+
+	// messageIndex, if set, is updated with each new head, loading messages
+	// from messageProvider.
+	messageIndex    *MessageIndex
+	messageProvider MessageProvider
+	// indexMu protects the fields below, which track the updates of the
+	// message index. indexedHead is the head it was last updated to. While
+	// indexBuilding is true the index is being built in the background and
+	// indexPending is the latest head for it to reach.
+	indexMu       sync.Mutex
+	indexedHead   block.TipSet
+	indexPending  block.TipSet
+	indexBuilding bool
+	// indexBuilt is closed once the index is first built.
+	indexBuilt     chan struct{}
+	indexBuiltOnce sync.Once
+	// indexCtx scopes the background build and is cancelled on Stop.
+	indexCtx    context.Context
+	indexCancel context.CancelFunc
 }
 
 // NewStore constructs a new default store.
@@ -284,6 +303,26 @@ func (store *Store) HasTipSetAndStatesWithParentsAndHeight(parentKey block.TipSe
 	return store.tipIndex.HasByParentsAndHeight(parentKey, h)
 }
 
+// UseMessageIndex has the store update `idx` with each new head, loading
+// messages from `messages`. The first head set starts building the index in
+// the background over the chain ending at it, or the chain since the index's
+// persisted head if it has one, so that setting the head does not wait on
+// it. Heads set meanwhile are caught up with once the build completes, and
+// later heads update the index as they are set. It must be called before the
+// store's head is set.
+func (store *Store) UseMessageIndex(idx *MessageIndex, messages MessageProvider) {
+	store.messageIndex = idx
+	store.messageProvider = messages
+	store.indexBuilt = make(chan struct{})
+	store.indexCtx, store.indexCancel = context.WithCancel(context.Background())
+}
+
+// MessageIndexBuilt returns a channel closed once the message index has first
+// been built up to the store's head. It returns nil if the store has no index.
+func (store *Store) MessageIndexBuilt() <-chan struct{} {
+	return store.indexBuilt
+}
+
 // HeadEvents returns a pubsub interface the pushes events each time the
 // default store's head is reset.
 func (store *Store) HeadEvents() *pubsub.PubSub {
@@ -309,12 +348,95 @@ func (store *Store) SetHead(ctx context.Context, ts block.TipSet) error {
 		return err
 	}
 	store.reporter.UpdateStatus(validateHead(ts.Key()), validateHeight(h))
+	store.updateMessageIndex(ctx, ts)
 	// Publish an event that we have a new head.
 	store.HeadEvents().Pub(ts, NewHeadTopic)
 
 	return nil
 }
 
+// updateMessageIndex moves the message index, if any, to the chain ending at
+// `ts`. If the index has not been built since the store started, or since it
+// was reset, it is built in the background instead. A failure is logged
+// rather than returned since the head has already been written, and the index
+// is rebuilt from scratch on the next head.
+func (store *Store) updateMessageIndex(ctx context.Context, ts block.TipSet) {
+	if store.messageIndex == nil {
+		return
+	}
+	store.indexMu.Lock()
+	defer store.indexMu.Unlock()
+	if store.indexBuilding {
+		store.indexPending = ts
+		return
+	}
+	if !store.indexedHead.Defined() {
+		store.indexBuilding = true
+		store.indexPending = ts
+		go store.buildMessageIndex(store.indexCtx)
+		return
+	}
+	if err := store.messageIndex.Update(ctx, store.indexedHead, ts, store.messageIndexReader()); err != nil {
+		logStore.Errorf("failed to update message index to %s: %s", ts.Key(), err)
+		store.resetMessageIndex()
+		return
+	}
+	store.indexedHead = ts
+}
+
+// buildMessageIndex builds the message index up to the latest head set,
+// resuming from the index's persisted head if it has one, and catches up with
+// the heads set while it runs.
+func (store *Store) buildMessageIndex(ctx context.Context) {
+	store.indexMu.Lock()
+	if key := store.messageIndex.Head(); !key.Empty() {
+		resumed, err := store.GetTipSet(key)
+		if err != nil {
+			logStore.Warnf("failed to load message index head %s, reindexing: %s", key, err)
+			store.resetMessageIndex()
+		} else {
+			store.indexedHead = resumed
+		}
+	}
+	for !store.indexedHead.Equals(store.indexPending) {
+		from, to := store.indexedHead, store.indexPending
+		// The lock is released while the index is updated so that heads can
+		// be set meanwhile.
+		store.indexMu.Unlock()
+		err := store.messageIndex.Update(ctx, from, to, store.messageIndexReader())
+		store.indexMu.Lock()
+		if err != nil {
+			logStore.Errorf("failed to build message index to %s: %s", to.Key(), err)
+			store.resetMessageIndex()
+			store.indexBuilding = false
+			store.indexMu.Unlock()
+			return
+		}
+		store.indexedHead = to
+	}
+	store.indexBuilding = false
+	store.indexMu.Unlock()
+	store.indexBuiltOnce.Do(func() { close(store.indexBuilt) })
+}
+
+// messageIndexReader returns a reader of the tipsets of the store and the
+// messages of its message provider.
+func (store *Store) messageIndexReader() messageIndexReader {
+	return struct {
+		*Store
+		MessageProvider
+	}{store, store.messageProvider}
+}
+
+// resetMessageIndex empties the message index so that it is rebuilt from the
+// next head. The caller must hold indexMu.
+func (store *Store) resetMessageIndex() {
+	if err := store.messageIndex.Reset(); err != nil {
+		logStore.Errorf("failed to reset message index: %s", err)
+	}
+	store.indexedHead = block.UndefTipSet
+}
+
 func (store *Store) setHeadPersistent(ctx context.Context, ts block.TipSet) error {
 	store.mu.Lock()
 	defer store.mu.Unlock()
@@ -382,5 +504,8 @@ func (store *Store) GenesisCid() cid.Cid {
 
 // Stop stops all activities and cleans up.
 func (store *Store) Stop() {
+	if store.indexCancel != nil {
+		store.indexCancel()
+	}
 	store.headEvents.Shutdown()
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/metrics/tracing"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/net"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 )
 
 var reorgCnt *metrics.Int64Counter
@@ -27,11 +28,14 @@ func init() {
 // UntrustedChainHeightLimit is the maximum number of blocks ahead of the current consensus
 // chain height to accept if syncing without trust.
 var UntrustedChainHeightLimit = 600
+
 var (
 	// ErrChainHasBadTipSet is returned when the syncer traverses a chain with a cached bad tipset.
 	ErrChainHasBadTipSet = errors.New("input chain contains a cached bad tipset")
 	// ErrNewChainTooLong is returned when processing a fork that split off from the main chain too many blocks ago.
 	ErrNewChainTooLong = errors.New("input chain forked from best chain too far in the past")
+	// ErrReorgTooDeep is returned when syncing a chain would revert more of the current chain than the syncer's reorg limit allows.
+	ErrReorgTooDeep = errors.New("input chain reverts too much of the current chain")
 	// ErrUnexpectedStoreState indicates that the syncer's chain store is violating expected invariants.
 	ErrUnexpectedStoreState = errors.New("the chain store is in an unexpected state")
 )
@@ -59,8 +63,8 @@ type syncChainSelector interface {
 	// IsHeavier returns true if tipset a is heavier than tipset b and false if
 	// tipset b is heavier than tipset a.
 	IsHeavier(ctx context.Context, a, b block.TipSet, aStateID, bStateID cid.Cid) (bool, error)
-	// NewWeight returns the weight of a tipset after the upgrade to version 1
-	NewWeight(ctx context.Context, ts block.TipSet, stRoot cid.Cid) (uint64, error)
+	// Weight and NewWeight weigh parent tipsets for validation.
+	consensus.ParentWeigher
 }
 
 type syncStateEvaluator interface {
@@ -108,36 +112,84 @@ type Syncer struct {
 	messageProvider MessageProvider
 
 	clock clock.Clock
+	// Selects the weight function in effect at a parent tipset's height.
+	pvt *version.ProtocolVersionTable
 
 	// Reporter is used by the syncer to update the current status of the chain.
 	reporter Reporter
+
+	// onBad, if set, is called with the key of each tipset found bad.
+	onBad func(block.TipSetKey)
+
+	// maxReorgDepth is the number of heights the syncer will revert from its
+	// head to switch to a fork that does not outweigh the head by more than
+	// reorgWeightMargin. Zero disables the limit.
+	maxReorgDepth     uint64
+	reorgWeightMargin uint64
 }
 
 // NewSyncer constructs a Syncer ready for use.
-func NewSyncer(e syncStateEvaluator, cs syncChainSelector, s syncerChainReaderWriter, m MessageProvider, f net.Fetcher, sr Reporter, c clock.Clock) *Syncer {
+func NewSyncer(e syncStateEvaluator, cs syncChainSelector, s syncerChainReaderWriter, m MessageProvider, f net.Fetcher, sr Reporter, c clock.Clock, pvt *version.ProtocolVersionTable) *Syncer {
 	return &Syncer{
 		fetcher: f,
 		badTipSets: &badTipSetCache{
-			bad: make(map[string]struct{}),
+			bad: make(map[string]error),
 		},
 		stateEvaluator:  e,
 		chainSelector:   cs,
 		chainStore:      s,
 		messageProvider: m,
 		clock:           c,
+		pvt:             pvt,
 		reporter:        sr,
 	}
 }
 
+// SetBadTipSetHandler configures the syncer to call `onBad` with the key of
+// each tipset it adds to its bad tipset cache, e.g. so that the dispatcher can
+// cancel queued targets with that head.  It must be called before syncing.
+func (syncer *Syncer) SetBadTipSetHandler(onBad func(block.TipSetKey)) {
+	syncer.onBad = onBad
+}
+
+// SetReorgLimit configures the syncer to switch to a fork reverting more than
+// `maxDepth` heights of its chain only if the fork outweighs its head by more
+// than `weightMargin`. Weights are compared once the fork is validated, so a
+// fork cannot pass the limit by claiming a weight in its headers. Zero
+// `maxDepth` disables the limit. It must be called before syncing.
+func (syncer *Syncer) SetReorgLimit(maxDepth, weightMargin uint64) {
+	syncer.maxReorgDepth = maxDepth
+	syncer.reorgWeightMargin = weightMargin
+}
+
+// rejectChain marks the tipsets of `chain` bad for `reason` in a single
+// operation.  `chain` should hold the first tipset found invalid followed by
+// its known descendants, none of which can be valid.
+func (syncer *Syncer) rejectChain(chain []block.TipSet, reason error) {
+	syncer.badTipSets.AddChain(chain, reason)
+	if syncer.onBad == nil {
+		return
+	}
+	for _, ts := range chain {
+		syncer.onBad(ts.Key())
+	}
+}
+
+// BadTipSetReason returns the reason the syncer rejected the tipset with key
+// `key`, or nil if it has not rejected it.
+func (syncer *Syncer) BadTipSetReason(key block.TipSetKey) error {
+	return syncer.badTipSets.Reason(key)
+}
+
 // syncOne syncs a single tipset with the chain store. syncOne calculates the
 // parent state of the tipset and calls into consensus to run a state transition
-// in order to validate the tipset.  In the case the input tipset is valid,
-// syncOne calls into consensus to check its weight, and then updates the head
-// of the store if this tipset is the heaviest.
+// in order to validate the tipset.  In the case the input tipset is valid and
+// `mayBecomeHead` is true, syncOne calls into consensus to check its weight,
+// and then updates the head of the store if this tipset is the heaviest.
 //
 // Precondition: the caller of syncOne must hold the syncer's lock (syncer.mu) to
 // ensure head is not modified by another goroutine during run.
-func (syncer *Syncer) syncOne(ctx context.Context, grandParent, parent, next block.TipSet) error {
+func (syncer *Syncer) syncOne(ctx context.Context, grandParent, parent, next block.TipSet, mayBecomeHead bool) error {
 	priorHeadKey := syncer.chainStore.GetHead()
 
 	// if tipset is already priorHeadKey, we've been here before. do nothing.
@@ -148,17 +200,30 @@ func (syncer *Syncer) syncOne(ctx context.Context, grandParent, parent, next blo
 	stopwatch := syncOneTimer.Start(ctx)
 	defer stopwatch.Stop(ctx)
 
-	// Lookup parent state root. It is guaranteed by the syncer that it is in the chainStore.
-	stateRoot, err := syncer.chainStore.GetTipSetStateRoot(parent.Key())
+	h, err := next.Height()
 	if err != nil {
 		return err
 	}
 
-	// Gather ancestor chain needed to process state transition.
-	h, err := next.Height()
+	// Once version.Protocol3 is in effect a tipset holding two blocks from
+	// one miner is invalid.
+	v, err := syncer.pvt.VersionAt(types.NewBlockHeight(h))
 	if err != nil {
 		return err
 	}
+	if v >= version.Protocol3 {
+		if err := consensus.DetectEquivocation(next); err != nil {
+			return err
+		}
+	}
+
+	// Lookup parent state root. It is guaranteed by the syncer that it is in the chainStore.
+	stateRoot, err := syncer.chainStore.GetTipSetStateRoot(parent.Key())
+	if err != nil {
+		return err
+	}
+
+	// Gather ancestor chain needed to process state transition.
 	ancestorHeight := types.NewBlockHeight(h).Sub(types.NewBlockHeight(consensus.AncestorRoundsNeeded))
 	ancestors, err := GetRecentAncestors(ctx, parent, syncer.chainStore, ancestorHeight)
 	if err != nil {
@@ -197,6 +262,12 @@ func (syncer *Syncer) syncOne(ctx context.Context, grandParent, parent, next blo
 	if err != nil {
 		return err
 	}
+	// The state of a single-block tipset is that claimed by its block's header.
+	// The blocks of larger tipsets each claim the state after their own
+	// messages alone, which the evaluator is responsible for checking.
+	if next.Len() == 1 && !root.Equals(next.At(0).StateRoot) {
+		return errors.Wrapf(consensus.ErrStateRootMismatch, "block %s claims state root %s, computed %s", next.At(0).Cid(), next.At(0).StateRoot, root)
+	}
 	err = syncer.chainStore.PutTipSetAndState(ctx, &TipSetAndState{
 		TipSet:          next,
 		TipSetStateRoot: root,
@@ -205,6 +276,9 @@ func (syncer *Syncer) syncOne(ctx context.Context, grandParent, parent, next blo
 		return err
 	}
 	logSyncer.Debugf("Successfully updated store with %s", next.String())
+	if !mayBecomeHead {
+		return nil
+	}
 
 	// TipSet is validated and added to store, now check if it is the heaviest.
 	nextParentStateID, err := syncer.chainStore.GetTipSetStateRoot(parent.Key())
@@ -249,14 +323,15 @@ func (syncer *Syncer) syncOne(ctx context.Context, grandParent, parent, next blo
 // TODO #3537 this should be stored the first time it is computed and retrieved
 // from disk just like aggregate state roots.
 func (syncer *Syncer) calculateParentWeight(ctx context.Context, parent, grandParent block.TipSet) (uint64, error) {
-	if grandParent.Equals(block.UndefTipSet) {
-		return syncer.chainSelector.NewWeight(ctx, parent, cid.Undef)
-	}
-	gpStRoot, err := syncer.chainStore.GetTipSetStateRoot(grandParent.Key())
-	if err != nil {
-		return 0, err
+	gpStRoot := cid.Undef
+	if !grandParent.Equals(block.UndefTipSet) {
+		var err error
+		gpStRoot, err = syncer.chainStore.GetTipSetStateRoot(grandParent.Key())
+		if err != nil {
+			return 0, err
+		}
 	}
-	return syncer.chainSelector.NewWeight(ctx, parent, gpStRoot)
+	return consensus.ComputeParentWeight(ctx, parent, gpStRoot, syncer.chainSelector, syncer.pvt)
 }
 
 // ancestorsFromStore returns the parent and grandparent tipsets of `ts`
@@ -376,11 +451,45 @@ func (syncer *Syncer) widen(ctx context.Context, ts block.TipSet) (block.TipSet,
 	return wts, nil
 }
 
+// SyncResult describes the effect a call to HandleNewTipSetWithResult had on
+// the syncer's chain store.
+type SyncResult struct {
+	// HeadAdvanced is true if the store's head changed.
+	HeadAdvanced bool
+	// Reorged is true if the new head is not a descendant of the prior head.
+	Reorged bool
+	// NewHead is the store's head after the call.
+	NewHead block.TipSetKey
+	// BlocksApplied is the number of blocks validated and added to the store.
+	BlocksApplied int
+}
+
+// fetchTipSets fetches the chain headed by `ci`, recording the fetcher's
+// progress in the status if it reports any.
+func (syncer *Syncer) fetchTipSets(ctx context.Context, ci *block.ChainInfo, done func(block.TipSet) (bool, error)) ([]block.TipSet, error) {
+	pf, ok := syncer.fetcher.(net.ProgressFetcher)
+	if !ok {
+		return syncer.fetcher.FetchTipSets(ctx, ci.Head, ci.Peer, done)
+	}
+	return pf.FetchTipSetsWithProgress(ctx, ci.Head, ci.Peer, done, func(p net.FetchProgress) {
+		syncer.reporter.UpdateStatus(fetchProgress(p))
+	})
+}
+
 // HandleNewTipSet extends the Syncer's chain store with the given tipset if they
 // represent a valid extension. It limits the length of new chains it will
 // attempt to validate and caches invalid blocks it has encountered to
 // help prevent DOS.
-func (syncer *Syncer) HandleNewTipSet(ctx context.Context, ci *block.ChainInfo, trusted bool) (err error) {
+func (syncer *Syncer) HandleNewTipSet(ctx context.Context, ci *block.ChainInfo, trusted bool) error {
+	_, err := syncer.HandleNewTipSetWithResult(ctx, ci, trusted)
+	return err
+}
+
+// HandleNewTipSetWithResult behaves like HandleNewTipSet and additionally
+// reports how the store's head moved as a result of the sync. The result is
+// populated even when an error is returned part way through a chain, since
+// tipsets synced before the failure remain in the store.
+func (syncer *Syncer) HandleNewTipSetWithResult(ctx context.Context, ci *block.ChainInfo, trusted bool) (result SyncResult, err error) {
 	logSyncer.Debugf("Begin fetch and sync of chain with head %v", ci.Head)
 	ctx, span := trace.StartSpan(ctx, "Syncer.HandleNewTipSet")
 	span.AddAttributes(trace.StringAttribute("tipset", ci.Head.String()))
@@ -392,18 +501,23 @@ func (syncer *Syncer) HandleNewTipSet(ctx context.Context, ci *block.ChainInfo,
 	syncer.mu.Lock()
 	defer syncer.mu.Unlock()
 
+	priorHeadKey := syncer.chainStore.GetHead()
+	result.NewHead = priorHeadKey
+
 	// If the store already has this tipset then the syncer is finished.
 	if syncer.chainStore.HasTipSetAndState(ctx, ci.Head) {
-		return nil
+		return result, nil
 	}
 
-	curHead, err := syncer.chainStore.GetTipSet(syncer.chainStore.GetHead())
+	curHead, err := syncer.chainStore.GetTipSet(priorHeadKey)
 	if err != nil {
-		return err
+		return result, err
 	}
+	defer syncer.describeHeadChange(ctx, curHead, &result)
+
 	curHeight, err := curHead.Height()
 	if err != nil {
-		return err
+		return result, err
 	}
 
 	syncer.reporter.UpdateStatus(syncingStarted(syncer.clock.Now().Unix()), syncHead(ci.Head), syncHeight(ci.Height), syncTrusted(trusted), syncComplete(false))
@@ -411,11 +525,11 @@ func (syncer *Syncer) HandleNewTipSet(ctx context.Context, ci *block.ChainInfo,
 
 	// If we do not trust the peer head check finality
 	if !trusted && ExceedsUntrustedChainLength(curHeight, ci.Height) {
-		return ErrNewChainTooLong
+		return result, ErrNewChainTooLong
 	}
 
-	syncer.reporter.UpdateStatus(syncFetchComplete(false))
-	chain, err := syncer.fetcher.FetchTipSets(ctx, ci.Head, ci.Peer, func(t block.TipSet) (bool, error) {
+	syncer.reporter.UpdateStatus(syncFetchComplete(false), fetchProgress(net.FetchProgress{}))
+	chain, err := syncer.fetchTipSets(ctx, ci, func(t block.TipSet) (bool, error) {
 		parents, err := t.Parents()
 		if err != nil {
 			return true, err
@@ -431,14 +545,21 @@ func (syncer *Syncer) HandleNewTipSet(ctx context.Context, ci *block.ChainInfo,
 	})
 	syncer.reporter.UpdateStatus(syncFetchComplete(true))
 	if err != nil {
-		return err
+		return result, err
 	}
 	// Fetcher returns chain in Traversal order, reverse it to height order
 	Reverse(chain)
 
 	parent, grandParent, err := syncer.ancestorsFromStore(chain[0])
 	if err != nil {
-		return err
+		return result, err
+	}
+
+	// The head does not move to a fork deeper than the reorg limit until the
+	// whole fork is validated and found heavy enough.
+	deep, err := syncer.exceedsReorgDepth(ctx, curHead, parent, chain[len(chain)-1])
+	if err != nil {
+		return result, err
 	}
 
 	// Try adding the tipsets of the chain to the store, checking for new
@@ -450,13 +571,13 @@ func (syncer *Syncer) HandleNewTipSet(ctx context.Context, ci *block.ChainInfo,
 		if i == 0 {
 			wts, err = syncer.widen(ctx, ts)
 			if err != nil {
-				return err
+				return result, err
 			}
 			if wts.Defined() {
 				logSyncer.Debug("attempt to sync after widen")
-				err = syncer.syncOne(ctx, grandParent, parent, wts)
+				err = syncer.syncOne(ctx, grandParent, parent, wts, !deep)
 				if err != nil {
-					return err
+					return result, err
 				}
 			}
 		}
@@ -467,26 +588,100 @@ func (syncer *Syncer) HandleNewTipSet(ctx context.Context, ci *block.ChainInfo,
 		// as a performance optimization, because this tipset cannot be heavier
 		// than the widened first tipset.
 		if !wts.Defined() || len(chain) > 1 {
-			err = syncer.syncOne(ctx, grandParent, parent, ts)
+			err = syncer.syncOne(ctx, grandParent, parent, ts, !deep)
 			if err != nil {
 				// While `syncOne` can indeed fail for reasons other than consensus,
-				// adding to the badTipSets at this point is the simplest, since we
+				// rejecting the chain at this point is the simplest, since we
 				// have access to the chain. If syncOne fails for non-consensus reasons,
 				// there is no assumption that the running node's data is valid at all,
 				// so we don't really lose anything with this simplification.
-				syncer.badTipSets.AddChain(chain[i:])
-				return err
+				syncer.rejectChain(chain[i:], err)
+				return result, err
 			}
 		}
+		result.BlocksApplied += ts.Len()
 		if i%500 == 0 {
 			logSyncer.Infof("processing block %d of %v for chain with head at %v", i, len(chain), ci.Head.String())
 		}
 		grandParent = parent
 		parent = ts
 	}
+	if deep {
+		if err := syncer.adoptDeepFork(ctx, curHead, parent, grandParent); err != nil {
+			syncer.rejectChain(chain, err)
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// exceedsReorgDepth returns true if switching from curHead to the chain from
+// base to newHead would revert more than the syncer's maximum reorg depth.
+func (syncer *Syncer) exceedsReorgDepth(ctx context.Context, curHead, base, newHead block.TipSet) (bool, error) {
+	// A chain extending the head reverts nothing.
+	if syncer.maxReorgDepth == 0 || base.Equals(curHead) {
+		return false, nil
+	}
+	commonAncestor, err := FindCommonAncestor(IterAncestors(ctx, syncer.chainStore, curHead), IterAncestors(ctx, syncer.chainStore, base))
+	if err != nil {
+		return false, err
+	}
+	dropped, _, err := ReorgDiff(curHead, newHead, commonAncestor)
+	if err != nil {
+		return false, err
+	}
+	return dropped > syncer.maxReorgDepth, nil
+}
+
+// adoptDeepFork sets the validated fork tipset `newHead`, child of `parent`,
+// as the head in place of curHead if its weight exceeds curHead's by more
+// than the reorg weight margin, and returns ErrReorgTooDeep otherwise. Both
+// weights are computed from validated state rather than read from headers.
+func (syncer *Syncer) adoptDeepFork(ctx context.Context, curHead, newHead, parent block.TipSet) error {
+	curParent, _, err := syncer.ancestorsFromStore(curHead)
+	if err != nil {
+		return err
+	}
+	curWeight, err := syncer.calculateParentWeight(ctx, curHead, curParent)
+	if err != nil {
+		return err
+	}
+	newWeight, err := syncer.calculateParentWeight(ctx, newHead, parent)
+	if err != nil {
+		return err
+	}
+	if newWeight <= curWeight || newWeight-curWeight <= syncer.reorgWeightMargin {
+		return errors.Wrapf(ErrReorgTooDeep, "chain with head %s and weight %d does not outweigh head %s with weight %d by more than %d", newHead.Key(), newWeight, curHead.Key(), curWeight, syncer.reorgWeightMargin)
+	}
+	if err := syncer.chainStore.SetHead(ctx, newHead); err != nil {
+		return err
+	}
+	syncer.logReorg(ctx, curHead, newHead)
 	return nil
 }
 
+// describeHeadChange fills in the head related fields of result by comparing
+// the store's current head against priorHead.
+func (syncer *Syncer) describeHeadChange(ctx context.Context, priorHead block.TipSet, result *SyncResult) {
+	result.NewHead = syncer.chainStore.GetHead()
+	if result.NewHead.Equals(priorHead.Key()) {
+		return
+	}
+	result.HeadAdvanced = true
+
+	newHead, err := syncer.chainStore.GetTipSet(result.NewHead)
+	if err != nil {
+		logSyncer.Warnf("unexpected error loading new head %s for sync result: %s", result.NewHead, err.Error())
+		return
+	}
+	commonAncestor, err := FindCommonAncestor(IterAncestors(ctx, syncer.chainStore, priorHead), IterAncestors(ctx, syncer.chainStore, newHead))
+	if err != nil {
+		logSyncer.Warnf("unexpected error when running FindCommonAncestor for sync result: %s", err.Error())
+		return
+	}
+	result.Reorged = IsReorg(priorHead, newHead, commonAncestor)
+}
+
 // Status returns the current chain status.
 func (syncer *Syncer) Status() Status {
 	return syncer.reporter.Status()
@@ -9,15 +9,19 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-hamt-ipld"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 )
 
 func heightFromTip(t *testing.T, tip block.TipSet) uint64 {
@@ -185,6 +189,60 @@ func TestAcceptHeavierFork(t *testing.T) {
 	verifyHead(t, store, fork3)
 }
 
+func TestSyncResult(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	t.Run("advance", func(t *testing.T) {
+		builder, store, syncer := setup(ctx, t)
+		genesis := builder.RequireTipSet(store.GetHead())
+		t1 := builder.AppendOn(genesis, 2)
+		t2 := builder.AppendOn(t1, 1)
+
+		result, err := syncer.HandleNewTipSetWithResult(ctx, block.NewChainInfo(peer.ID(""), t2.Key(), heightFromTip(t, t2)), true)
+		require.NoError(t, err)
+		assert.True(t, result.HeadAdvanced)
+		assert.False(t, result.Reorged)
+		assert.Equal(t, t2.Key(), result.NewHead)
+		assert.Equal(t, 3, result.BlocksApplied)
+	})
+
+	t.Run("reorg", func(t *testing.T) {
+		builder, store, syncer := setup(ctx, t)
+		genesis := builder.RequireTipSet(store.GetHead())
+		forkbase := builder.AppendOn(genesis, 1)
+		main1 := builder.AppendOn(forkbase, 1)
+		main2 := builder.AppendOn(main1, 1)
+		fork1 := builder.AppendOn(forkbase, 3)
+		fork2 := builder.AppendOn(fork1, 1)
+
+		_, err := syncer.HandleNewTipSetWithResult(ctx, block.NewChainInfo(peer.ID(""), main2.Key(), heightFromTip(t, main2)), true)
+		require.NoError(t, err)
+
+		result, err := syncer.HandleNewTipSetWithResult(ctx, block.NewChainInfo(peer.ID(""), fork2.Key(), heightFromTip(t, fork2)), true)
+		require.NoError(t, err)
+		assert.True(t, result.HeadAdvanced)
+		assert.True(t, result.Reorged)
+		assert.Equal(t, fork2.Key(), result.NewHead)
+		assert.Equal(t, 4, result.BlocksApplied)
+	})
+
+	t.Run("no-op", func(t *testing.T) {
+		builder, store, syncer := setup(ctx, t)
+		genesis := builder.RequireTipSet(store.GetHead())
+		t1 := builder.AppendOn(genesis, 1)
+
+		require.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), t1.Key(), heightFromTip(t, t1)), true))
+
+		result, err := syncer.HandleNewTipSetWithResult(ctx, block.NewChainInfo(peer.ID(""), t1.Key(), heightFromTip(t, t1)), true)
+		require.NoError(t, err)
+		assert.False(t, result.HeadAdvanced)
+		assert.False(t, result.Reorged)
+		assert.Equal(t, t1.Key(), result.NewHead)
+		assert.Equal(t, 0, result.BlocksApplied)
+	})
+}
+
 func TestFarFutureTipsets(t *testing.T) {
 	tf.UnitTest(t)
 	ctx := context.Background()
@@ -194,7 +252,9 @@ func TestFarFutureTipsets(t *testing.T) {
 		genesis := builder.RequireTipSet(store.GetHead())
 		farHead := builder.AppendManyOn(chain.UntrustedChainHeightLimit+1, genesis)
 
-		syncer := chain.NewSyncer(&chain.FakeStateEvaluator{}, &chain.FakeChainSelector{}, store, builder, builder, chain.NewStatusReporter(), th.NewFakeClock(time.Unix(1234567890, 0)))
+		pvt, err := version.ConfigureProtocolVersions(version.TEST)
+		require.NoError(t, err)
+		syncer := chain.NewSyncer(&chain.FakeStateEvaluator{}, &chain.FakeChainSelector{}, store, builder, builder, chain.NewStatusReporter(), th.NewFakeClock(time.Unix(1234567890, 0)), pvt)
 		assert.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), farHead.Key(), heightFromTip(t, farHead)), true))
 	})
 
@@ -203,12 +263,75 @@ func TestFarFutureTipsets(t *testing.T) {
 		genesis := builder.RequireTipSet(store.GetHead())
 		farHead := builder.AppendManyOn(chain.UntrustedChainHeightLimit+1, genesis)
 
-		syncer := chain.NewSyncer(&chain.FakeStateEvaluator{}, &chain.FakeChainSelector{}, store, builder, builder, chain.NewStatusReporter(), th.NewFakeClock(time.Unix(1234567890, 0)))
-		err := syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), farHead.Key(), heightFromTip(t, farHead)), false)
+		pvt, err := version.ConfigureProtocolVersions(version.TEST)
+		require.NoError(t, err)
+		syncer := chain.NewSyncer(&chain.FakeStateEvaluator{}, &chain.FakeChainSelector{}, store, builder, builder, chain.NewStatusReporter(), th.NewFakeClock(time.Unix(1234567890, 0)), pvt)
+		err = syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), farHead.Key(), heightFromTip(t, farHead)), false)
 		assert.Error(t, err)
 	})
 }
 
+func TestMaxReorgDepth(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	t.Run("rejects a lighter deep fork and marks it bad", func(t *testing.T) {
+		builder, store, syncer := setup(ctx, t)
+		syncer.SetReorgLimit(2, 1)
+		genesis := builder.RequireTipSet(store.GetHead())
+		mainHead := builder.AppendManyOn(5, genesis)
+		fork := builder.AppendManyOn(4, genesis)
+
+		require.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), mainHead.Key(), heightFromTip(t, mainHead)), true))
+		err := syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), fork.Key(), heightFromTip(t, fork)), true)
+		assert.Equal(t, chain.ErrReorgTooDeep, errors.Cause(err))
+		assert.Equal(t, chain.ErrReorgTooDeep, errors.Cause(syncer.BadTipSetReason(fork.Key())))
+		verifyHead(t, store, mainHead)
+	})
+
+	t.Run("rejects a deep fork heavier by less than the margin", func(t *testing.T) {
+		builder, store, syncer := setup(ctx, t)
+		syncer.SetReorgLimit(2, 1)
+		genesis := builder.RequireTipSet(store.GetHead())
+		mainHead := builder.AppendManyOn(5, genesis)
+		forkBase := builder.AppendOn(genesis, 3)
+		fork := builder.AppendManyOn(3, forkBase)
+
+		require.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), mainHead.Key(), heightFromTip(t, mainHead)), true))
+		err := syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), fork.Key(), heightFromTip(t, fork)), true)
+		assert.Equal(t, chain.ErrReorgTooDeep, errors.Cause(err))
+		assert.Error(t, syncer.BadTipSetReason(fork.Key()))
+		verifyHead(t, store, mainHead)
+	})
+
+	t.Run("accepts a deep fork heavier by the margin", func(t *testing.T) {
+		builder, store, syncer := setup(ctx, t)
+		syncer.SetReorgLimit(2, 1)
+		genesis := builder.RequireTipSet(store.GetHead())
+		mainHead := builder.AppendManyOn(5, genesis)
+		forkBase := builder.AppendOn(genesis, 3)
+		fork := builder.AppendManyOn(4, forkBase)
+
+		require.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), mainHead.Key(), heightFromTip(t, mainHead)), true))
+		require.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), fork.Key(), heightFromTip(t, fork)), true))
+		assert.NoError(t, syncer.BadTipSetReason(fork.Key()))
+		verifyHead(t, store, fork)
+	})
+
+	t.Run("accepts a shallow fork", func(t *testing.T) {
+		builder, store, syncer := setup(ctx, t)
+		syncer.SetReorgLimit(2, 1)
+		genesis := builder.RequireTipSet(store.GetHead())
+		base := builder.AppendManyOn(3, genesis)
+		mainHead := builder.AppendManyOn(2, base)
+		fork := builder.AppendOn(base, 3)
+
+		require.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), mainHead.Key(), heightFromTip(t, mainHead)), true))
+		require.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), fork.Key(), heightFromTip(t, fork)), true))
+		assert.True(t, store.HasTipSetAndState(ctx, fork.Key()))
+	})
+}
+
 func TestNoUncessesaryFetch(t *testing.T) {
 	tf.UnitTest(t)
 	ctx := context.Background()
@@ -221,7 +344,9 @@ func TestNoUncessesaryFetch(t *testing.T) {
 	// A new syncer unable to fetch blocks from the network can handle a tipset that's already
 	// in the store and linked to genesis.
 	emptyFetcher := chain.NewBuilder(t, address.Undef)
-	newSyncer := chain.NewSyncer(&chain.FakeStateEvaluator{}, &chain.FakeChainSelector{}, store, builder, emptyFetcher, chain.NewStatusReporter(), th.NewFakeClock(time.Unix(1234567890, 0)))
+	pvt, err := version.ConfigureProtocolVersions(version.TEST)
+	require.NoError(t, err)
+	newSyncer := chain.NewSyncer(&chain.FakeStateEvaluator{}, &chain.FakeChainSelector{}, store, builder, emptyFetcher, chain.NewStatusReporter(), th.NewFakeClock(time.Unix(1234567890, 0)), pvt)
 	assert.NoError(t, newSyncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), head.Key(), heightFromTip(t, head)), true))
 }
 
@@ -390,6 +515,94 @@ func TestBlockNotLinkedRejected(t *testing.T) {
 	assert.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), b1.Key(), heightFromTip(t, b1)), true))
 }
 
+func TestBlockWithWrongStateRootRejected(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	builder, store, syncer := setup(ctx, t)
+	genesis := builder.RequireTipSet(store.GetHead())
+
+	bad := builder.BuildOneOn(genesis, func(b *chain.BlockBuilder) {
+		b.SetStateRoot(types.CidFromString(t, "fabricated"))
+	})
+	err := syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), bad.Key(), heightFromTip(t, bad)), true)
+	require.Error(t, err)
+	assert.Equal(t, consensus.ErrStateRootMismatch, errors.Cause(err))
+
+	_, err = store.GetTipSet(bad.Key())
+	assert.Error(t, err) // Not present
+	verifyHead(t, store, genesis)
+
+	// The same block with the computed state root is accepted.
+	good := builder.AppendOn(genesis, 1)
+	assert.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), good.Key(), heightFromTip(t, good)), true))
+	verifyHead(t, store, good)
+}
+
+func TestInvalidBlockRejectsChain(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	builder, store, syncer := setup(ctx, t)
+	genesis := builder.RequireTipSet(store.GetHead())
+
+	good := builder.AppendOn(genesis, 1)
+	bad := builder.BuildOneOn(good, func(b *chain.BlockBuilder) {
+		b.SetStateRoot(types.CidFromString(t, "fabricated"))
+	})
+	child := builder.AppendOn(bad, 1)
+	head := builder.AppendOn(child, 1)
+
+	err := syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), head.Key(), heightFromTip(t, head)), true)
+	require.Error(t, err)
+	assert.Equal(t, consensus.ErrStateRootMismatch, errors.Cause(err))
+	verifyHead(t, store, good)
+
+	// The invalid tipset and all its descendants are rejected for the same
+	// reason.
+	for _, ts := range []block.TipSet{bad, child, head} {
+		reason := syncer.BadTipSetReason(ts.Key())
+		require.Error(t, reason)
+		assert.Equal(t, consensus.ErrStateRootMismatch, errors.Cause(reason))
+	}
+	assert.NoError(t, syncer.BadTipSetReason(good.Key()))
+}
+
+func TestEquivocatingTipSetRejected(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	t.Run("rejected from Protocol3", func(t *testing.T) {
+		builder, store, _ := setup(ctx, t)
+		pvt, err := version.NewProtocolVersionTableBuilder(version.TEST).
+			Add(version.TEST, version.Protocol3, types.NewBlockHeight(0)).
+			Build()
+		require.NoError(t, err)
+		syncer := chain.NewSyncer(&chain.FakeStateEvaluator{}, &chain.FakeChainSelector{}, store, builder, builder, chain.NewStatusReporter(), th.NewFakeClock(time.Unix(1234567890, 0)), pvt)
+		genesis := builder.RequireTipSet(store.GetHead())
+
+		blockA, blockB := builder.BuildEquivocation(genesis)
+		equivocating := th.RequireNewTipSet(t, blockA, blockB)
+		err = syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), equivocating.Key(), heightFromTip(t, equivocating)), true)
+		require.Error(t, err)
+		_, isEquivocation := errors.Cause(err).(*consensus.ErrEquivocation)
+		assert.True(t, isEquivocation)
+
+		_, err = store.GetTipSet(equivocating.Key())
+		assert.Error(t, err) // Not present
+		verifyHead(t, store, genesis)
+		assert.Error(t, syncer.BadTipSetReason(equivocating.Key()))
+	})
+
+	t.Run("accepted before Protocol3", func(t *testing.T) {
+		builder, store, syncer := setup(ctx, t)
+		genesis := builder.RequireTipSet(store.GetHead())
+
+		blockA, blockB := builder.BuildEquivocation(genesis)
+		equivocating := th.RequireNewTipSet(t, blockA, blockB)
+		require.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), equivocating.Key(), heightFromTip(t, equivocating)), true))
+		verifyHead(t, store, equivocating)
+	})
+}
+
 func TestSyncerStatus(t *testing.T) {
 	tf.UnitTest(t)
 	ctx := context.Background()
@@ -408,6 +621,7 @@ func TestSyncerStatus(t *testing.T) {
 	assert.Equal(t, true, s0.SyncingFetchComplete)
 	assert.Equal(t, block.UndefTipSet.Key(), s0.FetchingHead)
 	assert.Equal(t, uint64(0), s0.FetchingHeight)
+	assert.Equal(t, uint64(0), s0.FetchedTipsets)
 
 	// initial sync and status check
 	t1 := builder.AppendOn(genesis, 1)
@@ -419,6 +633,8 @@ func TestSyncerStatus(t *testing.T) {
 
 	assert.Equal(t, t1.Key(), s1.FetchingHead)
 	assert.Equal(t, uint64(1), s1.FetchingHeight)
+	assert.Equal(t, uint64(1), s1.FetchedTipsets)
+	assert.Equal(t, uint64(len(t1.At(0).ToNode().RawData())), s1.FetchedBytes)
 
 	assert.Equal(t, true, s1.SyncingFetchComplete)
 	assert.Equal(t, true, s1.SyncingComplete)
@@ -472,7 +688,9 @@ func setup(ctx context.Context, t *testing.T) (*chain.Builder, *chain.Store, *ch
 	// *not* as the store, to which the syncer must ensure to put blocks.
 	eval := &chain.FakeStateEvaluator{}
 	sel := &chain.FakeChainSelector{}
-	syncer := chain.NewSyncer(eval, sel, store, builder, builder, sr, th.NewFakeClock(time.Unix(1234567890, 0)))
+	pvt, err := version.ConfigureProtocolVersions(version.TEST)
+	require.NoError(t, err)
+	syncer := chain.NewSyncer(eval, sel, store, builder, builder, sr, th.NewFakeClock(time.Unix(1234567890, 0)), pvt)
 
 	return builder, store, syncer
 }
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"sync"
 	"testing"
 
+	"github.com/cskr/pubsub"
 	"github.com/filecoin-project/go-bls-sigs"
 	"github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
@@ -20,10 +22,13 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/net"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	vmerrors "github.com/filecoin-project/go-filecoin/internal/pkg/vm/errors"
 )
 
 // Builder builds fake chains and acts as a provider and fetcher for the chain thus generated.
@@ -45,11 +50,15 @@ type Builder struct {
 
 	// Cache of the state root CID computed for each tipset key.
 	tipStateCids map[string]cid.Cid
+	// stateCidLimit bounds the number of cached state root CIDs when positive.
+	stateCidLimit int
+	// stateCidOrder lists the keys of cached state roots, oldest first, when bounded.
+	stateCidOrder []string
 }
 
 var _ BlockProvider = (*Builder)(nil)
 var _ TipSetProvider = (*Builder)(nil)
-var _ net.Fetcher = (*Builder)(nil)
+var _ net.ProgressFetcher = (*Builder)(nil)
 var _ MessageProvider = (*Builder)(nil)
 
 // NewBuilder builds a new chain faker with default fake state building.
@@ -57,6 +66,12 @@ func NewBuilder(t *testing.T, miner address.Address) *Builder {
 	return NewBuilderWithState(t, miner, &FakeStateBuilder{})
 }
 
+// NewBuilderWithCidPrefix builds a new chain faker with fake state building computing state
+// CIDs with `prefix`, e.g. to use a different hash function.
+func NewBuilderWithCidPrefix(t *testing.T, miner address.Address, prefix cid.Prefix) *Builder {
+	return NewBuilderWithState(t, miner, &FakeStateBuilder{CidPrefix: prefix})
+}
+
 // NewBuilderWithState builds a new chain faker.
 // Blocks will have `miner` set as the miner address, or a default if empty.
 func NewBuilderWithState(t *testing.T, miner address.Address, sb StateBuilder) *Builder {
@@ -88,6 +103,34 @@ func NewBuilderWithState(t *testing.T, miner address.Address, sb StateBuilder) *
 	return b
 }
 
+// BuildBenchmarkChain builds a chain of `length` tipsets of `width` blocks
+// above a genesis block, each block carrying `msgsPerBlock` unsigned messages
+// with placeholder receipts, and returns the builder and the head's key. The
+// chain is a fixed function of its shape, so benchmarks building it with the
+// same parameters sync and validate identical chains.
+func BuildBenchmarkChain(t *testing.T, length int, width int, msgsPerBlock int) (*Builder, block.TipSetKey) {
+	require.True(t, length >= 0 && width > 0 && msgsPerBlock >= 0)
+	from, err := address.NewActorAddress([]byte("benchmark-sender"))
+	require.NoError(t, err)
+	to, err := address.NewActorAddress([]byte("benchmark-receiver"))
+	require.NoError(t, err)
+
+	builder := NewBuilder(t, address.Undef)
+	head := builder.NewGenesis()
+	nonce := uint64(0)
+	for i := 0; i < length; i++ {
+		head = builder.Build(head, width, func(b *BlockBuilder, _ int) {
+			msgs := make([]*types.UnsignedMessage, msgsPerBlock)
+			for j := range msgs {
+				msgs[j] = types.NewMeteredMessage(from, to, nonce, types.NewAttoFILFromFIL(1), "", nil, types.NewGasPrice(1), types.NewGasUnits(0))
+				nonce++
+			}
+			b.AddMessagesWithAutoReceipts([]*types.SignedMessage{}, msgs)
+		})
+	}
+	return builder, head.Key()
+}
+
 // NewGenesis creates and returns a tipset of one block with no parents.
 func (f *Builder) NewGenesis() block.TipSet {
 	return th.RequireNewTipSet(f.t, f.AppendBlockOn(block.UndefTipSet))
@@ -131,6 +174,51 @@ func (f *Builder) AppendManyOn(height int, parent block.TipSet) block.TipSet {
 	return f.BuildManyOn(height, parent, nil)
 }
 
+// AppendNullRounds creates and returns a new single-block tipset child of `parent`
+// following `n` null rounds. The block's height is `n` + 1 above the parent's and its
+// timestamp is `n` + 1 default block times after the parent's earliest timestamp.
+func (f *Builder) AppendNullRounds(parent block.TipSet, n int) block.TipSet {
+	require.True(f.t, n >= 0)
+	pmin, err := parent.MinTimestamp()
+	require.NoError(f.t, err)
+	return f.BuildOneOn(parent, func(b *BlockBuilder) {
+		b.IncHeight(types.Uint64(n))
+		rounds := types.Uint64(n + 1)
+		b.SetTimestamp(pmin + rounds*types.Uint64(consensus.DefaultBlockTime.Seconds()))
+	})
+}
+
+// BuildEquivocation creates and returns two distinct single-block children of
+// `parent` at the same height from the builder's miner, as produced by a
+// miner equivocating. Each block is individually valid.
+func (f *Builder) BuildEquivocation(parent block.TipSet) (*block.Block, *block.Block) {
+	blockA := f.BuildOneOn(parent, nil).At(0)
+	blockB := f.BuildOneOn(parent, func(b *BlockBuilder) {
+		b.SetMiner(blockA.Miner)
+	}).At(0)
+	return blockA, blockB
+}
+
+// BuildToWeight appends single-block tipsets to `parent` until the tip's weight,
+// as computed by the builder's StateBuilder, meets or exceeds `targetWeight`, and
+// returns that tip. At least one tipset is always appended. It fails the test if
+// an appended tipset does not add weight, as it would never reach the target.
+func (f *Builder) BuildToWeight(parent block.TipSet, targetWeight uint64) block.TipSet {
+	var weight uint64
+	if parent.Defined() {
+		weight = f.weigh(parent)
+	}
+	for {
+		parent = f.AppendOn(parent, 1)
+		next := f.weigh(parent)
+		if next >= targetWeight {
+			return parent
+		}
+		require.True(f.t, next > weight, "tipset %s does not add weight", parent.Key())
+		weight = next
+	}
+}
+
 // BuildOnBlock creates and returns a new block child of singleton tipset `parent`. See Build.
 func (f *Builder) BuildOnBlock(parent *block.Block, build func(b *BlockBuilder)) *block.Block {
 	tip := block.UndefTipSet
@@ -205,16 +293,24 @@ func (f *Builder) Build(parent block.TipSet, width int, build func(b *BlockBuild
 		// Nonce intentionally omitted as it will go away.
 
 		if build != nil {
-			build(&BlockBuilder{b, f.t, f.messages}, i)
+			build(&BlockBuilder{
+				block:        b,
+				t:            f.t,
+				messages:     f.messages,
+				stateBuilder: f.stateBuilder,
+				parentState:  f.StateForKey(parent.Key()),
+			}, i)
 		}
 
-		// Compute state root for this block.
+		// Compute state root for this block, unless one was set by `build`.
 		ctx := context.Background()
-		prevState := f.StateForKey(parent.Key())
-		smsgs, umsgs, err := f.messages.LoadMessages(ctx, b.Messages)
-		require.NoError(f.t, err)
-		b.StateRoot, err = f.stateBuilder.ComputeState(prevState, [][]*types.UnsignedMessage{umsgs}, [][]*types.SignedMessage{smsgs})
-		require.NoError(f.t, err)
+		if !b.StateRoot.Defined() {
+			prevState := f.StateForKey(parent.Key())
+			smsgs, umsgs, err := f.messages.LoadMessages(ctx, b.Messages)
+			require.NoError(f.t, err)
+			b.StateRoot, err = f.stateBuilder.ComputeState(prevState, [][]*types.UnsignedMessage{umsgs}, [][]*types.SignedMessage{smsgs})
+			require.NoError(f.t, err)
+		}
 
 		// add block to cstore
 		_, err = f.cstore.Put(ctx, b)
@@ -223,10 +319,50 @@ func (f *Builder) Build(parent block.TipSet, width int, build func(b *BlockBuild
 	}
 	tip := th.RequireNewTipSet(f.t, blocks...)
 	// Compute and remember state for the tipset.
-	f.tipStateCids[tip.Key().String()] = f.ComputeState(tip)
+	f.rememberState(tip.Key(), f.ComputeState(tip))
 	return tip
 }
 
+// SetStateCacheLimit bounds the number of tipset state root CIDs the builder
+// caches to `limit`, evicting the oldest first. Evicted state roots are
+// recomputed on demand. A limit of zero, the default, caches every state root.
+// It must be called before building any tipsets.
+func (f *Builder) SetStateCacheLimit(limit int) {
+	f.stateCidLimit = limit
+}
+
+// SetStateBuilder replaces the builder's StateBuilder with `sb` for tipsets
+// built subsequently. State root CIDs already computed remain cached, so
+// states and weights on either side of the switch may be computed by
+// different logic and be mutually inconsistent. State roots evicted from a
+// bounded cache are recomputed with `sb`.
+func (f *Builder) SetStateBuilder(sb StateBuilder) {
+	f.stateBuilder = sb
+}
+
+// rememberState caches the state root for a tipset key, evicting the oldest
+// cached state root if the cache is bounded and full.
+func (f *Builder) rememberState(key block.TipSetKey, state cid.Cid) {
+	k := key.String()
+	if _, found := f.tipStateCids[k]; !found && f.stateCidLimit > 0 {
+		f.stateCidOrder = append(f.stateCidOrder, k)
+		for len(f.stateCidOrder) > f.stateCidLimit {
+			delete(f.tipStateCids, f.stateCidOrder[0])
+			f.stateCidOrder = f.stateCidOrder[1:]
+		}
+	}
+	f.tipStateCids[k] = state
+}
+
+// weigh returns the weight of `tip` computed by the builder's StateBuilder.
+func (f *Builder) weigh(tip block.TipSet) uint64 {
+	parentKey, err := tip.Parents()
+	require.NoError(f.t, err)
+	weight, err := f.stateBuilder.Weigh(tip, f.StateForKey(parentKey))
+	require.NoError(f.t, err)
+	return weight
+}
+
 // StateForKey loads (or computes) the state root for a tipset key.
 func (f *Builder) StateForKey(key block.TipSetKey) cid.Cid {
 	state, found := f.tipStateCids[key.String()]
@@ -277,6 +413,77 @@ func singleBuilder(build func(b *BlockBuilder)) func(b *BlockBuilder, i int) {
 	return func(b *BlockBuilder, i int) { build(b) }
 }
 
+///// Fake store /////
+
+// FakeStore is an in-memory chain store over the tipsets of a Builder. It satisfies the chain
+// reading interfaces of consumers such as the message Waiter and, by adopting a target's head
+// as a trivially successful sync, the dispatcher's syncer.
+type FakeStore struct {
+	builder    *Builder
+	headEvents *pubsub.PubSub
+
+	lk   sync.Mutex
+	head block.TipSetKey
+}
+
+// NewFakeStore creates a fake store over `builder` with an empty head.
+func NewFakeStore(builder *Builder) *FakeStore {
+	return &FakeStore{
+		builder:    builder,
+		headEvents: pubsub.New(128),
+	}
+}
+
+// GetHead returns the head tipset key.
+func (fs *FakeStore) GetHead() block.TipSetKey {
+	fs.lk.Lock()
+	defer fs.lk.Unlock()
+	return fs.head
+}
+
+// SetHead sets the head and publishes it on NewHeadTopic.
+func (fs *FakeStore) SetHead(ctx context.Context, ts block.TipSet) error {
+	fs.lk.Lock()
+	fs.head = ts.Key()
+	fs.lk.Unlock()
+	fs.headEvents.Pub(ts, NewHeadTopic)
+	return nil
+}
+
+// GetTipSet returns the tipset with key `key` from the builder.
+func (fs *FakeStore) GetTipSet(key block.TipSetKey) (block.TipSet, error) {
+	return fs.builder.GetTipSet(key)
+}
+
+// GetTipSetStateRoot returns the builder's state root for the tipset with key `key`.
+func (fs *FakeStore) GetTipSetStateRoot(key block.TipSetKey) (cid.Cid, error) {
+	return fs.builder.GetTipSetStateRoot(key)
+}
+
+// GetTipSetState loads the state tree at the tipset's state root. This fails unless the
+// builder's StateBuilder computes the roots of real state trees.
+func (fs *FakeStore) GetTipSetState(ctx context.Context, key block.TipSetKey) (state.Tree, error) {
+	root, err := fs.builder.GetTipSetStateRoot(key)
+	if err != nil {
+		return nil, err
+	}
+	return state.LoadStateTree(ctx, fs.builder.cstore, root)
+}
+
+// HeadEvents returns the pubsub on which new heads are published.
+func (fs *FakeStore) HeadEvents() *pubsub.PubSub {
+	return fs.headEvents
+}
+
+// HandleNewTipSet sets the head to the tipset of `ci`, which must be known to the builder.
+func (fs *FakeStore) HandleNewTipSet(ctx context.Context, ci *block.ChainInfo, trusted bool) error {
+	ts, err := fs.builder.GetTipSet(ci.Head)
+	if err != nil {
+		return err
+	}
+	return fs.SetHead(ctx, ts)
+}
+
 ///// Block builder /////
 
 // BlockBuilder mutates blocks as they are generated.
@@ -284,6 +491,15 @@ type BlockBuilder struct {
 	block    *block.Block
 	t        *testing.T
 	messages *MessageStore
+	// stateBuilder and parentState compute receipts for
+	// AddMessagesWithAutoReceipts.
+	stateBuilder StateBuilder
+	parentState  cid.Cid
+}
+
+// SetMiner sets the block's miner.
+func (bb *BlockBuilder) SetMiner(miner address.Address) {
+	bb.block.Miner = miner
 }
 
 // SetTicket sets the block's ticket.
@@ -316,7 +532,27 @@ func (bb *BlockBuilder) AddMessages(secpmsgs []*types.SignedMessage, blsMsgs []*
 	bb.block.MessageReceipts = cR
 }
 
-// SetStateRoot sets the block's state root.
+// AddMessagesWithAutoReceipts adds a message collection to the block along
+// with receipts matching it, one per message with secp messages first. If the
+// builder's StateBuilder is a ReceiptBuilder the receipts are those of
+// applying the messages to the parent state; otherwise they are placeholder
+// success receipts.
+func (bb *BlockBuilder) AddMessagesWithAutoReceipts(secpmsgs []*types.SignedMessage, blsMsgs []*types.UnsignedMessage) {
+	var rcpts []*types.MessageReceipt
+	if rb, ok := bb.stateBuilder.(ReceiptBuilder); ok {
+		var err error
+		rcpts, err = rb.ComputeReceipts(bb.parentState, blsMsgs, secpmsgs)
+		require.NoError(bb.t, err)
+	} else {
+		for i := 0; i < len(secpmsgs)+len(blsMsgs); i++ {
+			rcpts = append(rcpts, &types.MessageReceipt{ExitCode: 0})
+		}
+	}
+	bb.AddMessages(secpmsgs, blsMsgs, rcpts)
+}
+
+// SetStateRoot sets the block's state root, overriding the root the builder
+// would compute.
 func (bb *BlockBuilder) SetStateRoot(root cid.Cid) {
 	bb.block.StateRoot = root
 }
@@ -329,8 +565,19 @@ type StateBuilder interface {
 	Weigh(tip block.TipSet, state cid.Cid) (uint64, error)
 }
 
+// ReceiptBuilder is implemented by StateBuilders that can compute the
+// receipts of applying a block's messages to a state.
+type ReceiptBuilder interface {
+	// ComputeReceipts returns a receipt for each of the messages applied to
+	// the state `prev`, secp messages first.
+	ComputeReceipts(prev cid.Cid, blsMessages []*types.UnsignedMessage, secpMessages []*types.SignedMessage) ([]*types.MessageReceipt, error)
+}
+
 // FakeStateBuilder computes a fake state CID by hashing the CIDs of a block's parents and messages.
 type FakeStateBuilder struct {
+	// CidPrefix is the prefix of computed state CIDs. If zero, a CBOR CID using the default
+	// hash function is computed.
+	CidPrefix cid.Prefix
 }
 
 // ComputeState computes a fake state from a previous state root CID and the messages contained
@@ -338,7 +585,7 @@ type FakeStateBuilder struct {
 // is the same as the input state.
 // This differs from the true state transition function in that messages that are duplicated
 // between blocks in the tipset are not ignored.
-func (FakeStateBuilder) ComputeState(prev cid.Cid, blsMessages [][]*types.UnsignedMessage, secpMessages [][]*types.SignedMessage) (cid.Cid, error) {
+func (fs FakeStateBuilder) ComputeState(prev cid.Cid, blsMessages [][]*types.UnsignedMessage, secpMessages [][]*types.SignedMessage) (cid.Cid, error) {
 	// Accumulate the cids of the previous state and of all messages in the tipset.
 	inputs := []cid.Cid{prev}
 	for _, blockMessages := range blsMessages {
@@ -364,7 +611,10 @@ func (FakeStateBuilder) ComputeState(prev cid.Cid, blsMessages [][]*types.Unsign
 		// If there are no messages, the state doesn't change!
 		return prev, nil
 	}
-	return makeCid(inputs)
+	if fs.CidPrefix == (cid.Prefix{}) {
+		return makeCid(inputs)
+	}
+	return makeCidWith(fs.CidPrefix, inputs)
 }
 
 // Weigh computes a tipset's weight as its parent weight plus one for each block in the tipset.
@@ -380,6 +630,123 @@ func (FakeStateBuilder) Weigh(tip block.TipSet, state cid.Cid) (uint64, error) {
 	return parentWeight + uint64(tip.Len()), nil
 }
 
+// LedgerStateBuilder is a StateBuilder that tracks account balances without a VM.
+// Each state CID it computes identifies a ledger of balances derived from the previous
+// state's ledger by applying the value transfers of the messages. A state CID not computed
+// by the builder, such as the state before genesis, holds the initial balances.
+// As with the VM, a transfer exceeding the sender's balance is not applied.
+type LedgerStateBuilder struct {
+	FakeStateBuilder
+
+	lk      sync.Mutex
+	initial map[address.Address]types.AttoFIL
+	ledgers map[cid.Cid]map[address.Address]types.AttoFIL
+}
+
+var _ StateBuilder = (*LedgerStateBuilder)(nil)
+var _ ReceiptBuilder = (*LedgerStateBuilder)(nil)
+
+// NewLedgerStateBuilder creates a ledger state builder with the given initial balances.
+func NewLedgerStateBuilder(initial map[address.Address]types.AttoFIL) *LedgerStateBuilder {
+	balances := make(map[address.Address]types.AttoFIL, len(initial))
+	for addr, bal := range initial {
+		balances[addr] = bal
+	}
+	return &LedgerStateBuilder{
+		initial: balances,
+		ledgers: make(map[cid.Cid]map[address.Address]types.AttoFIL),
+	}
+}
+
+// ComputeState computes the state CID as FakeStateBuilder does and records the ledger
+// resulting from applying the messages' transfers to the ledger of `prev`.
+func (lb *LedgerStateBuilder) ComputeState(prev cid.Cid, blsMessages [][]*types.UnsignedMessage, secpMessages [][]*types.SignedMessage) (cid.Cid, error) {
+	state, err := lb.FakeStateBuilder.ComputeState(prev, blsMessages, secpMessages)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	lb.lk.Lock()
+	defer lb.lk.Unlock()
+
+	ledger := lb.copyLedger(prev)
+	for _, blockMessages := range blsMessages {
+		for _, msg := range blockMessages {
+			ledgerTransfer(ledger, msg)
+		}
+	}
+	for _, blockMessages := range secpMessages {
+		for _, msg := range blockMessages {
+			ledgerTransfer(ledger, &msg.Message)
+		}
+	}
+	lb.ledgers[state] = ledger
+	return state, nil
+}
+
+// ComputeReceipts applies the messages' transfers to the ledger of `prev`, in
+// the order ComputeState does, and returns a receipt for each, secp messages
+// first. A transfer exceeding the sender's balance fails with
+// ErrInsufficientBalance.
+func (lb *LedgerStateBuilder) ComputeReceipts(prev cid.Cid, blsMessages []*types.UnsignedMessage, secpMessages []*types.SignedMessage) ([]*types.MessageReceipt, error) {
+	lb.lk.Lock()
+	defer lb.lk.Unlock()
+
+	ledger := lb.copyLedger(prev)
+	receiptFor := func(msg *types.UnsignedMessage) *types.MessageReceipt {
+		if !ledgerTransfer(ledger, msg) {
+			return &types.MessageReceipt{ExitCode: vmerrors.ErrInsufficientBalance}
+		}
+		return &types.MessageReceipt{ExitCode: 0}
+	}
+	blsReceipts := make([]*types.MessageReceipt, len(blsMessages))
+	for i, msg := range blsMessages {
+		blsReceipts[i] = receiptFor(msg)
+	}
+	var receipts []*types.MessageReceipt
+	for _, msg := range secpMessages {
+		receipts = append(receipts, receiptFor(&msg.Message))
+	}
+	return append(receipts, blsReceipts...), nil
+}
+
+// BalanceAt returns the balance of `addr` in the state identified by `state`.
+func (lb *LedgerStateBuilder) BalanceAt(state cid.Cid, addr address.Address) types.AttoFIL {
+	lb.lk.Lock()
+	defer lb.lk.Unlock()
+	return lb.ledgerFor(state)[addr]
+}
+
+// copyLedger returns a copy of the ledger of a state. The caller must hold
+// the lock.
+func (lb *LedgerStateBuilder) copyLedger(state cid.Cid) map[address.Address]types.AttoFIL {
+	ledger := make(map[address.Address]types.AttoFIL)
+	for addr, bal := range lb.ledgerFor(state) {
+		ledger[addr] = bal
+	}
+	return ledger
+}
+
+// ledgerTransfer applies the value transfer of `msg` to `ledger`, returning false
+// and leaving the ledger unchanged if it exceeds the sender's balance.
+func ledgerTransfer(ledger map[address.Address]types.AttoFIL, msg *types.UnsignedMessage) bool {
+	if ledger[msg.From].LessThan(msg.Value) {
+		return false
+	}
+	ledger[msg.From] = ledger[msg.From].Sub(msg.Value)
+	ledger[msg.To] = ledger[msg.To].Add(msg.Value)
+	return true
+}
+
+// ledgerFor returns the ledger of a state. The caller must hold the lock.
+func (lb *LedgerStateBuilder) ledgerFor(state cid.Cid) map[address.Address]types.AttoFIL {
+	ledger, ok := lb.ledgers[state]
+	if !ok {
+		return lb.initial
+	}
+	return ledger
+}
+
 ///// State evaluator /////
 
 // FakeStateEvaluator is a syncStateEvaluator that delegates to the FakeStateBuilder.
@@ -397,9 +764,41 @@ func (e *FakeStateEvaluator) RunStateTransition(ctx context.Context, tip block.T
 // FakeChainSelector is a syncChainSelector that delegates to the FakeStateBuilder
 type FakeChainSelector struct {
 	FakeStateBuilder
+	tieBreak     consensus.TieBreakRule
+	weightMargin uint64
+}
+
+// FakeChainSelectorOption configures a FakeChainSelector.
+type FakeChainSelectorOption func(*FakeChainSelector)
+
+// WithTieBreak returns an option ordering tipsets of equal weight by `rule`.
+// Without it, neither of two equally weighted tipsets is heavier.
+func WithTieBreak(rule consensus.TieBreakRule) FakeChainSelectorOption {
+	return func(e *FakeChainSelector) {
+		e.tieBreak = rule
+	}
+}
+
+// WithWeightMargin returns an option requiring a tipset to outweigh another
+// by more than `margin` to be heavier, as ChainSelector does once its weight
+// margin is in effect. Ties are then not broken.
+func WithWeightMargin(margin uint64) FakeChainSelectorOption {
+	return func(e *FakeChainSelector) {
+		e.weightMargin = margin
+	}
+}
+
+// NewFakeChainSelector returns a FakeChainSelector configured by `options`.
+func NewFakeChainSelector(options ...FakeChainSelectorOption) *FakeChainSelector {
+	e := &FakeChainSelector{}
+	for _, option := range options {
+		option(e)
+	}
+	return e
 }
 
-// IsHeavier compares chains weighed with StateBuilder.Weigh.
+// IsHeavier compares chains weighed with StateBuilder.Weigh, breaking ties
+// with the selector's tie break rule if it has one.
 func (e *FakeChainSelector) IsHeavier(ctx context.Context, a, b block.TipSet, aStateID, bStateID cid.Cid) (bool, error) {
 	aw, err := e.Weigh(a, aStateID)
 	if err != nil {
@@ -409,7 +808,13 @@ func (e *FakeChainSelector) IsHeavier(ctx context.Context, a, b block.TipSet, aS
 	if err != nil {
 		return false, err
 	}
-	return aw > bw, nil
+	if e.weightMargin > 0 {
+		return aw > bw && aw-bw > e.weightMargin, nil
+	}
+	if aw != bw || e.tieBreak == nil {
+		return aw > bw, nil
+	}
+	return e.tieBreak(a, b)
 }
 
 // NewWeight delegates to the statebuilder
@@ -417,6 +822,11 @@ func (e *FakeChainSelector) NewWeight(ctx context.Context, ts block.TipSet, stID
 	return e.Weigh(ts, stID)
 }
 
+// Weight delegates to the statebuilder
+func (e *FakeChainSelector) Weight(ctx context.Context, ts block.TipSet, stID cid.Cid) (uint64, error) {
+	return e.Weigh(ts, stID)
+}
+
 ///// Interface and accessor implementations /////
 
 // GetBlock returns the block identified by `c`.
@@ -441,14 +851,25 @@ func (f *Builder) GetBlocks(ctx context.Context, cids []cid.Cid) ([]*block.Block
 	return ret, nil
 }
 
-// GetTipSet returns the tipset identified by `key`.
+// ErrBlockNotInStore is returned by the Builder when asked for a block it
+// has not built.
+type ErrBlockNotInStore struct {
+	Cid cid.Cid
+}
+
+func (e *ErrBlockNotInStore) Error() string {
+	return fmt.Sprintf("no block %s", e.Cid)
+}
+
+// GetTipSet returns the tipset identified by `key`, or an *ErrBlockNotInStore
+// if any of its blocks is missing.
 func (f *Builder) GetTipSet(key block.TipSetKey) (block.TipSet, error) {
 	ctx := context.Background()
 	var blocks []*block.Block
 	for it := key.Iter(); !it.Complete(); it.Next() {
 		var blk block.Block
 		if err := f.cstore.Get(ctx, it.Value(), &blk); err != nil {
-			return block.UndefTipSet, fmt.Errorf("no block %s", it.Value())
+			return block.UndefTipSet, &ErrBlockNotInStore{Cid: it.Value()}
 		}
 		blocks = append(blocks, &blk)
 	}
@@ -456,6 +877,7 @@ func (f *Builder) GetTipSet(key block.TipSetKey) (block.TipSet, error) {
 }
 
 // FetchTipSets fetchs the tipset at `tsKey` from the fetchers blockStore backed by the Builder.
+// It returns an *ErrBlockNotInStore if a block of the chain has not been built.
 func (f *Builder) FetchTipSets(ctx context.Context, key block.TipSetKey, from peer.ID, done func(t block.TipSet) (bool, error)) ([]block.TipSet, error) {
 	var tips []block.TipSet
 	for {
@@ -479,10 +901,22 @@ func (f *Builder) FetchTipSets(ctx context.Context, key block.TipSetKey, from pe
 	return tips, nil
 }
 
+// FetchTipSetsWithProgress behaves as FetchTipSets, reporting progress after
+// each tipset is fetched.
+func (f *Builder) FetchTipSetsWithProgress(ctx context.Context, key block.TipSetKey, from peer.ID, done func(t block.TipSet) (bool, error), progress func(net.FetchProgress)) ([]block.TipSet, error) {
+	return f.FetchTipSets(ctx, key, from, net.DoneWithProgress(done, progress))
+}
+
 // GetTipSetStateRoot returns the state root that was computed for a tipset.
 func (f *Builder) GetTipSetStateRoot(key block.TipSetKey) (cid.Cid, error) {
 	found, ok := f.tipStateCids[key.String()]
 	if !ok {
+		// The state may have been evicted from a bounded cache.
+		if f.stateCidLimit > 0 {
+			if tip, err := f.GetTipSet(key); err == nil {
+				return f.ComputeState(tip), nil
+			}
+		}
 		return cid.Undef, errors.Errorf("no state for %s", key)
 	}
 	return found, nil
@@ -508,6 +942,92 @@ func (f *Builder) RequireTipSets(head block.TipSetKey, count int) []block.TipSet
 	return tips
 }
 
+// GetTipSets returns up to `max` tipsets of the chain from `head`, stopping
+// early at genesis if the chain is shorter.
+func (f *Builder) GetTipSets(head block.TipSetKey, max int) ([]block.TipSet, error) {
+	var tips []block.TipSet
+	for key := head; !key.Empty() && len(tips) < max; {
+		tip, err := f.GetTipSet(key)
+		if err != nil {
+			return nil, err
+		}
+		tips = append(tips, tip)
+		if key, err = tip.Parents(); err != nil {
+			return nil, err
+		}
+	}
+	return tips, nil
+}
+
+// AllMessages returns every secp and every bls message in the chain from
+// `head` to genesis, each keyed by CID.
+func (f *Builder) AllMessages(head block.TipSetKey) (map[cid.Cid]*types.SignedMessage, map[cid.Cid]*types.UnsignedMessage, error) {
+	ctx := context.Background()
+	secp := make(map[cid.Cid]*types.SignedMessage)
+	bls := make(map[cid.Cid]*types.UnsignedMessage)
+	for key := head; !key.Empty(); {
+		tip, err := f.GetTipSet(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := 0; i < tip.Len(); i++ {
+			secpMsgs, blsMsgs, err := f.LoadMessages(ctx, tip.At(i).Messages)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, msg := range secpMsgs {
+				c, err := msg.Cid()
+				if err != nil {
+					return nil, nil, err
+				}
+				secp[c] = msg
+			}
+			for _, msg := range blsMsgs {
+				c, err := msg.Cid()
+				if err != nil {
+					return nil, nil, err
+				}
+				bls[c] = msg
+			}
+		}
+		if key, err = tip.Parents(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return secp, bls, nil
+}
+
+// RequireBuildersEqual requires that builders `a` and `b` hold identical chains ending
+// at `head`: the same blocks, state roots and messages at every tipset down to genesis.
+func RequireBuildersEqual(t *testing.T, a, b *Builder, head block.TipSetKey) {
+	ctx := context.Background()
+	for key := head; !key.Empty(); {
+		aTip, err := a.GetTipSet(key)
+		require.NoError(t, err)
+		bTip, err := b.GetTipSet(key)
+		require.NoError(t, err)
+		require.Equal(t, a.StateForKey(key), b.StateForKey(key), "state roots differ at %s", key)
+
+		for i := 0; i < aTip.Len(); i++ {
+			aSecp, aBLS, err := a.LoadMessages(ctx, aTip.At(i).Messages)
+			require.NoError(t, err)
+			bSecp, bBLS, err := b.LoadMessages(ctx, bTip.At(i).Messages)
+			require.NoError(t, err)
+			require.Equal(t, len(aSecp), len(bSecp))
+			for j := range aSecp {
+				require.True(t, types.SmsgCidsEqual(aSecp[j], bSecp[j]), "messages differ in block %s", aTip.At(i).Cid())
+			}
+			require.Equal(t, len(aBLS), len(bBLS))
+			for j := range aBLS {
+				require.True(t, types.MsgCidsEqual(aBLS[j], bBLS[j]), "messages differ in block %s", aTip.At(i).Cid())
+			}
+		}
+
+		key, err = aTip.Parents()
+		require.NoError(t, err)
+	}
+}
+
 // LoadMessages returns the message collections tracked by the builder.
 func (f *Builder) LoadMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error) {
 	return f.messages.LoadMessages(ctx, meta)
@@ -520,15 +1040,22 @@ func (f *Builder) LoadReceipts(ctx context.Context, c cid.Cid) ([]*types.Message
 
 ///// Internals /////
 
+// defaultCidPrefix is the prefix of CIDs made by makeCid.
+var defaultCidPrefix = cid.Prefix{
+	Version:  1,
+	Codec:    cid.DagCBOR,
+	MhType:   types.DefaultHashFunction,
+	MhLength: -1,
+}
+
 func makeCid(i interface{}) (cid.Cid, error) {
+	return makeCidWith(defaultCidPrefix, i)
+}
+
+func makeCidWith(prefix cid.Prefix, i interface{}) (cid.Cid, error) {
 	bytes, err := encoding.Encode(i)
 	if err != nil {
 		return cid.Undef, err
 	}
-	return cid.Prefix{
-		Version:  1,
-		Codec:    cid.DagCBOR,
-		MhType:   types.DefaultHashFunction,
-		MhLength: -1,
-	}.Sum(bytes)
+	return prefix.Sum(bytes)
 }
@@ -0,0 +1,532 @@
+package chain_test
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	vmerrors "github.com/filecoin-project/go-filecoin/internal/pkg/vm/errors"
+)
+
+func TestLedgerStateBuilder(t *testing.T) {
+	tf.UnitTest(t)
+
+	signer, _ := types.NewMockSignersAndKeyInfo(2)
+	alice, bob := signer.Addresses[0], signer.Addresses[1]
+	lb := chain.NewLedgerStateBuilder(map[address.Address]types.AttoFIL{
+		alice: types.NewAttoFILFromFIL(100),
+	})
+	builder := chain.NewBuilderWithState(t, address.Undef, lb)
+
+	transfer := func(from, to address.Address, nonce uint64, fil uint64) func(*chain.BlockBuilder) {
+		msg := types.NewMeteredMessage(from, to, nonce, types.NewAttoFILFromFIL(fil), "", nil, types.ZeroAttoFIL, types.NewGasUnits(0))
+		smsgs, err := types.SignMsgs(signer, []*types.UnsignedMessage{msg})
+		require.NoError(t, err)
+		return func(b *chain.BlockBuilder) {
+			b.AddMessages(smsgs, []*types.UnsignedMessage{}, types.EmptyReceipts(1))
+		}
+	}
+	assertBalances := func(ts block.TipSet, aliceFIL, bobFIL uint64) {
+		state := builder.StateForKey(ts.Key())
+		assert.True(t, types.NewAttoFILFromFIL(aliceFIL).Equal(lb.BalanceAt(state, alice)))
+		assert.True(t, types.NewAttoFILFromFIL(bobFIL).Equal(lb.BalanceAt(state, bob)))
+	}
+
+	genesis := builder.NewGenesis()
+	assertBalances(genesis, 100, 0)
+
+	ts1 := builder.BuildOneOn(genesis, transfer(alice, bob, 0, 30))
+	assertBalances(ts1, 70, 30)
+
+	ts2 := builder.BuildOneOn(ts1, transfer(bob, alice, 0, 10))
+	assertBalances(ts2, 80, 20)
+
+	// A transfer exceeding the sender's balance is not applied.
+	ts3 := builder.BuildOneOn(ts2, transfer(bob, alice, 1, 50))
+	assertBalances(ts3, 80, 20)
+
+	// Earlier states are unchanged.
+	assertBalances(ts1, 70, 30)
+}
+
+func TestAddMessagesWithAutoReceipts(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	signer, _ := types.NewMockSignersAndKeyInfo(2)
+	alice, bob := signer.Addresses[0], signer.Addresses[1]
+	transfers := func(fil ...uint64) []*types.SignedMessage {
+		var msgs []*types.UnsignedMessage
+		for i, f := range fil {
+			msgs = append(msgs, types.NewMeteredMessage(alice, bob, uint64(i), types.NewAttoFILFromFIL(f), "", nil, types.ZeroAttoFIL, types.NewGasUnits(0)))
+		}
+		smsgs, err := types.SignMsgs(signer, msgs)
+		require.NoError(t, err)
+		return smsgs
+	}
+	requireReceipts := func(builder *chain.Builder, ts block.TipSet, exitCodes ...uint8) {
+		rcpts, err := builder.LoadReceipts(ctx, ts.At(0).MessageReceipts)
+		require.NoError(t, err)
+		require.Len(t, rcpts, len(exitCodes))
+		for i, code := range exitCodes {
+			assert.Equal(t, code, rcpts[i].ExitCode)
+		}
+	}
+
+	t.Run("fake state builder", func(t *testing.T) {
+		builder := chain.NewBuilder(t, address.Undef)
+		msgs := transfers(1, 2, 3)
+		ts := builder.BuildOneOn(builder.NewGenesis(), func(b *chain.BlockBuilder) {
+			b.AddMessagesWithAutoReceipts(msgs, []*types.UnsignedMessage{})
+		})
+		requireReceipts(builder, ts, 0, 0, 0)
+	})
+
+	t.Run("ledger state builder", func(t *testing.T) {
+		lb := chain.NewLedgerStateBuilder(map[address.Address]types.AttoFIL{
+			alice: types.NewAttoFILFromFIL(100),
+		})
+		builder := chain.NewBuilderWithState(t, address.Undef, lb)
+		// The second transfer exceeds alice's remaining balance.
+		msgs := transfers(60, 60, 40)
+		ts := builder.BuildOneOn(builder.NewGenesis(), func(b *chain.BlockBuilder) {
+			b.AddMessagesWithAutoReceipts(msgs, []*types.UnsignedMessage{})
+		})
+		requireReceipts(builder, ts, 0, vmerrors.ErrInsufficientBalance, 0)
+	})
+}
+
+func TestBuildBenchmarkChain(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	a, aHead := chain.BuildBenchmarkChain(t, 5, 3, 4)
+	b, bHead := chain.BuildBenchmarkChain(t, 5, 3, 4)
+	assert.Equal(t, aHead, bHead)
+	chain.RequireBuildersEqual(t, a, b, aHead)
+
+	head := a.RequireTipSet(aHead)
+	assert.Equal(t, 3, head.Len())
+	h, err := head.Height()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), h)
+	_, blsMsgs, err := a.LoadMessages(ctx, head.At(0).Messages)
+	require.NoError(t, err)
+	assert.Len(t, blsMsgs, 4)
+
+	_, otherHead := chain.BuildBenchmarkChain(t, 5, 3, 5)
+	assert.NotEqual(t, aHead, otherHead)
+}
+
+func TestBuilderDeterminism(t *testing.T) {
+	tf.UnitTest(t)
+
+	seed := time.Now().UnixNano()
+	t.Logf("random seed: %d", seed)
+	rng := rand.New(rand.NewSource(seed))
+
+	mm := types.NewMessageMaker(t, types.MustGenerateKeyInfo(1, 42))
+	sender := mm.Addresses()[0]
+
+	// Each step of the script applies the same call to a builder, choosing parents by
+	// index into the tipsets that builder has produced so far.
+	type step func(b *chain.Builder, tips []block.TipSet) block.TipSet
+	var script []step
+	for i := 0; i < 30; i++ {
+		parent := rng.Intn(i + 1)
+		switch rng.Intn(4) {
+		case 0:
+			width := 1 + rng.Intn(3)
+			script = append(script, func(b *chain.Builder, tips []block.TipSet) block.TipSet {
+				return b.AppendOn(tips[parent], width)
+			})
+		case 1:
+			height := 1 + rng.Intn(3)
+			script = append(script, func(b *chain.Builder, tips []block.TipSet) block.TipSet {
+				return b.AppendManyOn(height, tips[parent])
+			})
+		case 2:
+			nulls := rng.Intn(3)
+			script = append(script, func(b *chain.Builder, tips []block.TipSet) block.TipSet {
+				return b.AppendNullRounds(tips[parent], nulls)
+			})
+		default:
+			msgs := []*types.SignedMessage{mm.NewSignedMessage(sender, uint64(i))}
+			script = append(script, func(b *chain.Builder, tips []block.TipSet) block.TipSet {
+				return b.BuildOneOn(tips[parent], func(bb *chain.BlockBuilder) {
+					bb.AddMessages(msgs, []*types.UnsignedMessage{}, types.EmptyReceipts(1))
+				})
+			})
+		}
+	}
+
+	run := func(b *chain.Builder) []block.TipSet {
+		tips := []block.TipSet{b.NewGenesis()}
+		for _, s := range script {
+			tips = append(tips, s(b, tips))
+		}
+		return tips
+	}
+	a := chain.NewBuilder(t, address.Undef)
+	b := chain.NewBuilder(t, address.Undef)
+	aTips := run(a)
+	bTips := run(b)
+
+	require.Equal(t, len(aTips), len(bTips))
+	for i := range aTips {
+		require.Equal(t, aTips[i].Key(), bTips[i].Key())
+		chain.RequireBuildersEqual(t, a, b, aTips[i].Key())
+	}
+}
+
+func TestBuilderCidPrefix(t *testing.T) {
+	tf.UnitTest(t)
+
+	sha256Prefix := cid.Prefix{
+		Version:  1,
+		Codec:    cid.DagCBOR,
+		MhType:   multihash.SHA2_256,
+		MhLength: -1,
+	}
+	mm := types.NewMessageMaker(t, types.MustGenerateKeyInfo(1, 42))
+	msgs := []*types.SignedMessage{mm.NewSignedMessage(mm.Addresses()[0], 0)}
+
+	// Build the same chain with each builder.
+	build := func(b *chain.Builder) cid.Cid {
+		ts := b.BuildOneOn(b.NewGenesis(), func(bb *chain.BlockBuilder) {
+			bb.AddMessages(msgs, []*types.UnsignedMessage{}, types.EmptyReceipts(1))
+		})
+		return b.StateForKey(ts.Key())
+	}
+	defaultState := build(chain.NewBuilder(t, address.Undef))
+	sha256State := build(chain.NewBuilderWithCidPrefix(t, address.Undef, sha256Prefix))
+
+	assert.NotEqual(t, defaultState, sha256State)
+	assert.Equal(t, uint64(types.DefaultHashFunction), defaultState.Prefix().MhType)
+	assert.Equal(t, uint64(multihash.SHA2_256), sha256State.Prefix().MhType)
+}
+
+func TestBuilderMissingBlock(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	builder := chain.NewBuilder(t, address.Undef)
+	gen := builder.NewGenesis()
+	builder.AppendOn(gen, 1)
+
+	missing := types.CidFromString(t, "never-built")
+	key := block.NewTipSetKey(missing)
+
+	_, err := builder.GetTipSet(key)
+	notInStore, ok := err.(*chain.ErrBlockNotInStore)
+	require.True(t, ok, "unexpected error %v", err)
+	assert.Equal(t, missing, notInStore.Cid)
+
+	tips, err := builder.FetchTipSets(ctx, key, "", func(block.TipSet) (bool, error) { return true, nil })
+	assert.Nil(t, tips)
+	_, ok = err.(*chain.ErrBlockNotInStore)
+	assert.True(t, ok, "unexpected error %v", err)
+}
+
+func TestBuilderEquivocation(t *testing.T) {
+	tf.UnitTest(t)
+
+	builder := chain.NewBuilder(t, address.Undef)
+	gen := builder.NewGenesis()
+	parent := builder.AppendOn(gen, 1)
+
+	blockA, blockB := builder.BuildEquivocation(parent)
+	assert.NotEqual(t, blockA.Cid(), blockB.Cid())
+	assert.Equal(t, blockA.Miner, blockB.Miner)
+	assert.Equal(t, blockA.Height, blockB.Height)
+	assert.Equal(t, parent.Key(), blockA.Parents)
+	assert.Equal(t, parent.Key(), blockB.Parents)
+
+	// each block alone is a valid tipset
+	for _, blk := range []*block.Block{blockA, blockB} {
+		ts, err := block.NewTipSet(blk)
+		require.NoError(t, err)
+		assert.NoError(t, consensus.DetectEquivocation(ts))
+	}
+
+	// together they reveal the equivocation
+	ts, err := block.NewTipSet(blockA, blockB)
+	require.NoError(t, err)
+	err = consensus.DetectEquivocation(ts)
+	require.Error(t, err)
+	equivocation, ok := err.(*consensus.ErrEquivocation)
+	require.True(t, ok)
+	assert.Equal(t, blockA.Miner, equivocation.Miner)
+	assert.Equal(t, uint64(blockA.Height), equivocation.Height)
+}
+
+func TestFakeStateBuilderWeigh(t *testing.T) {
+	tf.UnitTest(t)
+
+	sb := chain.FakeStateBuilder{}
+	weighParent := func(parentWeight uint64) (uint64, error) {
+		blk := &block.Block{ParentWeight: types.Uint64(parentWeight)}
+		ts, err := block.NewTipSet(blk)
+		require.NoError(t, err)
+		return sb.Weigh(ts, cid.Undef)
+	}
+
+	w, err := weighParent(1000)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1001), w)
+}
+
+func TestFakeChainSelectorTieBreak(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	genesis := builder.NewGenesis()
+	// Equal weight, and a's ticket is smaller than b's.
+	a := builder.AppendOn(genesis, 1)
+	b := builder.AppendOn(genesis, 1)
+	stateID := builder.StateForKey(genesis.Key())
+
+	heavier := func(selector *chain.FakeChainSelector) (bool, bool) {
+		ab, err := selector.IsHeavier(ctx, a, b, stateID, stateID)
+		require.NoError(t, err)
+		ba, err := selector.IsHeavier(ctx, b, a, stateID, stateID)
+		require.NoError(t, err)
+		return ab, ba
+	}
+
+	t.Run("no tie break", func(t *testing.T) {
+		ab, ba := heavier(chain.NewFakeChainSelector())
+		assert.False(t, ab)
+		assert.False(t, ba)
+	})
+
+	t.Run("by ticket", func(t *testing.T) {
+		ab, ba := heavier(chain.NewFakeChainSelector(chain.WithTieBreak(consensus.BreakTieByTicket)))
+		assert.True(t, ab)
+		assert.False(t, ba)
+	})
+
+	t.Run("by key", func(t *testing.T) {
+		ab, ba := heavier(chain.NewFakeChainSelector(chain.WithTieBreak(consensus.BreakTieByKey)))
+		assert.NotEqual(t, ab, ba)
+		assert.Equal(t, a.String() > b.String(), ab)
+	})
+}
+
+func TestBuilderFetchProgress(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	builder := chain.NewBuilder(t, address.Undef)
+	gen := builder.NewGenesis()
+	head := builder.AppendManyOn(3, gen)
+
+	var progress []net.FetchProgress
+	tips, err := builder.FetchTipSetsWithProgress(ctx, head.Key(), "", func(ts block.TipSet) (bool, error) {
+		return ts.Equals(gen), nil
+	}, func(p net.FetchProgress) {
+		progress = append(progress, p)
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4, len(tips))
+
+	// one report per fetched tipset, in traversal order
+	require.Equal(t, len(tips), len(progress))
+	var bytes uint64
+	for i, p := range progress {
+		h, err := tips[i].Height()
+		require.NoError(t, err)
+		bytes += uint64(len(tips[i].At(0).ToNode().RawData()))
+		assert.Equal(t, uint64(i+1), p.TipsetsFetched)
+		assert.Equal(t, h, p.CurrentHeight)
+		assert.Equal(t, bytes, p.BytesFetched)
+	}
+}
+
+func TestBuilderBuildToWeight(t *testing.T) {
+	tf.UnitTest(t)
+
+	builder := chain.NewBuilder(t, address.Undef)
+	gen := builder.NewGenesis()
+	weigh := func(ts block.TipSet) uint64 {
+		w, err := chain.FakeStateBuilder{}.Weigh(ts, cid.Undef)
+		require.NoError(t, err)
+		return w
+	}
+
+	t.Run("appends until target is met", func(t *testing.T) {
+		target := weigh(gen) + 5
+		head := builder.BuildToWeight(gen, target)
+		assert.True(t, weigh(head) >= target)
+
+		prev := builder.RequireTipSet(requireParents(t, head))
+		assert.True(t, weigh(prev) < target)
+	})
+
+	t.Run("single append meets target", func(t *testing.T) {
+		target := weigh(gen) + 1
+		head := builder.BuildToWeight(gen, target)
+		assert.True(t, weigh(head) >= target)
+		assert.Equal(t, gen.Key(), requireParents(t, head))
+	})
+}
+
+func requireParents(t *testing.T, ts block.TipSet) block.TipSetKey {
+	parents, err := ts.Parents()
+	require.NoError(t, err)
+	return parents
+}
+
+// countingStateBuilder counts the states it computes.
+type countingStateBuilder struct {
+	chain.FakeStateBuilder
+	computed int
+}
+
+func (sb *countingStateBuilder) ComputeState(prev cid.Cid, blsMessages [][]*types.UnsignedMessage, secpMessages [][]*types.SignedMessage) (cid.Cid, error) {
+	sb.computed++
+	return sb.FakeStateBuilder.ComputeState(prev, blsMessages, secpMessages)
+}
+
+// fixedStateBuilder computes the same state for every tipset.
+type fixedStateBuilder struct {
+	chain.FakeStateBuilder
+	state cid.Cid
+}
+
+func (sb fixedStateBuilder) ComputeState(prev cid.Cid, blsMessages [][]*types.UnsignedMessage, secpMessages [][]*types.SignedMessage) (cid.Cid, error) {
+	return sb.state, nil
+}
+
+func TestBuilderSetStateBuilder(t *testing.T) {
+	tf.UnitTest(t)
+
+	builder := chain.NewBuilder(t, address.Undef)
+	gen := builder.NewGenesis()
+	before := builder.AppendManyOn(2, gen)
+	beforeState := builder.StateForKey(before.Key())
+
+	fixed := types.CidFromString(t, "fixed-state")
+	builder.SetStateBuilder(fixedStateBuilder{state: fixed})
+	after := builder.AppendManyOn(2, before)
+
+	// Earlier states are unchanged, later ones are computed by the new builder.
+	assert.Equal(t, beforeState, builder.StateForKey(before.Key()))
+	assert.NotEqual(t, fixed, beforeState)
+	assert.Equal(t, fixed, builder.StateForKey(after.Key()))
+	assert.Equal(t, fixed, after.At(0).StateRoot)
+}
+
+func TestBuilderStateCacheLimit(t *testing.T) {
+	tf.UnitTest(t)
+
+	sb := &countingStateBuilder{}
+	builder := chain.NewBuilderWithState(t, address.Undef, sb)
+	builder.SetStateCacheLimit(2)
+
+	gen := builder.NewGenesis()
+	first := builder.AppendOn(gen, 1)
+	original, err := builder.GetTipSetStateRoot(first.Key())
+	require.NoError(t, err)
+
+	// Building further evicts the first tipset's state.
+	head := builder.AppendManyOn(3, first)
+
+	computed := sb.computed
+	recomputed, err := builder.GetTipSetStateRoot(first.Key())
+	require.NoError(t, err)
+	assert.True(t, sb.computed > computed)
+	assert.Equal(t, original, recomputed)
+	assert.Equal(t, original, builder.StateForKey(first.Key()))
+
+	// The most recent state is still cached.
+	computed = sb.computed
+	_, err = builder.GetTipSetStateRoot(head.Key())
+	require.NoError(t, err)
+	assert.Equal(t, computed, sb.computed)
+}
+
+func TestBuilderAllMessages(t *testing.T) {
+	tf.UnitTest(t)
+
+	keys := types.MustGenerateKeyInfo(1, 42)
+	mm := types.NewMessageMaker(t, keys)
+	alice := mm.Addresses()[0]
+	msgs := []*types.SignedMessage{
+		mm.NewSignedMessage(alice, 0),
+		mm.NewSignedMessage(alice, 1),
+		mm.NewSignedMessage(alice, 2),
+	}
+
+	blsMsgs := []*types.UnsignedMessage{
+		types.NewUnsignedMessage(alice, alice, 3, types.ZeroAttoFIL, "", nil),
+		types.NewUnsignedMessage(alice, alice, 4, types.ZeroAttoFIL, "", nil),
+	}
+
+	builder := chain.NewBuilder(t, address.Undef)
+	gen := builder.NewGenesis()
+	first := builder.BuildOneOn(gen, func(b *chain.BlockBuilder) {
+		b.AddMessages(msgs[:2], blsMsgs[:1], []*types.MessageReceipt{})
+	})
+	middle := builder.AppendOn(first, 1)
+	head := builder.BuildOneOn(middle, func(b *chain.BlockBuilder) {
+		b.AddMessages(msgs[2:], blsMsgs[1:], []*types.MessageReceipt{})
+	})
+
+	secp, bls, err := builder.AllMessages(head.Key())
+	require.NoError(t, err)
+	require.Equal(t, len(msgs), len(secp))
+	for _, msg := range msgs {
+		c, err := msg.Cid()
+		require.NoError(t, err)
+		assert.Equal(t, msg, secp[c])
+	}
+	require.Equal(t, len(blsMsgs), len(bls))
+	for _, msg := range blsMsgs {
+		c, err := msg.Cid()
+		require.NoError(t, err)
+		assert.Equal(t, msg, bls[c])
+	}
+
+	// Messages above the given head are not included.
+	secp, bls, err = builder.AllMessages(middle.Key())
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(secp))
+	assert.Equal(t, 1, len(bls))
+}
+
+func TestBuilderGetTipSets(t *testing.T) {
+	tf.UnitTest(t)
+
+	builder := chain.NewBuilder(t, address.Undef)
+	gen := builder.NewGenesis()
+	head := builder.AppendManyOn(3, gen)
+
+	// Asking for more than the chain holds returns the chain down to genesis.
+	tips, err := builder.GetTipSets(head.Key(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(tips))
+	assert.Equal(t, head, tips[0])
+	assert.Equal(t, gen, tips[3])
+
+	tips, err = builder.GetTipSets(head.Key(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, builder.RequireTipSets(head.Key(), 2), tips)
+
+	_, err = builder.GetTipSets(block.NewTipSetKey(types.CidFromString(t, "missing")), 1)
+	assert.Error(t, err)
+}
@@ -19,6 +19,7 @@ import (
 type Config struct {
 	API           *APIConfig           `json:"api"`
 	Bootstrap     *BootstrapConfig     `json:"bootstrap"`
+	Chain         *ChainConfig         `json:"chain"`
 	Datastore     *DatastoreConfig     `json:"datastore"`
 	Heartbeat     *HeartbeatConfig     `json:"heartbeat"`
 	Mining        *MiningConfig        `json:"mining"`
@@ -127,6 +128,36 @@ func newDefaultWalletConfig() *WalletConfig {
 	}
 }
 
+// ChainConfig holds all configuration options related to validating and
+// syncing the chain.
+type ChainConfig struct {
+	// BlockFutureWindow is how far ahead of the local clock a block's
+	// timestamp may be for the block to be synced once its time comes rather
+	// than rejected. Golang duration units are accepted.
+	BlockFutureWindow string `json:"blockFutureWindow"`
+	// MaxReorgDepth is the number of heights of its chain the node will
+	// revert to switch to a fork that does not outweigh its head by more than
+	// ReorgWeightMargin. Zero disables the limit.
+	MaxReorgDepth uint64 `json:"maxReorgDepth"`
+	// MessageIndexRetention is the number of heights below and including the
+	// head whose messages are indexed. Older messages are found by scanning
+	// the chain. Zero indexes the whole chain, which the node then holds in
+	// memory. It defaults to about a day of chain.
+	MessageIndexRetention uint64 `json:"messageIndexRetention"`
+	// ReorgWeightMargin is the margin by which a fork deeper than
+	// MaxReorgDepth must outweigh the node's head to be synced.
+	ReorgWeightMargin uint64 `json:"reorgWeightMargin"`
+}
+
+func newDefaultChainConfig() *ChainConfig {
+	return &ChainConfig{
+		BlockFutureWindow:     "0s",
+		MaxReorgDepth:         900,
+		MessageIndexRetention: 2880,
+		ReorgWeightMargin:     0,
+	}
+}
+
 // HeartbeatConfig holds all configuration options related to node heartbeat.
 type HeartbeatConfig struct {
 	// BeatTarget represents the address the filecoin node will send heartbeats to.
@@ -206,12 +237,29 @@ type MessagePoolConfig struct {
 	MaxPoolSize uint `json:"maxPoolSize"`
 	// MaxNonceGap is the maximum nonce of a message past the last received on chain
 	MaxNonceGap types.Uint64 `json:"maxNonceGap"`
+	// MaxAgeTipsets is the maximum age, in non-empty tipsets, that a received message
+	// may stay in the pool before it is dropped as stale
+	MaxAgeTipsets uint `json:"maxAgeTipsets"`
+	// PersistPending saves pending messages to the repo when the node stops and
+	// restores them to the pool when it starts again
+	PersistPending bool `json:"persistPending"`
+	// MinGasPrice is the minimum gas price of a message admitted to the pool.
+	// Messages must in any case have a positive gas price
+	MinGasPrice types.AttoFIL `json:"minGasPrice"`
+	// MaxMessageSize is the maximum size in bytes of a serialized message
+	// admitted to the pool, or zero for no limit
+	MaxMessageSize uint `json:"maxMessageSize"`
+	// DisableBalanceCheck admits messages whose sender cannot cover the value
+	// and maximum gas charge according to the latest state
+	DisableBalanceCheck bool `json:"disableBalanceCheck"`
 }
 
 func newDefaultMessagePoolConfig() *MessagePoolConfig {
 	return &MessagePoolConfig{
-		MaxPoolSize: 10000,
-		MaxNonceGap: 100,
+		MaxPoolSize:   10000,
+		MaxNonceGap:   100,
+		MaxAgeTipsets: 6,
+		MinGasPrice:   types.ZeroAttoFIL,
 	}
 }
 
@@ -235,6 +283,7 @@ func NewDefaultConfig() *Config {
 	return &Config{
 		API:           newDefaultAPIConfig(),
 		Bootstrap:     newDefaultBootstrapConfig(),
+		Chain:         newDefaultChainConfig(),
 		Datastore:     newDefaultDatastoreConfig(),
 		Swarm:         newDefaultSwarmConfig(),
 		Mining:        newDefaultMiningConfig(),
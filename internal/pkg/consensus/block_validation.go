@@ -5,8 +5,13 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/filecoin-project/go-sectorbuilder"
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/proofs/verification"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 )
@@ -29,6 +34,8 @@ type SyntaxValidator interface {
 // semantics.
 type BlockSemanticValidator interface {
 	ValidateSemantic(ctx context.Context, child *block.Block, parents *block.TipSet, parentWeight uint64) error
+	ValidateMessageCount(ctx context.Context, blk *block.Block, count int) error
+	ValidateMessageNonces(ctx context.Context, blk *block.Block, messages []*types.UnsignedMessage, nonces NonceLookup) error
 }
 
 // BlockSyntaxValidator defines an interface used to validate a blocks
@@ -45,66 +52,398 @@ type MessageSyntaxValidator interface {
 	ValidateReceiptsSyntax(ctx context.Context, receipts []*types.MessageReceipt) error
 }
 
+// ErrBlockFromFuture is returned by syntax validation for a block whose
+// timestamp is ahead of the local clock but within the validator's future
+// window. Such a block is not invalid and may be validated again at ValidAt.
+type ErrBlockFromFuture struct {
+	ValidAt time.Time
+}
+
+func (e ErrBlockFromFuture) Error() string {
+	return fmt.Sprintf("block from future, valid at %s", e.ValidAt)
+}
+
+// PoStVerifier verifies proofs of spacetime.
+type PoStVerifier interface {
+	VerifyPoSt(verification.VerifyPoStRequest) (verification.VerifyPoStResponse, error)
+}
+
+// PoStSectorInfo describes the sectors and challenge a miner's proof of
+// spacetime proves.
+type PoStSectorInfo struct {
+	ChallengeSeed    types.PoStChallengeSeed
+	SortedSectorInfo go_sectorbuilder.SortedSectorInfo
+	Faults           []uint64
+	SectorSize       *types.BytesAmount
+}
+
+// PoStSectorLookup returns the sectors committed by the miner actor at
+// `miner` and the challenge its proof of spacetime answers, in the state on
+// which a block with parents `parents` is built.
+type PoStSectorLookup func(ctx context.Context, parents block.TipSet, miner address.Address) (PoStSectorInfo, error)
+
+// WorkerLookup returns the worker address of the miner actor at `miner` in
+// the state on which a block with parents `parents` is built.
+type WorkerLookup func(ctx context.Context, parents block.TipSet, miner address.Address) (address.Address, error)
+
+// DefaultMaxMessagesPerBlock is the number of messages a block may carry
+// from protocol version 2.
+const DefaultMaxMessagesPerBlock = 4000
+
+// ErrTooManyMessages is returned by message count validation for a block
+// carrying more than the maximum number of messages.
+type ErrTooManyMessages struct {
+	Count int
+	Max   int
+}
+
+func (e ErrTooManyMessages) Error() string {
+	return fmt.Sprintf("block has %d messages, more than the maximum %d", e.Count, e.Max)
+}
+
+// NonceLookup returns the nonce of the actor at `addr` in a block's parent
+// state.
+type NonceLookup func(ctx context.Context, addr address.Address) (uint64, error)
+
+// ErrEquivocation is returned for a tipset containing two blocks produced by
+// the same miner at the same height.
+type ErrEquivocation struct {
+	Miner  address.Address
+	Height uint64
+	First  cid.Cid
+	Second cid.Cid
+}
+
+func (e ErrEquivocation) Error() string {
+	return fmt.Sprintf("miner %s equivocated at height %d with blocks %s and %s", e.Miner, e.Height, e.First, e.Second)
+}
+
+// DetectEquivocation returns an *ErrEquivocation if more than one block in
+// the tipset was produced by the same miner.
+func DetectEquivocation(ts block.TipSet) error {
+	seen := make(map[address.Address]cid.Cid, ts.Len())
+	for i := 0; i < ts.Len(); i++ {
+		blk := ts.At(i)
+		if first, ok := seen[blk.Miner]; ok {
+			return &ErrEquivocation{Miner: blk.Miner, Height: uint64(blk.Height), First: first, Second: blk.Cid()}
+		}
+		seen[blk.Miner] = blk.Cid()
+	}
+	return nil
+}
+
+// ValidateTipSetSyntax validates that the blocks of a tipset are distinct,
+// share a height, parents and parent weight, and match the tipset's key.
+// Tipsets are checked on construction, but their blocks may since have been
+// altered.
+func ValidateTipSetSyntax(ts block.TipSet) error {
+	if !ts.Defined() {
+		return fmt.Errorf("tipset is undefined")
+	}
+	first := ts.At(0)
+	cids := make([]cid.Cid, ts.Len())
+	for i := 0; i < ts.Len(); i++ {
+		blk := ts.At(i)
+		if blk.Height != first.Height {
+			return fmt.Errorf("tipset %s has inconsistent block heights %d and %d", ts.Key(), first.Height, blk.Height)
+		}
+		if !blk.Parents.Equals(first.Parents) {
+			return fmt.Errorf("tipset %s has inconsistent block parents %s and %s", ts.Key(), first.Parents, blk.Parents)
+		}
+		if blk.ParentWeight != first.ParentWeight {
+			return fmt.Errorf("tipset %s has inconsistent block parent weights %d and %d", ts.Key(), first.ParentWeight, blk.ParentWeight)
+		}
+		cids[i] = blk.Cid()
+	}
+	if key := block.NewTipSetKey(cids...); !key.Equals(ts.Key()) || key.Len() != ts.Len() {
+		return fmt.Errorf("tipset %s does not match its blocks %s", ts.Key(), key)
+	}
+	return nil
+}
+
 // DefaultBlockValidator implements the BlockValidator interface.
 type DefaultBlockValidator struct {
 	clock.Clock
 	blockTime time.Duration
 	pvt       *version.ProtocolVersionTable
+	// futureWindow is how far ahead of the clock a block timestamp may be
+	// before the block is rejected outright.
+	futureWindow time.Duration
+	// maxMessages is the number of messages a block may carry once
+	// version.Protocol2 is in effect.
+	maxMessages int
+	// ticketValidator and workers, when set, check that tickets chain from
+	// their parents once version.Protocol3 is in effect.
+	ticketValidator TicketValidator
+	workers         WorkerLookup
+	// postVerifier and postSectors, when set, check the proofs of spacetime
+	// of blocks once version.Protocol3 is in effect.
+	postVerifier PoStVerifier
+	postSectors  PoStSectorLookup
 }
 
 // NewDefaultBlockValidator returns a new DefaultBlockValidator. It uses `blkTime`
-// to validate blocks and uses the DefaultBlockValidationClock.
-func NewDefaultBlockValidator(blkTime time.Duration, c clock.Clock, pvt *version.ProtocolVersionTable) *DefaultBlockValidator {
+// to validate blocks and uses the DefaultBlockValidationClock. Proofs of
+// spacetime are verified with `postVerifier`, which may be nil to skip them.
+func NewDefaultBlockValidator(blkTime time.Duration, c clock.Clock, pvt *version.ProtocolVersionTable, postVerifier PoStVerifier) *DefaultBlockValidator {
+	return NewDefaultBlockValidatorWithFutureWindow(blkTime, c, pvt, postVerifier, 0)
+}
+
+// NewDefaultBlockValidatorWithFutureWindow returns a new DefaultBlockValidator
+// that tolerates block timestamps up to `window` ahead of its clock, reporting
+// them with ErrBlockFromFuture rather than rejecting them.
+func NewDefaultBlockValidatorWithFutureWindow(blkTime time.Duration, c clock.Clock, pvt *version.ProtocolVersionTable, postVerifier PoStVerifier, window time.Duration) *DefaultBlockValidator {
 	return &DefaultBlockValidator{
-		Clock:     c,
-		blockTime: blkTime,
-		pvt:       pvt,
+		Clock:        c,
+		blockTime:    blkTime,
+		pvt:          pvt,
+		futureWindow: window,
+		maxMessages:  DefaultMaxMessagesPerBlock,
+		postVerifier: postVerifier,
 	}
 }
 
-// ValidateSemantic validates a block is correctly derived from its parent.
-func (dv *DefaultBlockValidator) ValidateSemantic(ctx context.Context, child *block.Block, parents *block.TipSet, parentWeight uint64) error {
-	pmin, err := parents.MinTimestamp()
+// SetMaxMessagesPerBlock sets the number of messages a block may carry once
+// version.Protocol2 is in effect. It must be called before the validator is
+// used.
+func (dv *DefaultBlockValidator) SetMaxMessagesPerBlock(max int) {
+	dv.maxMessages = max
+}
+
+// SetTicketChainVerifier enables checking, once version.Protocol3 is in
+// effect, that a block's ticket is derived from its parents' min ticket with
+// the key of its miner's worker, as returned by `workers`, using `v`. A nil
+// validator disables the check. It must be called before the validator is
+// used.
+func (dv *DefaultBlockValidator) SetTicketChainVerifier(v TicketValidator, workers WorkerLookup) {
+	dv.ticketValidator = v
+	dv.workers = workers
+}
+
+// SetPoStSectorLookup enables semantic validation of the proofs of spacetime
+// of blocks against the sectors returned by `sectors`, once version.Protocol3
+// is in effect, if the validator has a PoSt verifier. A nil lookup disables
+// the check. It must be called before the validator is used.
+func (dv *DefaultBlockValidator) SetPoStSectorLookup(sectors PoStSectorLookup) {
+	dv.postSectors = sectors
+}
+
+// ValidatePoSt validates that the proof of spacetime carried by `blk` proves
+// its miner's committed sectors described by `sectorInfo`. Proofs are not
+// checked before version.Protocol3 or without a PoSt verifier.
+func (dv *DefaultBlockValidator) ValidatePoSt(ctx context.Context, blk *block.Block, sectorInfo PoStSectorInfo) error {
+	if dv.postVerifier == nil {
+		return nil
+	}
+	v, err := dv.pvt.VersionAt(types.NewBlockHeight(uint64(blk.Height)))
 	if err != nil {
 		return err
 	}
+	if v < version.Protocol3 {
+		return nil
+	}
 
-	ph, err := parents.Height()
+	if len(blk.PoStProof) == 0 {
+		return fmt.Errorf("block %s has no PoSt", blk.Cid().String())
+	}
+	res, err := dv.postVerifier.VerifyPoSt(verification.VerifyPoStRequest{
+		ChallengeSeed:    sectorInfo.ChallengeSeed,
+		SortedSectorInfo: sectorInfo.SortedSectorInfo,
+		Faults:           sectorInfo.Faults,
+		Proof:            blk.PoStProof,
+		SectorSize:       sectorInfo.SectorSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify PoSt of block %s: %s", blk.Cid().String(), err)
+	}
+	if !res.IsValid {
+		return fmt.Errorf("block %s has invalid PoSt from miner %s", blk.Cid().String(), blk.Miner)
+	}
+	return nil
+}
+
+// ValidateMessageCount validates that a block carrying `count` messages does
+// not exceed the maximum in effect at its height, returning ErrTooManyMessages
+// if it does. Message counts are unlimited before version.Protocol2.
+func (dv *DefaultBlockValidator) ValidateMessageCount(ctx context.Context, blk *block.Block, count int) error {
+	max, limited, err := MaxMessagesAt(dv.pvt, dv.maxMessages, uint64(blk.Height))
 	if err != nil {
 		return err
 	}
+	if limited && count > max {
+		return &ErrTooManyMessages{Count: count, Max: max}
+	}
+	return nil
+}
 
-	parentVersion, err := dv.pvt.VersionAt(types.NewBlockHeight(ph))
+// MaxMessagesAt returns the number of messages a block at height `h` may
+// carry under the protocol versions `pvt`, where `max` is the limit from
+// version.Protocol2, typically DefaultMaxMessagesPerBlock. It returns false
+// if the count is unlimited at `h`. Miners use it to select no more messages
+// than validators accept.
+func MaxMessagesAt(pvt *version.ProtocolVersionTable, max int, h uint64) (int, bool, error) {
+	v, err := pvt.VersionAt(types.NewBlockHeight(h))
+	if err != nil {
+		return 0, false, err
+	}
+	if v < version.Protocol2 {
+		return 0, false, nil
+	}
+	return max, true, nil
+}
+
+// ValidateMessageNonces validates that the messages a block carries from each
+// sender, in the order they are applied, have consecutive nonces starting
+// from the sender's nonce as returned by `nonces`. Nonces are not checked
+// before version.Protocol3.
+func (dv *DefaultBlockValidator) ValidateMessageNonces(ctx context.Context, blk *block.Block, messages []*types.UnsignedMessage, nonces NonceLookup) error {
+	v, err := dv.pvt.VersionAt(types.NewBlockHeight(uint64(blk.Height)))
 	if err != nil {
 		return err
 	}
-	// Protocol version 1 upgrade introduces validation of the weight field
-	// on the header.  During protocol version 0 validators do not validate
-	// that the parent weight written to the header actually corresponds to
-	// the weight measured by the validators.  Introducing this check
-	// prevents a validator from writing arbitrary parent weight values
-	// into a header and trivially generating the heaviest chain.
-	if parentVersion >= version.Protocol1 {
-		// Protocol Version 1 upgrade
-		if uint64(child.ParentWeight) != parentWeight {
-			return fmt.Errorf("block %s has invalid parent weight %d", child.Cid().String(), parentWeight)
-		}
+	if v < version.Protocol3 {
+		return nil
 	}
 
-	if uint64(child.Height) <= ph {
-		return fmt.Errorf("block %s has invalid height %d", child.Cid().String(), child.Height)
+	next := make(map[address.Address]uint64)
+	for _, msg := range messages {
+		expected, ok := next[msg.From]
+		if !ok {
+			expected, err = nonces(ctx, msg.From)
+			if err != nil {
+				return fmt.Errorf("failed to look up nonce of %s: %s", msg.From, err)
+			}
+		}
+		if uint64(msg.CallSeqNum) != expected {
+			return fmt.Errorf("block %s has message from %s with nonce %d, expected %d", blk.Cid().String(), msg.From, msg.CallSeqNum, expected)
+		}
+		next[msg.From] = expected + 1
 	}
+	return nil
+}
 
-	// check that child is appropriately delayed from its parents including
-	// null blocks.
-	// TODO replace check on height when #2222 lands
-	limit := uint64(pmin) + uint64(dv.BlockTime().Seconds())*(uint64(child.Height)-ph)
-	if uint64(child.Timestamp) < limit {
-		return fmt.Errorf("block %s with timestamp %d generated too far past parent, expected timestamp < %d", child.Cid().String(), child.Timestamp, limit)
+// ValidateSemantic validates a block is correctly derived from its parent.
+func (dv *DefaultBlockValidator) ValidateSemantic(ctx context.Context, child *block.Block, parents *block.TipSet, parentWeight uint64) error {
+	checks, err := dv.semanticChecks(ctx, child, parents, parentWeight)
+	if err != nil {
+		return err
+	}
+	for _, check := range checks {
+		if err := check(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// ValidateSemanticVerbose runs every semantic check of ValidateSemantic and
+// returns all failures rather than only the first. Errors reading the parents
+// prevent any check from running and are returned alone.
+func (dv *DefaultBlockValidator) ValidateSemanticVerbose(ctx context.Context, child *block.Block, parents *block.TipSet, parentWeight uint64) []error {
+	checks, err := dv.semanticChecks(ctx, child, parents, parentWeight)
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	for _, check := range checks {
+		if err := check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// semanticChecks returns the checks run by semantic validation, in order.
+func (dv *DefaultBlockValidator) semanticChecks(ctx context.Context, child *block.Block, parents *block.TipSet, parentWeight uint64) ([]func() error, error) {
+	if !child.Parents.Equals(parents.Key()) {
+		return nil, fmt.Errorf("block %s has parents %s, not %s", child.Cid().String(), child.Parents, parents.Key())
+	}
+	if err := ValidateTipSetSyntax(*parents); err != nil {
+		return nil, fmt.Errorf("block %s has malformed parents: %s", child.Cid().String(), err)
+	}
+
+	pmin, err := parents.MinTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	ph, err := parents.Height()
+	if err != nil {
+		return nil, err
+	}
+
+	parentVersion, err := dv.pvt.VersionAt(types.NewBlockHeight(ph))
+	if err != nil {
+		return nil, err
+	}
+
+	return []func() error{
+		func() error {
+			// Protocol version 1 upgrade introduces validation of the weight field
+			// on the header.  During protocol version 0 validators do not validate
+			// that the parent weight written to the header actually corresponds to
+			// the weight measured by the validators.  Introducing this check
+			// prevents a validator from writing arbitrary parent weight values
+			// into a header and trivially generating the heaviest chain.
+			if parentVersion >= version.Protocol1 {
+				// Protocol Version 1 upgrade
+				if uint64(child.ParentWeight) != parentWeight {
+					return fmt.Errorf("block %s has invalid parent weight %d", child.Cid().String(), parentWeight)
+				}
+			}
+			return nil
+		},
+		func() error {
+			if uint64(child.Height) <= ph {
+				return fmt.Errorf("block %s has invalid height %d", child.Cid().String(), child.Height)
+			}
+			return nil
+		},
+		func() error {
+			// check that child is appropriately delayed from its parents including
+			// null blocks.
+			// TODO replace check on height when #2222 lands
+			if uint64(child.Height) <= ph {
+				// The height check reports the block, and the delay below
+				// would underflow.
+				return nil
+			}
+			limit := uint64(pmin) + uint64(dv.BlockTime().Seconds())*(uint64(child.Height)-ph)
+			if uint64(child.Timestamp) < limit {
+				return fmt.Errorf("block %s with timestamp %d generated too far past parent, expected timestamp < %d", child.Cid().String(), child.Timestamp, limit)
+			}
+			return nil
+		},
+		func() error {
+			if dv.ticketValidator == nil || dv.workers == nil || parentVersion < version.Protocol3 {
+				return nil
+			}
+			parentTicket, err := parents.MinTicket()
+			if err != nil {
+				return err
+			}
+			worker, err := dv.workers(ctx, *parents, child.Miner)
+			if err != nil {
+				return fmt.Errorf("failed to look up worker of miner %s: %s", child.Miner, err)
+			}
+			if !dv.ticketValidator.IsValidTicket(parentTicket, child.Ticket, worker) {
+				return fmt.Errorf("block %s has ticket %s not derived from parent ticket %s by worker %s", child.Cid().String(), child.Ticket.String(), parentTicket.String(), worker)
+			}
+			return nil
+		},
+		func() error {
+			if dv.postVerifier == nil || dv.postSectors == nil || parentVersion < version.Protocol3 {
+				return nil
+			}
+			sectorInfo, err := dv.postSectors(ctx, *parents, child.Miner)
+			if err != nil {
+				return fmt.Errorf("failed to look up sectors of miner %s: %s", child.Miner, err)
+			}
+			return dv.ValidatePoSt(ctx, child, sectorInfo)
+		},
+	}, nil
+}
+
 // ValidateSyntax validates a single block is correctly formed.
 // TODO this is an incomplete implementation #3277
 func (dv *DefaultBlockValidator) ValidateSyntax(ctx context.Context, blk *block.Block) error {
@@ -112,21 +451,84 @@ func (dv *DefaultBlockValidator) ValidateSyntax(ctx context.Context, blk *block.
 	if blk.Height == 0 {
 		return nil
 	}
-	now := uint64(dv.Now().Unix())
-	if uint64(blk.Timestamp) > now {
-		return fmt.Errorf("block %s with timestamp %d generate in future at time %d", blk.Cid().String(), blk.Timestamp, now)
+	for _, check := range dv.syntaxChecks() {
+		if err := check(blk); err != nil {
+			return err
+		}
 	}
-	if !blk.StateRoot.Defined() {
-		return fmt.Errorf("block %s has nil StateRoot", blk.Cid().String())
+	return nil
+}
+
+// ValidateSyntaxVerbose runs every syntax check of ValidateSyntax and returns
+// all failures rather than only the first.
+func (dv *DefaultBlockValidator) ValidateSyntaxVerbose(ctx context.Context, blk *block.Block) []error {
+	if blk.Height == 0 {
+		return nil
 	}
-	if blk.Miner.Empty() {
-		return fmt.Errorf("block %s has nil miner address", blk.Cid().String())
+	var errs []error
+	for _, check := range dv.syntaxChecks() {
+		if err := check(blk); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	if len(blk.Ticket.VRFProof) == 0 {
-		return fmt.Errorf("block %s has nil ticket", blk.Cid().String())
+	return errs
+}
+
+// syntaxChecks returns the checks run by syntax validation, in order.
+func (dv *DefaultBlockValidator) syntaxChecks() []func(*block.Block) error {
+	return []func(*block.Block) error{
+		func(blk *block.Block) error {
+			now := uint64(dv.Now().Unix())
+			if uint64(blk.Timestamp) > now {
+				if time.Duration(uint64(blk.Timestamp)-now)*time.Second <= dv.futureWindow {
+					return &ErrBlockFromFuture{ValidAt: time.Unix(int64(blk.Timestamp), 0)}
+				}
+				return fmt.Errorf("block %s with timestamp %d generate in future at time %d", blk.Cid().String(), blk.Timestamp, now)
+			}
+			return nil
+		},
+		func(blk *block.Block) error {
+			if !blk.StateRoot.Defined() {
+				return fmt.Errorf("block %s has nil StateRoot", blk.Cid().String())
+			}
+			return nil
+		},
+		func(blk *block.Block) error {
+			if blk.Miner.Empty() {
+				return fmt.Errorf("block %s has nil miner address", blk.Cid().String())
+			}
+			return nil
+		},
+		func(blk *block.Block) error {
+			if len(blk.Ticket.VRFProof) == 0 {
+				return fmt.Errorf("block %s has nil ticket", blk.Cid().String())
+			}
+			return nil
+		},
 	}
+}
 
-	return nil
+// MaxPlausibleHeight returns the greatest chain height that could have been
+// reached by `now` if a block had been mined in every round since genesis.
+// Heights claimed beyond it can only be lies.
+func MaxPlausibleHeight(genesisTime time.Time, blockTime time.Duration, now time.Time) uint64 {
+	if blockTime <= 0 || !now.After(genesisTime) {
+		return 0
+	}
+	return uint64(now.Sub(genesisTime) / blockTime)
+}
+
+// ExpectedTimestamp returns the time at which a block at `height` is expected
+// to be mined on a chain started at `genesisTime`, assuming one round per
+// block time and counting null rounds.
+func ExpectedTimestamp(genesisTime time.Time, blockTime time.Duration, height uint64) time.Time {
+	return genesisTime.Add(time.Duration(height) * blockTime)
+}
+
+// ExpectedTimestamp returns the time at which a block at `height` is expected
+// on a chain started at `genesisTime`, using the validator's block time.
+func (dv *DefaultBlockValidator) ExpectedTimestamp(genesisTime time.Time, height uint64) time.Time {
+	return ExpectedTimestamp(genesisTime, dv.blockTime, height)
 }
 
 // BlockTime returns the block time the DefaultBlockValidator uses to validate
@@ -136,16 +538,47 @@ func (dv *DefaultBlockValidator) BlockTime() time.Duration {
 }
 
 // ValidateMessagesSyntax validates a set of messages are correctly formed.
-// TODO: Create a real implementation
+// It rejects a message list containing the same message more than once.
+// TODO: Validate the messages themselves
 // See: https://github.com/filecoin-project/go-filecoin/issues/3312
 func (dv *DefaultBlockValidator) ValidateMessagesSyntax(ctx context.Context, messages []*types.SignedMessage) error {
+	seen := make(map[cid.Cid]struct{}, len(messages))
+	for _, msg := range messages {
+		c, err := msg.Cid()
+		if err != nil {
+			return fmt.Errorf("failed to compute message cid: %s", err)
+		}
+		if err := checkDuplicateMessage(seen, c); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // ValidateUnsignedMessagesSyntax validates a set of messages are correctly formed.
-// TODO: Create a real implementation
+// It rejects a message list containing the same message more than once.
+// TODO: Validate the messages themselves
 // See: https://github.com/filecoin-project/go-filecoin/issues/3312
 func (dv *DefaultBlockValidator) ValidateUnsignedMessagesSyntax(ctx context.Context, messages []*types.UnsignedMessage) error {
+	seen := make(map[cid.Cid]struct{}, len(messages))
+	for _, msg := range messages {
+		c, err := msg.Cid()
+		if err != nil {
+			return fmt.Errorf("failed to compute message cid: %s", err)
+		}
+		if err := checkDuplicateMessage(seen, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDuplicateMessage returns an error if `c` is in `seen`, and adds it otherwise.
+func checkDuplicateMessage(seen map[cid.Cid]struct{}, c cid.Cid) error {
+	if _, ok := seen[c]; ok {
+		return fmt.Errorf("duplicate message %s in block", c)
+	}
+	seen[c] = struct{}{}
 	return nil
 }
 
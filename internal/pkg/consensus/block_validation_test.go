@@ -1,17 +1,21 @@
 package consensus_test
 
 import (
+	"bytes"
 	"context"
 	"testing"
 	"time"
 
 	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/proofs/verification"
 	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
@@ -31,18 +35,19 @@ func TestBlockValidSemantic(t *testing.T) {
 		Build()
 	require.NoError(t, err)
 
-	validator := consensus.NewDefaultBlockValidator(blockTime, mclock, pvt)
+	validator := consensus.NewDefaultBlockValidator(blockTime, mclock, pvt, nil)
 
 	t.Run("reject block with same height as parents", func(t *testing.T) {
 		// passes with valid height
-		c := &block.Block{Height: 2, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
 		p := &block.Block{Height: 1, Timestamp: types.Uint64(ts.Unix())}
 		parents := consensus.RequireNewTipSet(require.New(t), p)
+		c := &block.Block{Parents: parents.Key(), Height: 2, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
 		require.NoError(t, validator.ValidateSemantic(ctx, c, &parents, 0))
 
 		// invalidate parent by matching child height
 		p = &block.Block{Height: 2, Timestamp: types.Uint64(ts.Unix())}
 		parents = consensus.RequireNewTipSet(require.New(t), p)
+		c.Parents = parents.Key()
 
 		err := validator.ValidateSemantic(ctx, c, &parents, 0)
 		assert.Error(t, err)
@@ -52,35 +57,49 @@ func TestBlockValidSemantic(t *testing.T) {
 
 	t.Run("reject block mined too soon after parent", func(t *testing.T) {
 		// Passes with correct timestamp
-		c := &block.Block{Height: 2, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
 		p := &block.Block{Height: 1, Timestamp: types.Uint64(ts.Unix())}
 		parents := consensus.RequireNewTipSet(require.New(t), p)
+		c := &block.Block{Parents: parents.Key(), Height: 2, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
 		require.NoError(t, validator.ValidateSemantic(ctx, c, &parents, 0))
 
 		// fails with invalid timestamp
-		c = &block.Block{Height: 2, Timestamp: types.Uint64(ts.Unix())}
+		c = &block.Block{Parents: parents.Key(), Height: 2, Timestamp: types.Uint64(ts.Unix())}
 		err := validator.ValidateSemantic(ctx, c, &parents, 0)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "too far")
 
 	})
 
+	t.Run("accept block built after null rounds", func(t *testing.T) {
+		builder := chain.NewBuilder(t, address.Undef)
+		genesis := builder.NewGenesis()
+		parent := builder.AppendOn(genesis, 1)
+		child := builder.AppendNullRounds(parent, 3)
+
+		ph, err := parent.Height()
+		require.NoError(t, err)
+		ch, err := child.Height()
+		require.NoError(t, err)
+		assert.Equal(t, ph+4, ch)
+		assert.NoError(t, validator.ValidateSemantic(ctx, child.At(0), &parent, 0))
+	})
+
 	t.Run("reject block mined too soon after parent with one null block", func(t *testing.T) {
 		// Passes with correct timestamp
-		c := &block.Block{Height: 3, Timestamp: types.Uint64(ts.Add(2 * blockTime).Unix())}
 		p := &block.Block{Height: 1, Timestamp: types.Uint64(ts.Unix())}
 		parents := consensus.RequireNewTipSet(require.New(t), p)
+		c := &block.Block{Parents: parents.Key(), Height: 3, Timestamp: types.Uint64(ts.Add(2 * blockTime).Unix())}
 		err := validator.ValidateSemantic(ctx, c, &parents, 0)
 		require.NoError(t, err)
 
 		// fail when nul block calc is off by one blocktime
-		c = &block.Block{Height: 3, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
+		c = &block.Block{Parents: parents.Key(), Height: 3, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
 		err = validator.ValidateSemantic(ctx, c, &parents, 0)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "too far")
 
 		// fail with same timestamp as parent
-		c = &block.Block{Height: 3, Timestamp: types.Uint64(ts.Unix())}
+		c = &block.Block{Parents: parents.Key(), Height: 3, Timestamp: types.Uint64(ts.Unix())}
 		err = validator.ValidateSemantic(ctx, c, &parents, 0)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "too far")
@@ -89,9 +108,9 @@ func TestBlockValidSemantic(t *testing.T) {
 
 	t.Run("reject block mined with invalid parent weight after protocol 1 upgrade", func(t *testing.T) {
 		hUpgrade := 300
-		c := &block.Block{Height: types.Uint64(hUpgrade) + 50, ParentWeight: 5000, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
 		p := &block.Block{Height: types.Uint64(hUpgrade) + 49, Timestamp: types.Uint64(ts.Unix())}
 		parents := consensus.RequireNewTipSet(require.New(t), p)
+		c := &block.Block{Parents: parents.Key(), Height: types.Uint64(hUpgrade) + 50, ParentWeight: 5000, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
 
 		// validator expects parent weight different from 5000
 		pwExpectedByValidator := uint64(30)
@@ -101,13 +120,30 @@ func TestBlockValidSemantic(t *testing.T) {
 	})
 
 	t.Run("accept block mined with invalid parent weight before alphanet upgrade", func(t *testing.T) {
-		c := &block.Block{Height: 2, ParentWeight: 5000, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
 		p := &block.Block{Height: 1, Timestamp: types.Uint64(ts.Unix())}
 		parents := consensus.RequireNewTipSet(require.New(t), p)
+		c := &block.Block{Parents: parents.Key(), Height: 2, ParentWeight: 5000, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
 
 		err := validator.ValidateSemantic(ctx, c, &parents, 30)
 		assert.NoError(t, err)
 	})
+
+	t.Run("reject block whose parents are not the given tipset", func(t *testing.T) {
+		p := &block.Block{Height: 1, Timestamp: types.Uint64(ts.Unix())}
+		parents := consensus.RequireNewTipSet(require.New(t), p)
+		other := &block.Block{Height: 1, Timestamp: types.Uint64(ts.Unix()) + 1}
+
+		// The child is also invalid in height and timestamp, but those
+		// checks are not run.
+		c := &block.Block{Parents: block.NewTipSetKey(other.Cid()), Height: 1, Timestamp: types.Uint64(ts.Unix())}
+		err := validator.ValidateSemantic(ctx, c, &parents, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "has parents")
+
+		errs := validator.ValidateSemanticVerbose(ctx, c, &parents, 0)
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Error(), "has parents")
+	})
 }
 
 func TestBlockValidSyntax(t *testing.T) {
@@ -120,7 +156,7 @@ func TestBlockValidSyntax(t *testing.T) {
 	pvt, err := version.ConfigureProtocolVersions(version.TEST)
 	require.NoError(t, err)
 
-	validator := consensus.NewDefaultBlockValidator(blockTime, mclock, pvt)
+	validator := consensus.NewDefaultBlockValidator(blockTime, mclock, pvt, nil)
 
 	validTs := types.Uint64(ts.Unix())
 	validSt := types.NewCidForTestGetter()()
@@ -164,3 +200,415 @@ func TestBlockValidSyntax(t *testing.T) {
 	require.NoError(t, validator.ValidateSyntax(ctx, blk))
 
 }
+
+func TestBlockValidSyntaxVerbose(t *testing.T) {
+	tf.UnitTest(t)
+
+	blockTime := consensus.DefaultBlockTime
+	ts := time.Unix(1234567890, 0)
+	mclock := th.NewFakeClock(ts)
+	ctx := context.Background()
+	pvt, err := version.ConfigureProtocolVersions(version.TEST)
+	require.NoError(t, err)
+
+	validator := consensus.NewDefaultBlockValidator(blockTime, mclock, pvt, nil)
+
+	blk := &block.Block{
+		Timestamp: types.Uint64(ts.Unix()),
+		StateRoot: types.NewCidForTestGetter()(),
+		Miner:     address.NewForTestGetter()(),
+		Ticket:    block.Ticket{VRFProof: []byte{1}},
+		Height:    1,
+	}
+	assert.Empty(t, validator.ValidateSyntaxVerbose(ctx, blk))
+
+	// invalidate timestamp, state root and ticket at once
+	blk.Timestamp = types.Uint64(ts.Add(time.Second).Unix())
+	blk.StateRoot = cid.Undef
+	blk.Ticket = block.Ticket{}
+
+	errs := validator.ValidateSyntaxVerbose(ctx, blk)
+	require.Len(t, errs, 3)
+	assert.Contains(t, errs[0].Error(), "future")
+	assert.Contains(t, errs[1].Error(), "nil StateRoot")
+	assert.Contains(t, errs[2].Error(), "nil ticket")
+
+	// the fast-fail method reports only the first
+	assert.Equal(t, errs[0], validator.ValidateSyntax(ctx, blk))
+}
+
+func TestBlockValidSemanticVerbose(t *testing.T) {
+	tf.UnitTest(t)
+
+	blockTime := consensus.DefaultBlockTime
+	ts := time.Unix(1234567890, 0)
+	mclock := th.NewFakeClock(ts)
+	ctx := context.Background()
+	pvt, err := version.ConfigureProtocolVersions(version.TEST)
+	require.NoError(t, err)
+
+	validator := consensus.NewDefaultBlockValidator(blockTime, mclock, pvt, nil)
+
+	p := &block.Block{Height: 1, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
+	parents := consensus.RequireNewTipSet(require.New(t), p)
+
+	// invalid parent weight and timestamp
+	c := &block.Block{Parents: parents.Key(), Height: 2, ParentWeight: 5000, Timestamp: types.Uint64(ts.Unix())}
+	errs := validator.ValidateSemanticVerbose(ctx, c, &parents, 30)
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "invalid parent weight")
+	assert.Contains(t, errs[1].Error(), "too far")
+
+	// the timestamp of a block with an invalid height is not checked
+	c = &block.Block{Parents: parents.Key(), Height: 1, ParentWeight: 5000, Timestamp: types.Uint64(ts.Unix())}
+	errs = validator.ValidateSemanticVerbose(ctx, c, &parents, 30)
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "invalid parent weight")
+	assert.Contains(t, errs[1].Error(), "invalid height")
+}
+
+func TestBlockValidSemanticMalformedParents(t *testing.T) {
+	tf.UnitTest(t)
+
+	blockTime := consensus.DefaultBlockTime
+	ts := time.Unix(1234567890, 0)
+	mclock := th.NewFakeClock(ts)
+	ctx := context.Background()
+	pvt, err := version.ConfigureProtocolVersions(version.TEST)
+	require.NoError(t, err)
+
+	validator := consensus.NewDefaultBlockValidator(blockTime, mclock, pvt, nil)
+
+	p1 := &block.Block{Height: 1, Timestamp: types.Uint64(ts.Unix())}
+	p2 := &block.Block{Height: 1, Timestamp: types.Uint64(ts.Unix()) + 1}
+	parents := consensus.RequireNewTipSet(require.New(t), p1, p2)
+	require.NoError(t, consensus.ValidateTipSetSyntax(parents))
+
+	// A block of the tipset is altered after construction.
+	p2.Height = 5
+	assert.Error(t, consensus.ValidateTipSetSyntax(parents))
+
+	// The child is also invalid in height and timestamp, but those checks
+	// are not run.
+	c := &block.Block{Parents: parents.Key(), Height: 1, Timestamp: types.Uint64(ts.Unix())}
+	err = validator.ValidateSemantic(ctx, c, &parents, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "malformed parents")
+
+	errs := validator.ValidateSemanticVerbose(ctx, c, &parents, 0)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "malformed parents")
+
+	assert.Error(t, consensus.ValidateTipSetSyntax(block.UndefTipSet))
+}
+
+func TestBlockValidSyntaxFutureWindow(t *testing.T) {
+	tf.UnitTest(t)
+
+	blockTime := consensus.DefaultBlockTime
+	ts := time.Unix(1234567890, 0)
+	mclock := th.NewFakeClock(ts)
+	ctx := context.Background()
+	pvt, err := version.ConfigureProtocolVersions(version.TEST)
+	require.NoError(t, err)
+
+	validator := consensus.NewDefaultBlockValidatorWithFutureWindow(blockTime, mclock, pvt, nil, 5*time.Second)
+
+	blk := &block.Block{
+		Timestamp: types.Uint64(ts.Add(2 * time.Second).Unix()),
+		StateRoot: types.NewCidForTestGetter()(),
+		Miner:     address.NewForTestGetter()(),
+		Ticket:    block.Ticket{VRFProof: []byte{1}},
+		Height:    1,
+	}
+
+	// a block slightly ahead of the clock carries its retry time
+	err = validator.ValidateSyntax(ctx, blk)
+	require.Error(t, err)
+	futureErr, ok := err.(*consensus.ErrBlockFromFuture)
+	require.True(t, ok)
+	assert.Equal(t, ts.Add(2*time.Second).Unix(), futureErr.ValidAt.Unix())
+
+	// once the clock catches up the block is valid
+	mclock.Advance(2 * time.Second)
+	assert.NoError(t, validator.ValidateSyntax(ctx, blk))
+
+	// a block beyond the window is rejected outright
+	blk.Timestamp = types.Uint64(mclock.Now().Add(10 * time.Second).Unix())
+	err = validator.ValidateSyntax(ctx, blk)
+	require.Error(t, err)
+	_, ok = err.(*consensus.ErrBlockFromFuture)
+	assert.False(t, ok)
+}
+
+func TestBlockValidMessageCount(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	mclock := th.NewFakeClock(time.Unix(1234567890, 0))
+	pvt, err := version.NewProtocolVersionTableBuilder(version.TEST).
+		Add(version.TEST, version.Protocol1, types.NewBlockHeight(0)).
+		Add(version.TEST, version.Protocol2, types.NewBlockHeight(100)).
+		Build()
+	require.NoError(t, err)
+
+	validator := consensus.NewDefaultBlockValidator(consensus.DefaultBlockTime, mclock, pvt, nil)
+	validator.SetMaxMessagesPerBlock(10)
+
+	t.Run("accepts a block at the cap", func(t *testing.T) {
+		assert.NoError(t, validator.ValidateMessageCount(ctx, &block.Block{Height: 100}, 10))
+	})
+
+	t.Run("rejects a block above the cap", func(t *testing.T) {
+		err := validator.ValidateMessageCount(ctx, &block.Block{Height: 100}, 11)
+		require.Error(t, err)
+		tooMany, ok := err.(*consensus.ErrTooManyMessages)
+		require.True(t, ok)
+		assert.Equal(t, 11, tooMany.Count)
+		assert.Equal(t, 10, tooMany.Max)
+	})
+
+	t.Run("no cap before protocol 2", func(t *testing.T) {
+		assert.NoError(t, validator.ValidateMessageCount(ctx, &block.Block{Height: 99}, 11))
+	})
+}
+
+// chainedTicketValidator accepts a ticket only if its proof is the parent's
+// proof followed by the signer's address bytes.
+type chainedTicketValidator struct{}
+
+func (chainedTicketValidator) IsValidTicket(parent, ticket block.Ticket, signerAddr address.Address) bool {
+	return bytes.Equal(chainedTicket(parent, signerAddr).VRFProof, ticket.VRFProof)
+}
+
+func chainedTicket(parent block.Ticket, signerAddr address.Address) block.Ticket {
+	return block.Ticket{VRFProof: append(append([]byte{}, parent.VRFProof...), signerAddr.Bytes()...)}
+}
+
+func TestBlockValidSemanticTicketChain(t *testing.T) {
+	tf.UnitTest(t)
+
+	blockTime := consensus.DefaultBlockTime
+	ts := time.Unix(1234567890, 0)
+	mclock := th.NewFakeClock(ts)
+	ctx := context.Background()
+	pvt, err := version.NewProtocolVersionTableBuilder(version.TEST).
+		Add(version.TEST, version.Protocol2, types.NewBlockHeight(0)).
+		Add(version.TEST, version.Protocol3, types.NewBlockHeight(100)).
+		Build()
+	require.NoError(t, err)
+
+	newAddr := address.NewForTestGetter()
+	miner, worker := newAddr(), newAddr()
+	workers := func(ctx context.Context, parents block.TipSet, addr address.Address) (address.Address, error) {
+		if addr != miner {
+			return address.Undef, errors.New("no such miner")
+		}
+		return worker, nil
+	}
+
+	validator := consensus.NewDefaultBlockValidator(blockTime, mclock, pvt, nil)
+	validator.SetTicketChainVerifier(chainedTicketValidator{}, workers)
+
+	parentTicket := block.Ticket{VRFProof: []byte{1, 2, 3}}
+	brokenTicket := block.Ticket{VRFProof: []byte{4, 5, 6}}
+
+	newParents := func(height uint64) block.TipSet {
+		p := &block.Block{Height: types.Uint64(height), Ticket: parentTicket, Timestamp: types.Uint64(ts.Unix())}
+		return consensus.RequireNewTipSet(require.New(t), p)
+	}
+	newChild := func(parents block.TipSet, ticket block.Ticket) *block.Block {
+		h, err := parents.Height()
+		require.NoError(t, err)
+		return &block.Block{Parents: parents.Key(), Height: types.Uint64(h + 1), Miner: miner, Ticket: ticket, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
+	}
+
+	t.Run("accepts a ticket chained from the parent by the worker", func(t *testing.T) {
+		parents := newParents(100)
+		assert.NoError(t, validator.ValidateSemantic(ctx, newChild(parents, chainedTicket(parentTicket, worker)), &parents, 0))
+	})
+
+	t.Run("rejects a broken ticket chain", func(t *testing.T) {
+		parents := newParents(100)
+		err := validator.ValidateSemantic(ctx, newChild(parents, brokenTicket), &parents, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not derived from parent ticket")
+	})
+
+	t.Run("rejects a ticket not signed by the worker", func(t *testing.T) {
+		parents := newParents(100)
+		err := validator.ValidateSemantic(ctx, newChild(parents, chainedTicket(parentTicket, miner)), &parents, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not derived from parent ticket")
+	})
+
+	t.Run("ignores ticket chain before protocol 3", func(t *testing.T) {
+		parents := newParents(98)
+		assert.NoError(t, validator.ValidateSemantic(ctx, newChild(parents, brokenTicket), &parents, 0))
+	})
+
+	t.Run("ignores ticket chain without a verifier", func(t *testing.T) {
+		unchecked := consensus.NewDefaultBlockValidator(blockTime, mclock, pvt, nil)
+		parents := newParents(100)
+		assert.NoError(t, unchecked.ValidateSemantic(ctx, newChild(parents, brokenTicket), &parents, 0))
+	})
+}
+
+// provenPoStVerifier accepts only the proof it holds.
+type provenPoStVerifier struct {
+	proof types.PoStProof
+}
+
+func (v provenPoStVerifier) VerifyPoSt(req verification.VerifyPoStRequest) (verification.VerifyPoStResponse, error) {
+	return verification.VerifyPoStResponse{IsValid: bytes.Equal(req.Proof, v.proof)}, nil
+}
+
+func TestBlockValidPoSt(t *testing.T) {
+	tf.UnitTest(t)
+
+	blockTime := consensus.DefaultBlockTime
+	ts := time.Unix(1234567890, 0)
+	ctx := context.Background()
+	pvt, err := version.NewProtocolVersionTableBuilder(version.TEST).
+		Add(version.TEST, version.Protocol2, types.NewBlockHeight(0)).
+		Add(version.TEST, version.Protocol3, types.NewBlockHeight(100)).
+		Build()
+	require.NoError(t, err)
+
+	proof := types.PoStProof([]byte{1, 2, 3})
+	tampered := types.PoStProof([]byte{1, 2, 4})
+	validator := consensus.NewDefaultBlockValidator(blockTime, th.NewFakeClock(ts), pvt, provenPoStVerifier{proof})
+
+	miner := address.NewForTestGetter()()
+	sectorInfo := consensus.PoStSectorInfo{SectorSize: types.OneKiBSectorSize}
+
+	t.Run("accepts a valid proof", func(t *testing.T) {
+		blk := &block.Block{Height: 100, Miner: miner, PoStProof: proof}
+		assert.NoError(t, validator.ValidatePoSt(ctx, blk, sectorInfo))
+	})
+
+	t.Run("rejects a tampered proof", func(t *testing.T) {
+		blk := &block.Block{Height: 100, Miner: miner, PoStProof: tampered}
+		err := validator.ValidatePoSt(ctx, blk, sectorInfo)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid PoSt")
+	})
+
+	t.Run("ignores proofs before protocol 3", func(t *testing.T) {
+		blk := &block.Block{Height: 99, Miner: miner, PoStProof: tampered}
+		assert.NoError(t, validator.ValidatePoSt(ctx, blk, sectorInfo))
+	})
+
+	t.Run("checked by semantic validation", func(t *testing.T) {
+		var looked []address.Address
+		validator := consensus.NewDefaultBlockValidator(blockTime, th.NewFakeClock(ts), pvt, provenPoStVerifier{proof})
+		validator.SetPoStSectorLookup(func(ctx context.Context, parents block.TipSet, addr address.Address) (consensus.PoStSectorInfo, error) {
+			looked = append(looked, addr)
+			return sectorInfo, nil
+		})
+
+		p := &block.Block{Height: 100, Timestamp: types.Uint64(ts.Unix())}
+		parents := consensus.RequireNewTipSet(require.New(t), p)
+		c := &block.Block{Parents: parents.Key(), Height: 101, Miner: miner, PoStProof: proof, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
+		assert.NoError(t, validator.ValidateSemantic(ctx, c, &parents, 0))
+		assert.Equal(t, []address.Address{miner}, looked)
+
+		c = &block.Block{Parents: parents.Key(), Height: 101, Miner: miner, PoStProof: tampered, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
+		err := validator.ValidateSemantic(ctx, c, &parents, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid PoSt")
+	})
+}
+
+func TestMaxPlausibleHeight(t *testing.T) {
+	tf.UnitTest(t)
+
+	genesis := time.Unix(1234567890, 0)
+	blockTime := consensus.DefaultBlockTime
+
+	assert.Equal(t, uint64(0), consensus.MaxPlausibleHeight(genesis, blockTime, genesis))
+	assert.Equal(t, uint64(0), consensus.MaxPlausibleHeight(genesis, blockTime, genesis.Add(-time.Hour)))
+	assert.Equal(t, uint64(0), consensus.MaxPlausibleHeight(genesis, blockTime, genesis.Add(blockTime-time.Second)))
+	assert.Equal(t, uint64(1), consensus.MaxPlausibleHeight(genesis, blockTime, genesis.Add(blockTime)))
+	assert.Equal(t, uint64(120), consensus.MaxPlausibleHeight(genesis, blockTime, genesis.Add(time.Hour)))
+}
+
+func TestBlockValidMessagesSyntaxDuplicates(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	mclock := th.NewFakeClock(time.Unix(1234567890, 0))
+	pvt, err := version.ConfigureProtocolVersions(version.TEST)
+	require.NoError(t, err)
+	validator := consensus.NewDefaultBlockValidator(consensus.DefaultBlockTime, mclock, pvt, nil)
+
+	t.Run("signed", func(t *testing.T) {
+		signer, _ := types.NewMockSignersAndKeyInfo(1)
+		msgs := types.NewSignedMsgs(2, signer)
+		assert.NoError(t, validator.ValidateMessagesSyntax(ctx, msgs))
+
+		dup, err := msgs[0].Cid()
+		require.NoError(t, err)
+		err = validator.ValidateMessagesSyntax(ctx, append(msgs, msgs[0]))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), dup.String())
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		msgs := types.NewMsgs(2)
+		assert.NoError(t, validator.ValidateUnsignedMessagesSyntax(ctx, msgs))
+
+		dup, err := msgs[1].Cid()
+		require.NoError(t, err)
+		err = validator.ValidateUnsignedMessagesSyntax(ctx, append(msgs, msgs[1]))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), dup.String())
+	})
+}
+
+func TestBlockValidMessageNonces(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	mclock := th.NewFakeClock(time.Unix(1234567890, 0))
+	pvt, err := version.NewProtocolVersionTableBuilder(version.TEST).
+		Add(version.TEST, version.Protocol1, types.NewBlockHeight(0)).
+		Add(version.TEST, version.Protocol3, types.NewBlockHeight(100)).
+		Build()
+	require.NoError(t, err)
+	validator := consensus.NewDefaultBlockValidator(consensus.DefaultBlockTime, mclock, pvt, nil)
+
+	newAddr := address.NewForTestGetter()
+	alice, bob, to := newAddr(), newAddr(), newAddr()
+	nonces := func(ctx context.Context, addr address.Address) (uint64, error) {
+		return map[address.Address]uint64{alice: 5, bob: 0}[addr], nil
+	}
+	msg := func(from address.Address, nonce uint64) *types.UnsignedMessage {
+		return types.NewUnsignedMessage(from, to, nonce, types.ZeroAttoFIL, "", nil)
+	}
+	blk := &block.Block{Height: 100}
+
+	t.Run("accepts in order nonces", func(t *testing.T) {
+		msgs := []*types.UnsignedMessage{msg(alice, 5), msg(bob, 0), msg(alice, 6), msg(bob, 1)}
+		assert.NoError(t, validator.ValidateMessageNonces(ctx, blk, msgs, nonces))
+	})
+
+	t.Run("rejects out of order nonces", func(t *testing.T) {
+		msgs := []*types.UnsignedMessage{msg(alice, 6), msg(alice, 5)}
+		err := validator.ValidateMessageNonces(ctx, blk, msgs, nonces)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "nonce 6, expected 5")
+	})
+
+	t.Run("rejects nonce gaps", func(t *testing.T) {
+		msgs := []*types.UnsignedMessage{msg(alice, 5), msg(alice, 7)}
+		err := validator.ValidateMessageNonces(ctx, blk, msgs, nonces)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "nonce 7, expected 6")
+	})
+
+	t.Run("unchecked before protocol 3", func(t *testing.T) {
+		msgs := []*types.UnsignedMessage{msg(alice, 7), msg(alice, 5)}
+		assert.NoError(t, validator.ValidateMessageNonces(ctx, &block.Block{Height: 99}, msgs, nonces))
+	})
+}
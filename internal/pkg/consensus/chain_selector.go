@@ -42,6 +42,10 @@ type ChainSelector struct {
 	genesisCid cid.Cid
 
 	pvt *version.ProtocolVersionTable
+
+	// weightMargin is the weight by which a tipset must exceed another to be
+	// heavier once version.Protocol3 is in effect.
+	weightMargin uint64
 }
 
 // NewChainSelector is the constructor for chain selection module.
@@ -54,6 +58,15 @@ func NewChainSelector(cs *hamt.CborIpldStore, actorState SnapshotGenerator, gCid
 	}
 }
 
+// SetWeightMargin sets the weight, in fixed point representation, by which a
+// tipset must exceed another for IsHeavier to consider it heavier once
+// version.Protocol3 is in effect. Requiring a margin keeps competing chains
+// of nearly equal weight from repeatedly reorging each other. It must be
+// called before the selector is used.
+func (c *ChainSelector) SetWeightMargin(margin uint64) {
+	c.weightMargin = margin
+}
+
 // NewWeight returns the EC weight of this TipSet in uint64 encoded fixed point
 // representation.
 //
@@ -100,6 +113,35 @@ func (c *ChainSelector) NewWeight(ctx context.Context, ts block.TipSet, pStateID
 	return types.BigToFixed(w)
 }
 
+// ParentWeigher weighs tipsets with the weight function of each protocol
+// version.
+type ParentWeigher interface {
+	// Weight is the weight function before version.Protocol1.
+	Weight(ctx context.Context, ts block.TipSet, pStateID cid.Cid) (uint64, error)
+	// NewWeight is the weight function from version.Protocol1.
+	NewWeight(ctx context.Context, ts block.TipSet, pStateID cid.Cid) (uint64, error)
+}
+
+// ComputeParentWeight returns the weight a block built on `parents` must carry
+// as its parent weight, using the weight function in effect at the parents'
+// height. `pStateID` is the parent state of `parents`, undefined for genesis.
+// Block validation checks parent weights from version.Protocol1, where this is
+// the NewWeight of the parents.
+func ComputeParentWeight(ctx context.Context, parents block.TipSet, pStateID cid.Cid, selector ParentWeigher, pvt *version.ProtocolVersionTable) (uint64, error) {
+	h, err := parents.Height()
+	if err != nil {
+		return 0, err
+	}
+	v, err := pvt.VersionAt(types.NewBlockHeight(h))
+	if err != nil {
+		return 0, err
+	}
+	if v >= version.Protocol1 {
+		return selector.NewWeight(ctx, parents, pStateID)
+	}
+	return selector.Weight(ctx, parents, pStateID)
+}
+
 // Weight returns the EC weight of this TipSet in uint64 encoded fixed point
 // representation.
 func (c *ChainSelector) Weight(ctx context.Context, ts block.TipSet, pStateID cid.Cid) (uint64, error) {
@@ -156,6 +198,9 @@ func (c *ChainSelector) Weight(ctx context.Context, ts block.TipSet, pStateID ci
 // concatenation of block cids in the tipset.
 // TODO BLOCK CID CONCAT TIE BREAKER IS NOT IN THE SPEC AND SHOULD BE
 // EVALUATED BEFORE GETTING TO PRODUCTION.
+// Once version.Protocol3 is in effect at a's height and a weight margin is
+// set, a is heavier only if it outweighs b by more than the margin, and ties
+// are not broken.
 func (c *ChainSelector) IsHeavier(ctx context.Context, a, b block.TipSet, aStateID, bStateID cid.Cid) (bool, error) {
 	// Select weighting function based on protocol version
 	aWfun, err := c.chooseWeightFunc(a)
@@ -176,12 +221,35 @@ func (c *ChainSelector) IsHeavier(ctx context.Context, a, b block.TipSet, aState
 	if err != nil {
 		return false, err
 	}
+	if c.weightMargin > 0 {
+		h, err := a.Height()
+		if err != nil {
+			return false, err
+		}
+		v, err := c.pvt.VersionAt(types.NewBlockHeight(h))
+		if err != nil {
+			return false, err
+		}
+		if v >= version.Protocol3 {
+			return aW > bW && aW-bW > c.weightMargin, nil
+		}
+	}
 	// Without ties pass along the comparison.
 	if aW != bW {
 		return aW > bW, nil
 	}
 
-	// To break ties compare the min tickets.
+	return BreakTieByTicket(a, b)
+}
+
+// TieBreakRule orders two tipsets of equal weight, returning true if a is
+// to be considered heavier than b.
+type TieBreakRule func(a, b block.TipSet) (bool, error)
+
+// BreakTieByTicket is the TieBreakRule used by ChainSelector. The tipset with
+// the smaller min ticket is heavier, falling back to BreakTieByKey when the
+// min tickets are equal.
+func BreakTieByTicket(a, b block.TipSet) (bool, error) {
 	aTicket, err := a.MinTicket()
 	if err != nil {
 		return false, err
@@ -196,11 +264,15 @@ func (c *ChainSelector) IsHeavier(ctx context.Context, a, b block.TipSet, aState
 		// a is heavier if b's ticket is greater than a's ticket.
 		return cmp == 1, nil
 	}
+	return BreakTieByKey(a, b)
+}
 
-	// Tie break on cid ids.
+// BreakTieByKey is a TieBreakRule under which the tipset whose key sorts
+// last as a string is heavier.
+func BreakTieByKey(a, b block.TipSet) (bool, error) {
 	// TODO: I think this is drastically impacted by number of blocks in tipset
 	// i.e. bigger tipset is always heavier.  Not sure if this is ok, need to revist.
-	cmp = strings.Compare(a.String(), b.String())
+	cmp := strings.Compare(a.String(), b.String())
 	if cmp == 0 {
 		// Caller is mistakenly calling on two identical tipsets.
 		return false, ErrUnorderedTipSets
@@ -150,6 +150,10 @@ func (c *Expected) RunStateTransition(ctx context.Context, ts block.TipSet, blsM
 		if err := c.BlockValidator.ValidateSemantic(ctx, ts.At(i), &ancestors[0], parentWeight); err != nil {
 			return cid.Undef, err
 		}
+		// Reject oversized blocks before applying any of their messages.
+		if err := c.BlockValidator.ValidateMessageCount(ctx, ts.At(i), len(blsMessages[i])+len(secpMessages[i])); err != nil {
+			return cid.Undef, err
+		}
 	}
 
 	priorState, err := c.loadStateTree(ctx, priorStateID)
@@ -161,6 +165,27 @@ func (c *Expected) RunStateTransition(ctx context.Context, ts block.TipSet, blsM
 		return cid.Undef, err
 	}
 
+	nonces := func(ctx context.Context, addr address.Address) (uint64, error) {
+		a, err := priorState.GetActor(ctx, addr)
+		if state.IsActorNotFoundError(err) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		return uint64(a.Nonce), nil
+	}
+	for i := 0; i < ts.Len(); i++ {
+		// BLS messages are applied before secp messages.
+		msgs := append([]*types.UnsignedMessage{}, blsMessages[i]...)
+		for _, smsg := range secpMessages[i] {
+			msgs = append(msgs, &smsg.Message)
+		}
+		if err := c.BlockValidator.ValidateMessageNonces(ctx, ts.At(i), msgs, nonces); err != nil {
+			return cid.Undef, err
+		}
+	}
+
 	vms := vm.NewStorageMap(c.bstore)
 	st, err := c.runMessages(ctx, priorState, vms, ts, blsMessages, secpMessages, tsReceipts, ancestors)
 	if err != nil {
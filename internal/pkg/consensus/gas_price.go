@@ -0,0 +1,90 @@
+package consensus
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+// GasPricePercentiles are the percentiles SampleGasPrices reports.
+var GasPricePercentiles = []int{25, 50, 75}
+
+type gasPriceReader interface {
+	GetTipSet(key block.TipSetKey) (block.TipSet, error)
+	LoadMessages(ctx context.Context, meta types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error)
+}
+
+// SampleGasPrices returns the GasPricePercentiles of the gas prices of the
+// messages in the `n` tipsets ending at `head`, keyed by percentile. A message
+// included in more than one block is sampled once. Every percentile is zero if
+// the tipsets carry no messages.
+func SampleGasPrices(ctx context.Context, reader gasPriceReader, head block.TipSetKey, n int) (map[int]types.AttoFIL, error) {
+	var prices []types.AttoFIL
+	seen := make(map[cid.Cid]struct{})
+	sample := func(msg *types.UnsignedMessage) error {
+		c, err := msg.Cid()
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[c]; ok {
+			return nil
+		}
+		seen[c] = struct{}{}
+		prices = append(prices, msg.GasPrice)
+		return nil
+	}
+
+	key := head
+	for i := 0; i < n && !key.Empty(); i++ {
+		ts, err := reader.GetTipSet(key)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < ts.Len(); j++ {
+			secpMsgs, blsMsgs, err := reader.LoadMessages(ctx, ts.At(j).Messages)
+			if err != nil {
+				return nil, err
+			}
+			for _, msg := range blsMsgs {
+				if err := sample(msg); err != nil {
+					return nil, err
+				}
+			}
+			for _, msg := range secpMsgs {
+				if err := sample(&msg.Message); err != nil {
+					return nil, err
+				}
+			}
+		}
+		key, err = ts.Parents()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].LessThan(prices[j])
+	})
+	percentiles := make(map[int]types.AttoFIL, len(GasPricePercentiles))
+	for _, p := range GasPricePercentiles {
+		percentiles[p] = nearestRank(prices, p)
+	}
+	return percentiles, nil
+}
+
+// nearestRank returns the `p`th percentile of sorted `prices` by the nearest
+// rank method, or zero if there are no prices.
+func nearestRank(prices []types.AttoFIL, p int) types.AttoFIL {
+	if len(prices) == 0 {
+		return types.ZeroAttoFIL
+	}
+	rank := (p*len(prices) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return prices[rank-1]
+}
@@ -0,0 +1,83 @@
+package consensus_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func TestSampleGasPrices(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	keys := types.MustGenerateKeyInfo(1, 42)
+	mm := types.NewMessageMaker(t, keys)
+	alice := mm.Addresses()[0]
+	nonce := uint64(0)
+	pricedMessages := func(prices ...int64) []*types.SignedMessage {
+		var msgs []*types.SignedMessage
+		for _, p := range prices {
+			mm.DefaultGasPrice = types.NewGasPrice(p)
+			msgs = append(msgs, mm.NewSignedMessage(alice, nonce))
+			nonce++
+		}
+		return msgs
+	}
+	withMessages := func(builder *chain.Builder, parent block.TipSet, msgs []*types.SignedMessage) block.TipSet {
+		return builder.BuildOneOn(parent, func(b *chain.BlockBuilder) {
+			b.AddMessages(msgs, []*types.UnsignedMessage{}, []*types.MessageReceipt{})
+		})
+	}
+
+	t.Run("percentiles across recent tipsets", func(t *testing.T) {
+		builder := chain.NewBuilder(t, address.Undef)
+		gen := builder.NewGenesis()
+		ts1 := withMessages(builder, gen, pricedMessages(7, 1, 5, 3))
+		ts2 := withMessages(builder, ts1, pricedMessages(10, 40, 20, 30))
+		head := builder.AppendOn(ts2, 1)
+
+		percentiles, err := consensus.SampleGasPrices(ctx, builder, head.Key(), 3)
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasPrice(3), percentiles[25])
+		assert.Equal(t, types.NewGasPrice(7), percentiles[50])
+		assert.Equal(t, types.NewGasPrice(20), percentiles[75])
+
+		// Only the most recent tipsets are sampled.
+		percentiles, err = consensus.SampleGasPrices(ctx, builder, head.Key(), 2)
+		require.NoError(t, err)
+		assert.Equal(t, types.NewGasPrice(10), percentiles[25])
+		assert.Equal(t, types.NewGasPrice(20), percentiles[50])
+		assert.Equal(t, types.NewGasPrice(30), percentiles[75])
+	})
+
+	t.Run("no messages", func(t *testing.T) {
+		builder := chain.NewBuilder(t, address.Undef)
+		head := builder.AppendManyOn(3, builder.NewGenesis())
+
+		percentiles, err := consensus.SampleGasPrices(ctx, builder, head.Key(), 10)
+		require.NoError(t, err)
+		for _, p := range consensus.GasPricePercentiles {
+			assert.Equal(t, types.ZeroAttoFIL, percentiles[p])
+		}
+	})
+
+	t.Run("zero prices", func(t *testing.T) {
+		builder := chain.NewBuilder(t, address.Undef)
+		head := withMessages(builder, builder.NewGenesis(), pricedMessages(0, 0, 0))
+
+		percentiles, err := consensus.SampleGasPrices(ctx, builder, head.Key(), 1)
+		require.NoError(t, err)
+		for _, p := range consensus.GasPricePercentiles {
+			assert.True(t, percentiles[p].IsZero())
+		}
+	})
+}
@@ -2,13 +2,23 @@ package consensus
 
 import (
 	"context"
+	"io"
+	"sort"
+	"time"
 
 	"github.com/filecoin-project/go-amt-ipld"
 	"github.com/filecoin-project/go-bls-sigs"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/ipfs/go-car"
+	carutil "github.com/ipfs/go-car/util"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
 	"github.com/ipfs/go-hamt-ipld"
 	"github.com/ipfs/go-ipfs-blockstore"
 	"github.com/libp2p/go-libp2p-core/peer"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/pkg/errors"
 	"github.com/whyrusleeping/cbor-gen"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/actor"
@@ -18,8 +28,10 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/actor/builtin/paymentbroker"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/actor/builtin/storagemarket"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
 )
 
@@ -44,14 +56,23 @@ type minerActorConfig struct {
 	balance types.AttoFIL
 }
 
+type miningNodeConfig struct {
+	owner  address.Address
+	worker address.Address
+	power  *types.BytesAmount
+}
+
 // Config is used to configure values in the GenesisInitFunction.
 type Config struct {
 	accounts   map[address.Address]types.AttoFIL
 	nonces     map[address.Address]uint64
 	actors     map[address.Address]*actor.Actor
 	miners     map[address.Address]*minerActorConfig
+	nodes      map[address.Address]*miningNodeConfig
 	network    string
 	proofsMode types.ProofsMode
+	schedule   []version.ProtocolVersion
+	time       time.Time
 }
 
 // GenOption is a configuration option for the GenesisInitFunction.
@@ -76,6 +97,35 @@ func MinerActor(addr address.Address, owner address.Address, pid peer.ID, coll t
 	}
 }
 
+// MiningNode returns a config option that sets up everything a node needs to
+// mine from genesis: a funded owner account, an account for the worker key, and
+// a miner actor at MiningNodeAddress(owner) holding `power` that is registered
+// with the storage market.
+func MiningNode(owner, worker address.Address, balance types.AttoFIL, power *types.BytesAmount) GenOption {
+	return func(gc *Config) error {
+		addr, err := MiningNodeAddress(owner)
+		if err != nil {
+			return err
+		}
+		gc.accounts[owner] = balance
+		if _, ok := gc.accounts[worker]; !ok && worker != owner {
+			gc.accounts[worker] = types.ZeroAttoFIL
+		}
+		gc.nodes[addr] = &miningNodeConfig{
+			owner:  owner,
+			worker: worker,
+			power:  power,
+		}
+		return nil
+	}
+}
+
+// MiningNodeAddress returns the address of the miner actor created for `owner`
+// by the MiningNode genesis option.
+func MiningNodeAddress(owner address.Address) (address.Address, error) {
+	return address.NewActorAddress(owner.Bytes())
+}
+
 // ActorNonce returns a config option that sets the nonce of an existing actor.
 func ActorNonce(addr address.Address, nonce uint64) GenOption {
 	return func(gc *Config) error {
@@ -118,6 +168,25 @@ func ProofsMode(proofsMode types.ProofsMode) GenOption {
 	}
 }
 
+// ProtocolSchedule records the protocol version upgrade schedule in the genesis
+// state so that it can be read back from chain with LoadProtocolSchedule.
+func ProtocolSchedule(pvt *version.ProtocolVersionTable) GenOption {
+	return func(gc *Config) error {
+		gc.schedule = pvt.Versions()
+		return nil
+	}
+}
+
+// GenesisTime returns a config option that stamps the genesis block with `t`
+// and records it in the init actor's state, where it can be read back with
+// the init actor's getGenesisTime method.
+func GenesisTime(t time.Time) GenOption {
+	return func(gc *Config) error {
+		gc.time = t
+		return nil
+	}
+}
+
 // NewEmptyConfig inits and returns an empty config
 func NewEmptyConfig() *Config {
 	return &Config{
@@ -125,6 +194,7 @@ func NewEmptyConfig() *Config {
 		nonces:     make(map[address.Address]uint64),
 		actors:     make(map[address.Address]*actor.Actor),
 		miners:     make(map[address.Address]*minerActorConfig),
+		nodes:      make(map[address.Address]*miningNodeConfig),
 		network:    "localnet",
 		proofsMode: types.TestProofsMode,
 	}
@@ -184,6 +254,27 @@ func MakeGenesisFunc(opts ...GenOption) GenesisInitFunc {
 		if err := SetupDefaultActors(ctx, st, storageMap, genCfg.proofsMode, genCfg.network); err != nil {
 			return nil, err
 		}
+		for addr, node := range genCfg.nodes {
+			if err := setupMiningNode(ctx, st, storageMap, genCfg.proofsMode, addr, node); err != nil {
+				return nil, errors.Wrapf(err, "failed to set up mining node %s", addr)
+			}
+		}
+		if len(genCfg.schedule) > 0 {
+			if err := updateInitState(ctx, st, storageMap, func(initState *initactor.State) {
+				initState.ProtocolVersions = genCfg.schedule
+			}); err != nil {
+				return nil, err
+			}
+		}
+		var timestamp uint64
+		if !genCfg.time.IsZero() {
+			timestamp = uint64(genCfg.time.Unix())
+			if err := updateInitState(ctx, st, storageMap, func(initState *initactor.State) {
+				initState.GenesisTime = timestamp
+			}); err != nil {
+				return nil, err
+			}
+		}
 		// Now add any other actors configured.
 		for addr, a := range genCfg.actors {
 			if err := st.SetActor(ctx, addr, a); err != nil {
@@ -209,6 +300,7 @@ func MakeGenesisFunc(opts ...GenOption) GenesisInitFunc {
 			MessageReceipts: emptyAMTCid,
 			BLSAggregateSig: emptyBLSSignature[:],
 			Ticket:          block.Ticket{VRFProof: []byte{0xec}},
+			Timestamp:       types.Uint64(timestamp),
 		}
 
 		if _, err := cst.Put(ctx, genesis); err != nil {
@@ -224,6 +316,187 @@ func MakeGenesisFunc(opts ...GenOption) GenesisInitFunc {
 	}
 }
 
+// ErrNoProtocolSchedule is returned by LoadProtocolSchedule when the genesis
+// state does not record a protocol upgrade schedule.
+var ErrNoProtocolSchedule = errors.New("no protocol schedule recorded in state")
+
+// LoadProtocolSchedule reads the protocol version upgrade schedule recorded in
+// the init actor's state by the ProtocolSchedule genesis option.
+func LoadProtocolSchedule(ctx context.Context, st state.Tree, bs blockstore.Blockstore) (*version.ProtocolVersionTable, error) {
+	initActor, err := st.GetActor(ctx, address.InitAddress)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get init actor")
+	}
+	raw, err := vm.NewStorage(bs, initActor).Get(initActor.Head)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load init actor state")
+	}
+	var initState initactor.State
+	if err := encoding.Decode(raw, &initState); err != nil {
+		return nil, errors.Wrap(err, "failed to decode init actor state")
+	}
+	if len(initState.ProtocolVersions) == 0 {
+		return nil, ErrNoProtocolSchedule
+	}
+	return version.NewProtocolVersionTableFromVersions(initState.ProtocolVersions)
+}
+
+func updateInitState(ctx context.Context, st state.Tree, storageMap vm.StorageMap, update func(*initactor.State)) error {
+	initActor, err := st.GetActor(ctx, address.InitAddress)
+	if err != nil {
+		return err
+	}
+	storage := storageMap.NewStorage(address.InitAddress, initActor)
+	raw, err := storage.Get(initActor.Head)
+	if err != nil {
+		return err
+	}
+	var initState initactor.State
+	if err := encoding.Decode(raw, &initState); err != nil {
+		return err
+	}
+	update(&initState)
+
+	stateBytes, err := encoding.Encode(initState)
+	if err != nil {
+		return err
+	}
+	id, err := storage.Put(stateBytes)
+	if err != nil {
+		return err
+	}
+	if err := storage.Commit(id, initActor.Head); err != nil {
+		return err
+	}
+	return st.SetActor(ctx, address.InitAddress, initActor)
+}
+
+func setupMiningNode(ctx context.Context, st state.Tree, storageMap vm.StorageMap, proofsMode types.ProofsMode, addr address.Address, node *miningNodeConfig) error {
+	h, err := mh.Sum(node.owner.Bytes(), mh.SHA2_256, -1)
+	if err != nil {
+		return err
+	}
+	sectorSize := types.OneKiBSectorSize
+	if proofsMode == types.LiveProofsMode {
+		sectorSize = types.TwoHundredFiftySixMiBSectorSize
+	}
+	minerState := miner.NewState(node.owner, node.worker, peer.ID(h), sectorSize)
+	minerState.Power = node.power
+
+	minerActor := miner.NewActor()
+	minerStorage := storageMap.NewStorage(addr, minerActor)
+	scid, err := minerStorage.Put(minerState)
+	if err != nil {
+		return err
+	}
+	if err := minerStorage.Commit(scid, minerActor.Head); err != nil {
+		return err
+	}
+	if err := st.SetActor(ctx, addr, minerActor); err != nil {
+		return err
+	}
+
+	// Register the miner and its power with the storage market so that it
+	// appears in the power table.
+	smActor, err := st.GetActor(ctx, address.StorageMarketAddress)
+	if err != nil {
+		return err
+	}
+	smStorage := storageMap.NewStorage(address.StorageMarketAddress, smActor)
+	raw, err := smStorage.Get(smActor.Head)
+	if err != nil {
+		return err
+	}
+	var smState storagemarket.State
+	if err := encoding.Decode(raw, &smState); err != nil {
+		return err
+	}
+	smState.Miners, err = actor.SetKeyValue(ctx, smStorage, smState.Miners, addr.String(), true)
+	if err != nil {
+		return err
+	}
+	smState.TotalCommittedStorage = smState.TotalCommittedStorage.Add(node.power)
+
+	stateBytes, err := encoding.Encode(smState)
+	if err != nil {
+		return err
+	}
+	id, err := smStorage.Put(stateBytes)
+	if err != nil {
+		return err
+	}
+	if err := smStorage.Commit(id, smActor.Head); err != nil {
+		return err
+	}
+	return st.SetActor(ctx, address.StorageMarketAddress, smActor)
+}
+
+// ExportGenesis runs `gen` against a fresh store and writes the genesis block
+// together with all of its state to `w` as a CAR file rooted at the genesis
+// block. Blocks are written in CID order so that the same genesis always
+// produces the same bytes. The genesis block CID is returned.
+func ExportGenesis(ctx context.Context, gen GenesisInitFunc, w io.Writer) (cid.Cid, error) {
+	bs := blockstore.NewBlockstore(syncds.MutexWrap(ds.NewMapDatastore()))
+	genesis, err := gen(hamt.CSTFromBstore(bs), bs)
+	if err != nil {
+		return cid.Undef, errors.Wrap(err, "failed to generate genesis")
+	}
+
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return cid.Undef, err
+	}
+	var cids []cid.Cid
+	for c := range keys {
+		cids = append(cids, c)
+	}
+	sort.Slice(cids, func(i, j int) bool {
+		return cids[i].KeyString() < cids[j].KeyString()
+	})
+
+	header, err := encoding.Encode(car.CarHeader{
+		Roots:   []cid.Cid{genesis.Cid()},
+		Version: 1,
+	})
+	if err != nil {
+		return cid.Undef, err
+	}
+	if err := carutil.LdWrite(w, header); err != nil {
+		return cid.Undef, err
+	}
+	for _, c := range cids {
+		blk, err := bs.Get(c)
+		if err != nil {
+			return cid.Undef, err
+		}
+		if err := carutil.LdWrite(w, c.Bytes(), blk.RawData()); err != nil {
+			return cid.Undef, err
+		}
+	}
+	return genesis.Cid(), nil
+}
+
+// ImportGenesis loads a genesis CAR file written by ExportGenesis from `r`
+// into `bs` and returns the genesis tipset.
+func ImportGenesis(ctx context.Context, bs blockstore.Blockstore, r io.Reader) (block.TipSet, error) {
+	header, err := car.LoadCar(bs, r)
+	if err != nil {
+		return block.UndefTipSet, errors.Wrap(err, "failed to load genesis car")
+	}
+	if len(header.Roots) != 1 {
+		return block.UndefTipSet, errors.Errorf("genesis car must have exactly one root, found %d", len(header.Roots))
+	}
+
+	var genesis block.Block
+	if err := hamt.CSTFromBstore(bs).Get(ctx, header.Roots[0], &genesis); err != nil {
+		return block.UndefTipSet, errors.Wrap(err, "failed to load genesis block")
+	}
+	if genesis.Height != 0 {
+		return block.UndefTipSet, errors.Errorf("car root %s is not a genesis block", header.Roots[0])
+	}
+	return block.NewTipSet(&genesis)
+}
+
 // SetupDefaultActors inits the builtin actors that are required to run filecoin.
 func SetupDefaultActors(ctx context.Context, st state.Tree, storageMap vm.StorageMap, storeType types.ProofsMode, network string) error {
 	for addr, val := range defaultAccounts {
@@ -0,0 +1,190 @@
+package consensus_test
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-hamt-ipld"
+	"github.com/ipfs/go-ipfs-blockstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/abi"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
+)
+
+func TestExportImportGenesis(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	addr := address.NewForTestGetter()()
+	gen := consensus.MakeGenesisFunc(
+		consensus.ActorAccount(addr, types.NewAttoFILFromFIL(1234)),
+		consensus.Network("exporttest"),
+	)
+
+	var out bytes.Buffer
+	genCid, err := consensus.ExportGenesis(ctx, gen, &out)
+	require.NoError(t, err)
+
+	// Exporting the same genesis again produces identical bytes.
+	var again bytes.Buffer
+	againCid, err := consensus.ExportGenesis(ctx, gen, &again)
+	require.NoError(t, err)
+	assert.Equal(t, genCid, againCid)
+	assert.Equal(t, out.Bytes(), again.Bytes())
+
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	genesis, err := consensus.ImportGenesis(ctx, bs, &out)
+	require.NoError(t, err)
+	require.Equal(t, 1, genesis.Len())
+	assert.Equal(t, genCid, genesis.At(0).Cid())
+
+	// The imported state is complete.
+	st, err := state.LoadStateTree(ctx, hamt.CSTFromBstore(bs), genesis.At(0).StateRoot)
+	require.NoError(t, err)
+	act, err := st.GetActor(ctx, addr)
+	require.NoError(t, err)
+	assert.Equal(t, types.NewAttoFILFromFIL(1234), act.Balance)
+}
+
+func TestProtocolScheduleGenesis(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	pvt, err := version.NewProtocolVersionTableBuilder(version.TEST).
+		Add(version.TEST, version.Protocol0, types.NewBlockHeight(0)).
+		Add(version.TEST, version.Protocol1, types.NewBlockHeight(300)).
+		Build()
+	require.NoError(t, err)
+
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	cst := hamt.CSTFromBstore(bs)
+	genesis, err := consensus.MakeGenesisFunc(consensus.ProtocolSchedule(pvt))(cst, bs)
+	require.NoError(t, err)
+	st, err := state.LoadStateTree(ctx, cst, genesis.StateRoot)
+	require.NoError(t, err)
+
+	loaded, err := consensus.LoadProtocolSchedule(ctx, st, bs)
+	require.NoError(t, err)
+	assert.Equal(t, pvt.Versions(), loaded.Versions())
+
+	ts := time.Unix(1234567890, 0)
+	blockTime := consensus.DefaultBlockTime
+	validator := consensus.NewDefaultBlockValidator(blockTime, th.NewFakeClock(ts), loaded, nil)
+
+	// An invalid parent weight is accepted before the embedded upgrade height...
+	p := &block.Block{Height: 1, Timestamp: types.Uint64(ts.Unix())}
+	parents := consensus.RequireNewTipSet(require.New(t), p)
+	c := &block.Block{Parents: parents.Key(), Height: 2, ParentWeight: 5000, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
+	assert.NoError(t, validator.ValidateSemantic(ctx, c, &parents, 30))
+
+	// ...and rejected after it.
+	p = &block.Block{Height: 350, Timestamp: types.Uint64(ts.Unix())}
+	parents = consensus.RequireNewTipSet(require.New(t), p)
+	c = &block.Block{Parents: parents.Key(), Height: 351, ParentWeight: 5000, Timestamp: types.Uint64(ts.Add(blockTime).Unix())}
+	err = validator.ValidateSemantic(ctx, c, &parents, 30)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid parent weight")
+}
+
+func TestLoadProtocolScheduleMissing(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	cst := hamt.CSTFromBstore(bs)
+	genesis, err := consensus.MakeGenesisFunc()(cst, bs)
+	require.NoError(t, err)
+	st, err := state.LoadStateTree(ctx, cst, genesis.StateRoot)
+	require.NoError(t, err)
+
+	_, err = consensus.LoadProtocolSchedule(ctx, st, bs)
+	assert.Equal(t, consensus.ErrNoProtocolSchedule, err)
+}
+
+func TestGenesisTime(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	genesisTime := time.Unix(1234567890, 0)
+	cst, bs := setupCborBlockstore()
+	genesis, err := consensus.MakeGenesisFunc(consensus.GenesisTime(genesisTime))(cst, bs)
+	require.NoError(t, err)
+	assert.Equal(t, types.Uint64(genesisTime.Unix()), genesis.Timestamp)
+
+	// The genesis time is recorded in state.
+	st, err := state.LoadStateTree(ctx, cst, genesis.StateRoot)
+	require.NoError(t, err)
+	snapshot := consensus.NewActorStateStore(nil, cst, bs, consensus.NewDefaultProcessor()).StateTreeSnapshot(st, nil)
+	rets, err := snapshot.Query(ctx, address.Undef, address.InitAddress, "getGenesisTime")
+	require.NoError(t, err)
+	val, err := abi.Deserialize(rets[0], abi.Integer)
+	require.NoError(t, err)
+	recorded := time.Unix(val.Val.(*big.Int).Int64(), 0)
+	assert.Equal(t, genesisTime, recorded)
+
+	blockTime := 30 * time.Second
+	validator := consensus.NewDefaultBlockValidator(blockTime, th.NewFakeClock(genesisTime), nil, nil)
+	assert.Equal(t, genesisTime.Add(300*time.Second), validator.ExpectedTimestamp(recorded, 10))
+}
+
+func TestMiningNodeGenesis(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	signer, kis := types.NewMockSignersAndKeyInfo(1)
+	worker, err := kis[0].Address()
+	require.NoError(t, err)
+	owner := address.NewForTestGetter()()
+	minerAddr, err := consensus.MiningNodeAddress(owner)
+	require.NoError(t, err)
+	power := types.NewBytesAmount(1024)
+
+	cst, bs := setupCborBlockstore()
+	genesis, err := consensus.MakeGenesisFunc(
+		consensus.MiningNode(owner, worker, types.NewAttoFILFromFIL(1000), power),
+	)(cst, bs)
+	require.NoError(t, err)
+
+	st, err := state.LoadStateTree(ctx, cst, genesis.StateRoot)
+	require.NoError(t, err)
+	ownerActor, err := st.GetActor(ctx, owner)
+	require.NoError(t, err)
+	assert.Equal(t, types.NewAttoFILFromFIL(1000), ownerActor.Balance)
+	_, err = st.GetActor(ctx, worker)
+	require.NoError(t, err)
+
+	actorState := consensus.NewActorStateStore(nil, cst, bs, consensus.NewDefaultProcessor())
+	ptv := consensus.NewPowerTableView(actorState.StateTreeSnapshot(st, nil))
+	minerPower, err := ptv.Miner(ctx, minerAddr)
+	require.NoError(t, err)
+	assert.Equal(t, power, minerPower)
+	total, err := ptv.Total(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, power, total)
+	workerAddr, err := ptv.WorkerAddr(ctx, minerAddr)
+	require.NoError(t, err)
+	assert.Equal(t, worker, workerAddr)
+
+	// The node can mine a block on top of genesis that passes validation.
+	genTS := th.RequireNewTipSet(t, genesis)
+	blk, err := th.NewValidTestBlockFromTipSet(genTS, genesis.StateRoot, 1, minerAddr, worker, signer)
+	require.NoError(t, err)
+
+	exp := consensus.NewExpected(cst, bs, th.NewFakeProcessor(), th.NewFakeBlockValidator(), actorState, genesis.Cid(), th.BlockTimeTest, &consensus.FakeElectionMachine{}, &consensus.FakeTicketMachine{})
+	blsMsgs, secpMsgs, receipts := emptyMessagesAndReceipts(1)
+	_, err = exp.RunStateTransition(ctx, th.RequireNewTipSet(t, blk), blsMsgs, secpMsgs, receipts, []block.TipSet{genTS}, 0, genesis.StateRoot)
+	assert.NoError(t, err)
+}
@@ -37,6 +37,9 @@ type BlockRewarder interface {
 
 	// GasReward pays gas from the sender to the miner
 	GasReward(ctx context.Context, st state.Tree, minerOwnerAddr address.Address, msg *types.SignedMessage, cost types.AttoFIL) error
+
+	// RewardAt returns the block reward paid for a block at the given height
+	RewardAt(height uint64) types.AttoFIL
 }
 
 // ApplicationResult contains the result of successfully applying one message.
@@ -50,11 +53,13 @@ type ApplicationResult struct {
 // ProcessTipSetResponse records the results of successfully applied messages,
 // and the sets of successful and failed message cids.  Information of successes
 // and failures is key for helping match user messages with receipts in the case
-// of message conflicts.
+// of message conflicts. FailureReasons holds the apply error of each failed
+// message; its errors.Cause() is the validation error, e.g. ErrNonceTooLow.
 type ProcessTipSetResponse struct {
-	Results   []*ApplicationResult
-	Successes map[cid.Cid]struct{}
-	Failures  map[cid.Cid]struct{}
+	Results        []*ApplicationResult
+	Successes      map[cid.Cid]struct{}
+	Failures       map[cid.Cid]struct{}
+	FailureReasons map[cid.Cid]error
 }
 
 // DefaultProcessor handles all block processing.
@@ -84,6 +89,11 @@ func NewConfiguredProcessor(validator SignedMessageValidator, rewarder BlockRewa
 	}
 }
 
+// BlockRewarder returns the rewarder the processor pays block rewards with.
+func (p *DefaultProcessor) BlockRewarder() BlockRewarder {
+	return p.blockRewarder
+}
+
 // ProcessBlock is the entrypoint for validating the state transitions
 // of the messages in a block. When we receive a new block from the
 // network ProcessBlock applies the block's messages to the beginning
@@ -164,6 +174,7 @@ func (p *DefaultProcessor) ProcessTipSet(ctx context.Context, st state.Tree, vms
 
 	var res ProcessTipSetResponse
 	res.Failures = make(map[cid.Cid]struct{})
+	res.FailureReasons = make(map[cid.Cid]error)
 	res.Successes = make(map[cid.Cid]struct{})
 
 	// TODO: this can be made slightly more efficient by reusing the validation
@@ -204,25 +215,44 @@ func (p *DefaultProcessor) ProcessTipSet(ctx context.Context, st state.Tree, vms
 			}
 			res.Successes[mCid] = struct{}{}
 		}
-		for _, msg := range amRes.PermanentFailures {
+		for j, msg := range amRes.PermanentFailures {
 			mCid, err := msg.Cid()
 			if err != nil {
 				return &ProcessTipSetResponse{}, errors.FaultErrorWrap(err, "error getting message cid")
 			}
 			res.Failures[mCid] = struct{}{}
+			res.FailureReasons[mCid] = amRes.PermanentErrors[j]
 		}
-		for _, msg := range amRes.TemporaryFailures {
+		for j, msg := range amRes.TemporaryFailures {
 			mCid, err := msg.Cid()
 			if err != nil {
 				return &ProcessTipSetResponse{}, errors.FaultErrorWrap(err, "error getting message cid")
 			}
 			res.Failures[mCid] = struct{}{}
+			res.FailureReasons[mCid] = amRes.TemporaryErrors[j]
 		}
 	}
 
 	return &res, nil
 }
 
+// ProcessTipSetDryRun computes the results ProcessTipSet would for the given
+// tipset, without changing `st` or `vms`: the messages are applied to copies
+// of them. Each result's receipt records the gas charged for its message, and
+// the failures record why messages could not be applied. This allows previewing
+// a tipset before building or accepting it.
+func (p *DefaultProcessor) ProcessTipSetDryRun(ctx context.Context, st state.Tree, vms vm.StorageMap, ts block.TipSet, tsMessages [][]*types.SignedMessage, ancestors []block.TipSet) (*ProcessTipSetResponse, error) {
+	stCopy, err := state.Copy(ctx, st)
+	if err != nil {
+		return &ProcessTipSetResponse{}, errors.FaultErrorWrap(err, "could not copy state tree")
+	}
+	vmsCopy, err := vm.CopyStorageMap(vms)
+	if err != nil {
+		return &ProcessTipSetResponse{}, errors.FaultErrorWrap(err, "could not copy storage")
+	}
+	return p.ProcessTipSet(ctx, stCopy, vmsCopy, ts, tsMessages, ancestors)
+}
+
 // ApplyMessage attempts to apply a message to a state tree. It is the
 // sole driver of state tree transitions in the system. Both block
 // validation and mining use this function and we should treat any changes
@@ -362,17 +392,7 @@ var (
 	// These errors are only to be used by ApplyMessage; they shouldn't be
 	// used in any other context as they are an implementation detail.
 	errFromAccountNotFound       = errors.NewRevertError("from (sender) account not found")
-	errGasAboveBlockLimit        = errors.NewRevertError("message gas limit above block gas limit")
-	errGasPriceZero              = errors.NewRevertError("message gas price is zero")
 	errGasTooHighForCurrentBlock = errors.NewRevertError("message gas limit too high for current block")
-	errNonceTooHigh              = errors.NewRevertError("nonce too high")
-	errNonceTooLow               = errors.NewRevertError("nonce too low")
-	errNonAccountActor           = errors.NewRevertError("message from non-account actor")
-	errNegativeValue             = errors.NewRevertError("negative value")
-	errInsufficientGas           = errors.NewRevertError("balance insufficient to cover transfer+gas")
-	errInvalidSignature          = errors.NewRevertError("invalid signature by sender over message data")
-	// TODO we'll eventually handle sending to self.
-	errSelfSend = errors.NewRevertError("cannot send to self")
 )
 
 // CallQueryMethod calls a method on an actor in the given state tree. It does
@@ -616,6 +636,12 @@ func (br *DefaultBlockRewarder) GasReward(ctx context.Context, st state.Tree, mi
 	return cachedTree.Commit(ctx)
 }
 
+// RewardAt returns the block reward paid for a block at the given height.
+// The reward does not yet vary with height.
+func (br *DefaultBlockRewarder) RewardAt(height uint64) types.AttoFIL {
+	return br.BlockRewardAmount()
+}
+
 // BlockRewardAmount returns the max FIL value miners can claim as the block reward.
 // TODO this is one of the system parameters that should be configured as part of
 // https://github.com/filecoin-project/go-filecoin/issues/884.
@@ -642,7 +668,7 @@ func rewardTransfer(ctx context.Context, fromAddr, toAddr address.Address, value
 
 func blockGasLimitError(gasTracker *vm.GasTracker) error {
 	if gasTracker.GasAboveBlockLimit() {
-		return errGasAboveBlockLimit
+		return ErrGasAboveBlockLimit
 	} else if gasTracker.GasTooHighForCurrentBlock() {
 		return errGasTooHighForCurrentBlock
 	}
@@ -651,19 +677,19 @@ func blockGasLimitError(gasTracker *vm.GasTracker) error {
 
 func isTemporaryError(err error) bool {
 	return err == errFromAccountNotFound ||
-		err == errNonceTooHigh ||
+		err == ErrNonceTooHigh ||
 		err == errGasTooHighForCurrentBlock
 }
 
 func isPermanentError(err error) bool {
-	return err == errInsufficientGas ||
-		err == errSelfSend ||
-		err == errInvalidSignature ||
-		err == errNonceTooLow ||
-		err == errNonAccountActor ||
-		err == errNegativeValue ||
+	return err == ErrInsufficientBalance ||
+		err == ErrSelfSend ||
+		err == ErrInvalidSignature ||
+		err == ErrNonceTooLow ||
+		err == ErrNonAccountActor ||
+		err == ErrNegativeValue ||
 		err == errors.Errors[errors.ErrCannotTransferNegativeValue] ||
-		err == errGasAboveBlockLimit
+		err == ErrGasAboveBlockLimit
 }
 
 // minerOwnerAddress finds the address of the owner of the given miner
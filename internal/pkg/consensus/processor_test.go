@@ -9,6 +9,7 @@ import (
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-hamt-ipld"
 	"github.com/ipfs/go-ipfs-blockstore"
+	pkgerrors "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -225,6 +226,13 @@ func TestProcessTipsConflicts(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, res.Results, 1)
 
+	// The conflicting message is reported as failed with its reason.
+	c2, err := smsg2.Cid()
+	require.NoError(t, err)
+	assert.Contains(t, res.Failures, c2)
+	require.Contains(t, res.FailureReasons, c2)
+	assert.Equal(t, ErrNonceTooLow, pkgerrors.Cause(res.FailureReasons[c2]))
+
 	gotStCid, err := st.Flush(ctx)
 	assert.NoError(t, err)
 
@@ -243,6 +251,63 @@ func TestProcessTipsConflicts(t *testing.T) {
 	assert.True(t, expStCid.Equals(gotStCid))
 }
 
+func TestProcessTipSetDryRun(t *testing.T) {
+	tf.UnitTest(t)
+
+	newAddress := address.NewForTestGetter()
+	minerAddr := newAddress()
+
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+	vms := th.VMStorage()
+	mockSigner, _ := types.NewMockSignersAndKeyInfo(2)
+
+	fromAddr, toAddr := mockSigner.Addresses[0], mockSigner.Addresses[1]
+	_, st := th.RequireMakeStateTree(t, cst, map[address.Address]*actor.Actor{
+		address.NetworkAddress: th.RequireNewAccountActor(t, types.NewAttoFILFromFIL(1000000)),
+		fromAddr:               th.RequireNewAccountActor(t, types.NewAttoFILFromFIL(1000)),
+	})
+
+	minerOwner, err := address.NewActorAddress([]byte("mo"))
+	require.NoError(t, err)
+	stCid, _ := mustCreateStorageMiner(ctx, t, st, vms, minerAddr, minerOwner)
+
+	newMsg := func(nonce uint64, value uint64) *types.SignedMessage {
+		msg := types.NewMeteredMessage(fromAddr, toAddr, nonce, types.NewAttoFILFromFIL(value), "", nil, types.NewGasPrice(1), types.NewGasUnits(0))
+		smsg, err := types.NewSignedMessage(*msg, &mockSigner)
+		require.NoError(t, err)
+		return smsg
+	}
+	blk1 := &block.Block{Height: 20, StateRoot: stCid, Ticket: block.Ticket{VRFProof: []byte{0, 0}}, Miner: minerAddr}
+	blk2 := &block.Block{Height: 20, StateRoot: stCid, Ticket: block.Ticket{VRFProof: []byte{1, 1}}, Miner: minerAddr}
+	ts := th.RequireNewTipSet(t, blk1, blk2)
+	// The second block's first message conflicts with the first block's.
+	tsMsgs := [][]*types.SignedMessage{{newMsg(0, 100)}, {newMsg(0, 200), newMsg(1, 300)}}
+
+	processor := NewDefaultProcessor()
+	dryRes, err := processor.ProcessTipSetDryRun(ctx, st, vms, ts, tsMsgs, nil)
+	require.NoError(t, err)
+
+	afterDryRun, err := st.Flush(ctx)
+	require.NoError(t, err)
+	assert.True(t, stCid.Equals(afterDryRun), "dry run changed the state tree")
+
+	res, err := processor.ProcessTipSet(ctx, st, vms, ts, tsMsgs, nil)
+	require.NoError(t, err)
+	afterRun, err := st.Flush(ctx)
+	require.NoError(t, err)
+	assert.False(t, stCid.Equals(afterRun))
+
+	require.Len(t, res.Results, 2)
+	require.Len(t, dryRes.Results, len(res.Results))
+	for i := range res.Results {
+		assert.Equal(t, res.Results[i].Receipt, dryRes.Results[i].Receipt)
+	}
+	assert.Equal(t, res.Successes, dryRes.Successes)
+	assert.Equal(t, res.Failures, dryRes.Failures)
+	assert.Len(t, dryRes.Failures, 1)
+}
+
 // ProcessBlock should not fail with an unsigned block reward message.
 func TestProcessBlockReward(t *testing.T) {
 	tf.UnitTest(t)
@@ -283,6 +348,18 @@ func TestProcessBlockReward(t *testing.T) {
 	assert.Equal(t, minerBalance.Add(blockRewardAmount), minerOwnerActor.Balance)
 }
 
+func TestBlockRewarderRewardAt(t *testing.T) {
+	tf.UnitTest(t)
+
+	rewarder := NewDefaultBlockRewarder()
+	for _, height := range []uint64{0, 1, 100, 1000000} {
+		assert.Equal(t, types.NewAttoFILFromFIL(1000), rewarder.RewardAt(height), "height %d", height)
+	}
+
+	processor := NewConfiguredProcessor(NewDefaultMessageValidator(), rewarder, builtin.DefaultActors)
+	assert.Equal(t, BlockRewarder(rewarder), processor.BlockRewarder())
+}
+
 func TestProcessBlockVMErrors(t *testing.T) {
 	tf.BadUnitTestWithSideEffects(t)
 
@@ -429,7 +506,7 @@ func TestApplyMessagesValidation(t *testing.T) {
 
 		_, err = NewDefaultProcessor().ApplyMessage(ctx, st, th.VMStorage(), smsg, addr2, types.NewBlockHeight(0), vm.NewGasTracker(), nil)
 		assert.Error(t, err)
-		assert.Equal(t, "nonce too high", err.(*errors.ApplyErrorTemporary).Cause().Error())
+		assert.Equal(t, ErrNonceTooHigh, err.(*errors.ApplyErrorTemporary).Cause())
 	})
 
 	t.Run("Errors when nonce too low", func(t *testing.T) {
@@ -454,7 +531,7 @@ func TestApplyMessagesValidation(t *testing.T) {
 
 		_, err = NewDefaultProcessor().ApplyMessage(ctx, st, th.VMStorage(), smsg, addr2, types.NewBlockHeight(0), vm.NewGasTracker(), nil)
 		assert.Error(t, err)
-		assert.Equal(t, "nonce too low", err.(*errors.ApplyErrorPermanent).Cause().Error())
+		assert.Equal(t, ErrNonceTooLow, err.(*errors.ApplyErrorPermanent).Cause())
 	})
 
 	t.Run("errors when specifying a gas limit in excess of balance", func(t *testing.T) {
@@ -466,7 +543,7 @@ func TestApplyMessagesValidation(t *testing.T) {
 		// the maximum gas charge (10*50 = 500) is greater than the sender balance minus the message value (1000-550 = 450)
 		_, err = NewDefaultProcessor().ApplyMessage(context.Background(), st, th.VMStorage(), smsg, addr2, types.NewBlockHeight(0), vm.NewGasTracker(), nil)
 		require.Error(t, err)
-		assert.Equal(t, "balance insufficient to cover transfer+gas", err.(*errors.ApplyErrorPermanent).Cause().Error())
+		assert.Equal(t, ErrInsufficientBalance, err.(*errors.ApplyErrorPermanent).Cause())
 	})
 
 	t.Run("errors when sender is not an account actor", func(t *testing.T) {
@@ -484,7 +561,7 @@ func TestApplyMessagesValidation(t *testing.T) {
 
 		_, err = NewDefaultProcessor().ApplyMessage(context.Background(), st, th.VMStorage(), smsg, addr2, types.NewBlockHeight(0), vm.NewGasTracker(), nil)
 		require.Error(t, err)
-		assert.Equal(t, "message from non-account actor", err.(*errors.ApplyErrorPermanent).Cause().Error())
+		assert.Equal(t, ErrNonAccountActor, err.(*errors.ApplyErrorPermanent).Cause())
 	})
 
 	t.Run("errors when sender is not an actor", func(t *testing.T) {
@@ -544,7 +621,7 @@ func TestApplyMessagesValidation(t *testing.T) {
 		// the maximum gas charge (10*50 = 500) is greater than the sender balance minus the message value (1000-550 = 450)
 		_, err = NewDefaultProcessor().ApplyMessage(context.Background(), st, th.VMStorage(), smsg, addr2, types.NewBlockHeight(0), vm.NewGasTracker(), nil)
 		require.Error(t, err)
-		assert.Equal(t, "cannot send to self", err.(*errors.ApplyErrorPermanent).Cause().Error())
+		assert.Equal(t, ErrSelfSend, err.(*errors.ApplyErrorPermanent).Cause())
 	})
 
 	t.Run("errors when specifying a gas limit in excess of balance", func(t *testing.T) {
@@ -556,7 +633,7 @@ func TestApplyMessagesValidation(t *testing.T) {
 		// the maximum gas charge (10*50 = 500) is greater than the sender balance minus the message value (1000-550 = 450)
 		_, err = NewDefaultProcessor().ApplyMessage(context.Background(), st, th.VMStorage(), smsg, address.Undef, types.NewBlockHeight(0), vm.NewGasTracker(), nil)
 		require.Error(t, err)
-		assert.Equal(t, "balance insufficient to cover transfer+gas", err.(*errors.ApplyErrorPermanent).Cause().Error())
+		assert.Equal(t, ErrInsufficientBalance, err.(*errors.ApplyErrorPermanent).Cause())
 	})
 }
 
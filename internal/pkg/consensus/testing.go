@@ -113,6 +113,11 @@ func (tbr *FakeBlockRewarder) GasReward(ctx context.Context, st state.Tree, mine
 	return nil
 }
 
+// RewardAt returns zero, matching the rewards this rewarder pays
+func (tbr *FakeBlockRewarder) RewardAt(height uint64) types.AttoFIL {
+	return types.ZeroAttoFIL
+}
+
 // NewFakeProcessor creates a processor with a test validator and test rewarder
 func NewFakeProcessor(actors builtin.Actors) *DefaultProcessor {
 	return &DefaultProcessor{
@@ -28,6 +28,23 @@ func init() {
 	errNonceTooHighCt = metrics.NewInt64Counter("consensus/msg_nonce_high_err", "Number of messages with nonce too high")
 }
 
+// Errors returned by message validation. ApplyMessage wraps them as temporary
+// or permanent apply errors; their errors.Cause() is one of these.
+var (
+	ErrGasAboveBlockLimit  = errors.NewRevertError("message gas limit above block gas limit")
+	ErrGasPriceTooLow      = errors.NewRevertError("message gas price is zero")
+	ErrGasPriceBelowMin    = errors.NewRevertError("message gas price below minimum")
+	ErrMessageTooLarge     = errors.NewRevertError("message too large")
+	ErrNonceTooHigh        = errors.NewRevertError("nonce too high")
+	ErrNonceTooLow         = errors.NewRevertError("nonce too low")
+	ErrNonAccountActor     = errors.NewRevertError("message from non-account actor")
+	ErrNegativeValue       = errors.NewRevertError("negative value")
+	ErrInsufficientBalance = errors.NewRevertError("balance insufficient to cover transfer+gas")
+	ErrInvalidSignature    = errors.NewRevertError("invalid signature by sender over message data")
+	// TODO we'll eventually handle sending to self.
+	ErrSelfSend = errors.NewRevertError("cannot send to self")
+)
+
 // SignedMessageValidator validates incoming signed messages.
 type SignedMessageValidator interface {
 	// Validate checks that a message is semantically valid for processing, returning any
@@ -36,7 +53,8 @@ type SignedMessageValidator interface {
 }
 
 type defaultMessageValidator struct {
-	allowHighNonce bool
+	allowHighNonce   bool
+	skipBalanceCheck bool
 }
 
 // NewDefaultMessageValidator creates a new default validator.
@@ -58,48 +76,48 @@ var _ SignedMessageValidator = (*defaultMessageValidator)(nil)
 func (v *defaultMessageValidator) Validate(ctx context.Context, smsg *types.SignedMessage, fromActor *actor.Actor) error {
 	msg := smsg.Message
 	if msg.From == msg.To {
-		return errSelfSend
+		return ErrSelfSend
 	}
 
 	if msg.GasPrice.LessEqual(types.ZeroAttoFIL) {
-		return errGasPriceZero
+		return ErrGasPriceTooLow
 	}
 
 	// Sender must be an account actor, or an empty actor which will be upgraded to an account actor
 	// when the message is processed.
 	if !(fromActor.Empty() || account.IsAccount(fromActor)) {
-		return errNonAccountActor
+		return ErrNonAccountActor
 	}
 
 	if msg.Value.IsNegative() {
 		log.Debugf("Cannot transfer negative value: %s from actor: %s", msg.Value.String(), msg.From.String())
 		errNegativeValueCt.Inc(ctx, 1)
-		return errNegativeValue
+		return ErrNegativeValue
 	}
 
 	if msg.GasLimit > types.BlockGasLimit {
 		log.Debugf("Message: %s gas limit from actor: %s above block limit: %s", msg.String(), msg.From.String(), string(types.BlockGasLimit))
 		errGasAboveBlockLimitCt.Inc(ctx, 1)
-		return errGasAboveBlockLimit
+		return ErrGasAboveBlockLimit
 	}
 
 	// Avoid processing messages for actors that cannot pay.
-	if !canCoverGasLimit(smsg, fromActor) {
+	if !v.skipBalanceCheck && !canCoverGasLimit(smsg, fromActor) {
 		log.Debugf("Insufficient funds for message: %s to cover gas limit from actor: %s", msg.String(), msg.From.String())
 		errInsufficientGasCt.Inc(ctx, 1)
-		return errInsufficientGas
+		return ErrInsufficientBalance
 	}
 
 	if msg.CallSeqNum < fromActor.Nonce {
 		log.Debugf("Message: %s nonce lower than actor nonce: %s from actor: %s", msg.String(), fromActor.Nonce, msg.From.String())
 		errNonceTooLowCt.Inc(ctx, 1)
-		return errNonceTooLow
+		return ErrNonceTooLow
 	}
 
 	if !v.allowHighNonce && msg.CallSeqNum > fromActor.Nonce {
 		log.Debugf("Message: %s nonce greater than actor nonce: %s from actor: %s", msg.String(), fromActor.Nonce, msg.From.String())
 		errNonceTooHighCt.Inc(ctx, 1)
-		return errNonceTooHigh
+		return ErrNonceTooHigh
 	}
 
 	return nil
@@ -118,19 +136,54 @@ type ingestionValidatorAPI interface {
 	GetActor(context.Context, address.Address) (*actor.Actor, error)
 }
 
+// IngestionRules are the thresholds the IngestionValidator applies to
+// messages in addition to the default message validation.
+type IngestionRules struct {
+	// MaxNonceGap is the greatest amount by which a message's nonce may
+	// exceed its sender's nonce.
+	MaxNonceGap types.Uint64
+	// MinGasPrice is the lowest gas price admitted. Messages must in any
+	// case have a positive gas price.
+	MinGasPrice types.AttoFIL
+	// MaxMessageSize is the greatest size in bytes of a serialized message
+	// admitted, or zero for no limit.
+	MaxMessageSize uint
+	// SkipBalanceCheck admits messages whose sender cannot cover the value
+	// and maximum gas charge, e.g. in tests with unfunded senders.
+	SkipBalanceCheck bool
+}
+
+// IngestionRulesFromConfig returns the ingestion rules set by the message
+// pool config.
+func IngestionRulesFromConfig(cfg *config.MessagePoolConfig) IngestionRules {
+	return IngestionRules{
+		MaxNonceGap:      cfg.MaxNonceGap,
+		MinGasPrice:      cfg.MinGasPrice,
+		MaxMessageSize:   cfg.MaxMessageSize,
+		SkipBalanceCheck: cfg.DisableBalanceCheck,
+	}
+}
+
 // IngestionValidator can access latest state and runs additional checks to mitigate DoS attacks
 type IngestionValidator struct {
 	api       ingestionValidatorAPI
-	cfg       *config.MessagePoolConfig
+	rules     IngestionRules
 	validator defaultMessageValidator
 }
 
-// NewIngestionValidator creates a new validator with an api
+// NewIngestionValidator creates a new validator with an api and the rules
+// set by the message pool config
 func NewIngestionValidator(api ingestionValidatorAPI, cfg *config.MessagePoolConfig) *IngestionValidator {
+	return NewIngestionValidatorWithRules(api, IngestionRulesFromConfig(cfg))
+}
+
+// NewIngestionValidatorWithRules creates a new validator with an api applying
+// `rules`
+func NewIngestionValidatorWithRules(api ingestionValidatorAPI, rules IngestionRules) *IngestionValidator {
 	return &IngestionValidator{
 		api:       api,
-		cfg:       cfg,
-		validator: defaultMessageValidator{allowHighNonce: true},
+		rules:     rules,
+		validator: defaultMessageValidator{allowHighNonce: true, skipBalanceCheck: rules.SkipBalanceCheck},
 	}
 }
 
@@ -139,7 +192,23 @@ func NewIngestionValidator(api ingestionValidatorAPI, cfg *config.MessagePoolCon
 func (v *IngestionValidator) Validate(ctx context.Context, msg *types.SignedMessage) error {
 	// ensure message is properly signed
 	if !msg.VerifySignature() {
-		return errInvalidSignature
+		return ErrInvalidSignature
+	}
+
+	// check that the message is not too large
+	if v.rules.MaxMessageSize > 0 {
+		raw, err := msg.Marshal()
+		if err != nil {
+			return err
+		}
+		if uint(len(raw)) > v.rules.MaxMessageSize {
+			return ErrMessageTooLarge
+		}
+	}
+
+	// check that the gas price is high enough
+	if msg.Message.GasPrice.LessThan(v.rules.MinGasPrice) {
+		return ErrGasPriceBelowMin
 	}
 
 	// retrieve from actor
@@ -153,7 +222,7 @@ func (v *IngestionValidator) Validate(ctx context.Context, msg *types.SignedMess
 	}
 
 	// check that message nonce is not too high
-	if msg.Message.CallSeqNum > fromActor.Nonce && msg.Message.CallSeqNum-fromActor.Nonce > v.cfg.MaxNonceGap {
+	if msg.Message.CallSeqNum > fromActor.Nonce && msg.Message.CallSeqNum-fromActor.Nonce > v.rules.MaxNonceGap {
 		return errors.NewRevertErrorf("message nonce (%d) is too much greater than actor nonce (%d)", msg.Message.CallSeqNum, fromActor.Nonce)
 	}
 
@@ -46,42 +46,47 @@ func TestMessageValidator(t *testing.T) {
 
 	t.Run("self send fails", func(t *testing.T) {
 		msg := newMessage(t, alice, alice, 100, 5, 1, 0)
-		assert.Errorf(t, validator.Validate(ctx, msg, actor), "self")
+		assert.Equal(t, consensus.ErrSelfSend, validator.Validate(ctx, msg, actor))
 	})
 
 	t.Run("non-account actor fails", func(t *testing.T) {
 		badActor := newActor(t, 1000, 100)
 		badActor.Code = types.CidFromString(t, "somecid")
 		msg := newMessage(t, alice, bob, 100, 5, 1, 0)
-		assert.Errorf(t, validator.Validate(ctx, msg, badActor), "account")
+		assert.Equal(t, consensus.ErrNonAccountActor, validator.Validate(ctx, msg, badActor))
 	})
 
 	t.Run("negative value fails", func(t *testing.T) {
-		msg := newMessage(t, alice, alice, 100, -5, 1, 0)
-		assert.Errorf(t, validator.Validate(ctx, msg, actor), "negative")
+		msg := newMessage(t, alice, bob, 100, -5, 1, 0)
+		assert.Equal(t, consensus.ErrNegativeValue, validator.Validate(ctx, msg, actor))
 	})
 
 	t.Run("block gas limit fails", func(t *testing.T) {
 		msg := newMessage(t, alice, bob, 100, 5, 1, uint64(types.BlockGasLimit)+1)
-		assert.Errorf(t, validator.Validate(ctx, msg, actor), "block limit")
+		assert.Equal(t, consensus.ErrGasAboveBlockLimit, validator.Validate(ctx, msg, actor))
 	})
 
 	t.Run("can't cover value", func(t *testing.T) {
 		msg := newMessage(t, alice, bob, 100, 2000, 1, 0) // lots of value
-		assert.Errorf(t, validator.Validate(ctx, msg, actor), "funds")
+		assert.Equal(t, consensus.ErrInsufficientBalance, validator.Validate(ctx, msg, actor))
 
 		msg = newMessage(t, alice, bob, 100, 5, 100000, 200) // lots of expensive gas
-		assert.Errorf(t, validator.Validate(ctx, msg, actor), "funds")
+		assert.Equal(t, consensus.ErrInsufficientBalance, validator.Validate(ctx, msg, actor))
 	})
 
 	t.Run("low nonce", func(t *testing.T) {
 		msg := newMessage(t, alice, bob, 99, 5, 1, 0)
-		assert.Errorf(t, validator.Validate(ctx, msg, actor), "too low")
+		assert.Equal(t, consensus.ErrNonceTooLow, validator.Validate(ctx, msg, actor))
 	})
 
 	t.Run("high nonce", func(t *testing.T) {
 		msg := newMessage(t, alice, bob, 101, 5, 1, 0)
-		assert.Errorf(t, validator.Validate(ctx, msg, actor), "too high")
+		assert.Equal(t, consensus.ErrNonceTooHigh, validator.Validate(ctx, msg, actor))
+	})
+
+	t.Run("zero gas price fails", func(t *testing.T) {
+		msg := newMessage(t, alice, bob, 100, 5, 0, 0)
+		assert.Equal(t, consensus.ErrGasPriceTooLow, validator.Validate(ctx, msg, actor))
 	})
 }
 
@@ -114,8 +119,7 @@ func TestBLSSignatureValidationConfiguration(t *testing.T) {
 		validator := consensus.NewIngestionValidator(api, mpoolCfg)
 
 		err := validator.Validate(ctx, unsigned)
-		require.Error(t, err)
-		assert.Contains(t, err.Error(), "invalid signature")
+		assert.Equal(t, consensus.ErrInvalidSignature, err)
 	})
 }
 
@@ -168,6 +172,51 @@ func TestIngestionValidator(t *testing.T) {
 	})
 }
 
+func TestIngestionValidatorRules(t *testing.T) {
+	tf.UnitTest(t)
+
+	alice := addresses[0]
+	bob := addresses[1]
+	act := newActor(t, 1000, 53)
+	api := NewMockIngestionValidatorAPI()
+	api.ActorAddr = alice
+	api.Actor = act
+	ctx := context.Background()
+
+	t.Run("rejects gas price below configured minimum", func(t *testing.T) {
+		mpoolCfg := config.NewDefaultConfig().Mpool
+		mpoolCfg.MinGasPrice = types.NewGasPrice(5)
+		validator := consensus.NewIngestionValidator(api, mpoolCfg)
+
+		msg := newMessage(t, alice, bob, 53, 5, 4, 0)
+		assert.Equal(t, consensus.ErrGasPriceBelowMin, validator.Validate(ctx, msg))
+
+		msg = newMessage(t, alice, bob, 53, 5, 5, 0)
+		assert.NoError(t, validator.Validate(ctx, msg))
+	})
+
+	t.Run("rejects messages above max size", func(t *testing.T) {
+		rules := consensus.IngestionRulesFromConfig(config.NewDefaultConfig().Mpool)
+		rules.MaxMessageSize = 16
+		validator := consensus.NewIngestionValidatorWithRules(api, rules)
+
+		msg := newMessage(t, alice, bob, 53, 5, 1, 0)
+		assert.Equal(t, consensus.ErrMessageTooLarge, validator.Validate(ctx, msg))
+	})
+
+	t.Run("balance check can be disabled", func(t *testing.T) {
+		rules := consensus.IngestionRulesFromConfig(config.NewDefaultConfig().Mpool)
+		underFunded := newMessage(t, alice, bob, 53, 2000, 1, 0)
+
+		validator := consensus.NewIngestionValidatorWithRules(api, rules)
+		assert.Equal(t, consensus.ErrInsufficientBalance, validator.Validate(ctx, underFunded))
+
+		rules.SkipBalanceCheck = true
+		validator = consensus.NewIngestionValidatorWithRules(api, rules)
+		assert.NoError(t, validator.Validate(ctx, underFunded))
+	})
+}
+
 func newActor(t *testing.T, balanceAF int, nonce uint64) *actor.Actor {
 	actor, err := account.NewActor(attoFil(balanceAF))
 	require.NoError(t, err)
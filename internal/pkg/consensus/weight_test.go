@@ -6,14 +6,17 @@ import (
 	"testing"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-hamt-ipld"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 )
@@ -81,6 +84,16 @@ func TestNewWeight(t *testing.T) {
 		assertEqualInt(t, 56, fixWeight)
 	})
 
+	t.Run("overflow near max parent weight", func(t *testing.T) {
+		toWeighNearMax := th.RequireNewTipSet(t, &block.Block{
+			ParentWeight: types.Uint64(types.MaxFixedPointIntegralNum * 1000),
+			Ticket:       ticket,
+		})
+
+		_, err := sel.NewWeight(ctx, toWeighNearMax, fakeRoot)
+		assert.Equal(t, types.ErrFixedPointOverflow, err)
+	})
+
 	t.Run("many blocks", func(t *testing.T) {
 		toWeighThreeBlock := th.RequireNewTipSet(t,
 			&block.Block{
@@ -119,3 +132,108 @@ func assertEqualInt(t *testing.T, i int, fixed uint64) {
 	fixed2int := requireFixedToInt(t, fixed)
 	assert.Equal(t, i, fixed2int)
 }
+
+// recordingWeigher records which weight function was last used.
+type recordingWeigher struct {
+	chain.FakeChainSelector
+	used string
+}
+
+func (rw *recordingWeigher) Weight(ctx context.Context, ts block.TipSet, pStateID cid.Cid) (uint64, error) {
+	rw.used = "Weight"
+	return rw.FakeChainSelector.Weight(ctx, ts, pStateID)
+}
+
+func (rw *recordingWeigher) NewWeight(ctx context.Context, ts block.TipSet, pStateID cid.Cid) (uint64, error) {
+	rw.used = "NewWeight"
+	return rw.FakeChainSelector.NewWeight(ctx, ts, pStateID)
+}
+
+func TestComputeParentWeight(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	pvt, err := version.NewProtocolVersionTableBuilder(version.TEST).
+		Add(version.TEST, version.Protocol0, types.NewBlockHeight(0)).
+		Add(version.TEST, version.Protocol1, types.NewBlockHeight(10)).
+		Build()
+	require.NoError(t, err)
+
+	builder := chain.NewBuilder(t, address.Undef)
+	gen := builder.NewGenesis()
+	before := builder.AppendManyOn(9, gen)
+	after := builder.AppendOn(before, 2)
+	require.Equal(t, uint64(10), uint64(after.At(0).Height))
+
+	for _, tc := range []struct {
+		name    string
+		parents block.TipSet
+		used    string
+	}{
+		{"before protocol 1", before, "Weight"},
+		{"from protocol 1", after, "NewWeight"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			weigher := &recordingWeigher{}
+			grandparents, err := tc.parents.Parents()
+			require.NoError(t, err)
+			pStateID := builder.StateForKey(grandparents)
+
+			w, err := consensus.ComputeParentWeight(ctx, tc.parents, pStateID, weigher, pvt)
+			require.NoError(t, err)
+			assert.Equal(t, tc.used, weigher.used)
+
+			expected, err := chain.FakeStateBuilder{}.Weigh(tc.parents, pStateID)
+			require.NoError(t, err)
+			assert.Equal(t, expected, w)
+		})
+	}
+}
+
+func TestIsHeavierWeightMargin(t *testing.T) {
+	tf.UnitTest(t)
+
+	cst := hamt.NewCborStore()
+	ctx := context.Background()
+	fakeTree := state.TreeFromString(t, "test-IsHeavierWeightMargin-StateCid", cst)
+	fakeRoot, err := fakeTree.Flush(ctx)
+	require.NoError(t, err)
+	pvt, err := version.NewProtocolVersionTableBuilder(version.TEST).
+		Add(version.TEST, version.Protocol1, types.NewBlockHeight(0)).
+		Add(version.TEST, version.Protocol3, types.NewBlockHeight(10)).
+		Build()
+	require.NoError(t, err)
+	as := consensus.NewFakeActorStateStore(types.NewBytesAmount(1), types.NewBytesAmount(16), make(map[address.Address]address.Address))
+	sel := consensus.NewChainSelector(cst, as, types.CidFromString(t, "genesisCid"), pvt)
+	margin, err := types.BigToFixed(new(big.Float).SetInt64(2))
+	require.NoError(t, err)
+	sel.SetWeightMargin(margin)
+
+	tipAt := func(height uint64, parentWeight int64) block.TipSet {
+		w, err := types.BigToFixed(new(big.Float).SetInt64(parentWeight))
+		require.NoError(t, err)
+		return th.RequireNewTipSet(t, &block.Block{
+			Height:       types.Uint64(height),
+			ParentWeight: types.Uint64(w),
+			Ticket:       consensus.MakeFakeTicketForTest(),
+		})
+	}
+
+	t.Run("heavier within the margin does not win", func(t *testing.T) {
+		heavier, err := sel.IsHeavier(ctx, tipAt(10, 11), tipAt(10, 10), fakeRoot, fakeRoot)
+		require.NoError(t, err)
+		assert.False(t, heavier)
+	})
+
+	t.Run("heavier beyond the margin wins", func(t *testing.T) {
+		heavier, err := sel.IsHeavier(ctx, tipAt(10, 13), tipAt(10, 10), fakeRoot, fakeRoot)
+		require.NoError(t, err)
+		assert.True(t, heavier)
+	})
+
+	t.Run("no margin before protocol 3", func(t *testing.T) {
+		heavier, err := sel.IsHeavier(ctx, tipAt(9, 11), tipAt(9, 10), fakeRoot, fakeRoot)
+		require.NoError(t, err)
+		assert.True(t, heavier)
+	})
+}
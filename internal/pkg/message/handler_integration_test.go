@@ -24,6 +24,7 @@ func TestNewHeadHandlerIntegration(t *testing.T) {
 	tf.UnitTest(t)
 	signer, _ := types.NewMockSignersAndKeyInfo(2)
 	objournal := journal.NewInMemoryJournal(t, th.NewFakeClock(time.Unix(1234567890, 0))).Topic("outbox")
+	ibjournal := journal.NewInMemoryJournal(t, th.NewFakeClock(time.Unix(1234567890, 0))).Topic("inbox")
 	sender := signer.Addresses[0]
 	dest := signer.Addresses[1]
 	ctx := context.Background()
@@ -37,7 +38,7 @@ func TestNewHeadHandlerIntegration(t *testing.T) {
 
 	makeHandler := func(provider *message.FakeProvider, root block.TipSet) *message.HeadHandler {
 		mpool := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		inbox := message.NewInbox(mpool, maxAge, provider, provider)
+		inbox := message.NewInbox(mpool, maxAge, provider, provider, ibjournal)
 		queue := message.NewQueue()
 		publisher := message.NewDefaultPublisher(&message.MockNetworkPublisher{}, "Topic", mpool)
 		policy := message.NewMessageQueuePolicy(provider, maxAge)
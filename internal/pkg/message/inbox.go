@@ -7,11 +7,12 @@ import (
 	"github.com/ipfs/go-cid"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/journal"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
 
-// InboxMaxAgeTipsets is maximum age (in non-empty tipsets) to permit messages to stay in the pool after reception.
-// It should be a little shorter than the outbox max age so that messages expire from mining
+// InboxMaxAgeTipsets is the default maximum age (in non-empty tipsets) to permit messages to stay in the pool
+// after reception, as set in the default message pool config. It should be a little shorter than the outbox max age so that messages expire from mining
 // pools a little before the sender gives up on them.
 const InboxMaxAgeTipsets = 6
 
@@ -25,6 +26,8 @@ type Inbox struct {
 	// Provides tipsets for chain traversal.
 	chain           chainProvider
 	messageProvider messageProvider
+
+	journal journal.Writer
 }
 
 // messageProvider provides message collections given their cid.
@@ -32,13 +35,15 @@ type messageProvider interface {
 	LoadMessages(context.Context, types.TxMeta) ([]*types.SignedMessage, []*types.UnsignedMessage, error)
 }
 
-// NewInbox constructs a new inbox.
-func NewInbox(pool *Pool, maxAgeRounds uint, chain chainProvider, messages messageProvider) *Inbox {
+// NewInbox constructs a new inbox. Messages dropped from the pool for exceeding
+// maxAgeRounds are recorded as "Expire" events to `jw`.
+func NewInbox(pool *Pool, maxAgeRounds uint, chain chainProvider, messages messageProvider, jw journal.Writer) *Inbox {
 	return &Inbox{
 		pool:            pool,
 		maxAgeTipsets:   maxAgeRounds,
 		chain:           chain,
 		messageProvider: messages,
+		journal:         jw,
 	}
 }
 
@@ -58,6 +63,12 @@ func (ib *Inbox) Add(ctx context.Context, msg *types.SignedMessage) (cid.Cid, er
 	return ib.pool.Add(ctx, msg, blockTime)
 }
 
+// MaxAge returns the maximum age, in non-empty tipsets, that a message may stay
+// in the pool after reception.
+func (ib *Inbox) MaxAge() uint64 {
+	return uint64(ib.maxAgeTipsets)
+}
+
 // Pool returns the inbox's message pool.
 func (ib *Inbox) Pool() *Pool {
 	return ib.pool
@@ -93,32 +104,52 @@ func (ib *Inbox) HandleNewHead(ctx context.Context, oldChain, newChain []block.T
 	}
 
 	// Remove all messages in the new tipsets from the pool, now mined.
+	if _, err := ib.removeMined(ctx, newChain); err != nil {
+		return err
+	}
+
+	// prune all messages that have been in the pool too long
+	if len(newChain) > 0 {
+		return timeoutMessages(ctx, ib.pool, ib.chain, newChain[0], ib.maxAgeTipsets, ib.journal)
+	}
+	return nil
+}
+
+// OnNewHead removes the messages mined in `newHead` from the pool, returning the
+// number of messages removed. Unlike HandleNewHead it neither restores messages
+// from a removed chain nor times out old messages.
+func (ib *Inbox) OnNewHead(ctx context.Context, newHead block.TipSet) (int, error) {
+	return ib.removeMined(ctx, []block.TipSet{newHead})
+}
+
+// removeMined removes the messages in `tipsets` from the pool and returns the
+// number of messages that were pending.
+func (ib *Inbox) removeMined(ctx context.Context, tipsets []block.TipSet) (int, error) {
 	// Cid() can error, so collect all the CIDs up front.
 	var removeCids []cid.Cid
-	for _, tipset := range newChain {
+	for _, tipset := range tipsets {
 		for i := 0; i < tipset.Len(); i++ {
 			secpMsgs, _, err := ib.messageProvider.LoadMessages(ctx, tipset.At(i).Messages)
 			if err != nil {
-				return err
+				return 0, err
 			}
 			for _, msg := range secpMsgs {
 				cid, err := msg.Cid()
 				if err != nil {
-					return err
+					return 0, err
 				}
 				removeCids = append(removeCids, cid)
 			}
 		}
 	}
+	removed := 0
 	for _, c := range removeCids {
-		ib.pool.Remove(c)
-	}
-
-	// prune all messages that have been in the pool too long
-	if len(newChain) > 0 {
-		return timeoutMessages(ctx, ib.pool, ib.chain, newChain[0], ib.maxAgeTipsets)
+		if _, ok := ib.pool.Get(c); ok {
+			ib.pool.Remove(c)
+			removed++
+		}
 	}
-	return nil
+	return removed, nil
 }
 
 // timeoutMessages removes all messages from the pool that arrived more than maxAgeTipsets tip sets ago.
@@ -126,7 +157,7 @@ func (ib *Inbox) HandleNewHead(ctx context.Context, oldChain, newChain []block.T
 // height. This prevents us from prematurely timing messages that arrive during long chains of null blocks.
 // Also when blocks fill, the rate of message processing will correspond more closely to rate of tip
 // sets than to the expected block time over short timescales.
-func timeoutMessages(ctx context.Context, pool *Pool, chains chain.TipSetProvider, head block.TipSet, maxAgeTipsets uint) error {
+func timeoutMessages(ctx context.Context, pool *Pool, chains chain.TipSetProvider, head block.TipSet, maxAgeTipsets uint, jw journal.Writer) error {
 	var err error
 
 	var minimumHeight uint64
@@ -146,6 +177,7 @@ func timeoutMessages(ctx context.Context, pool *Pool, chains chain.TipSetProvide
 	// remove all messages added before minimumHeight
 	for _, cid := range pool.PendingBefore(minimumHeight) {
 		pool.Remove(cid)
+		jw.Write("Expire", "cid", cid.String(), "maxAge", uint64(maxAgeTipsets), "minimumHeight", minimumHeight)
 	}
 
 	return nil
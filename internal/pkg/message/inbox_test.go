@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/stretchr/testify/assert"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/journal"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/message"
 	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
@@ -31,7 +33,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [m0],     Chain: b[m1]
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 10, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(2, mockSigner)
 		requireAdd(t, ib, m[0], m[1])
@@ -50,7 +52,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [m0, m1], Chain: b[m2]
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 10, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(3, mockSigner)
 		requireAdd(t, ib, m[0], m[1])
@@ -67,7 +69,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [m1],         Chain: b[m2, m3] -> b[m4] -> b[m0] -> b[] -> b[m5, m6]
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 10, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(7, mockSigner)
 		requireAdd(t, ib, m[2], m[5])
@@ -92,7 +94,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [m1],         Chain: b[m2, m3] -> {b[m4], b[m0], b[], b[]} -> {b[], b[m6,m5]}
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 10, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(7, mockSigner)
 		requireAdd(t, ib, m[2], m[5])
@@ -115,7 +117,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [m1, m2],     Chain: b[m0] -> b[m3] -> b[m4, m5]
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 10, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(6, mockSigner)
 		requireAdd(t, ib, m[3], m[5])
@@ -141,7 +143,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [m6],         Chain: b[m0] -> b[m3] -> b[m4] -> b[m5] -> b[m1, m2]
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 10, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(7, mockSigner)
 		requireAdd(t, ib, m[6])
@@ -168,7 +170,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [m6],         Chain: {b[m0], b[m1]} -> b[m3] -> b[m4] -> {b[m5], b[m1, m2]}
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 10, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(7, mockSigner)
 		requireAdd(t, ib, m[6])
@@ -194,7 +196,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [m3, m5],     Chain: {b[m0], b[m1], b[m2]}
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 10, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(6, mockSigner)
 		requireAdd(t, ib, m[3], m[5])
@@ -219,7 +221,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [m2, m3],         Chain: b[m0] -> b[m1]
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 10, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
 		m := types.NewSignedMsgs(4, mockSigner)
 
 		oldChain := requireChainWithMessages(t, chainProvider.Builder, parent,
@@ -239,7 +241,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [m0],     Chain: b[] -> b[m1, m2]
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 10, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(3, mockSigner)
 		requireAdd(t, ib, m[0], m[1])
@@ -258,7 +260,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [],           Chain: b[m0] -> b[m1] -> b[m2, m3] -> b[m4] -> b[m5, m6]
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 10, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(7, mockSigner)
 		requireAdd(t, ib, m[2], m[5])
@@ -284,7 +286,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		// Msg pool: [],           Chain: b[m0] -> b[m1] -> b[m2, m3] -> b[m4] -> b[m5, m6]
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
-		ib := message.NewInbox(p, 5, chainProvider, chainProvider)
+		ib := message.NewInbox(p, 5, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(1, mockSigner)
 
@@ -308,7 +310,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
 		maxAge := uint(10)
-		ib := message.NewInbox(p, maxAge, chainProvider, chainProvider)
+		ib := message.NewInbox(p, maxAge, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(maxAge, mockSigner)
 
@@ -353,7 +355,7 @@ func TestUpdateMessagePool(t *testing.T) {
 		chainProvider, parent := newProviderWithGenesis(t)
 		p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
 		maxAge := uint(10)
-		ib := message.NewInbox(p, maxAge, chainProvider, chainProvider)
+		ib := message.NewInbox(p, maxAge, chainProvider, chainProvider, newInboxTestJournal(t))
 
 		m := types.NewSignedMsgs(maxAge, mockSigner)
 		head := requireChainWithMessages(t, chainProvider.Builder, parent, msgsSet{msgs{}})[0]
@@ -386,6 +388,88 @@ func TestUpdateMessagePool(t *testing.T) {
 	})
 }
 
+func TestInboxMaxAge(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	type msgs []*types.SignedMessage
+	type msgsSet [][]*types.SignedMessage
+
+	var mockSigner, _ = types.NewMockSignersAndKeyInfo(1)
+
+	chainProvider, parent := newProviderWithGenesis(t)
+	p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
+	jw := &recordingWriter{}
+	maxAge := uint(2)
+	ib := message.NewInbox(p, maxAge, chainProvider, chainProvider, jw)
+	assert.Equal(t, uint64(2), ib.MaxAge())
+
+	m := types.NewSignedMsgs(1, mockSigner)
+	requireAdd(t, ib, m[0])
+	c, err := m[0].Cid()
+	require.NoError(t, err)
+
+	// The message survives maxAge tipsets.
+	head := parent
+	for i := uint(0); i < maxAge; i++ {
+		head = requireChainWithMessages(t, chainProvider.Builder, head, msgsSet{msgs{}})[0]
+		require.NoError(t, ib.HandleNewHead(ctx, nil, []block.TipSet{head}))
+		assertPoolEquals(t, p, m[0])
+	}
+	assert.Empty(t, jw.events)
+
+	// One more tipset evicts it.
+	head = requireChainWithMessages(t, chainProvider.Builder, head, msgsSet{msgs{}})[0]
+	require.NoError(t, ib.HandleNewHead(ctx, nil, []block.TipSet{head}))
+	assertPoolEquals(t, p)
+	require.Len(t, jw.events, 1)
+	assert.Equal(t, "Expire", jw.events[0])
+	assert.Equal(t, c.String(), jw.kvs[0][1])
+}
+
+func TestInboxOnNewHead(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	type msgs []*types.SignedMessage
+	type msgsSet [][]*types.SignedMessage
+
+	var mockSigner, _ = types.NewMockSignersAndKeyInfo(10)
+
+	chainProvider, parent := newProviderWithGenesis(t)
+	p := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
+	ib := message.NewInbox(p, 10, chainProvider, chainProvider, newInboxTestJournal(t))
+
+	m := types.NewSignedMsgs(3, mockSigner)
+	requireAdd(t, ib, m[0], m[1])
+
+	// m[2] was never pending so is not counted as removed.
+	head := requireChainWithMessages(t, chainProvider.Builder, parent, msgsSet{msgs{m[1], m[2]}})[0]
+	removed, err := ib.OnNewHead(ctx, head)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assertPoolEquals(t, p, m[0])
+
+	// Handling the same head again removes nothing.
+	removed, err = ib.OnNewHead(ctx, head)
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+	assertPoolEquals(t, p, m[0])
+}
+
+// recordingWriter is a journal writer that records the events written to it.
+type recordingWriter struct {
+	events []string
+	kvs    [][]interface{}
+}
+
+func (w *recordingWriter) Write(event string, kvs ...interface{}) {
+	w.events = append(w.events, event)
+	w.kvs = append(w.kvs, kvs)
+}
+
+func newInboxTestJournal(t *testing.T) journal.Writer {
+	return journal.NewInMemoryJournal(t, th.NewFakeClock(time.Unix(1234567890, 0))).Topic("inbox")
+}
+
 func newProviderWithGenesis(t *testing.T) (*message.FakeProvider, block.TipSet) {
 	provider := message.NewFakeProvider(t)
 	head := provider.Builder.NewGenesis()
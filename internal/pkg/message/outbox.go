@@ -75,6 +75,16 @@ func (ob *Outbox) Queue() *Queue {
 	return ob.queue
 }
 
+// BatchMessage is a message for SendBatch to send.
+type BatchMessage struct {
+	To       address.Address
+	Value    types.AttoFIL
+	GasPrice types.AttoFIL
+	GasLimit types.GasUnits
+	Method   string
+	Params   []interface{}
+}
+
 // Send marshals and sends a message, retaining it in the outbound message queue.
 // If bcast is true, the publisher broadcasts the message to the network at the current block height.
 func (ob *Outbox) Send(ctx context.Context, from, to address.Address, value types.AttoFIL,
@@ -89,9 +99,45 @@ func (ob *Outbox) Send(ctx context.Context, from, to address.Address, value type
 			"params", params, "error", err, "cid", out.String())
 	}()
 
-	encodedParams, err := abi.ToEncodedValues(params...)
+	cids, err := ob.send(ctx, from, []BatchMessage{{
+		To:       to,
+		Value:    value,
+		GasPrice: gasPrice,
+		GasLimit: gasLimit,
+		Method:   method,
+		Params:   params,
+	}}, bcast)
 	if err != nil {
-		return cid.Undef, errors.Wrap(err, "invalid params")
+		return cid.Undef, err
+	}
+	return cids[0], nil
+}
+
+// SendBatch marshals and sends messages from `from` with consecutive nonces,
+// retaining them in the outbound message queue, and returns their CIDs in
+// order. The messages are signed together with types.SignBatch, so a batch
+// signer signs them in one call. None is queued unless all are valid.
+// If bcast is true, the publisher broadcasts the messages to the network at the current block height.
+func (ob *Outbox) SendBatch(ctx context.Context, from address.Address, msgs []BatchMessage, bcast bool) (out []cid.Cid, err error) {
+	defer func() {
+		if err != nil {
+			msgSendErrCt.Inc(ctx, 1)
+		}
+		ob.journal.Write("SendBatch",
+			"from", from.String(), "count", len(msgs), "bcast", bcast, "error", err, "cids", out)
+	}()
+
+	return ob.send(ctx, from, msgs, bcast)
+}
+
+func (ob *Outbox) send(ctx context.Context, from address.Address, msgs []BatchMessage, bcast bool) ([]cid.Cid, error) {
+	encodedParams := make([][]byte, len(msgs))
+	for i, msg := range msgs {
+		encoded, err := abi.ToEncodedValues(msg.Params...)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid params")
+		}
+		encodedParams[i] = encoded
 	}
 
 	// Lock to avoid a race inspecting the actor state and message queue to calculate next nonce.
@@ -102,41 +148,60 @@ func (ob *Outbox) Send(ctx context.Context, from, to address.Address, value type
 
 	fromActor, err := ob.actors.GetActorAt(ctx, head, from)
 	if err != nil {
-		return cid.Undef, errors.Wrapf(err, "no actor at address %s", from)
+		return nil, errors.Wrapf(err, "no actor at address %s", from)
 	}
 
 	nonce, err := nextNonce(fromActor, ob.queue, from)
 	if err != nil {
-		return cid.Undef, errors.Wrapf(err, "failed calculating nonce for actor at %s", from)
+		return nil, errors.Wrapf(err, "failed calculating nonce for actor at %s", from)
 	}
 
-	rawMsg := types.NewMeteredMessage(from, to, nonce, value, method, encodedParams, gasPrice, gasLimit)
-	signed, err := types.NewSignedMessage(*rawMsg, ob.signer)
+	rawMsgs := make([]*types.UnsignedMessage, len(msgs))
+	items := make([]types.SignItem, len(msgs))
+	for i, msg := range msgs {
+		rawMsgs[i] = types.NewMeteredMessage(from, msg.To, nonce+uint64(i), msg.Value, msg.Method, encodedParams[i], msg.GasPrice, msg.GasLimit)
+		data, err := rawMsgs[i].Marshal()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to sign message")
+		}
+		items[i] = types.SignItem{Data: data, Addr: from}
+	}
+	sigs, err := types.SignBatch(ob.signer, items)
 	if err != nil {
-		return cid.Undef, errors.Wrap(err, "failed to sign message")
+		return nil, errors.Wrap(err, "failed to sign message")
 	}
 
-	err = ob.validator.Validate(ctx, signed, fromActor)
-	if err != nil {
-		return cid.Undef, errors.Wrap(err, "invalid message")
+	signed := make([]*types.SignedMessage, len(msgs))
+	for i, rawMsg := range rawMsgs {
+		signed[i] = &types.SignedMessage{Message: *rawMsg, Signature: sigs[i]}
+		err = ob.validator.Validate(ctx, signed[i], fromActor)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid message")
+		}
 	}
 
 	height, err := tipsetHeight(ob.chains, head)
 	if err != nil {
-		return cid.Undef, errors.Wrap(err, "failed to get block height")
+		return nil, errors.Wrap(err, "failed to get block height")
 	}
 
-	// Add to the local message queue at the last possible moment before
-	// calling Publish.
-	if err := ob.queue.Enqueue(ctx, signed, height); err != nil {
-		return cid.Undef, errors.Wrap(err, "failed to add message to outbound queue")
-	}
-	err = ob.publisher.Publish(ctx, signed, height, bcast)
-	if err != nil {
-		return cid.Undef, err
+	cids := make([]cid.Cid, len(signed))
+	for i, msg := range signed {
+		// Add to the local message queue at the last possible moment before
+		// calling Publish.
+		if err := ob.queue.Enqueue(ctx, msg, height); err != nil {
+			return nil, errors.Wrap(err, "failed to add message to outbound queue")
+		}
+		err = ob.publisher.Publish(ctx, msg, height, bcast)
+		if err != nil {
+			return nil, err
+		}
+		cids[i], err = msg.Cid()
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	return signed.Cid()
+	return cids, nil
 }
 
 // HandleNewHead maintains the message queue in response to a new head tipset.
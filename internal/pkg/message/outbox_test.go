@@ -138,6 +138,53 @@ func TestOutbox(t *testing.T) {
 		}
 	})
 
+	t.Run("send batch signs messages together with consecutive nonces", func(t *testing.T) {
+		w, _ := types.NewMockSignersAndKeyInfo(1)
+		signer := &batchCountingSigner{MockSigner: w}
+		sender := w.Addresses[0]
+		toAddr := address.NewForTestGetter()()
+		queue := message.NewQueue()
+		publisher := &message.MockPublisher{}
+		provider := message.NewFakeProvider(t)
+
+		head := provider.NewGenesis()
+		actr, _ := account.NewActor(types.ZeroAttoFIL)
+		actr.Nonce = 42
+		provider.SetHeadAndActor(t, head.Key(), sender, actr)
+
+		ob := message.NewOutbox(signer, message.FakeValidator{}, queue, publisher, message.NullPolicy{}, provider, provider, newOutboxTestJournal(t))
+		msg := message.BatchMessage{To: toAddr, Value: types.ZeroAttoFIL, GasPrice: types.NewGasPrice(0), GasLimit: types.NewGasUnits(0)}
+		cids, err := ob.SendBatch(context.Background(), sender, []message.BatchMessage{msg, msg, msg}, true)
+		require.NoError(t, err)
+		assert.Equal(t, 1, signer.batches)
+
+		queued := queue.List(sender)
+		require.Len(t, queued, 3)
+		for i, qm := range queued {
+			assert.Equal(t, actr.Nonce+types.Uint64(i), qm.Msg.Message.CallSeqNum)
+			c, err := qm.Msg.Cid()
+			require.NoError(t, err)
+			assert.Equal(t, c, cids[i])
+			assert.True(t, qm.Msg.VerifySignature())
+		}
+	})
+
+	t.Run("send batch queues nothing if a message is invalid", func(t *testing.T) {
+		w, _ := types.NewMockSignersAndKeyInfo(1)
+		sender := w.Addresses[0]
+		queue := message.NewQueue()
+		publisher := &message.MockPublisher{}
+		provider := message.NewFakeProvider(t)
+
+		ob := message.NewOutbox(w, message.FakeValidator{RejectMessages: true}, queue, publisher,
+			message.NullPolicy{}, provider, provider, newOutboxTestJournal(t))
+		msg := message.BatchMessage{To: sender, Value: types.ZeroAttoFIL, GasPrice: types.NewGasPrice(0), GasLimit: types.NewGasUnits(0)}
+		_, err := ob.SendBatch(context.Background(), sender, []message.BatchMessage{msg, msg}, true)
+		assert.Error(t, err)
+		assert.Empty(t, queue.List(sender))
+		assert.Nil(t, publisher.Message)
+	})
+
 	t.Run("fails with non-account actor", func(t *testing.T) {
 		w, _ := types.NewMockSignersAndKeyInfo(1)
 		sender := w.Addresses[0]
@@ -157,3 +204,22 @@ func TestOutbox(t *testing.T) {
 		assert.Contains(t, err.Error(), "account or empty")
 	})
 }
+
+// batchCountingSigner is a MockSigner counting the batches it signs.
+type batchCountingSigner struct {
+	types.MockSigner
+	batches int
+}
+
+func (s *batchCountingSigner) SignBatch(items []types.SignItem) ([]types.Signature, error) {
+	s.batches++
+	sigs := make([]types.Signature, len(items))
+	for i, item := range items {
+		sig, err := s.SignBytes(item.Data, item.Addr)
+		if err != nil {
+			return nil, err
+		}
+		sigs[i] = sig
+	}
+	return sigs, nil
+}
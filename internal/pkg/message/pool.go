@@ -87,6 +87,27 @@ func (pool *Pool) Add(ctx context.Context, msg *types.SignedMessage, height uint
 	return c, nil
 }
 
+// Restore re-admits previously pending messages to the pool, tagged with the block
+// height at which they are restored. Each message is validated again as if it had
+// just been received; messages that are no longer valid are dropped and reported
+// in the returned error.
+func (pool *Pool) Restore(ctx context.Context, msgs []*types.SignedMessage, height uint64) error {
+	var rejected int
+	var firstErr error
+	for _, msg := range msgs {
+		if _, err := pool.Add(ctx, msg, height); err != nil {
+			rejected++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if rejected > 0 {
+		return errors.Wrapf(firstErr, "%d of %d restored messages rejected", rejected, len(msgs))
+	}
+	return nil
+}
+
 // Pending returns all pending messages.
 func (pool *Pool) Pending() []*types.SignedMessage {
 	pool.lk.Lock()
@@ -0,0 +1,47 @@
+package message
+
+import (
+	"github.com/ipfs/go-datastore"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+var pendingKey = datastore.NewKey("/mpool/pending")
+
+// PoolStore persists pending messages in a datastore so that they can be
+// restored to a Pool after a restart.
+type PoolStore struct {
+	ds datastore.Datastore
+}
+
+// NewPoolStore creates a PoolStore backed by `ds`.
+func NewPoolStore(ds datastore.Datastore) *PoolStore {
+	return &PoolStore{ds: ds}
+}
+
+// Save replaces any previously saved messages with `msgs`.
+func (ps *PoolStore) Save(msgs []*types.SignedMessage) error {
+	data, err := encoding.Encode(msgs)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode pending messages")
+	}
+	return ps.ds.Put(pendingKey, data)
+}
+
+// Load returns the messages last saved, or none if nothing has been saved.
+func (ps *PoolStore) Load() ([]*types.SignedMessage, error) {
+	data, err := ps.ds.Get(pendingKey)
+	if err == datastore.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read pending messages")
+	}
+	var msgs []*types.SignedMessage
+	if err := encoding.Decode(data, &msgs); err != nil {
+		return nil, errors.Wrap(err, "failed to decode pending messages")
+	}
+	return msgs, nil
+}
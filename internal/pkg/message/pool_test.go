@@ -5,6 +5,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/ipfs/go-datastore"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -130,6 +131,60 @@ func TestMessagePoolDedup(t *testing.T) {
 	assert.Len(t, pool.Pending(), 1)
 }
 
+func TestMessagePoolRestore(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	store := message.NewPoolStore(datastore.NewMapDatastore())
+
+	t.Run("restores nothing when nothing was saved", func(t *testing.T) {
+		msgs, err := store.Load()
+		require.NoError(t, err)
+		assert.Empty(t, msgs)
+	})
+
+	msg1 := newSignedMessage()
+	msg2 := mustSetNonce(mockSigner, newSignedMessage(), 1)
+	pool := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
+	_, err := pool.Add(ctx, msg1, 0)
+	require.NoError(t, err)
+	_, err = pool.Add(ctx, msg2, 0)
+	require.NoError(t, err)
+	require.NoError(t, store.Save(pool.Pending()))
+
+	t.Run("saved messages are restored", func(t *testing.T) {
+		msgs, err := store.Load()
+		require.NoError(t, err)
+
+		restored := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
+		require.NoError(t, restored.Restore(ctx, msgs, 5))
+		assert.Len(t, restored.Pending(), 2)
+
+		c1, err := msg1.Cid()
+		require.NoError(t, err)
+		m, ok := restored.Get(c1)
+		require.True(t, ok)
+		assert.True(t, msg1.Equals(m))
+
+		// Restored messages are tagged with the restore height.
+		assert.Empty(t, restored.PendingBefore(5))
+		assert.Len(t, restored.PendingBefore(6), 2)
+	})
+
+	t.Run("messages that fail validation are dropped", func(t *testing.T) {
+		msgs, err := store.Load()
+		require.NoError(t, err)
+
+		validator := th.NewMockMessagePoolValidator()
+		validator.Valid = false
+		restored := message.NewPool(config.NewDefaultConfig().Mpool, validator)
+		err = restored.Restore(ctx, msgs, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "2 of 2 restored messages rejected")
+		assert.Empty(t, restored.Pending())
+	})
+}
+
 func TestMessagePoolAsync(t *testing.T) {
 	tf.UnitTest(t)
 
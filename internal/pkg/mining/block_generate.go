@@ -62,7 +62,20 @@ func (w *DefaultWorker) Generate(ctx context.Context,
 
 	pending := w.messageSource.Pending()
 	mq := NewMessageQueue(pending)
-	secpMessages, blsMessages := divideMessages(mq.Drain())
+	selected := mq.Drain()
+	// Select no more messages than validators accept at the block's height.
+	// Draining orders each sender's messages by nonce, so a prefix keeps them
+	// consecutive.
+	if w.messageLimit != nil {
+		max, limited, err := w.messageLimit(blockHeight)
+		if err != nil {
+			return nil, errors.Wrap(err, "get message limit")
+		}
+		if limited && len(selected) > max {
+			selected = selected[:max]
+		}
+	}
+	secpMessages, blsMessages := divideMessages(selected)
 
 	// bls messages are processed first
 	messages := append(blsMessages, secpMessages...)
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
 	"github.com/pkg/errors"
 )
 
@@ -36,6 +37,9 @@ type timingScheduler struct {
 	// pollHeadFunc is the function the scheduler uses to poll for the
 	// current heaviest tipset
 	pollHeadFunc func() (block.TipSet, error)
+	// clock is the clock the scheduler waits on between rounds. It should be
+	// the clock the worker stamps blocks with.
+	clock clock.Clock
 
 	isStarted bool
 }
@@ -77,7 +81,7 @@ func (s *timingScheduler) Start(miningCtx context.Context) (<-chan Output, *sync
 			default:
 			}
 			// This is the sleep during which we collect. TODO: maybe this should vary?
-			time.Sleep(s.mineDelay)
+			s.clock.Sleep(s.mineDelay)
 			// Ask for the heaviest tipset.
 			base, _ := s.pollHeadFunc()
 			if !base.Defined() { // Don't try to mine on an unset head.
@@ -127,9 +131,9 @@ func nextNullBlkCount(prevNullBlkCount uint64, prevBase, currBase block.TipSet)
 }
 
 // NewScheduler returns a new timingScheduler to schedule mining work on the
-// input worker.
-func NewScheduler(w Worker, md time.Duration, f func() (block.TipSet, error)) Scheduler {
-	return &timingScheduler{worker: w, mineDelay: md, pollHeadFunc: f}
+// input worker, waiting on `clk` between rounds.
+func NewScheduler(w Worker, md time.Duration, f func() (block.TipSet, error), clk clock.Clock) Scheduler {
+	return &timingScheduler{worker: w, mineDelay: md, pollHeadFunc: f, clock: clk}
 }
 
 // MineOnce is a convenience function that presents a synchronous blocking
@@ -138,11 +142,11 @@ func NewScheduler(w Worker, md time.Duration, f func() (block.TipSet, error)) Sc
 // It makes a polling function that simply returns the provided tipset.
 // Then the scheduler takes this polling function, and the worker and the
 // mining duration
-func MineOnce(ctx context.Context, w Worker, md time.Duration, ts block.TipSet) (Output, error) {
+func MineOnce(ctx context.Context, w Worker, md time.Duration, ts block.TipSet, clk clock.Clock) (Output, error) {
 	pollHeadFunc := func() (block.TipSet, error) {
 		return ts, nil
 	}
-	s := NewScheduler(w, md, pollHeadFunc)
+	s := NewScheduler(w, md, pollHeadFunc, clk)
 	subCtx, subCtxCancel := context.WithCancel(ctx)
 	defer subCtxCancel()
 
@@ -36,7 +36,7 @@ func TestMineOnce(t *testing.T) {
 
 	// Echoes the sent block to output.
 	worker := NewTestWorkerWithDeps(MakeEchoMine(t))
-	result, err := MineOnce(context.Background(), worker, MineDelayTest, ts)
+	result, err := MineOnce(context.Background(), worker, MineDelayTest, ts, clock.NewSystemClock())
 	assert.NoError(t, err)
 	assert.NoError(t, result.Err)
 	assert.True(t, ts.ToSlice()[0].StateRoot.Equals(result.NewBlock.StateRoot))
@@ -94,7 +94,7 @@ func TestMineOnce10Null(t *testing.T) {
 		Clock:         clock.NewSystemClock(),
 	})
 
-	result, err := MineOnce(context.Background(), worker, MineDelayTest, baseTs)
+	result, err := MineOnce(context.Background(), worker, MineDelayTest, baseTs, clock.NewSystemClock())
 	assert.NoError(t, err)
 	assert.NoError(t, result.Err)
 	block := result.NewBlock
@@ -119,7 +119,7 @@ func TestSchedulerPassesValue(t *testing.T) {
 		return head, nil
 	}
 	worker := NewTestWorkerWithDeps(checkValsMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, clock.NewSystemClock())
 	head = ts // set head so headFunc returns correctly
 	outCh, _ := scheduler.Start(ctx)
 	<-outCh
@@ -139,7 +139,7 @@ func TestSchedulerErrorsOnUnsetHead(t *testing.T) {
 		return block.UndefTipSet, nil
 	}
 	worker := NewTestWorkerWithDeps(nothingMine)
-	scheduler := NewScheduler(worker, MineDelayTest, nilHeadFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, nilHeadFunc, clock.NewSystemClock())
 	outCh, doneWg := scheduler.Start(ctx)
 	output := <-outCh
 	assert.Error(t, output.Err)
@@ -170,7 +170,7 @@ func TestSchedulerUpdatesNullBlkCount(t *testing.T) {
 		return head, nil
 	}
 	worker := NewTestWorkerWithDeps(checkTArrMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, clock.NewSystemClock())
 	head = ts
 	outCh, _ := scheduler.Start(ctx)
 	<-outCh
@@ -207,7 +207,7 @@ func TestSchedulerPassesManyValues(t *testing.T) {
 		return false
 	}
 	worker := NewTestWorkerWithDeps(checkValsMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, clock.NewSystemClock())
 	checkTS = ts1
 	head = ts1
 	outCh, _ := scheduler.Start(ctx)
@@ -243,7 +243,7 @@ func TestSchedulerCollect(t *testing.T) {
 		return false
 	}
 	worker := NewTestWorkerWithDeps(checkValsMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, clock.NewSystemClock())
 	head = ts1
 	outCh, _ := scheduler.Start(ctx)
 	// again this is racing on the assumption that mining delay is long
@@ -275,7 +275,7 @@ func TestSchedulerCancelMiningCtx(t *testing.T) {
 		return false
 	}
 	worker := NewTestWorkerWithDeps(shouldCancelMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, clock.NewSystemClock())
 	head = ts
 	outCh, doneWg := scheduler.Start(miningCtx)
 	miningCtxCancel()
@@ -304,7 +304,7 @@ func TestSchedulerMultiRoundWithCollect(t *testing.T) {
 		return false
 	}
 	worker := NewTestWorkerWithDeps(checkValsMine)
-	scheduler := NewScheduler(worker, MineDelayTest, headFunc)
+	scheduler := NewScheduler(worker, MineDelayTest, headFunc, clock.NewSystemClock())
 	checkTS = ts1
 	head = ts1
 	outCh, doneWg := scheduler.Start(ctx)
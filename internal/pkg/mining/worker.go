@@ -52,6 +52,10 @@ type GetStateTree func(context.Context, block.TipSet) (state.Tree, error)
 // expressed as two uint64s comprising a rational number.
 type GetWeight func(context.Context, block.TipSet) (uint64, error)
 
+// MessageLimit is a function that returns the number of messages a block at a
+// height may carry, and false if the count is unlimited.
+type MessageLimit func(height uint64) (int, bool, error)
+
 // GetAncestors is a function that returns the necessary ancestor chain to
 // process the input tipset.
 type GetAncestors func(context.Context, block.TipSet, *types.BlockHeight) ([]block.TipSet, error)
@@ -100,6 +104,8 @@ type DefaultWorker struct {
 	getAncestors GetAncestors
 	election     electionUtil
 	ticketGen    ticketGenerator
+	// messageLimit, if set, caps the messages selected for a block.
+	messageLimit MessageLimit
 
 	// core filecoin things
 	messageSource MessageSource
@@ -123,6 +129,7 @@ type WorkerParameters struct {
 	GetAncestors GetAncestors
 	Election     electionUtil
 	TicketGen    ticketGenerator
+	MessageLimit MessageLimit
 
 	// core filecoin things
 	MessageSource MessageSource
@@ -148,6 +155,7 @@ func NewDefaultWorker(parameters WorkerParameters) *DefaultWorker {
 		workerSigner:   parameters.WorkerSigner,
 		election:       parameters.Election,
 		ticketGen:      parameters.TicketGen,
+		messageLimit:   parameters.MessageLimit,
 		clock:          parameters.Clock,
 	}
 }
@@ -28,6 +28,7 @@ import (
 	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 )
 
 type mockTicketGen struct {
@@ -229,7 +230,7 @@ func TestApplyMessagesForSuccessTempAndPermFailures(t *testing.T) {
 	smsg2, err := types.NewSignedMessage(*msg2, &mockSigner)
 	require.NoError(t, err)
 
-	// The following two are sending to self -- errSelfSend, a permanent error.
+	// The following two are sending to self -- ErrSelfSend, a permanent error.
 	msg3 := types.NewMeteredMessage(addr1, addr1, 1, types.ZeroAttoFIL, "", nil, types.NewGasPrice(1), types.NewGasUnits(0))
 	smsg3, err := types.NewSignedMessage(*msg3, &mockSigner)
 	require.NoError(t, err)
@@ -544,6 +545,73 @@ func TestGeneratePoolBlockResults(t *testing.T) {
 	assert.Len(t, rcpts, 1)
 }
 
+func TestGenerateRespectsMessageLimit(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	mockSigner, blockSignerAddr := setupSigner()
+	newCid := types.NewCidForTestGetter()
+	st, pool, addrs, bs := sharedSetup(t, mockSigner)
+
+	getStateTree := func(c context.Context, ts block.TipSet) (state.Tree, error) {
+		return st, nil
+	}
+	getAncestors := func(ctx context.Context, ts block.TipSet, newBlockHeight *types.BlockHeight) ([]block.TipSet, error) {
+		return nil, nil
+	}
+	var limitHeight uint64
+	messages := chain.NewMessageStore(bs)
+	worker := mining.NewDefaultWorker(mining.WorkerParameters{
+		API: th.NewDefaultFakeWorkerPorcelainAPI(blockSignerAddr),
+
+		MinerAddr:      addrs[4],
+		MinerOwnerAddr: addrs[3],
+		WorkerSigner:   mockSigner,
+
+		GetStateTree: getStateTree,
+		GetWeight:    getWeightTest,
+		GetAncestors: getAncestors,
+		Election:     &consensus.FakeElectionMachine{},
+		TicketGen:    &consensus.FakeTicketMachine{},
+		MessageLimit: func(height uint64) (int, bool, error) {
+			limitHeight = height
+			return 1, true, nil
+		},
+
+		MessageSource: pool,
+		Processor:     consensus.NewDefaultProcessor(),
+		Blockstore:    bs,
+		MessageStore:  messages,
+		Clock:         th.NewFakeClock(time.Unix(1234567890, 0)),
+	})
+
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		msg := types.NewMeteredMessage(addrs[0], addrs[1], nonce, types.ZeroAttoFIL, "", nil, types.NewGasPrice(1), types.NewGasUnits(0))
+		smsg, err := types.NewSignedMessage(*msg, &mockSigner)
+		require.NoError(t, err)
+		_, err = pool.Add(ctx, smsg, 0)
+		require.NoError(t, err)
+	}
+
+	stateRoot, err := st.Flush(ctx)
+	require.NoError(t, err)
+	baseBlock := block.Block{
+		Parents:       block.NewTipSetKey(newCid()),
+		Height:        types.Uint64(100),
+		StateRoot:     stateRoot,
+		ElectionProof: consensus.MakeFakeElectionProofForTest(),
+	}
+	blk, err := worker.Generate(ctx, th.RequireNewTipSet(t, &baseBlock), block.Ticket{VRFProof: []byte{0}}, consensus.MakeFakeElectionProofForTest(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(101), limitHeight)
+
+	// Only the first of the sender's messages fits.
+	msgs, _, err := messages.LoadMessages(ctx, blk.Messages)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, types.Uint64(0), msgs[0].Message.CallSeqNum)
+}
+
 func TestGenerateSetsBasicFields(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -609,6 +677,83 @@ func TestGenerateSetsBasicFields(t *testing.T) {
 	assert.Equal(t, minerAddr, blk.Miner)
 }
 
+func TestMineOnFakeClockPassesValidation(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	mockSigner, blockSignerAddr := setupSigner()
+	newCid := types.NewCidForTestGetter()
+
+	st, pool, addrs, bs := sharedSetup(t, mockSigner)
+	getStateTree := func(c context.Context, ts block.TipSet) (state.Tree, error) {
+		return st, nil
+	}
+	getAncestors := func(ctx context.Context, ts block.TipSet, newBlockHeight *types.BlockHeight) ([]block.TipSet, error) {
+		return nil, nil
+	}
+
+	genesisTime := time.Unix(1234567890, 0)
+	fakeClock := th.NewFakeClock(genesisTime)
+
+	worker := mining.NewDefaultWorker(mining.WorkerParameters{
+		API: th.NewDefaultFakeWorkerPorcelainAPI(blockSignerAddr),
+
+		MinerAddr:      addrs[4],
+		MinerOwnerAddr: addrs[3],
+		WorkerSigner:   mockSigner,
+
+		GetStateTree: getStateTree,
+		GetWeight:    getWeightTest,
+		GetAncestors: getAncestors,
+		Election:     &consensus.FakeElectionMachine{},
+		TicketGen:    &consensus.FakeTicketMachine{},
+
+		MessageSource: pool,
+		Processor:     consensus.NewDefaultProcessor(),
+		Blockstore:    bs,
+		MessageStore:  chain.NewMessageStore(bs),
+		Clock:         fakeClock,
+	})
+
+	baseBlock := block.Block{
+		Height:        0,
+		StateRoot:     newCid(),
+		Timestamp:     types.Uint64(genesisTime.Unix()),
+		Ticket:        block.Ticket{VRFProof: []byte{0}},
+		ElectionProof: consensus.MakeFakeElectionProofForTest(),
+	}
+	baseTipSet := th.RequireNewTipSet(t, &baseBlock)
+
+	// Mine with no scheduler delay so the only wait is the worker's block
+	// time sleep, which the test releases by advancing the clock.
+	type mineResult struct {
+		out mining.Output
+		err error
+	}
+	resCh := make(chan mineResult, 1)
+	go func() {
+		out, err := mining.MineOnce(ctx, worker, 0, baseTipSet, fakeClock)
+		resCh <- mineResult{out, err}
+	}()
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(th.BlockTimeTest)
+
+	res := <-resCh
+	require.NoError(t, res.err)
+	require.NoError(t, res.out.Err)
+	blk := res.out.NewBlock
+	assert.Equal(t, types.Uint64(genesisTime.Add(th.BlockTimeTest).Unix()), blk.Timestamp)
+
+	pvt, err := version.ConfigureProtocolVersions(version.TEST)
+	require.NoError(t, err)
+	validator := consensus.NewDefaultBlockValidator(th.BlockTimeTest, fakeClock, pvt, nil)
+
+	parentWeight, err := getWeightTest(ctx, baseTipSet)
+	require.NoError(t, err)
+	assert.NoError(t, validator.ValidateSyntax(ctx, blk))
+	assert.NoError(t, validator.ValidateSemantic(ctx, blk, &baseTipSet, parentWeight))
+}
+
 func TestGenerateWithoutMessages(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -0,0 +1,48 @@
+package net
+
+import (
+	"context"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+)
+
+// FetchProgress reports how far a fetch of a chain of tipsets has got.
+type FetchProgress struct {
+	// TipsetsFetched is the number of tipsets fetched so far.
+	TipsetsFetched uint64
+	// BytesFetched is the encoded size of the block headers fetched so far.
+	BytesFetched uint64
+	// CurrentHeight is the height of the tipset fetched most recently.
+	CurrentHeight uint64
+}
+
+// ProgressFetcher is a Fetcher that can report its progress.
+type ProgressFetcher interface {
+	Fetcher
+	// FetchTipSetsWithProgress behaves as FetchTipSets, calling `progress`
+	// once for each tipset fetched, before `done` is evaluated on it.
+	FetchTipSetsWithProgress(context.Context, block.TipSetKey, peer.ID, func(block.TipSet) (bool, error), func(FetchProgress)) ([]block.TipSet, error)
+}
+
+// DoneWithProgress wraps the `done` function passed to FetchTipSets so that
+// it also reports the progress of the fetch to `progress`. Fetchers call
+// `done` exactly once per fetched tipset, so the wrapped function lets any
+// fetcher implement ProgressFetcher.
+func DoneWithProgress(done func(block.TipSet) (bool, error), progress func(FetchProgress)) func(block.TipSet) (bool, error) {
+	var p FetchProgress
+	return func(ts block.TipSet) (bool, error) {
+		height, err := ts.Height()
+		if err != nil {
+			return false, err
+		}
+		p.TipsetsFetched++
+		for i := 0; i < ts.Len(); i++ {
+			p.BytesFetched += uint64(len(ts.At(i).ToNode().RawData()))
+		}
+		p.CurrentHeight = height
+		progress(p)
+		return done(ts)
+	}
+}
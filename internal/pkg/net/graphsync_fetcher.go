@@ -59,6 +59,7 @@ type Fetcher interface {
 
 // interface conformance check
 var _ Fetcher = (*GraphSyncFetcher)(nil)
+var _ ProgressFetcher = (*GraphSyncFetcher)(nil)
 
 // GraphExchange is an interface wrapper to Graphsync so it can be stubbed in
 // unit testing
@@ -131,6 +132,12 @@ func (gsf *GraphSyncFetcher) FetchTipSets(ctx context.Context, tsKey block.TipSe
 	return gsf.fetchTipSetsCommon(ctx, tsKey, originatingPeer, done, gsf.loadAndVerifyFullBlock, gsf.fullBlockSel, gsf.recFullBlockSel)
 }
 
+// FetchTipSetsWithProgress behaves as FetchTipSets, reporting progress after
+// each tipset is fetched.
+func (gsf *GraphSyncFetcher) FetchTipSetsWithProgress(ctx context.Context, tsKey block.TipSetKey, originatingPeer peer.ID, done func(block.TipSet) (bool, error), progress func(FetchProgress)) ([]block.TipSet, error) {
+	return gsf.FetchTipSets(ctx, tsKey, originatingPeer, DoneWithProgress(done, progress))
+}
+
 // FetchTipSetHeaders behaves as FetchTipSets but it only fetches and
 // syntactically validates a chain of headers, not full blocks.
 func (gsf *GraphSyncFetcher) FetchTipSetHeaders(ctx context.Context, tsKey block.TipSetKey, originatingPeer peer.ID, done func(block.TipSet) (bool, error)) ([]block.TipSet, error) {
@@ -59,7 +59,7 @@ func TestGraphsyncFetcher(t *testing.T) {
 	clock := th.NewFakeClock(time.Now())
 	pvt, err := version.ConfigureProtocolVersions(version.TEST)
 	require.NoError(t, err)
-	bv := consensus.NewDefaultBlockValidator(5*time.Millisecond, clock, pvt)
+	bv := consensus.NewDefaultBlockValidator(5*time.Millisecond, clock, pvt, nil)
 	pid0 := th.RequireIntPeerID(t, 0)
 	builder := chain.NewBuilder(t, address.Undef)
 	keys := types.MustGenerateKeyInfo(2, 42)
@@ -745,7 +745,7 @@ func TestHeadersOnlyGraphsyncFetch(t *testing.T) {
 	clock := th.NewFakeClock(time.Now())
 	pvt, err := version.ConfigureProtocolVersions(version.TEST)
 	require.NoError(t, err)
-	bv := consensus.NewDefaultBlockValidator(5*time.Millisecond, clock, pvt)
+	bv := consensus.NewDefaultBlockValidator(5*time.Millisecond, clock, pvt, nil)
 	pid0 := th.RequireIntPeerID(t, 0)
 	builder := chain.NewBuilder(t, address.Undef)
 	keys := types.MustGenerateKeyInfo(1, 42)
@@ -2,6 +2,7 @@ package net
 
 import (
 	"context"
+	"sync"
 
 	logging "github.com/ipfs/go-log"
 	"github.com/libp2p/go-libp2p-core/peer"
@@ -10,12 +11,17 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/metrics"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
 
 var blockTopicLogger = logging.Logger("net/block_validator")
 var mDecodeBlkFail = metrics.NewInt64Counter("net/pubsub_block_decode_failure", "Number of blocks that fail to decode seen on BlockTopic pubsub channel")
 var mInvalidBlk = metrics.NewInt64Counter("net/pubsub_invalid_block", "Number of blocks that fail syntax validation seen on BlockTopic pubsub channel")
 
+var messageTopicLogger = logging.Logger("net/message_validator")
+var mDecodeMsgFail = metrics.NewInt64Counter("net/pubsub_message_decode_failure", "Number of messages that fail to decode seen on MessageTopic pubsub channel")
+var mInvalidMsg = metrics.NewInt64Counter("net/pubsub_invalid_message", "Number of messages that fail validation seen on MessageTopic pubsub channel")
+
 // BlockTopicValidator may be registered on go-libp2p-pubsub to validate pubsub messages on the
 // BlockTopic.
 type BlockTopicValidator struct {
@@ -34,7 +40,13 @@ func NewBlockTopicValidator(bv consensus.BlockSyntaxValidator, opts ...pubsub.Va
 				mDecodeBlkFail.Inc(ctx, 1)
 				return false
 			}
-			if err := bv.ValidateSyntax(ctx, blk); err != nil {
+			err = bv.ValidateSyntax(ctx, blk)
+			if _, future := err.(*consensus.ErrBlockFromFuture); future {
+				// The block is not invalid, and the syncer retries it once
+				// its time comes, so it is passed on.
+				return true
+			}
+			if err != nil {
 				blockTopicLogger.Debugf("block: %s from peer: %s failed to validate: %s", blk.Cid().String(), p.String(), err.Error())
 				mInvalidBlk.Inc(ctx, 1)
 				return false
@@ -58,3 +70,82 @@ func (btv *BlockTopicValidator) Validator() pubsub.Validator {
 func (btv *BlockTopicValidator) Opts() []pubsub.ValidatorOpt {
 	return btv.opts
 }
+
+// MessageValidator validates a signed message received from the network.
+type MessageValidator interface {
+	Validate(ctx context.Context, msg *types.SignedMessage) error
+}
+
+// MessageTopicValidator may be registered on go-libp2p-pubsub to validate pubsub messages on the
+// MessageTopic. Pubsub only forwards messages that pass validation, so the validator also counts,
+// per peer, the messages received and the messages accepted for forwarding.
+type MessageTopicValidator struct {
+	validator pubsub.Validator
+	opts      []pubsub.ValidatorOpt
+
+	lk        sync.Mutex
+	received  map[peer.ID]uint64
+	forwarded map[peer.ID]uint64
+}
+
+// NewMessageTopicValidator returns a MessageTopicValidator using `mv` for message validation
+func NewMessageTopicValidator(mv MessageValidator, opts ...pubsub.ValidatorOpt) *MessageTopicValidator {
+	mtv := &MessageTopicValidator{
+		opts:      opts,
+		received:  make(map[peer.ID]uint64),
+		forwarded: make(map[peer.ID]uint64),
+	}
+	mtv.validator = func(ctx context.Context, p peer.ID, msg *pubsub.Message) bool {
+		mtv.count(mtv.received, p)
+		unmarshaled := &types.SignedMessage{}
+		if err := unmarshaled.Unmarshal(msg.GetData()); err != nil {
+			messageTopicLogger.Debugf("message from peer: %s failed to decode: %s", p.String(), err.Error())
+			mDecodeMsgFail.Inc(ctx, 1)
+			return false
+		}
+		if err := mv.Validate(ctx, unmarshaled); err != nil {
+			messageTopicLogger.Debugf("message: %s from peer: %s failed to validate: %s", unmarshaled.String(), p.String(), err.Error())
+			mInvalidMsg.Inc(ctx, 1)
+			return false
+		}
+		mtv.count(mtv.forwarded, p)
+		return true
+	}
+	return mtv
+}
+
+// Topic returns the topic string MessageTopic
+func (mtv *MessageTopicValidator) Topic(network string) string {
+	return MessageTopic(network)
+}
+
+// Validator returns a validation method matching the Validator pubsub function signature.
+func (mtv *MessageTopicValidator) Validator() pubsub.Validator {
+	return mtv.validator
+}
+
+// Opts returns the pubsub ValidatorOpts the MessageTopicValidator is configured to use.
+func (mtv *MessageTopicValidator) Opts() []pubsub.ValidatorOpt {
+	return mtv.opts
+}
+
+// Received returns the number of messages received from peer `p`.
+func (mtv *MessageTopicValidator) Received(p peer.ID) uint64 {
+	mtv.lk.Lock()
+	defer mtv.lk.Unlock()
+	return mtv.received[p]
+}
+
+// Forwarded returns the number of messages received from peer `p` that passed
+// validation and so were accepted for forwarding to other peers.
+func (mtv *MessageTopicValidator) Forwarded(p peer.ID) uint64 {
+	mtv.lk.Lock()
+	defer mtv.lk.Unlock()
+	return mtv.forwarded[p]
+}
+
+func (mtv *MessageTopicValidator) count(counts map[peer.ID]uint64, p peer.ID) {
+	mtv.lk.Lock()
+	defer mtv.lk.Unlock()
+	counts[p]++
+}
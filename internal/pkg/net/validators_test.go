@@ -67,7 +67,7 @@ func TestBlockPubSubValidation(t *testing.T) {
 	pvt, err := version.ConfigureProtocolVersions(version.TEST)
 	require.NoError(t, err)
 
-	bv := consensus.NewDefaultBlockValidator(blocktime, mclock, pvt)
+	bv := consensus.NewDefaultBlockValidator(blocktime, mclock, pvt, nil)
 	btv := net.NewBlockTopicValidator(bv)
 
 	// setup a floodsub instance on the host and register the topic validator
@@ -134,6 +134,55 @@ func TestBlockPubSubValidation(t *testing.T) {
 	assert.Equal(t, validBlk.Cid().String(), maybeBlk.Cid().String())
 }
 
+func TestMessageTopicValidator(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	signer, _ := types.NewMockSignersAndKeyInfo(2)
+	newMsg := types.NewSignedMessageForTestGetter(signer)
+	goodMsg := newMsg()
+	badMsg := newMsg()
+
+	mv := &stubMessageValidator{invalid: badMsg}
+	tv := net.NewMessageTopicValidator(mv)
+	validator := tv.Validator()
+	pid1 := th.RequireIntPeerID(t, 1)
+	pid2 := th.RequireIntPeerID(t, 2)
+
+	network := "go-filecoin-test"
+	assert.Equal(t, net.MessageTopic(network), tv.Topic(network))
+	assert.True(t, validator(ctx, pid1, msgToPubSub(t, goodMsg)))
+	assert.False(t, validator(ctx, pid1, msgToPubSub(t, badMsg)))
+	assert.False(t, validator(ctx, pid2, nonBlkPubSubMsg()))
+
+	assert.Equal(t, uint64(2), tv.Received(pid1))
+	assert.Equal(t, uint64(1), tv.Forwarded(pid1))
+	assert.Equal(t, uint64(1), tv.Received(pid2))
+	assert.Equal(t, uint64(0), tv.Forwarded(pid2))
+}
+
+type stubMessageValidator struct {
+	invalid *types.SignedMessage
+}
+
+func (mv *stubMessageValidator) Validate(_ context.Context, msg *types.SignedMessage) error {
+	if msg.Equals(mv.invalid) {
+		return fmt.Errorf("invalid message")
+	}
+	return nil
+}
+
+// convert a types.SignedMessage to a pubsub message
+func msgToPubSub(t *testing.T, msg *types.SignedMessage) *pubsub.Message {
+	data, err := msg.Marshal()
+	require.NoError(t, err)
+	return &pubsub.Message{
+		Message: &pubsub_pb.Message{
+			Data: data,
+		},
+	}
+}
+
 // convert a types.Block to a pubsub message
 func blkToPubSub(blk *block.Block) *pubsub.Message {
 	pbm := &pubsub_pb.Message{
@@ -6,6 +6,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/mining"
 	"github.com/pkg/errors"
 )
@@ -22,6 +23,7 @@ type API struct {
 	chainReader     miningChainReader
 	isMiningFunc    func() bool
 	mineDelay       time.Duration
+	clock           clock.Clock
 	setupMiningFunc func(context.Context) error
 	startMiningFunc func(context.Context) error
 	stopMiningFunc  func(context.Context)
@@ -35,6 +37,7 @@ func New(
 	chainReader miningChainReader,
 	isMiningFunc func() bool,
 	blockMineDelay time.Duration,
+	clk clock.Clock,
 	setupMiningFunc func(ctx context.Context) error,
 	startMiningFunc func(context.Context) error,
 	stopMiningfunc func(context.Context),
@@ -46,6 +49,7 @@ func New(
 		chainReader:     chainReader,
 		isMiningFunc:    isMiningFunc,
 		mineDelay:       blockMineDelay,
+		clock:           clk,
 		setupMiningFunc: setupMiningFunc,
 		startMiningFunc: startMiningFunc,
 		stopMiningFunc:  stopMiningfunc,
@@ -80,7 +84,7 @@ func (a *API) MiningOnce(ctx context.Context) (*block.Block, error) {
 		return nil, err
 	}
 
-	res, err := mining.MineOnce(ctx, miningWorker, a.mineDelay, ts)
+	res, err := mining.MineOnce(ctx, miningWorker, a.mineDelay, ts, a.clock)
 	if err != nil {
 		return nil, err
 	}
@@ -145,6 +145,7 @@ func newAPI(t *testing.T) (bapi.API, *node.Node) {
 		nd.Chain().ChainReader,
 		nd.IsMining,
 		bt,
+		nd.Clock,
 		nd.SetupMining,
 		nd.StartMining,
 		nd.StopMining,
@@ -10,6 +10,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/abi"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/message"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
 
@@ -29,14 +30,7 @@ type monitorPlumbing interface {
 
 // slashingMsgOutbox is the interface for the functionality of Outbox FaultSlasher needs
 type slashingMsgOutbox interface {
-	Send(ctx context.Context,
-		from, to address.Address,
-		value types.AttoFIL,
-		gasPrice types.AttoFIL,
-		gasLimit types.GasUnits,
-		bcast bool,
-		method string,
-		params ...interface{}) (out cid.Cid, err error)
+	SendBatch(ctx context.Context, from address.Address, msgs []message.BatchMessage, bcast bool) ([]cid.Cid, error)
 }
 
 // FaultSlasher checks for unreported storage faults by miners, a.k.a. market faults.
@@ -108,7 +102,10 @@ func (sfm *FaultSlasher) Slash(ctx context.Context, currentHeight *types.BlockHe
 	}
 	sfm.log.Debugf("there are %d late miners", len(*lms))
 
-	// Slash late miners.
+	// Slash late miners, with messages signed and added to the message pool
+	// in one batch.
+	var slashing []string
+	var msgs []message.BatchMessage
 	for lateMinerActor, state := range *lms {
 		if _, ok := sfm.slashed[lateMinerActor]; ok {
 			// Skip slashed miner.
@@ -134,14 +131,25 @@ func (sfm *FaultSlasher) Slash(ctx context.Context, currentHeight *types.BlockHe
 			continue
 		}
 
-		// add slash message to message pull w/o broadcasting
 		sfm.log.Debugf("Slashing %s with state %d", lateMinerActorAddr, state)
+		slashing = append(slashing, lateMinerActor)
+		msgs = append(msgs, message.BatchMessage{
+			To:       lateMinerActorAddr,
+			Value:    types.ZeroAttoFIL,
+			GasPrice: sfm.gasPrice,
+			GasLimit: sfm.gasLimit,
+			Method:   "slashStorageFault",
+		})
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
 
-		_, err = sfm.outbox.Send(ctx, myWorkerAddr, lateMinerActorAddr, types.ZeroAttoFIL, sfm.gasPrice,
-			sfm.gasLimit, false, "slashStorageFault")
-		if err != nil {
-			return errors.Wrap(err, "slashStorageFault message failed")
-		}
+	// add slash messages to message pool w/o broadcasting
+	if _, err := sfm.outbox.SendBatch(ctx, myWorkerAddr, msgs, false); err != nil {
+		return errors.Wrap(err, "slashStorageFault message failed")
+	}
+	for _, lateMinerActor := range slashing {
 		sfm.slashed[lateMinerActor] = struct{}{}
 	}
 	return nil
@@ -15,6 +15,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/actor/builtin/miner"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/message"
 	. "github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
@@ -113,6 +114,7 @@ func TestFaultSlasher_Slash(t *testing.T) {
 		err = fm.Slash(ctx, height)
 		assert.NoError(t, err)
 		assert.Equal(t, 3, ob.msgCount)
+		assert.Equal(t, 1, ob.batches)
 	})
 
 	t.Run("slashes miner only once", func(t *testing.T) {
@@ -327,34 +329,29 @@ type outbox struct {
 	failSend bool
 	failErr  string
 	msgCount int
+	batches  int
 }
 
-func (ob *outbox) Send(ctx context.Context,
-	from, to address.Address,
-	value types.AttoFIL,
-	gasPrice types.AttoFIL,
-	gasLimit types.GasUnits,
-	bcast bool,
-	method string,
-	params ...interface{}) (out cid.Cid, err error) {
-
-	_, err = abi.ToEncodedValues(params...)
-	if err != nil {
-		return cid.Undef, err
-	}
+func (ob *outbox) SendBatch(ctx context.Context, from address.Address, msgs []message.BatchMessage, bcast bool) ([]cid.Cid, error) {
+	for _, msg := range msgs {
+		if _, err := abi.ToEncodedValues(msg.Params...); err != nil {
+			return nil, err
+		}
 
-	if gasPrice.LessEqual(types.ZeroAttoFIL) {
-		return cid.Undef, errors.New("gas price must be >0")
-	}
+		if msg.GasPrice.LessEqual(types.ZeroAttoFIL) {
+			return nil, errors.New("gas price must be >0")
+		}
 
-	if gasLimit < types.Uint64(300) {
-		return cid.Undef, errors.New("gas limit must be >= 300")
+		if msg.GasLimit < types.Uint64(300) {
+			return nil, errors.New("gas limit must be >= 300")
+		}
 	}
 
 	if ob.failSend {
-		return cid.Undef, errors.New(ob.failErr)
+		return nil, errors.New(ob.failErr)
 	}
-	ob.msgCount++
-	// we ignore the CID returned from Send anyway
-	return cid.Undef, nil
+	ob.msgCount += len(msgs)
+	ob.batches++
+	// we ignore the CIDs returned from SendBatch anyway
+	return make([]cid.Cid, len(msgs)), nil
 }
@@ -162,12 +162,10 @@ func (sm *Miner) handleMakeDeal(s inet.Stream) {
 // receiveStorageProposal is the entry point for the miner storage protocol
 func (sm *Miner) receiveStorageProposal(ctx context.Context, sp *storagedeal.SignedProposal) (*storagedeal.SignedResponse, error) {
 	// Validate deal signature
-	bdp, err := sp.Proposal.Marshal()
-	if err != nil {
-		return nil, err
-	}
-
-	if !types.IsValidSignature(bdp, sp.Payment.Payer, sp.Signature) {
+	if err := sp.Verify(types.DefaultVerifier{}); err != nil {
+		if errors.Cause(err) != storagedeal.ErrInvalidSignature {
+			return nil, err
+		}
 		return sm.rejectProposal(ctx, sp, fmt.Sprint("invalid deal signature"))
 	}
 
@@ -478,6 +476,13 @@ func processStorageDeal(ctx context.Context, sm *Miner, proposalCid cid.Cid) {
 		return
 	}
 
+	// The proposal's price and the sector's capacity were computed from the
+	// declared size, so it must be the size of the data received.
+	if err := d.Proposal.ValidateSize(types.NewBytesAmount(r.Size())); err != nil {
+		fail("invalid proposal", fmt.Sprintf("failed to add piece: %s", err))
+		return
+	}
+
 	// There is a race here that requires us to use dealsAwaitingSeal below. If the
 	// sector gets sealed and OnCommitmentSent is called right after
 	// AddPiece returns but before we record the sector/deal mapping we might
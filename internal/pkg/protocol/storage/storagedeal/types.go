@@ -2,12 +2,21 @@ package storagedeal
 
 import (
 	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
 
+// ErrProposalSizeMismatch is returned when a proposal's size differs from the
+// size of the piece it references.
+var ErrProposalSizeMismatch = errors.New("proposal size does not match piece size")
+
+// ErrInvalidSignature is returned when a signed proposal or response fails
+// verification.
+var ErrInvalidSignature = errors.New("invalid signature")
+
 // PaymentInfo contains all the payment related information for a storage deal.
 type PaymentInfo struct {
 	// PayChActor is the address of the payment channel actor
@@ -63,6 +72,18 @@ func (dp *Proposal) Marshal() ([]byte, error) {
 	return encoding.Encode(dp)
 }
 
+// ValidateSize checks that the proposal's declared size is the actual size of
+// the referenced piece.
+func (dp *Proposal) ValidateSize(actualSize *types.BytesAmount) error {
+	if dp.Size == nil {
+		return errors.Wrapf(ErrProposalSizeMismatch, "proposal declares no size, piece %s is %s bytes", dp.PieceRef, actualSize)
+	}
+	if !dp.Size.Equal(actualSize) {
+		return errors.Wrapf(ErrProposalSizeMismatch, "proposal declares %s bytes, piece %s is %s bytes", dp.Size, dp.PieceRef, actualSize)
+	}
+	return nil
+}
+
 // NewSignedProposal signs Proposal with address `addr` and returns a SignedProposal.
 func (dp *Proposal) NewSignedProposal(addr address.Address, signer types.Signer) (*SignedProposal, error) {
 	data, err := dp.Marshal()
@@ -87,6 +108,18 @@ type SignedProposal struct {
 	Signature types.Signature
 }
 
+// Verify checks that the proposal was signed by the client paying for it.
+func (sp *SignedProposal) Verify(verifier types.Verifier) error {
+	data, err := sp.Proposal.Marshal()
+	if err != nil {
+		return err
+	}
+	if !verifier.VerifySignature(data, sp.Payment.Payer, sp.Signature) {
+		return errors.Wrapf(ErrInvalidSignature, "proposal not signed by client %s", sp.Payment.Payer)
+	}
+	return nil
+}
+
 // Response is the information sent over the wire, when a miner responds to a client.
 type Response struct {
 	// State is the current state of this deal
@@ -132,6 +165,19 @@ func (r *SignedResponse) VerifySignature(addr address.Address) (bool, error) {
 	return types.IsValidSignature(respBytes, addr, r.Signature), nil
 }
 
+// Verify checks that the response was signed by `expectedSigner`, the miner's
+// worker.
+func (r *SignedResponse) Verify(verifier types.Verifier, expectedSigner address.Address) error {
+	data, err := encoding.Encode(r.Response)
+	if err != nil {
+		return err
+	}
+	if !verifier.VerifySignature(data, expectedSigner, r.Signature) {
+		return errors.Wrapf(ErrInvalidSignature, "response not signed by miner %s", expectedSigner)
+	}
+	return nil
+}
+
 // Deal is a storage deal struct
 type Deal struct {
 	Miner    address.Address
@@ -0,0 +1,83 @@
+package storagedeal_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/storagedeal"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func TestProposalValidateSize(t *testing.T) {
+	tf.UnitTest(t)
+
+	proposal := &storagedeal.Proposal{
+		PieceRef: types.NewCidForTestGetter()(),
+		Size:     types.NewBytesAmount(1024),
+	}
+
+	assert.NoError(t, proposal.ValidateSize(types.NewBytesAmount(1024)))
+
+	err := proposal.ValidateSize(types.NewBytesAmount(1000))
+	assert.Equal(t, storagedeal.ErrProposalSizeMismatch, errors.Cause(err))
+
+	proposal.Size = nil
+	err = proposal.ValidateSize(types.NewBytesAmount(1024))
+	assert.Equal(t, storagedeal.ErrProposalSizeMismatch, errors.Cause(err))
+}
+
+func TestSignedProposalVerify(t *testing.T) {
+	tf.UnitTest(t)
+
+	signer, _ := types.NewMockSignersAndKeyInfo(2)
+	client, other := signer.Addresses[0], signer.Addresses[1]
+	proposal := &storagedeal.Proposal{
+		PieceRef:   types.NewCidForTestGetter()(),
+		Size:       types.NewBytesAmount(1024),
+		TotalPrice: types.NewAttoFILFromFIL(1),
+		Payment:    storagedeal.PaymentInfo{Payer: client},
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		sp, err := proposal.NewSignedProposal(client, signer)
+		require.NoError(t, err)
+		assert.NoError(t, sp.Verify(types.DefaultVerifier{}))
+	})
+
+	t.Run("signed by other than the client", func(t *testing.T) {
+		sp, err := proposal.NewSignedProposal(other, signer)
+		require.NoError(t, err)
+		assert.Equal(t, storagedeal.ErrInvalidSignature, errors.Cause(sp.Verify(types.DefaultVerifier{})))
+	})
+
+	t.Run("tampered signature", func(t *testing.T) {
+		sp, err := proposal.NewSignedProposal(client, signer)
+		require.NoError(t, err)
+		sp.Signature[0] ^= 0xff
+		assert.Equal(t, storagedeal.ErrInvalidSignature, errors.Cause(sp.Verify(types.DefaultVerifier{})))
+	})
+}
+
+func TestSignedResponseVerify(t *testing.T) {
+	tf.UnitTest(t)
+
+	signer, _ := types.NewMockSignersAndKeyInfo(2)
+	miner, other := signer.Addresses[0], signer.Addresses[1]
+	resp := &storagedeal.SignedResponse{
+		Response: storagedeal.Response{
+			State:       storagedeal.Accepted,
+			ProposalCid: types.NewCidForTestGetter()(),
+		},
+	}
+	require.NoError(t, resp.Sign(signer, miner))
+
+	assert.NoError(t, resp.Verify(types.DefaultVerifier{}, miner))
+	assert.Equal(t, storagedeal.ErrInvalidSignature, errors.Cause(resp.Verify(types.DefaultVerifier{}, other)))
+
+	resp.Signature[0] ^= 0xff
+	assert.Equal(t, storagedeal.ErrInvalidSignature, errors.Cause(resp.Verify(types.DefaultVerifier{}, miner)))
+}
@@ -40,6 +40,12 @@ const (
 		"minPeerThreshold": 0,
 		"period": "1m"
 	},
+	"chain": {
+		"blockFutureWindow": "0s",
+		"maxReorgDepth": 900,
+		"messageIndexRetention": 2880,
+		"reorgWeightMargin": 0
+	},
 	"datastore": {
 		"type": "badgerds",
 		"path": "badger"
@@ -57,7 +63,12 @@ const (
 	},
 	"mpool": {
 		"maxPoolSize": 10000,
-		"maxNonceGap": "100"
+		"maxNonceGap": "100",
+		"maxAgeTipsets": 6,
+		"persistPending": false,
+		"minGasPrice": "0",
+		"maxMessageSize": 0,
+		"disableBalanceCheck": false
 	},
 	"observability": {
 		"metrics": {
@@ -3,6 +3,7 @@ package state
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-hamt-ipld"
@@ -100,6 +101,20 @@ func (t *tree) Flush(ctx context.Context) (cid.Cid, error) {
 	return t.store.Put(ctx, t.root)
 }
 
+// Copy returns a copy of `t` over the same store, so changes made to either
+// tree are not seen by the other. Changes pending in `t` are flushed first.
+func Copy(ctx context.Context, t Tree) (Tree, error) {
+	st, ok := t.(*tree)
+	if !ok {
+		return nil, errors.Errorf("cannot copy state tree of type %T", t)
+	}
+	root, err := st.Flush(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return LoadStateTree(ctx, st.store, root)
+}
+
 // IsActorNotFoundError is true of the error returned by
 // GetActorCode when no actor was found at the given address.
 func IsActorNotFoundError(err error) bool {
@@ -233,41 +248,97 @@ func GetAllActors(ctx context.Context, t Tree) <-chan GetAllActorsResult {
 	out := make(chan GetAllActorsResult)
 	go func() {
 		defer close(out)
-		st.getActorsFromPointers(ctx, out, st.root.Pointers)
+		st.getActorsFromPointers(ctx, out, st.root.Pointers, nil)
+	}()
+	return out
+}
+
+// GetAllActorsWithCode returns a channel which provides the actors in the
+// StateTree, t, having any of the given codes.
+func GetAllActorsWithCode(ctx context.Context, t Tree, codes ...cid.Cid) <-chan GetAllActorsResult {
+	st := t.(*tree)
+	out := make(chan GetAllActorsResult)
+	keep := func(a *actor.Actor) bool {
+		for _, code := range codes {
+			if code.Equals(a.Code) {
+				return true
+			}
+		}
+		return false
+	}
+	go func() {
+		defer close(out)
+		st.getActorsFromPointers(ctx, out, st.root.Pointers, keep)
 	}()
 	return out
 }
 
 // NOTE: This extracts actors from pointers recursively. Maybe we shouldn't recurse here.
-func (t *tree) getActorsFromPointers(ctx context.Context, out chan<- GetAllActorsResult, ps []*hamt.Pointer) {
+// Only actors for which keep returns true are sent, or all actors if keep is
+// nil. It returns false once the context is done, having sent the context's
+// error if the consumer is still receiving, so that the producer never blocks
+// on a consumer that has gone away.
+func (t *tree) getActorsFromPointers(ctx context.Context, out chan<- GetAllActorsResult, ps []*hamt.Pointer, keep func(*actor.Actor) bool) bool {
 	for _, p := range ps {
 		for _, kv := range p.KVs {
 			var a actor.Actor
 			if err := encoding.Decode(kv.Value.Raw, &a); err != nil {
 				panic(err) // uhm, ignoring errors is bad
 			}
+			if keep != nil && !keep(&a) {
+				continue
+			}
 
 			select {
 			case <-ctx.Done():
-				out <- GetAllActorsResult{
-					Error: ctx.Err(),
-				}
-				return
-			default:
-				out <- GetAllActorsResult{
-					Address: kv.Key,
-					Actor:   &a,
+				select {
+				case out <- GetAllActorsResult{Error: ctx.Err()}:
+				default:
 				}
+				return false
+			case out <- GetAllActorsResult{Address: kv.Key, Actor: &a}:
 			}
 		}
 		if p.Link.Defined() {
-			n, err := hamt.LoadNode(context.Background(), t.store, p.Link, hamt.UseTreeBitWidth(TreeBitWidth))
+			n, err := hamt.LoadNode(ctx, t.store, p.Link, hamt.UseTreeBitWidth(TreeBitWidth))
 			// Even if we hit an error and can't follow this link, we should
 			// keep traversing its siblings.
 			if err != nil {
 				continue
 			}
-			t.getActorsFromPointers(ctx, out, n.Pointers)
+			if !t.getActorsFromPointers(ctx, out, n.Pointers, keep) {
+				return false
+			}
 		}
 	}
+	return true
+}
+
+// GetActorsPage returns up to `limit` actors of the StateTree, t, with
+// addresses following `cursor` in address order, and the cursor from which
+// to list the next page. The first page is listed from an empty cursor and
+// the next cursor is empty after the last page. Listing a page visits every
+// actor in the tree.
+func GetActorsPage(ctx context.Context, t Tree, cursor string, limit int) ([]GetAllActorsResult, string, error) {
+	if limit <= 0 {
+		return nil, "", errors.Errorf("invalid page limit %d", limit)
+	}
+
+	var page []GetAllActorsResult
+	err := t.ForEachActor(ctx, func(addr address.Address, a *actor.Actor) error {
+		if key := addr.String(); key > cursor {
+			page = append(page, GetAllActorsResult{Address: key, Actor: a})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sort.Slice(page, func(i, j int) bool { return page[i].Address < page[j].Address })
+	if len(page) <= limit {
+		return page, "", nil
+	}
+	page = page[:limit]
+	return page, page[limit-1].Address, nil
 }
@@ -3,7 +3,10 @@ package state
 import (
 	"context"
 	"fmt"
+	"runtime"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-hamt-ipld"
@@ -117,6 +120,33 @@ func TestStateGetOrCreate(t *testing.T) {
 	})
 }
 
+func TestCopy(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+	tree := NewEmptyStateTree(cst)
+	addrGetter := address.NewForTestGetter()
+	addr1, addr2 := addrGetter(), addrGetter()
+	require.NoError(t, tree.SetActor(ctx, addr1, actor.NewActor(types.AccountActorCodeCid, types.ZeroAttoFIL)))
+
+	cp, err := Copy(ctx, tree)
+	require.NoError(t, err)
+	_, err = cp.GetActor(ctx, addr1)
+	require.NoError(t, err)
+
+	// Changes to the copy are not seen by the original, and vice versa.
+	require.NoError(t, cp.SetActor(ctx, addr2, actor.NewActor(types.AccountActorCodeCid, types.ZeroAttoFIL)))
+	_, err = tree.GetActor(ctx, addr2)
+	assert.True(t, IsActorNotFoundError(err))
+
+	act1 := actor.NewActor(types.AccountActorCodeCid, types.NewAttoFILFromFIL(1))
+	require.NoError(t, tree.SetActor(ctx, addr1, act1))
+	act1out, err := cp.GetActor(ctx, addr1)
+	require.NoError(t, err)
+	assert.Equal(t, types.ZeroAttoFIL, act1out.Balance)
+}
+
 func TestGetAllActors(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -140,3 +170,109 @@ func TestGetAllActors(t *testing.T) {
 		assert.Equal(t, actor.Balance, result.Actor.Balance)
 	}
 }
+
+func TestGetAllActorsStopsOnCancel(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+	tree := NewEmptyStateTree(cst)
+	addrGetter := address.NewForTestGetter()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, tree.SetActor(ctx, addrGetter(), &actor.Actor{Code: types.AccountActorCodeCid, Balance: types.NewAttoFILFromFIL(1)}))
+	}
+	_, err := tree.Flush(ctx)
+	require.NoError(t, err)
+
+	baseline := runtime.NumGoroutine()
+
+	listCtx, cancel := context.WithCancel(ctx)
+	results := GetAllActors(listCtx, tree)
+	first := <-results
+	require.NoError(t, first.Error)
+	cancel()
+
+	// The producer exits without the consumer draining the channel.
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, runtime.NumGoroutine() <= baseline, "actor producer goroutine leaked")
+}
+
+func TestGetAllActorsWithCode(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+	tree := NewEmptyStateTree(cst)
+	addrGetter := address.NewForTestGetter()
+
+	miners := make(map[string]struct{})
+	for i := 0; i < 6; i++ {
+		addr := addrGetter()
+		code := types.AccountActorCodeCid
+		if i%2 == 0 {
+			code = types.MinerActorCodeCid
+			miners[addr.String()] = struct{}{}
+		}
+		require.NoError(t, tree.SetActor(ctx, addr, &actor.Actor{Code: code, Balance: types.NewAttoFILFromFIL(1)}))
+	}
+	_, err := tree.Flush(ctx)
+	require.NoError(t, err)
+
+	found := make(map[string]struct{})
+	for result := range GetAllActorsWithCode(ctx, tree, types.MinerActorCodeCid) {
+		require.NoError(t, result.Error)
+		assert.True(t, types.MinerActorCodeCid.Equals(result.Actor.Code))
+		found[result.Address] = struct{}{}
+	}
+	assert.Equal(t, miners, found)
+}
+
+func TestGetActorsPage(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	cst := hamt.NewCborStore()
+	tree := NewEmptyStateTree(cst)
+	addrGetter := address.NewForTestGetter()
+
+	const actorCount, limit = 7, 4
+	expected := make(map[string]struct{})
+	for i := 0; i < actorCount; i++ {
+		addr := addrGetter()
+		require.NoError(t, tree.SetActor(ctx, addr, &actor.Actor{Code: types.AccountActorCodeCid, Nonce: types.Uint64(i), Balance: types.NewAttoFILFromFIL(1)}))
+		expected[addr.String()] = struct{}{}
+	}
+	_, err := tree.Flush(ctx)
+	require.NoError(t, err)
+
+	first, cursor, err := GetActorsPage(ctx, tree, "", limit)
+	require.NoError(t, err)
+	assert.Len(t, first, limit)
+	assert.NotEmpty(t, cursor)
+
+	second, cursor, err := GetActorsPage(ctx, tree, cursor, limit)
+	require.NoError(t, err)
+	assert.Len(t, second, actorCount-limit)
+	assert.Empty(t, cursor)
+
+	// Together the pages list every actor exactly once, in address order.
+	var listed []string
+	for _, result := range append(first, second...) {
+		require.NoError(t, result.Error)
+		listed = append(listed, result.Address)
+	}
+	assert.True(t, sort.StringsAreSorted(listed))
+	assert.Len(t, listed, actorCount)
+	for _, addr := range listed {
+		_, ok := expected[addr]
+		assert.True(t, ok)
+		delete(expected, addr)
+	}
+	assert.Empty(t, expected)
+
+	_, _, err = GetActorsPage(ctx, tree, "", 0)
+	assert.Error(t, err)
+}
@@ -3,10 +3,18 @@ package syncer
 import (
 	"container/heap"
 	"context"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	logging "github.com/ipfs/go-log"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 )
 
 var log = logging.Logger("sync.dispatch")
@@ -17,25 +25,99 @@ const DefaultInQueueSize = 5
 // DefaultWorkQueueSize is the size of the work queue
 const DefaultWorkQueueSize = 20
 
+// DefaultFollowDistance is the greatest number of rounds a target may be ahead
+// of the local head to be synced in FollowMode.  It leaves room for a few null
+// rounds between the head and a newly announced tipset.
+const DefaultFollowDistance = 3
+
+// DefaultCompletedWindow is the time during which a target head that was
+// successfully synced is not synced again if re-announced.
+const DefaultCompletedWindow = 10 * time.Second
+
+// ErrImplausibleHeight is returned when a chain info claims a height greater
+// than the chain could have reached.
+var ErrImplausibleHeight = errors.New("chain info claims implausible height")
+
+// SyncMode is the mode of operation in which the dispatcher syncs a target.
+type SyncMode int
+
+const (
+	// CatchupMode is used for targets far ahead of the local head.  The
+	// target's chain is trusted and fetched in bulk.
+	CatchupMode SyncMode = iota
+	// FollowMode is used for targets within DefaultFollowDistance of the
+	// local head, as when the node is caught up and handling newly
+	// announced tipsets.  The target's chain is not trusted.
+	FollowMode
+)
+
+func (m SyncMode) String() string {
+	switch m {
+	case CatchupMode:
+		return "CHAIN_CATCHUP"
+	case FollowMode:
+		return "CHAIN_FOLLOW"
+	default:
+		return "UNKNOWN"
+	}
+}
+
 // syncer is the interface of the logic syncing incoming chains
 type syncer interface {
-	HandleNewTipSet(context.Context, *block.ChainInfo, bool) error
+	HandleNewTipSetWithResult(context.Context, *block.ChainInfo, bool) (chain.SyncResult, error)
+}
+
+// DispatcherObserver is notified of the lifecycle of each sync target.
+// OnEnqueue is called from the goroutine sending the target; OnStart and
+// OnComplete are called from the dispatcher's goroutine.
+type DispatcherObserver interface {
+	// OnEnqueue is called when a target is received by the dispatcher.
+	OnEnqueue(Target)
+	// OnStart is called when the dispatcher begins syncing a target.
+	OnStart(Target)
+	// OnComplete is called when syncing a target finishes, with the result
+	// and error returned by the syncer.
+	OnComplete(Target, chain.SyncResult, error)
 }
 
+// nopObserver is the DispatcherObserver used when none is provided.
+type nopObserver struct{}
+
+func (nopObserver) OnEnqueue(Target)                           {}
+func (nopObserver) OnStart(Target)                             {}
+func (nopObserver) OnComplete(Target, chain.SyncResult, error) {}
+
 // NewDispatcher creates a new syncing dispatcher with default queue sizes.
-func NewDispatcher(catchupSyncer syncer) *Dispatcher {
-	return NewDispatcherWithSizes(catchupSyncer, DefaultWorkQueueSize, DefaultInQueueSize)
+// The observer may be nil.
+func NewDispatcher(catchupSyncer syncer, observer DispatcherObserver) *Dispatcher {
+	return NewDispatcherWithSizes(catchupSyncer, observer, DefaultWorkQueueSize, DefaultInQueueSize)
 }
 
-// NewDispatcherWithSizes creates a new syncing dispatcher.
-func NewDispatcherWithSizes(catchupSyncer syncer, workQueueSize, inQueueSize int) *Dispatcher {
+// NewDispatcherWithSizes creates a new syncing dispatcher. The observer may be
+// nil.
+func NewDispatcherWithSizes(catchupSyncer syncer, observer DispatcherObserver, workQueueSize, inQueueSize int) *Dispatcher {
+	return NewDispatcherWithQueue(catchupSyncer, observer, NewTargetQueue(), workQueueSize, inQueueSize)
+}
+
+// NewDispatcherWithQueue creates a new syncing dispatcher ordering its work
+// with the given target queue, e.g. one created by NewFairTargetQueue. The
+// observer may be nil.
+func NewDispatcherWithQueue(catchupSyncer syncer, observer DispatcherObserver, workQueue *TargetQueue, workQueueSize, inQueueSize int) *Dispatcher {
+	if observer == nil {
+		observer = nopObserver{}
+	}
 	return &Dispatcher{
-		workQueue:     NewTargetQueue(),
+		workQueue:     workQueue,
 		workQueueSize: workQueueSize,
 		catchupSyncer: catchupSyncer,
+		observer:      observer,
 		incoming:      make(chan Target, inQueueSize),
 		control:       make(chan interface{}, 1),
+		stopped:       make(chan struct{}),
+		claims:        make(map[string]uint64),
 		registeredCb:  func(t Target) {},
+		failureLog:    newRateLimitedLogger(log.Infof, clock.NewSystemClock(), DefaultLogInterval),
+		clock:         clock.NewSystemClock(),
 	}
 }
 
@@ -45,6 +127,16 @@ type cbMessage struct {
 	cb func(Target)
 }
 
+// timeoutMessage sets the time allowed for syncing a single target.
+type timeoutMessage struct {
+	timeout time.Duration
+}
+
+// drainMessage requests that done be closed once the dispatcher is idle.
+type drainMessage struct {
+	done chan struct{}
+}
+
 // Dispatcher receives, sorts and dispatches targets to the syncer to control
 // chain syncing.
 //
@@ -54,8 +146,10 @@ type cbMessage struct {
 // to sync the target using its internal catchupSyncer.
 //
 // The dispatcher has a simple control channel. It reads this for external
-// controls. Currently there is only one kind of control message.  It registers
-// a callback that the dispatcher will call after every non-erroring sync.
+// controls. One kind of control message registers a callback that the
+// dispatcher will call after every non-erroring sync.  Another sets a timeout
+// after which the sync of a single target is cancelled and counted as a stall.
+// A third waits for all queued targets to be processed.
 type Dispatcher struct {
 	// workQueue is a priority queue of target chain heads that should be
 	// synced
@@ -64,17 +158,79 @@ type Dispatcher struct {
 	// incoming is the queue of incoming sync targets to the dispatcher.
 	incoming chan Target
 	// catchupSyncer is used for dispatching sync targets for chain heads
-	// during the CHAIN_CATCHUP mode of operation
+	// in both the CHAIN_CATCHUP and CHAIN_FOLLOW modes of operation.  The
+	// mode determines whether the target is trusted.
 	catchupSyncer syncer
+	// observer is notified of target lifecycle events.
+	observer DispatcherObserver
 
 	// registeredCb is a callback registered over the control channel.  It
 	// is called after every successful sync.
 	registeredCb func(Target)
 	// control is a queue of control messages not yet processed.
 	control chan interface{}
+	// drainWaiters are closed the next time the dispatcher is idle.
+	drainWaiters []chan struct{}
+	// started is set when the dispatcher is started.  It is accessed
+	// atomically.
+	started uint32
+	// stopped is closed once the dispatcher has stopped.
+	stopped chan struct{}
 
 	// syncTargetCount counts the number of successful syncs.
 	syncTargetCount uint64
+
+	// syncTimeout bounds the time spent syncing a single target.  Zero means
+	// no bound.  It is set over the control channel.
+	syncTimeout time.Duration
+	// stalls counts the syncs cancelled for exceeding syncTimeout.  It is
+	// accessed atomically.
+	stalls uint64
+	// claimsMu protects claims and syncedHeight, which are accessed outside
+	// the dispatcher's goroutine.
+	claimsMu sync.Mutex
+	// claims maps the head of each target received and not yet synced,
+	// failed, dropped or marked bad to the height it claims.
+	claims map[string]uint64
+	// syncedHeight is the greatest height of a target synced successfully.
+	syncedHeight uint64
+
+	// targetStore, if set, persists queued targets across restarts.
+	targetStore *TargetStore
+	// headHeight reports the current chain height for pruning loaded targets
+	// and choosing the mode of each sync.  If nil all syncs use CatchupMode.
+	headHeight func() (uint64, error)
+	// maxHeight reports the greatest height a target may plausibly claim.
+	// If nil targets are not checked.
+	maxHeight func() (uint64, error)
+
+	// currentMu protects current.
+	currentMu sync.Mutex
+	// current is the target being synced by the catchupSyncer, or nil if
+	// the dispatcher is idle.  It is guarded by a mutex rather than read
+	// over the control channel because the control channel is not serviced
+	// while a sync is in progress.
+	current *Target
+
+	// failureLog logs failed syncs and unknown control messages without
+	// flooding the log when they repeat.
+	failureLog *rateLimitedLogger
+
+	// completedWindow is the time during which a successfully synced head
+	// is not synced again.  Zero disables the suppression.
+	completedWindow time.Duration
+	// clock times the completed window and deferred targets.
+	clock clock.Clock
+	// completed maps the heads synced within completedWindow to the time
+	// their sync finished.  It is only accessed by the dispatcher's
+	// goroutine.
+	completed map[string]time.Time
+
+	// badMu protects badHeads.
+	badMu sync.Mutex
+	// badHeads are the heads marked bad since the dispatcher last removed
+	// them from the work queue.
+	badHeads []block.TipSetKey
 }
 
 // SendHello handles chain information from bootstrap peers.
@@ -87,14 +243,125 @@ func (d *Dispatcher) SendOwnBlock(ci *block.ChainInfo) error { return d.enqueue(
 func (d *Dispatcher) SendGossipBlock(ci *block.ChainInfo) error { return d.enqueue(ci) }
 
 func (d *Dispatcher) enqueue(ci *block.ChainInfo) error {
-	d.incoming <- Target{ChainInfo: *ci}
+	if err := d.checkPlausible(ci); err != nil {
+		return err
+	}
+	t := Target{ChainInfo: *ci}
+	d.addClaim(t)
+	d.observer.OnEnqueue(t)
+	d.incoming <- t
+	return nil
+}
+
+// UseTargetStore configures the dispatcher to load targets saved in `store`
+// on Start and to save its outstanding targets when the syncing context is
+// done. Loaded targets at or below the head height configured with
+// UseHeadHeight are pruned. It must be called before Start.
+func (d *Dispatcher) UseTargetStore(store *TargetStore) {
+	d.targetStore = store
+}
+
+// UseHeadHeight configures the dispatcher to sync targets within
+// DefaultFollowDistance of the height reported by `headHeight` in FollowMode,
+// and to prune saved targets at or below it.  Without it every target is
+// synced in CatchupMode.  It must be called before Start.
+func (d *Dispatcher) UseHeadHeight(headHeight func() (uint64, error)) {
+	d.headHeight = headHeight
+}
+
+// UseFailureLogger configures the dispatcher to log failed syncs and unknown
+// control messages with `logf`, logging each kind at most once per `interval`
+// according to `clk`.  By default they are logged at info level at most once
+// per DefaultLogInterval.  It must be called before Start.
+func (d *Dispatcher) UseFailureLogger(logf func(format string, args ...interface{}), clk clock.Clock, interval time.Duration) {
+	d.failureLog = newRateLimitedLogger(logf, clk, interval)
+}
+
+// UseMaxHeight configures the dispatcher to reject chain infos claiming a
+// height above that reported by `maxHeight`, e.g. one derived from
+// consensus.MaxPlausibleHeight.  It must be called before any chain info is
+// sent.
+func (d *Dispatcher) UseMaxHeight(maxHeight func() (uint64, error)) {
+	d.maxHeight = maxHeight
+}
+
+// UseCompletedWindow configures the dispatcher to drop targets whose head was
+// successfully synced less than `window` ago according to `clk`, so that
+// heads re-announced by peers flapping between chains are not synced
+// repeatedly. This is distinct from the TargetQueue's suppression of
+// duplicate queued targets.  It must be called before Start.
+func (d *Dispatcher) UseCompletedWindow(window time.Duration, clk clock.Clock) {
+	d.completedWindow = window
+	d.clock = clk
+	d.completed = make(map[string]time.Time)
+}
+
+// UseClock configures the dispatcher to time deferred targets, and the
+// completed window if any, with `clk` rather than the system clock.  It must
+// be called before Start.
+func (d *Dispatcher) UseClock(clk clock.Clock) {
+	d.clock = clk
+}
+
+// MarkBad cancels any queued target with head `head`, which the syncer has
+// found to be bad, so that it is not popped and synced in vain.  It may be
+// called from any goroutine, including from within the syncer while the
+// dispatcher is syncing; the target is removed before the next pop.
+func (d *Dispatcher) MarkBad(head block.TipSetKey) {
+	d.dropClaim(head)
+	d.badMu.Lock()
+	defer d.badMu.Unlock()
+	d.badHeads = append(d.badHeads, head)
+}
+
+// removeBadTargets removes the targets with heads marked bad from the work
+// queue.
+func (d *Dispatcher) removeBadTargets() {
+	d.badMu.Lock()
+	heads := d.badHeads
+	d.badHeads = nil
+	d.badMu.Unlock()
+	for _, head := range heads {
+		if d.workQueue.Remove(head) {
+			log.Debugf("removed bad target %s", head.String())
+		}
+	}
+}
+
+// checkPlausible returns ErrImplausibleHeight if the chain info claims a
+// height beyond the maximum.  Chain infos are accepted if the maximum is
+// unavailable.
+func (d *Dispatcher) checkPlausible(ci *block.ChainInfo) error {
+	if d.maxHeight == nil {
+		return nil
+	}
+	max, err := d.maxHeight()
+	if err != nil {
+		log.Warnf("failed to get max plausible height, accepting %s: %s", ci.String(), err)
+		return nil
+	}
+	if ci.Height > max {
+		return errors.Wrapf(ErrImplausibleHeight, "%s above max height %d", ci.String(), max)
+	}
 	return nil
 }
 
 // Start launches the business logic for the syncing subsystem.
 func (d *Dispatcher) Start(syncingCtx context.Context) {
+	atomic.StoreUint32(&d.started, 1)
+	d.loadTargets()
 	go func() {
 		var last *Target
+		defer func() {
+			if last != nil {
+				d.workQueue.Push(*last)
+			}
+			for _, t := range d.drainIncoming() {
+				d.workQueue.Push(t)
+			}
+			d.saveTargets()
+			close(d.stopped)
+		}()
 		for {
 			// Handle shutdown
 			select {
@@ -125,33 +392,181 @@ func (d *Dispatcher) Start(syncingCtx context.Context) {
 			for _, syncTarget := range ws {
 				// Drop targets we don't have room for
 				if d.workQueue.Len() >= d.workQueueSize {
-					break
+					d.dropClaim(syncTarget.Head)
+					continue
+				}
+				// Drop targets synced moments ago
+				if d.recentlyCompleted(syncTarget) {
+					log.Debugf("dropping recently synced target %s", syncTarget.ChainInfo.String())
+					d.dropClaim(syncTarget.Head)
+					continue
 				}
 				// Sort new targets by putting on work queue.
 				d.workQueue.Push(syncTarget)
 			}
 
+			// Cancel targets marked bad while queued
+			d.removeBadTargets()
+
 			// Check for work to do
 			syncTarget, popped := d.workQueue.Pop()
 			if popped {
 				// Do work
-				err := d.catchupSyncer.HandleNewTipSet(syncingCtx, &syncTarget.ChainInfo, true)
-				if err != nil {
-					log.Info("sync request could not complete: %s", err)
-				}
-				d.syncTargetCount++
-				d.registeredCb(syncTarget)
+				d.syncTarget(syncingCtx, syncTarget)
 			} else {
-				// No work left, block until something shows up
+				// No work left, release anyone waiting for a drain and
+				// block until something shows up
+				if len(d.incoming) == 0 {
+					d.releaseDrainWaiters()
+				}
 				select {
 				case extra := <-d.incoming:
 					last = &extra
+				case ctrl := <-d.control:
+					d.processCtrl(ctrl)
+				case <-syncingCtx.Done():
+					return
 				}
 			}
 		}
 	}()
 }
 
+// Stopped returns a channel that is closed once the dispatcher has stopped
+// after its syncing context is done, having saved its outstanding targets if
+// it uses a target store. The channel is closed already if the dispatcher was
+// never started.
+func (d *Dispatcher) Stopped() <-chan struct{} {
+	if atomic.LoadUint32(&d.started) == 0 {
+		stopped := make(chan struct{})
+		close(stopped)
+		return stopped
+	}
+	return d.stopped
+}
+
+func (d *Dispatcher) loadTargets() {
+	if d.targetStore == nil {
+		return
+	}
+	h := uint64(0)
+	if d.headHeight != nil {
+		var err error
+		h, err = d.headHeight()
+		if err != nil {
+			log.Errorf("failed to get head height, not loading saved sync targets: %s", err)
+			return
+		}
+	}
+	if err := d.targetStore.Load(d.workQueue, h); err != nil {
+		log.Errorf("failed to load saved sync targets: %s", err)
+	}
+}
+
+func (d *Dispatcher) saveTargets() {
+	if d.targetStore == nil {
+		return
+	}
+	if err := d.targetStore.Save(d.workQueue); err != nil {
+		log.Errorf("failed to save sync targets: %s", err)
+	}
+}
+
+// syncTarget runs the catchupSyncer on a single target, cancelling it if it
+// takes longer than the sync timeout.  The syncer is expected to return
+// promptly once its context is cancelled.
+func (d *Dispatcher) syncTarget(syncingCtx context.Context, syncTarget Target) {
+	ctx, cancel := syncingCtx, context.CancelFunc(func() {})
+	if d.syncTimeout > 0 {
+		ctx, cancel = context.WithTimeout(syncingCtx, d.syncTimeout)
+	}
+	defer cancel()
+
+	syncTarget.Mode = d.modeFor(syncTarget)
+	d.setCurrent(&syncTarget)
+	d.observer.OnStart(syncTarget)
+	result, err := d.catchupSyncer.HandleNewTipSetWithResult(ctx, &syncTarget.ChainInfo, syncTarget.Mode == CatchupMode)
+	d.setCurrent(nil)
+	if ctx.Err() == context.DeadlineExceeded {
+		atomic.AddUint64(&d.stalls, 1)
+		log.Warnf("sync of target %s stalled after %s", syncTarget.ChainInfo.String(), d.syncTimeout)
+	}
+	d.observer.OnComplete(syncTarget, result, err)
+	if future, ok := errors.Cause(err).(*consensus.ErrBlockFromFuture); ok {
+		d.deferTarget(syncingCtx, syncTarget, future.ValidAt)
+	} else if err != nil {
+		d.failureLog.Logf("sync failure", "sync request could not complete: %s", err)
+		d.dropClaim(syncTarget.Head)
+	}
+	if err == nil {
+		d.recordCompleted(syncTarget)
+		d.settleClaim(syncTarget)
+	}
+	d.syncTargetCount++
+	d.registeredCb(syncTarget)
+}
+
+// deferTarget sends `t` to the dispatcher again once `validAt` has passed.
+// It is used for a target whose chain holds a block from the future: the
+// block is not invalid, so the target is retried rather than dropped.
+func (d *Dispatcher) deferTarget(ctx context.Context, t Target, validAt time.Time) {
+	log.Debugf("deferring target %s with block from the future until %s", t.ChainInfo.String(), validAt)
+	after := d.clock.After(validAt.Sub(d.clock.Now()))
+	go func() {
+		select {
+		case <-after:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case d.incoming <- t:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// recentlyCompleted returns true if the target's head was successfully synced
+// within the completed window.
+func (d *Dispatcher) recentlyCompleted(t Target) bool {
+	if d.completedWindow == 0 {
+		return false
+	}
+	at, ok := d.completed[t.ChainInfo.Head.String()]
+	return ok && d.clock.Since(at) < d.completedWindow
+}
+
+// recordCompleted notes the target's head as synced now, forgetting heads
+// synced outside the completed window.
+func (d *Dispatcher) recordCompleted(t Target) {
+	if d.completedWindow == 0 {
+		return
+	}
+	now := d.clock.Now()
+	for head, at := range d.completed {
+		if now.Sub(at) >= d.completedWindow {
+			delete(d.completed, head)
+		}
+	}
+	d.completed[t.ChainInfo.Head.String()] = now
+}
+
+// modeFor returns the mode in which to sync the target given the current head
+// height.
+func (d *Dispatcher) modeFor(t Target) SyncMode {
+	if d.headHeight == nil {
+		return CatchupMode
+	}
+	h, err := d.headHeight()
+	if err != nil {
+		log.Errorf("failed to get head height, syncing %s in %s: %s", t.ChainInfo.String(), CatchupMode, err)
+		return CatchupMode
+	}
+	if t.Height <= h+DefaultFollowDistance {
+		return FollowMode
+	}
+	return CatchupMode
+}
+
 func (d *Dispatcher) drainIncoming() []Target {
 	// drainProduced reads all values within the incoming channel buffer at time
 	// of calling without blocking.  It reads at most incomingBufferSize.
@@ -166,24 +581,124 @@ func (d *Dispatcher) drainIncoming() []Target {
 	return produced
 }
 
+// addClaim records the height claimed by `t`.
+func (d *Dispatcher) addClaim(t Target) {
+	d.claimsMu.Lock()
+	defer d.claimsMu.Unlock()
+	d.claims[t.Head.String()] = t.Height
+}
+
+// dropClaim forgets the height claimed by the target with head `head`, which
+// will not be synced.
+func (d *Dispatcher) dropClaim(head block.TipSetKey) {
+	d.claimsMu.Lock()
+	defer d.claimsMu.Unlock()
+	delete(d.claims, head.String())
+}
+
+// settleClaim replaces the height claimed by `t`, which was synced, with the
+// synced height.
+func (d *Dispatcher) settleClaim(t Target) {
+	d.claimsMu.Lock()
+	defer d.claimsMu.Unlock()
+	delete(d.claims, t.Head.String())
+	if t.Height > d.syncedHeight {
+		d.syncedHeight = t.Height
+	}
+}
+
+// HighestTarget returns the greatest height of a target the dispatcher has
+// synced or has yet to sync, or zero if there is none. The heights claimed by
+// targets that failed to sync, were dropped or were marked bad are not
+// counted, so a peer cannot inflate it with an invalid chain.
+func (d *Dispatcher) HighestTarget() uint64 {
+	d.claimsMu.Lock()
+	defer d.claimsMu.Unlock()
+	highest := d.syncedHeight
+	for _, h := range d.claims {
+		if h > highest {
+			highest = h
+		}
+	}
+	return highest
+}
+
+// CurrentTarget returns the target currently being synced. The second
+// return value is false if the dispatcher is idle.
+func (d *Dispatcher) CurrentTarget() (*Target, bool) {
+	d.currentMu.Lock()
+	defer d.currentMu.Unlock()
+	if d.current == nil {
+		return nil, false
+	}
+	current := *d.current
+	return &current, true
+}
+
+func (d *Dispatcher) setCurrent(t *Target) {
+	d.currentMu.Lock()
+	defer d.currentMu.Unlock()
+	d.current = t
+}
+
 // RegisterCallback registers a callback on the dispatcher that
 // will fire after every successful target sync.
 func (d *Dispatcher) RegisterCallback(cb func(Target)) {
 	d.control <- cbMessage{cb: cb}
 }
 
+// SetSyncTimeout sets the time allowed for syncing a single target before the
+// sync is cancelled and recorded as a stall.  A zero timeout disables the
+// bound.
+func (d *Dispatcher) SetSyncTimeout(timeout time.Duration) {
+	d.control <- timeoutMessage{timeout: timeout}
+}
+
+// Drain blocks until the dispatcher has no queued targets and no sync in
+// progress, or until ctx is done. Targets sent before Drain is called are
+// processed (or dropped if the work queue is full) before it returns nil.
+// The dispatcher must be started for Drain to return before ctx is done.
+func (d *Dispatcher) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case d.control <- drainMessage{done: done}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) releaseDrainWaiters() {
+	for _, done := range d.drainWaiters {
+		close(done)
+	}
+	d.drainWaiters = nil
+}
+
+// Stalls returns the number of target syncs cancelled for exceeding the sync
+// timeout.
+func (d *Dispatcher) Stalls() uint64 {
+	return atomic.LoadUint64(&d.stalls)
+}
+
 func (d *Dispatcher) processCtrl(ctrlMsg interface{}) {
 	// processCtrl takes a control message, determines its type, and performs the
 	// specified action.
-	//
-	// Using interfaces is overkill for now but is the way to make this
-	// extensible.  (Delete this comment if we add more than one control)
 	switch typedMsg := ctrlMsg.(type) {
 	case cbMessage:
 		d.registeredCb = typedMsg.cb
+	case timeoutMessage:
+		d.syncTimeout = typedMsg.timeout
+	case drainMessage:
+		d.drainWaiters = append(d.drainWaiters, typedMsg.done)
 	default:
 		// We don't know this type, log and ignore
-		log.Info("dispatcher control can not handle type %T", typedMsg)
+		d.failureLog.Logf("unknown control", "dispatcher control can not handle type %T", typedMsg)
 	}
 }
 
@@ -191,19 +706,41 @@ func (d *Dispatcher) processCtrl(ctrlMsg interface{}) {
 // syncing job against given inputs.
 type Target struct {
 	block.ChainInfo
+	// Mode is the mode in which the target is synced.  It is set by the
+	// dispatcher when the sync starts.
+	Mode SyncMode
+}
+
+// SameTarget returns true if `t` and `other` sync to the same head, in which
+// case one is a duplicate of the other. The claimed height and source peer
+// are ignored since they do not change the chain synced.
+func (t Target) SameTarget(other Target) bool {
+	return t.ChainInfo.Head.Equals(other.ChainInfo.Head)
 }
 
 // TargetQueue orders dispatcher syncRequests by the underlying `targetQueue`'s
 // prioritization policy.
 //
-// It also filters the `targetQueue` so that it always contains targets with
-// unique chain heads.
+// It also filters the `targetQueue` so that it never contains two targets
+// that are the SameTarget.
+//
+// A fair TargetQueue additionally interleaves targets from distinct peers:
+// within a round, each peer with queued targets has its highest priority
+// target popped once, in priority order, before any peer is served again.
+// This prevents a single peer announcing many heads from starving others.
 //
 // It wraps the `targetQueue` to prevent panics during
 // normal operation.
 type TargetQueue struct {
-	q         targetQueue
-	targetSet map[string]struct{}
+	q targetQueue
+
+	fair bool
+	// byPeer holds a queue per peer when fair.
+	byPeer map[peer.ID]*targetQueue
+	// served tracks the peers already popped from in the current round.
+	served map[peer.ID]struct{}
+	// count is the number of targets held in byPeer.
+	count int
 }
 
 // NewTargetQueue returns a new target queue.
@@ -211,36 +748,151 @@ func NewTargetQueue() *TargetQueue {
 	rq := make(targetQueue, 0)
 	heap.Init(&rq)
 	return &TargetQueue{
-		q:         rq,
-		targetSet: make(map[string]struct{}),
+		q: rq,
 	}
 }
 
+// NewFairTargetQueue returns a new target queue that interleaves targets
+// across their source peers.
+func NewFairTargetQueue() *TargetQueue {
+	tq := NewTargetQueue()
+	tq.fair = true
+	tq.byPeer = make(map[peer.ID]*targetQueue)
+	tq.served = make(map[peer.ID]struct{})
+	return tq
+}
+
 // Push adds a sync target to the target queue.
 func (tq *TargetQueue) Push(t Target) {
 	// If already in queue drop quickly
-	if _, inQ := tq.targetSet[t.ChainInfo.Head.String()]; inQ {
+	if tq.has(t) {
 		return
 	}
-	heap.Push(&tq.q, t)
-	tq.targetSet[t.ChainInfo.Head.String()] = struct{}{}
+	if tq.fair {
+		pq, ok := tq.byPeer[t.Peer]
+		if !ok {
+			rq := make(targetQueue, 0)
+			pq = &rq
+			tq.byPeer[t.Peer] = pq
+		}
+		heap.Push(pq, t)
+		tq.count++
+	} else {
+		heap.Push(&tq.q, t)
+	}
 	return
 }
 
+// has returns true if a target that is the same as `t` is queued.
+func (tq *TargetQueue) has(t Target) bool {
+	for _, queued := range tq.targets() {
+		if queued.SameTarget(t) {
+			return true
+		}
+	}
+	return false
+}
+
 // Pop removes and returns the highest priority syncing target. If there is
 // nothing in the queue the second argument returns false
 func (tq *TargetQueue) Pop() (Target, bool) {
 	if tq.Len() == 0 {
 		return Target{}, false
 	}
-	req := heap.Pop(&tq.q).(Target)
-	popKey := req.ChainInfo.Head.String()
-	delete(tq.targetSet, popKey)
+	var req Target
+	if tq.fair {
+		req = tq.popFair()
+	} else {
+		req = heap.Pop(&tq.q).(Target)
+	}
 	return req, true
 }
 
+// Remove removes the queued target with head `head`. It returns false if no
+// such target is queued.
+func (tq *TargetQueue) Remove(head block.TipSetKey) bool {
+	target := Target{ChainInfo: block.ChainInfo{Head: head}}
+	if !tq.fair {
+		return removeFrom(&tq.q, target)
+	}
+	for p, pq := range tq.byPeer {
+		if removeFrom(pq, target) {
+			if pq.Len() == 0 {
+				delete(tq.byPeer, p)
+			}
+			tq.count--
+			return true
+		}
+	}
+	return false
+}
+
+// removeFrom removes the target that is the same as `target` from `rq`,
+// returning false if it holds none.
+func removeFrom(rq *targetQueue, target Target) bool {
+	for i, t := range *rq {
+		if t.SameTarget(target) {
+			heap.Remove(rq, i)
+			return true
+		}
+	}
+	return false
+}
+
+// popFair pops the highest priority target among peers not yet served this
+// round, starting a new round once every peer has been served.
+// The queue must not be empty.
+func (tq *TargetQueue) popFair() Target {
+	next, found := tq.nextUnservedPeer()
+	if !found {
+		tq.served = make(map[peer.ID]struct{})
+		next, _ = tq.nextUnservedPeer()
+	}
+
+	pq := tq.byPeer[next]
+	req := heap.Pop(pq).(Target)
+	if pq.Len() == 0 {
+		delete(tq.byPeer, next)
+	}
+	tq.served[next] = struct{}{}
+	tq.count--
+	return req
+}
+
+func (tq *TargetQueue) nextUnservedPeer() (peer.ID, bool) {
+	var next peer.ID
+	found := false
+	for p, pq := range tq.byPeer {
+		if _, done := tq.served[p]; done {
+			continue
+		}
+		// Ties are broken by peer ID to keep popping deterministic.
+		if !found || (*pq)[0].Height > (*tq.byPeer[next])[0].Height ||
+			((*pq)[0].Height == (*tq.byPeer[next])[0].Height && p < next) {
+			next = p
+			found = true
+		}
+	}
+	return next, found
+}
+
+// targets returns all targets in the queue in no particular order.
+func (tq *TargetQueue) targets() []Target {
+	if !tq.fair {
+		return append([]Target{}, tq.q...)
+	}
+	var all []Target
+	for _, pq := range tq.byPeer {
+		all = append(all, *pq...)
+	}
+	return all
+}
+
 // Len returns the number of targets in the queue.
 func (tq *TargetQueue) Len() int {
+	if tq.fair {
+		return tq.count
+	}
 	return tq.q.Len()
 }
 
@@ -2,15 +2,25 @@ package syncer_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p-core/peer"
+	pkgerrors "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/syncer"
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/util/moresync"
 )
@@ -19,9 +29,9 @@ type mockSyncer struct {
 	headsCalled []block.TipSetKey
 }
 
-func (fs *mockSyncer) HandleNewTipSet(ctx context.Context, ci *block.ChainInfo, t bool) error {
+func (fs *mockSyncer) HandleNewTipSetWithResult(ctx context.Context, ci *block.ChainInfo, t bool) (chain.SyncResult, error) {
 	fs.headsCalled = append(fs.headsCalled, ci.Head)
-	return nil
+	return chain.SyncResult{}, nil
 }
 
 func TestDispatchStartHappy(t *testing.T) {
@@ -29,7 +39,7 @@ func TestDispatchStartHappy(t *testing.T) {
 	s := &mockSyncer{
 		headsCalled: make([]block.TipSetKey, 0),
 	}
-	testDispatch := syncer.NewDispatcher(s)
+	testDispatch := syncer.NewDispatcher(s, nil)
 
 	cis := []*block.ChainInfo{
 		// We need to put these in priority order to avoid a race.
@@ -70,7 +80,7 @@ func TestDispatcherDropsWhenFull(t *testing.T) {
 	}
 	testWorkSize := 20
 	testBufferSize := 30
-	testDispatch := syncer.NewDispatcherWithSizes(s, testWorkSize, testBufferSize)
+	testDispatch := syncer.NewDispatcherWithSizes(s, nil, testWorkSize, testBufferSize)
 
 	finished := moresync.NewLatch(1)
 	testDispatch.RegisterCallback(func(target syncer.Target) {
@@ -97,6 +107,484 @@ func TestDispatcherDropsWhenFull(t *testing.T) {
 	finished.Wait()
 }
 
+func TestDispatcherDrain(t *testing.T) {
+	tf.UnitTest(t)
+	s := &mockSyncer{
+		headsCalled: make([]block.TipSetKey, 0),
+	}
+	testDispatch := syncer.NewDispatcher(s, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	testDispatch.Start(ctx)
+
+	// nothing queued drains immediately
+	require.NoError(t, testDispatch.Drain(ctx))
+
+	cis := []*block.ChainInfo{
+		chainInfoFromHeight(t, 8),
+		chainInfoFromHeight(t, 6),
+		chainInfoFromHeight(t, 4),
+		chainInfoFromHeight(t, 2),
+	}
+	for _, ci := range cis {
+		require.NoError(t, testDispatch.SendHello(ci))
+	}
+	require.NoError(t, testDispatch.Drain(ctx))
+
+	// all targets were synced before Drain returned
+	require.Equal(t, len(cis), len(s.headsCalled))
+	for _, ci := range cis {
+		assert.Contains(t, s.headsCalled, ci.Head)
+	}
+	_, busy := testDispatch.CurrentTarget()
+	assert.False(t, busy)
+}
+
+func TestDispatcherDrainContextCancelled(t *testing.T) {
+	tf.UnitTest(t)
+	s := &blockingSyncer{
+		started: make(chan block.TipSetKey),
+		release: make(chan struct{}),
+	}
+	testDispatch := syncer.NewDispatcher(s, nil)
+	testDispatch.Start(context.Background())
+
+	require.NoError(t, testDispatch.SendHello(chainInfoFromHeight(t, 3)))
+	<-s.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, testDispatch.Drain(ctx))
+	close(s.release)
+}
+
+func TestDispatcherSuppressesRecentlyCompleted(t *testing.T) {
+	tf.UnitTest(t)
+	s := &mockSyncer{
+		headsCalled: make([]block.TipSetKey, 0),
+	}
+	fc := th.NewFakeClock(time.Unix(1234567890, 0))
+	testDispatch := syncer.NewDispatcher(s, nil)
+	testDispatch.UseCompletedWindow(10*time.Second, fc)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	testDispatch.Start(ctx)
+
+	a := chainInfoFromHeight(t, 5)
+	b := chainInfoFromHeight(t, 6)
+	sendAndDrain := func(ci *block.ChainInfo) {
+		require.NoError(t, testDispatch.SendHello(ci))
+		require.NoError(t, testDispatch.Drain(ctx))
+	}
+
+	// the head flaps from a to b and back to a within the window
+	sendAndDrain(a)
+	sendAndDrain(b)
+	fc.Advance(9 * time.Second)
+	sendAndDrain(a)
+	assert.Equal(t, []block.TipSetKey{a.Head, b.Head}, s.headsCalled)
+
+	// once the window passes a is synced again
+	fc.Advance(time.Second)
+	sendAndDrain(a)
+	assert.Equal(t, []block.TipSetKey{a.Head, b.Head, a.Head}, s.headsCalled)
+}
+
+// badMarkingSyncer marks a head bad with the dispatcher while syncing its
+// first target, as the chain syncer does on finding a bad tipset.
+type badMarkingSyncer struct {
+	dispatcher  *syncer.Dispatcher
+	bad         block.TipSetKey
+	headsCalled []block.TipSetKey
+}
+
+func (bs *badMarkingSyncer) HandleNewTipSetWithResult(ctx context.Context, ci *block.ChainInfo, t bool) (chain.SyncResult, error) {
+	if len(bs.headsCalled) == 0 {
+		bs.dispatcher.MarkBad(bs.bad)
+	}
+	bs.headsCalled = append(bs.headsCalled, ci.Head)
+	return chain.SyncResult{}, nil
+}
+
+func TestDispatcherRemovesBadTargets(t *testing.T) {
+	tf.UnitTest(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bad := chainInfoFromHeight(t, 5)
+	s := &badMarkingSyncer{bad: bad.Head}
+	testDispatch := syncer.NewDispatcher(s, nil)
+	s.dispatcher = testDispatch
+
+	// All targets are queued before the first is synced.
+	for _, ci := range []*block.ChainInfo{chainInfoFromHeight(t, 9), bad, chainInfoFromHeight(t, 3)} {
+		require.NoError(t, testDispatch.SendHello(ci))
+	}
+	testDispatch.Start(ctx)
+	require.NoError(t, testDispatch.Drain(ctx))
+
+	assert.Equal(t, []block.TipSetKey{chainInfoFromHeight(t, 9).Head, chainInfoFromHeight(t, 3).Head}, s.headsCalled)
+}
+
+// futureSyncer fails its first sync with a block from the future, valid at
+// validAt, and succeeds after. It sends the head of each sync on synced.
+type futureSyncer struct {
+	validAt time.Time
+	calls   int
+	synced  chan block.TipSetKey
+}
+
+func (fs *futureSyncer) HandleNewTipSetWithResult(ctx context.Context, ci *block.ChainInfo, t bool) (chain.SyncResult, error) {
+	fs.calls++
+	fs.synced <- ci.Head
+	if fs.calls == 1 {
+		return chain.SyncResult{}, pkgerrors.Wrap(&consensus.ErrBlockFromFuture{ValidAt: fs.validAt}, "invalid block")
+	}
+	return chain.SyncResult{}, nil
+}
+
+func TestDispatcherDefersBlocksFromFuture(t *testing.T) {
+	tf.UnitTest(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fc := th.NewFakeClock(time.Unix(1234567890, 0))
+	s := &futureSyncer{validAt: fc.Now().Add(10 * time.Second), synced: make(chan block.TipSetKey, 2)}
+	testDispatch := syncer.NewDispatcher(s, nil)
+	testDispatch.UseClock(fc)
+	ci := chainInfoFromHeight(t, 3)
+	require.NoError(t, testDispatch.SendHello(ci))
+	testDispatch.Start(ctx)
+	require.NoError(t, testDispatch.Drain(ctx))
+	assert.Equal(t, ci.Head, <-s.synced)
+	select {
+	case <-s.synced:
+		t.Fatal("target retried before its block's time")
+	default:
+	}
+
+	// The target is synced again once the block's time comes.
+	fc.Advance(10 * time.Second)
+	select {
+	case head := <-s.synced:
+		assert.Equal(t, ci.Head, head)
+	case <-ctx.Done():
+		t.Fatal("target from the future was not retried")
+	}
+}
+
+func TestDispatcherHighestTarget(t *testing.T) {
+	tf.UnitTest(t)
+	testDispatch := syncer.NewDispatcher(&mockSyncer{}, nil)
+	assert.Equal(t, uint64(0), testDispatch.HighestTarget())
+
+	require.NoError(t, testDispatch.SendHello(chainInfoFromHeight(t, 7)))
+	require.NoError(t, testDispatch.SendGossipBlock(chainInfoFromHeight(t, 3)))
+	assert.Equal(t, uint64(7), testDispatch.HighestTarget())
+
+	require.NoError(t, testDispatch.SendOwnBlock(chainInfoFromHeight(t, 9)))
+	assert.Equal(t, uint64(9), testDispatch.HighestTarget())
+
+	// A claim found bad no longer counts.
+	testDispatch.MarkBad(chainInfoFromHeight(t, 9).Head)
+	assert.Equal(t, uint64(7), testDispatch.HighestTarget())
+}
+
+func TestDispatcherHighestTargetAfterSync(t *testing.T) {
+	tf.UnitTest(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	failing := chainInfoFromHeight(t, 12)
+	testDispatch := syncer.NewDispatcher(&failingSyncer{fail: failing.Head}, nil)
+
+	// Enqueue before starting so both targets are synced in order.
+	require.NoError(t, testDispatch.SendHello(failing))
+	require.NoError(t, testDispatch.SendHello(chainInfoFromHeight(t, 8)))
+	testDispatch.Start(ctx)
+	require.NoError(t, testDispatch.Drain(ctx))
+
+	// The synced target's height remains and the failed claim is dropped.
+	assert.Equal(t, uint64(8), testDispatch.HighestTarget())
+}
+
+type alwaysFailingSyncer struct{}
+
+func (alwaysFailingSyncer) HandleNewTipSetWithResult(ctx context.Context, ci *block.ChainInfo, t bool) (chain.SyncResult, error) {
+	return chain.SyncResult{}, errors.New("peer failed")
+}
+
+func TestDispatcherRateLimitsFailureLogs(t *testing.T) {
+	tf.UnitTest(t)
+	fc := th.NewFakeClock(time.Unix(1234567890, 0))
+	var logs []string
+	logf := func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+
+	testDispatch := syncer.NewDispatcher(alwaysFailingSyncer{}, nil)
+	testDispatch.UseFailureLogger(logf, fc, 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	testDispatch.Start(ctx)
+
+	// the first failure is logged immediately and repeats are suppressed
+	for h := 1; h <= 5; h++ {
+		require.NoError(t, testDispatch.SendHello(chainInfoFromHeight(t, h)))
+		require.NoError(t, testDispatch.Drain(ctx))
+	}
+	require.Equal(t, 1, len(logs))
+	assert.Contains(t, logs[0], "peer failed")
+
+	// after the interval the next failure is logged with the suppressed count
+	fc.Advance(10 * time.Second)
+	require.NoError(t, testDispatch.SendHello(chainInfoFromHeight(t, 6)))
+	require.NoError(t, testDispatch.Drain(ctx))
+	require.Equal(t, 2, len(logs))
+	assert.Contains(t, logs[1], "4 similar messages suppressed")
+}
+
+type blockingSyncer struct {
+	started chan block.TipSetKey
+	release chan struct{}
+}
+
+func (bs *blockingSyncer) HandleNewTipSetWithResult(ctx context.Context, ci *block.ChainInfo, t bool) (chain.SyncResult, error) {
+	bs.started <- ci.Head
+	<-bs.release
+	return chain.SyncResult{}, nil
+}
+
+func TestDispatcherCurrentTarget(t *testing.T) {
+	tf.UnitTest(t)
+	s := &blockingSyncer{
+		started: make(chan block.TipSetKey),
+		release: make(chan struct{}),
+	}
+	testDispatch := syncer.NewDispatcher(s, nil)
+
+	_, busy := testDispatch.CurrentTarget()
+	assert.False(t, busy)
+
+	finished := moresync.NewLatch(1)
+	testDispatch.RegisterCallback(func(target syncer.Target) { finished.Done() })
+	testDispatch.Start(context.Background())
+
+	ci := chainInfoFromHeight(t, 7)
+	assert.NoError(t, testDispatch.SendHello(ci))
+
+	// observe the target while the syncer is mid-processing
+	assert.Equal(t, ci.Head, <-s.started)
+	current, busy := testDispatch.CurrentTarget()
+	require.True(t, busy)
+	assert.Equal(t, ci.Head, current.Head)
+
+	close(s.release)
+	finished.Wait()
+	_, busy = testDispatch.CurrentTarget()
+	assert.False(t, busy)
+}
+
+func TestDispatcherSavesTargetsOnStop(t *testing.T) {
+	tf.UnitTest(t)
+	s := &blockingSyncer{
+		started: make(chan block.TipSetKey),
+		release: make(chan struct{}),
+	}
+	store := syncer.NewTargetStore(datastore.NewMapDatastore())
+	testDispatch := syncer.NewDispatcher(s, nil)
+	testDispatch.UseTargetStore(store)
+	ctx, cancel := context.WithCancel(context.Background())
+	testDispatch.Start(ctx)
+
+	// Targets sent while a sync is in progress wait in the incoming buffer.
+	syncing := chainInfoFromHeight(t, 7)
+	require.NoError(t, testDispatch.SendHello(syncing))
+	assert.Equal(t, syncing.Head, <-s.started)
+	buffered := []*block.ChainInfo{chainInfoFromHeight(t, 9), chainInfoFromHeight(t, 11)}
+	for _, ci := range buffered {
+		require.NoError(t, testDispatch.SendHello(ci))
+	}
+
+	cancel()
+	close(s.release)
+	<-testDispatch.Stopped()
+
+	saved := syncer.NewTargetQueue()
+	require.NoError(t, store.Load(saved, 0))
+	var heads []block.TipSetKey
+	for saved.Len() > 0 {
+		target, _ := saved.Pop()
+		heads = append(heads, target.Head)
+	}
+	assert.ElementsMatch(t, []block.TipSetKey{buffered[0].Head, buffered[1].Head}, heads)
+}
+
+type failingSyncer struct {
+	fail block.TipSetKey
+}
+
+func (fs *failingSyncer) HandleNewTipSetWithResult(ctx context.Context, ci *block.ChainInfo, t bool) (chain.SyncResult, error) {
+	if ci.Head.Equals(fs.fail) {
+		return chain.SyncResult{}, errors.New("sync failed")
+	}
+	return chain.SyncResult{HeadAdvanced: true, NewHead: ci.Head}, nil
+}
+
+type recordingObserver struct {
+	lk     sync.Mutex
+	events []string
+}
+
+func (ro *recordingObserver) record(event string, target syncer.Target) {
+	ro.lk.Lock()
+	defer ro.lk.Unlock()
+	ro.events = append(ro.events, fmt.Sprintf("%s %d", event, target.Height))
+}
+
+func (ro *recordingObserver) OnEnqueue(target syncer.Target) { ro.record("enqueue", target) }
+func (ro *recordingObserver) OnStart(target syncer.Target)   { ro.record("start", target) }
+func (ro *recordingObserver) OnComplete(target syncer.Target, result chain.SyncResult, err error) {
+	if err != nil {
+		ro.record("fail", target)
+		return
+	}
+	if result.HeadAdvanced {
+		ro.record("advance", target)
+		return
+	}
+	ro.record("complete", target)
+}
+
+func TestDispatcherObserver(t *testing.T) {
+	tf.UnitTest(t)
+	failing := chainInfoFromHeight(t, 2)
+	s := &failingSyncer{fail: failing.Head}
+	observer := &recordingObserver{}
+	testDispatch := syncer.NewDispatcher(s, observer)
+
+	finished := moresync.NewLatch(2)
+	testDispatch.RegisterCallback(func(target syncer.Target) { finished.Done() })
+
+	// Enqueue before starting so the order of processing is deterministic
+	assert.NoError(t, testDispatch.SendHello(chainInfoFromHeight(t, 5)))
+	assert.NoError(t, testDispatch.SendHello(failing))
+	testDispatch.Start(context.Background())
+	finished.Wait()
+
+	observer.lk.Lock()
+	defer observer.lk.Unlock()
+	assert.Equal(t, []string{
+		"enqueue 5",
+		"enqueue 2",
+		"start 5",
+		"advance 5",
+		"start 2",
+		"fail 2",
+	}, observer.events)
+}
+
+type stallingSyncer struct {
+	errs chan error
+}
+
+func (ss *stallingSyncer) HandleNewTipSetWithResult(ctx context.Context, ci *block.ChainInfo, t bool) (chain.SyncResult, error) {
+	<-ctx.Done()
+	ss.errs <- ctx.Err()
+	return chain.SyncResult{}, ctx.Err()
+}
+
+func TestDispatcherStallDetection(t *testing.T) {
+	tf.UnitTest(t)
+	s := &stallingSyncer{errs: make(chan error, 1)}
+	testDispatch := syncer.NewDispatcher(s, nil)
+	testDispatch.Start(context.Background())
+
+	finished := moresync.NewLatch(1)
+	testDispatch.RegisterCallback(func(target syncer.Target) { finished.Done() })
+	testDispatch.SetSyncTimeout(10 * time.Millisecond)
+
+	assert.NoError(t, testDispatch.SendHello(chainInfoFromHeight(t, 3)))
+	finished.Wait()
+
+	// The sync was cancelled by the timeout and counted as a stall.
+	assert.Equal(t, context.DeadlineExceeded, <-s.errs)
+	assert.Equal(t, uint64(1), testDispatch.Stalls())
+}
+
+type trustRecordingSyncer struct {
+	lk      sync.Mutex
+	trusted map[uint64]bool
+}
+
+func (ts *trustRecordingSyncer) HandleNewTipSetWithResult(ctx context.Context, ci *block.ChainInfo, t bool) (chain.SyncResult, error) {
+	ts.lk.Lock()
+	defer ts.lk.Unlock()
+	ts.trusted[ci.Height] = t
+	return chain.SyncResult{}, nil
+}
+
+func TestDispatcherSyncMode(t *testing.T) {
+	tf.UnitTest(t)
+	s := &trustRecordingSyncer{trusted: make(map[uint64]bool)}
+	testDispatch := syncer.NewDispatcher(s, nil)
+	testDispatch.UseHeadHeight(func() (uint64, error) { return 10, nil })
+
+	var lk sync.Mutex
+	modes := make(map[uint64]syncer.SyncMode)
+	finished := moresync.NewLatch(2)
+	testDispatch.RegisterCallback(func(target syncer.Target) {
+		lk.Lock()
+		defer lk.Unlock()
+		modes[target.Height] = target.Mode
+		finished.Done()
+	})
+
+	// Enqueue before starting so both targets are handled by one dispatcher
+	// loop iteration.
+	assert.NoError(t, testDispatch.SendGossipBlock(chainInfoFromHeight(t, 11)))
+	assert.NoError(t, testDispatch.SendHello(chainInfoFromHeight(t, 50)))
+	testDispatch.Start(context.Background())
+	finished.Wait()
+
+	lk.Lock()
+	defer lk.Unlock()
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	assert.Equal(t, syncer.FollowMode, modes[11])
+	assert.False(t, s.trusted[11])
+	assert.Equal(t, syncer.CatchupMode, modes[50])
+	assert.True(t, s.trusted[50])
+}
+
+func TestDispatcherRejectsImplausibleHeight(t *testing.T) {
+	tf.UnitTest(t)
+	s := &mockSyncer{headsCalled: make([]block.TipSetKey, 0)}
+	testDispatch := syncer.NewDispatcher(s, nil)
+
+	blockTime := consensus.DefaultBlockTime
+	now := time.Unix(1234567890, 0)
+	genesisTime := now.Add(-100 * blockTime)
+	testDispatch.UseMaxHeight(func() (uint64, error) {
+		return consensus.MaxPlausibleHeight(genesisTime, blockTime, now), nil
+	})
+
+	finished := moresync.NewLatch(1)
+	testDispatch.RegisterCallback(func(target syncer.Target) { finished.Done() })
+	testDispatch.Start(context.Background())
+
+	// A claim far beyond what could have been mined since genesis is dropped.
+	err := testDispatch.SendHello(chainInfoFromHeight(t, 1000000))
+	assert.Equal(t, syncer.ErrImplausibleHeight, pkgerrors.Cause(err))
+
+	// A claim of a block in every round since genesis is accepted.
+	plausible := chainInfoFromHeight(t, 100)
+	require.NoError(t, testDispatch.SendHello(plausible))
+	finished.Wait()
+
+	require.Equal(t, 1, len(s.headsCalled))
+	assert.Equal(t, plausible.Head, s.headsCalled[0])
+}
+
 func TestQueueHappy(t *testing.T) {
 	tf.UnitTest(t)
 	testQ := syncer.NewTargetQueue()
@@ -154,6 +642,119 @@ func TestQueueDuplicates(t *testing.T) {
 	assert.Equal(t, uint64(0), second.ChainInfo.Height)
 }
 
+func TestTargetSameTarget(t *testing.T) {
+	tf.UnitTest(t)
+
+	target := syncer.Target{ChainInfo: *(chainInfoFromHeightAndPeer(t, 5, th.DeterministicPeerID(1)))}
+
+	// The same head claimed at a different height by a different peer.
+	claimed := syncer.Target{ChainInfo: *(chainInfoFromHeightAndPeer(t, 5, th.DeterministicPeerID(2)))}
+	claimed.Height = 50
+	assert.True(t, target.SameTarget(claimed))
+	assert.True(t, claimed.SameTarget(target))
+
+	other := syncer.Target{ChainInfo: *(chainInfoFromHeight(t, 6))}
+	assert.False(t, target.SameTarget(other))
+
+	// The queue drops the duplicate.
+	testQ := syncer.NewTargetQueue()
+	testQ.Push(target)
+	testQ.Push(claimed)
+	assert.Equal(t, 1, testQ.Len())
+	assert.Equal(t, uint64(5), requirePop(t, testQ).Height)
+}
+
+func TestQueueRemove(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("removes a queued target", func(t *testing.T) {
+		testQ := syncer.NewTargetQueue()
+		for _, h := range []int{3, 9, 1, 5, 7} {
+			testQ.Push(syncer.Target{ChainInfo: *(chainInfoFromHeight(t, h))})
+		}
+
+		assert.True(t, testQ.Remove(chainInfoFromHeight(t, 5).Head))
+		assert.Equal(t, 4, testQ.Len())
+		assert.False(t, testQ.Remove(chainInfoFromHeight(t, 5).Head))
+		assert.False(t, testQ.Remove(chainInfoFromHeight(t, 4).Head))
+
+		for _, h := range []uint64{9, 7, 3, 1} {
+			assert.Equal(t, h, requirePop(t, testQ).Height)
+		}
+		assert.Equal(t, 0, testQ.Len())
+
+		// A removed head may be pushed again.
+		testQ.Push(syncer.Target{ChainInfo: *(chainInfoFromHeight(t, 5))})
+		assert.Equal(t, uint64(5), requirePop(t, testQ).Height)
+	})
+
+	t.Run("fair queue", func(t *testing.T) {
+		testQ := syncer.NewFairTargetQueue()
+		a := th.DeterministicPeerID(1)
+		b := th.DeterministicPeerID(2)
+		testQ.Push(syncer.Target{ChainInfo: *(chainInfoFromHeightAndPeer(t, 9, a))})
+		testQ.Push(syncer.Target{ChainInfo: *(chainInfoFromHeightAndPeer(t, 5, b))})
+		testQ.Push(syncer.Target{ChainInfo: *(chainInfoFromHeightAndPeer(t, 3, a))})
+
+		assert.True(t, testQ.Remove(chainInfoFromHeight(t, 5).Head))
+		assert.Equal(t, 2, testQ.Len())
+		assert.Equal(t, uint64(9), requirePop(t, testQ).Height)
+		assert.Equal(t, uint64(3), requirePop(t, testQ).Height)
+		_, popped := testQ.Pop()
+		assert.False(t, popped)
+	})
+}
+
+func TestFairQueueInterleavesPeers(t *testing.T) {
+	tf.UnitTest(t)
+	testQ := syncer.NewFairTargetQueue()
+
+	busy := th.DeterministicPeerID(1)
+	lone := th.DeterministicPeerID(2)
+
+	// The busy peer announces many heads, all higher than the lone peer's.
+	for h := 100; h < 110; h++ {
+		ci := chainInfoFromHeightAndPeer(t, h, busy)
+		testQ.Push(syncer.Target{ChainInfo: *ci})
+	}
+	loneCi := chainInfoFromHeightAndPeer(t, 50, lone)
+	testQ.Push(syncer.Target{ChainInfo: *loneCi})
+	assert.Equal(t, 11, testQ.Len())
+
+	// The busy peer's best target goes first, but the lone peer is served
+	// before the busy peer is served again.
+	first := requirePop(t, testQ)
+	assert.Equal(t, busy, first.Peer)
+	assert.Equal(t, uint64(109), first.Height)
+	second := requirePop(t, testQ)
+	assert.Equal(t, lone, second.Peer)
+	assert.Equal(t, uint64(50), second.Height)
+
+	// Remaining targets drain in priority order.
+	for h := 108; h >= 100; h-- {
+		next := requirePop(t, testQ)
+		assert.Equal(t, uint64(h), next.Height)
+	}
+	assert.Equal(t, 0, testQ.Len())
+	_, popped := testQ.Pop()
+	assert.False(t, popped)
+}
+
+func TestQueueDuplicatesReorderedHead(t *testing.T) {
+	tf.UnitTest(t)
+	testQ := syncer.NewTargetQueue()
+
+	c1, c2 := types.CidFromString(t, "a"), types.CidFromString(t, "b")
+	sR := syncer.Target{ChainInfo: block.ChainInfo{Head: block.NewTipSetKey(c1, c2), Height: 1}}
+	sRdup := syncer.Target{ChainInfo: block.ChainInfo{Head: block.NewTipSetKey(c2, c1), Height: 1}}
+
+	testQ.Push(sR)
+	testQ.Push(sRdup)
+
+	// Heads with the same CIDs in any order are the same target
+	assert.Equal(t, 1, testQ.Len())
+}
+
 func TestQueueEmptyPopErrors(t *testing.T) {
 	tf.UnitTest(t)
 	testQ := syncer.NewTargetQueue()
@@ -194,3 +795,11 @@ func chainInfoFromHeight(t *testing.T, h int) *block.ChainInfo {
 		Height: uint64(h),
 	}
 }
+
+// chainInfoFromHeightAndPeer returns a chain info as chainInfoFromHeight does,
+// sent by peer `p`.
+func chainInfoFromHeightAndPeer(t *testing.T, h int, p peer.ID) *block.ChainInfo {
+	ci := chainInfoFromHeight(t, h)
+	ci.Peer = p
+	return ci
+}
@@ -0,0 +1,51 @@
+package syncer
+
+import (
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+)
+
+// DefaultLogInterval is the least time between two logs of the same class of
+// dispatcher failure.
+const DefaultLogInterval = 10 * time.Second
+
+// rateLimitedLogger logs each class of message at most once per interval.
+// The first message of a class is logged immediately and later ones within
+// the interval are counted and reported with the next message logged. It is
+// not safe for concurrent use.
+type rateLimitedLogger struct {
+	logf     func(format string, args ...interface{})
+	clock    clock.Clock
+	interval time.Duration
+
+	last       map[string]time.Time
+	suppressed map[string]uint64
+}
+
+func newRateLimitedLogger(logf func(string, ...interface{}), clk clock.Clock, interval time.Duration) *rateLimitedLogger {
+	return &rateLimitedLogger{
+		logf:       logf,
+		clock:      clk,
+		interval:   interval,
+		last:       make(map[string]time.Time),
+		suppressed: make(map[string]uint64),
+	}
+}
+
+// Logf logs the message unless a message of the same class was logged less
+// than the interval ago.
+func (l *rateLimitedLogger) Logf(class string, format string, args ...interface{}) {
+	now := l.clock.Now()
+	if last, ok := l.last[class]; ok && now.Sub(last) < l.interval {
+		l.suppressed[class]++
+		return
+	}
+	if n := l.suppressed[class]; n > 0 {
+		format += " (%d similar messages suppressed)"
+		args = append(args, n)
+	}
+	l.last[class] = now
+	l.suppressed[class] = 0
+	l.logf(format, args...)
+}
@@ -0,0 +1,72 @@
+package syncer
+
+import (
+	"github.com/ipfs/go-datastore"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+)
+
+func init() {
+	encoding.RegisterIpldCborType(persistedTarget{})
+}
+
+// targetsKey is the datastore key under which queued targets are persisted.
+var targetsKey = datastore.NewKey("/syncer/targets")
+
+// persistedTarget is the stored form of a Target. The source peer is not
+// persisted since it is unlikely to be meaningful after a restart.
+type persistedTarget struct {
+	Head   block.TipSetKey
+	Height uint64
+}
+
+// TargetStore persists the dispatcher's outstanding targets so that syncing
+// can resume after a restart without waiting for fresh hellos.
+type TargetStore struct {
+	ds datastore.Datastore
+}
+
+// NewTargetStore returns a new TargetStore writing to `ds`.
+func NewTargetStore(ds datastore.Datastore) *TargetStore {
+	return &TargetStore{ds: ds}
+}
+
+// Save writes the targets held in `q` to the datastore, replacing any
+// previously saved targets. The queue is not modified.
+func (ts *TargetStore) Save(q *TargetQueue) error {
+	var stored []persistedTarget
+	for _, t := range q.targets() {
+		stored = append(stored, persistedTarget{Head: t.Head, Height: t.Height})
+	}
+	val, err := encoding.Encode(stored)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode targets")
+	}
+	return ts.ds.Put(targetsKey, val)
+}
+
+// Load pushes previously saved targets onto `q`, pruning those at or below
+// `headHeight` since they can no longer extend the chain. It is not an error
+// if no targets were saved.
+func (ts *TargetStore) Load(q *TargetQueue, headHeight uint64) error {
+	val, err := ts.ds.Get(targetsKey)
+	if err == datastore.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to read targets")
+	}
+	var stored []persistedTarget
+	if err := encoding.Decode(val, &stored); err != nil {
+		return errors.Wrap(err, "failed to decode targets")
+	}
+	for _, t := range stored {
+		if t.Height <= headHeight {
+			continue
+		}
+		q.Push(Target{ChainInfo: block.ChainInfo{Head: t.Head, Height: t.Height}})
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package syncer_test
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/syncer"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestTargetStoreRoundTrip(t *testing.T) {
+	tf.UnitTest(t)
+	store := syncer.NewTargetStore(datastore.NewMapDatastore())
+
+	saved := syncer.NewTargetQueue()
+	for _, h := range []int{5, 47, 2, 12, 30} {
+		saved.Push(syncer.Target{ChainInfo: *chainInfoFromHeight(t, h)})
+	}
+	require.NoError(t, store.Save(saved))
+	// Saving does not consume the queue.
+	assert.Equal(t, 5, saved.Len())
+
+	// Targets at or below the head height are pruned on load.
+	loaded := syncer.NewTargetQueue()
+	require.NoError(t, store.Load(loaded, 5))
+	require.Equal(t, 3, loaded.Len())
+
+	for _, h := range []int{47, 30, 12} {
+		next := requirePop(t, loaded)
+		assert.Equal(t, uint64(h), next.Height)
+		assert.Equal(t, chainInfoFromHeight(t, h).Head, next.Head)
+	}
+}
+
+func TestTargetStoreLoadEmpty(t *testing.T) {
+	tf.UnitTest(t)
+	store := syncer.NewTargetStore(datastore.NewMapDatastore())
+
+	q := syncer.NewTargetQueue()
+	require.NoError(t, store.Load(q, 0))
+	assert.Equal(t, 0, q.Len())
+}
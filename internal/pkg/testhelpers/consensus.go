@@ -82,6 +82,11 @@ func (tbr *FakeBlockRewarder) GasReward(ctx context.Context, st state.Tree, mine
 	return nil
 }
 
+// RewardAt returns zero, matching the rewards this rewarder pays
+func (tbr *FakeBlockRewarder) RewardAt(height uint64) types.AttoFIL {
+	return types.ZeroAttoFIL
+}
+
 // FakeBlockValidator passes everything as valid
 type FakeBlockValidator struct{}
 
@@ -95,6 +100,16 @@ func (fbv *FakeBlockValidator) ValidateSemantic(ctx context.Context, child *bloc
 	return nil
 }
 
+// ValidateMessageCount does nothing.
+func (fbv *FakeBlockValidator) ValidateMessageCount(ctx context.Context, blk *block.Block, count int) error {
+	return nil
+}
+
+// ValidateMessageNonces does nothing.
+func (fbv *FakeBlockValidator) ValidateMessageNonces(ctx context.Context, blk *block.Block, messages []*types.UnsignedMessage, nonces consensus.NonceLookup) error {
+	return nil
+}
+
 // ValidateSyntax does nothing.
 func (fbv *FakeBlockValidator) ValidateSyntax(ctx context.Context, blk *block.Block) error {
 	return nil
@@ -135,6 +150,16 @@ func (mbv *StubBlockValidator) ValidateSemantic(ctx context.Context, child *bloc
 	return mbv.semanticStubs[child.Cid()]
 }
 
+// ValidateMessageCount does nothing.
+func (mbv *StubBlockValidator) ValidateMessageCount(ctx context.Context, blk *block.Block, count int) error {
+	return nil
+}
+
+// ValidateMessageNonces does nothing.
+func (mbv *StubBlockValidator) ValidateMessageNonces(ctx context.Context, blk *block.Block, messages []*types.UnsignedMessage, nonces consensus.NonceLookup) error {
+	return nil
+}
+
 // ValidateSyntax return nil or error for stubbed block `blk`.
 func (mbv *StubBlockValidator) ValidateSyntax(ctx context.Context, blk *block.Block) error {
 	return mbv.syntaxStubs[blk.Cid()]
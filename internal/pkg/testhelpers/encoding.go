@@ -0,0 +1,32 @@
+package testhelpers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+)
+
+// AssertCBORRoundTrip encodes `v`, decodes the result into a fresh value of the
+// same type and asserts the two are deeply equal. `v` may be a value or a pointer.
+func AssertCBORRoundTrip(t *testing.T, v interface{}) {
+	data, err := encoding.Encode(v)
+	require.NoError(t, err)
+
+	typ := reflect.TypeOf(v)
+	isPtr := typ.Kind() == reflect.Ptr
+	if isPtr {
+		typ = typ.Elem()
+	}
+	out := reflect.New(typ)
+	require.NoError(t, encoding.Decode(data, out.Interface()))
+
+	if isPtr {
+		assert.Equal(t, v, out.Interface())
+	} else {
+		assert.Equal(t, v, out.Elem().Interface())
+	}
+}
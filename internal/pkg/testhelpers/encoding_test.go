@@ -0,0 +1,70 @@
+package testhelpers_test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func TestCBORRoundTrip(t *testing.T) {
+	tf.UnitTest(t)
+
+	// Fields are populated with non-empty values since a nil slice may
+	// legitimately decode as an empty one.
+	newAddr := address.NewForTestGetter()
+	newCid := types.NewCidForTestGetter()
+
+	header := &block.Block{
+		Miner:           newAddr(),
+		Ticket:          block.Ticket{VRFProof: []byte{1, 2, 3}},
+		Parents:         block.NewTipSetKey(newCid()),
+		ParentWeight:    types.Uint64(10),
+		Height:          types.Uint64(2),
+		Messages:        types.TxMeta{SecpRoot: newCid(), BLSRoot: newCid()},
+		StateRoot:       newCid(),
+		MessageReceipts: newCid(),
+		ElectionProof:   []byte{4, 5, 6},
+		PoStProof:       []byte{10, 11},
+		Timestamp:       types.Uint64(1234567890),
+		BlockSig:        []byte{7, 8},
+		BLSAggregateSig: []byte{9},
+	}
+	msg := &types.SignedMessage{
+		Message: types.UnsignedMessage{
+			To:         newAddr(),
+			From:       newAddr(),
+			CallSeqNum: types.Uint64(3),
+			Value:      types.NewAttoFILFromFIL(5),
+			Method:     "method",
+			Params:     []byte{1},
+			GasPrice:   types.NewAttoFILFromFIL(1),
+			GasLimit:   types.NewGasUnits(300),
+		},
+		Signature: []byte{2, 3},
+	}
+	receipt := &types.MessageReceipt{
+		ExitCode:   1,
+		Return:     [][]byte{{1, 2, 3}},
+		GasAttoFIL: types.NewAttoFILFromFIL(2),
+	}
+
+	t.Run("block", func(t *testing.T) {
+		th.AssertCBORRoundTrip(t, header)
+	})
+
+	t.Run("signed message", func(t *testing.T) {
+		th.AssertCBORRoundTrip(t, msg)
+	})
+
+	t.Run("message receipt", func(t *testing.T) {
+		th.AssertCBORRoundTrip(t, receipt)
+	})
+
+	t.Run("full block", func(t *testing.T) {
+		th.AssertCBORRoundTrip(t, block.NewFullBlock(header, []*types.SignedMessage{msg}, []*types.MessageReceipt{receipt}))
+	})
+}
@@ -135,6 +135,19 @@ func RequireIntPeerID(t *testing.T, i int64) peer.ID {
 	return pid
 }
 
+// DeterministicPeerID returns a peer ID derived from `seed`. The same seed
+// always yields the same ID and distinct seeds yield distinct IDs, so tests
+// can name peers without a *testing.T or a source of randomness.
+func DeterministicPeerID(seed int) peer.ID {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(buf, int64(seed))
+	h, err := mh.Sum(buf[:n], mh.SHA2_256, -1)
+	if err != nil {
+		panic(err)
+	}
+	return peer.ID(h)
+}
+
 // TestFetcher is an object with the same method set as Fetcher plus a method
 // for adding blocks to the source.  It is used to implement an object that
 // behaves like Fetcher but does not go to the network for use in tests.
@@ -0,0 +1,18 @@
+package testhelpers_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestDeterministicPeerID(t *testing.T) {
+	tf.UnitTest(t)
+
+	assert.Equal(t, th.DeterministicPeerID(7), th.DeterministicPeerID(7))
+	assert.NotEqual(t, th.DeterministicPeerID(7), th.DeterministicPeerID(8))
+	assert.NotEqual(t, th.DeterministicPeerID(1), th.DeterministicPeerID(-1))
+}
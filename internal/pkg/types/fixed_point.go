@@ -25,11 +25,18 @@ import (
 // as a fixed point.
 const MaxFixedPointIntegralNum = 18014398509481983 // (2^54 - 1)
 
+// maxFixed is the largest uint64 that validly encodes a fixed point.
+const maxFixed = uint64((MaxFixedPointIntegralNum + 1) * 1000)
+
+// ErrFixedPointOverflow is returned when a value is too big to store in
+// fixed point.
+var ErrFixedPointOverflow = errors.New("float too big to store in fixed point")
+
 // BigToFixed takes in a big Float and returns a uint64 encoded fixed point.
 func BigToFixed(f *big.Float) (uint64, error) {
 	// check that f is not too big.
 	if cmp := f.Cmp(big.NewFloat(float64(MaxFixedPointIntegralNum))); cmp == 1 {
-		return uint64(0), errors.New("float too big to store in fixed point")
+		return uint64(0), ErrFixedPointOverflow
 	}
 
 	s := fmt.Sprintf("%.3f", f) // nolint: govet
@@ -50,7 +57,7 @@ func BigToFixed(f *big.Float) (uint64, error) {
 
 // FixedToBig takes in a uint64 encoded fixed point and returns a big Float.
 func FixedToBig(fixed uint64) (*big.Float, error) {
-	if fixed > uint64((MaxFixedPointIntegralNum+1)*1000) {
+	if fixed > maxFixed {
 		return nil, errors.New("uint64 does not validly encode fixed point")
 	}
 	q := int64(fixed / 1000)
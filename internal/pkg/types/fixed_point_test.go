@@ -51,6 +51,18 @@ func TestBigToFixed(t *testing.T) {
 	})
 }
 
+func TestFixedOverflow(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("big to fixed beyond upper limit", func(t *testing.T) {
+		bigY := new(big.Float)
+		_, _, err := bigY.Parse("18014398509481984.001", 10)
+		assert.NoError(t, err)
+		_, err = BigToFixed(bigY)
+		assert.Equal(t, ErrFixedPointOverflow, err)
+	})
+}
+
 func TestFixedToBig(t *testing.T) {
 	tf.UnitTest(t)
 
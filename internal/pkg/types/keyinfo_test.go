@@ -3,8 +3,11 @@ package types
 import (
 	"testing"
 
+	bls "github.com/filecoin-project/go-bls-sigs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 )
@@ -31,3 +34,37 @@ func TestKeyInfoMarshal(t *testing.T) {
 	assert.Equal(t, ki.Type(), kiBack.Type())
 	assert.True(t, ki.Equals(kiBack))
 }
+
+func TestKeyInfoAddress(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("secp256k1", func(t *testing.T) {
+		sk, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		ki := &KeyInfo{PrivateKey: sk, CryptSystem: SECP256K1}
+
+		addr, err := ki.Address()
+		require.NoError(t, err)
+		expected, err := address.NewSecp256k1Address(crypto.PublicKey(sk))
+		require.NoError(t, err)
+		assert.Equal(t, expected, addr)
+		assert.Equal(t, address.SECP256K1, addr.Protocol())
+	})
+
+	t.Run("bls", func(t *testing.T) {
+		sk := bls.PrivateKeyGenerate()
+		ki := &KeyInfo{PrivateKey: sk[:], CryptSystem: BLS}
+
+		addr, err := ki.Address()
+		require.NoError(t, err)
+		pk := bls.PrivateKeyPublicKey(sk)
+		assert.Equal(t, address.BLS, addr.Protocol())
+		assert.Equal(t, pk[:], addr.Payload())
+	})
+
+	t.Run("unknown crypto system", func(t *testing.T) {
+		ki := &KeyInfo{PrivateKey: []byte{1}, CryptSystem: "rot13"}
+		_, err := ki.Address()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+)
+
+var (
+	// ErrUnknownSigningAddress is returned by a ResolvingSigner when no key is
+	// held for either form of an address.
+	ErrUnknownSigningAddress = errors.New("no key held for address or its resolved form")
+	// ErrAddressNotInBook is returned when an AddressBook has no entry for an address.
+	ErrAddressNotInBook = errors.New("address not in address book")
+)
+
+// KeyHolder is a Signer that reports which addresses it holds keys for.
+type KeyHolder interface {
+	Signer
+	HasAddress(addr address.Address) bool
+}
+
+// AddressResolver maps an actor's ID address to the robust (secp or bls)
+// address of the key controlling it, and back.
+type AddressResolver interface {
+	// Resolve returns the other form of `addr`: the robust address for an ID
+	// address and the ID address for a robust one.
+	Resolve(addr address.Address) (address.Address, error)
+}
+
+// AddressBook is an in-memory AddressResolver. The init actor does not yet
+// assign ID addresses, so entries are added explicitly.
+type AddressBook struct {
+	lk         sync.RWMutex
+	idToRobust map[address.Address]address.Address
+	robustToID map[address.Address]address.Address
+}
+
+var _ AddressResolver = (*AddressBook)(nil)
+
+// NewAddressBook returns an empty address book.
+func NewAddressBook() *AddressBook {
+	return &AddressBook{
+		idToRobust: make(map[address.Address]address.Address),
+		robustToID: make(map[address.Address]address.Address),
+	}
+}
+
+// Add records that the actor with ID address `id` is controlled by the key
+// with robust address `robust`.
+func (ab *AddressBook) Add(id, robust address.Address) error {
+	if id.Protocol() != address.ID {
+		return errors.Errorf("%s is not an ID address", id)
+	}
+	if robust.Protocol() == address.ID {
+		return errors.Errorf("%s is not a robust address", robust)
+	}
+
+	ab.lk.Lock()
+	defer ab.lk.Unlock()
+	ab.idToRobust[id] = robust
+	ab.robustToID[robust] = id
+	return nil
+}
+
+// Resolve returns the other form of `addr`.
+func (ab *AddressBook) Resolve(addr address.Address) (address.Address, error) {
+	ab.lk.RLock()
+	defer ab.lk.RUnlock()
+
+	book := ab.robustToID
+	if addr.Protocol() == address.ID {
+		book = ab.idToRobust
+	}
+	resolved, ok := book[addr]
+	if !ok {
+		return address.Undef, errors.Wrapf(ErrAddressNotInBook, "%s", addr)
+	}
+	return resolved, nil
+}
+
+// ResolvingSigner is a Signer that signs for an address with whichever of its
+// ID or robust forms the wrapped signer holds a key for.
+type ResolvingSigner struct {
+	signer   KeyHolder
+	resolver AddressResolver
+}
+
+var _ Signer = (*ResolvingSigner)(nil)
+
+// NewResolvingSigner wraps `signer`, resolving addresses it holds no key for
+// with `resolver`.
+func NewResolvingSigner(signer KeyHolder, resolver AddressResolver) *ResolvingSigner {
+	return &ResolvingSigner{
+		signer:   signer,
+		resolver: resolver,
+	}
+}
+
+// SignBytes signs `data` with the key for `addr` or for its resolved form.
+func (rs *ResolvingSigner) SignBytes(data []byte, addr address.Address) (Signature, error) {
+	if rs.signer.HasAddress(addr) {
+		return rs.signer.SignBytes(data, addr)
+	}
+	resolved, err := rs.resolver.Resolve(addr)
+	if err == nil && rs.signer.HasAddress(resolved) {
+		return rs.signer.SignBytes(data, resolved)
+	}
+	return nil, errors.Wrapf(ErrUnknownSigningAddress, "%s", addr)
+}
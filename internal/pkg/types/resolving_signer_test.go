@@ -0,0 +1,49 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestResolvingSigner(t *testing.T) {
+	tf.UnitTest(t)
+
+	signer, _ := NewMockSignersAndKeyInfo(1)
+	robust := signer.Addresses[0]
+	id, err := address.NewIDAddress(100)
+	require.NoError(t, err)
+
+	book := NewAddressBook()
+	require.NoError(t, book.Add(id, robust))
+	rs := NewResolvingSigner(signer, book)
+	data := []byte("data")
+
+	t.Run("signs with the ID address when only the robust key is held", func(t *testing.T) {
+		sig, err := rs.SignBytes(data, id)
+		require.NoError(t, err)
+		assert.True(t, IsValidSignature(data, robust, sig))
+	})
+
+	t.Run("signs with the robust address", func(t *testing.T) {
+		sig, err := rs.SignBytes(data, robust)
+		require.NoError(t, err)
+		assert.True(t, IsValidSignature(data, robust, sig))
+	})
+
+	t.Run("errors when neither form is known", func(t *testing.T) {
+		unknown, err := address.NewIDAddress(101)
+		require.NoError(t, err)
+		_, err = rs.SignBytes(data, unknown)
+		assert.Equal(t, ErrUnknownSigningAddress, errors.Cause(err))
+	})
+
+	t.Run("address book rejects a non ID address", func(t *testing.T) {
+		assert.Error(t, book.Add(robust, robust))
+	})
+}
@@ -13,6 +13,20 @@ var log = logging.Logger("types")
 // Signature is the result of a cryptographic sign operation.
 type Signature []byte
 
+// Verifier verifies that a signature over data was made with the key for an
+// address.
+type Verifier interface {
+	VerifySignature(data []byte, addr address.Address, sig Signature) bool
+}
+
+// DefaultVerifier is a Verifier checking signatures with IsValidSignature.
+type DefaultVerifier struct{}
+
+// VerifySignature returns true if `sig` is a valid signature of `data` by `addr`.
+func (DefaultVerifier) VerifySignature(data []byte, addr address.Address, sig Signature) bool {
+	return IsValidSignature(data, addr, sig)
+}
+
 // IsValidSignature cryptographically verifies that 'sig' is the signed hash of 'data' with
 // the public key belonging to `addr`.
 func IsValidSignature(data []byte, addr address.Address, sig Signature) bool {
@@ -1,8 +1,50 @@
 package types
 
-import "github.com/filecoin-project/go-filecoin/internal/pkg/address"
+import (
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/address"
+)
 
 // Signer is an interface for SignBytes
 type Signer interface {
 	SignBytes(data []byte, addr address.Address) (Signature, error)
 }
+
+// SignItem is a payload to be signed with the key for an address.
+type SignItem struct {
+	Data []byte
+	Addr address.Address
+}
+
+// BatchSigner is implemented by signers able to sign many payloads at less
+// cost than signing them one at a time, e.g. remote signers saving round trips.
+type BatchSigner interface {
+	// SignBatch returns a signature for each item, in order.
+	SignBatch(items []SignItem) ([]Signature, error)
+}
+
+// SignBatch signs each item with `signer`, in a single call if it is a
+// BatchSigner and with SignBytes per item otherwise. It fails if a
+// BatchSigner does not return exactly one signature per item.
+func SignBatch(signer Signer, items []SignItem) ([]Signature, error) {
+	if bs, ok := signer.(BatchSigner); ok {
+		sigs, err := bs.SignBatch(items)
+		if err != nil {
+			return nil, err
+		}
+		if len(sigs) != len(items) {
+			return nil, errors.Errorf("batch signer returned %d signatures for %d items", len(sigs), len(items))
+		}
+		return sigs, nil
+	}
+	sigs := make([]Signature, len(items))
+	for i, item := range items {
+		sig, err := signer.SignBytes(item.Data, item.Addr)
+		if err != nil {
+			return nil, err
+		}
+		sigs[i] = sig
+	}
+	return sigs, nil
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+type countingBatchSigner struct {
+	MockSigner
+	batches int
+	// drop is the number of trailing signatures to leave out of a batch.
+	drop int
+}
+
+func (cs *countingBatchSigner) SignBatch(items []SignItem) ([]Signature, error) {
+	cs.batches++
+	var sigs []Signature
+	for _, item := range items {
+		sig, err := cs.SignBytes(item.Data, item.Addr)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs[:len(sigs)-cs.drop], nil
+}
+
+func TestSignBatch(t *testing.T) {
+	tf.UnitTest(t)
+
+	signer, _ := NewMockSignersAndKeyInfo(2)
+	items := []SignItem{
+		{Data: []byte("one"), Addr: signer.Addresses[0]},
+		{Data: []byte("two"), Addr: signer.Addresses[1]},
+		{Data: []byte("three"), Addr: signer.Addresses[0]},
+	}
+
+	var expected []Signature
+	for _, item := range items {
+		sig, err := signer.SignBytes(item.Data, item.Addr)
+		require.NoError(t, err)
+		expected = append(expected, sig)
+	}
+
+	t.Run("falls back to SignBytes", func(t *testing.T) {
+		sigs, err := SignBatch(signer, items)
+		require.NoError(t, err)
+		assert.Equal(t, expected, sigs)
+	})
+
+	t.Run("uses a native BatchSigner", func(t *testing.T) {
+		bs := &countingBatchSigner{MockSigner: signer}
+		sigs, err := SignBatch(bs, items)
+		require.NoError(t, err)
+		assert.Equal(t, expected, sigs)
+		assert.Equal(t, 1, bs.batches)
+	})
+
+	t.Run("fails for a short batch", func(t *testing.T) {
+		bs := &countingBatchSigner{MockSigner: signer, drop: 1}
+		_, err := SignBatch(bs, items)
+		assert.Error(t, err)
+	})
+
+	t.Run("fails for an unknown address", func(t *testing.T) {
+		other := NewMockSigner(MustGenerateKeyInfo(1, 7))
+		_, err := SignBatch(other, items)
+		assert.Error(t, err)
+	})
+}
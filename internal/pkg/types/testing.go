@@ -132,6 +132,12 @@ func (ms MockSigner) SignBytes(data []byte, addr address.Address) (Signature, er
 	return crypto.SignBLS(ki.PrivateKey, data)
 }
 
+// HasAddress returns true if the MockSigner holds the key for `addr`.
+func (ms MockSigner) HasAddress(addr address.Address) bool {
+	_, ok := ms.AddrKeyInfo[addr]
+	return ok
+}
+
 // GetAddressForPubKey looks up a KeyInfo address associated with a given PublicKey for a MockSigner
 func (ms MockSigner) GetAddressForPubKey(pk []byte) (address.Address, error) {
 	var addr address.Address
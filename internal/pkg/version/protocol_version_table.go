@@ -3,12 +3,17 @@ package version
 import (
 	"sort"
 
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/pkg/errors"
 )
 
-// protocolVersion specifies that a particular protocol version goes into effect at a particular block height
-type protocolVersion struct {
+func init() {
+	encoding.RegisterIpldCborType(ProtocolVersion{})
+}
+
+// ProtocolVersion specifies that a particular protocol version goes into effect at a particular block height
+type ProtocolVersion struct {
 	Version     uint64
 	EffectiveAt *types.BlockHeight
 }
@@ -17,7 +22,7 @@ type protocolVersion struct {
 // It must be constructed with the ProtocolVersionTableBuilder which enforces that the table has at least one
 // entry at block height zero and that all the versions are sorted.
 type ProtocolVersionTable struct {
-	versions []protocolVersion
+	versions []ProtocolVersion
 }
 
 // VersionAt returns the protocol versions at the given block height for this PVT's network.
@@ -40,6 +45,25 @@ func (pvt *ProtocolVersionTable) VersionAt(height *types.BlockHeight) (uint64, e
 	return pvt.versions[idx-1].Version, nil
 }
 
+// Versions returns the protocol versions in the table ordered by the height at
+// which they take effect.
+func (pvt *ProtocolVersionTable) Versions() []ProtocolVersion {
+	versions := make([]ProtocolVersion, len(pvt.versions))
+	copy(versions, pvt.versions)
+	return versions
+}
+
+// NewProtocolVersionTableFromVersions builds a table from previously recorded
+// versions, e.g. those returned by Versions. The versions are subject to the same
+// checks as those added through a ProtocolVersionTableBuilder.
+func NewProtocolVersionTableFromVersions(versions []ProtocolVersion) (*ProtocolVersionTable, error) {
+	builder := NewProtocolVersionTableBuilder("")
+	for _, v := range versions {
+		builder.Add("", v.Version, v.EffectiveAt)
+	}
+	return builder.Build()
+}
+
 // ProtocolVersionTableBuilder constructs a protocol version table
 type ProtocolVersionTableBuilder struct {
 	network  string
@@ -50,7 +74,7 @@ type ProtocolVersionTableBuilder struct {
 func NewProtocolVersionTableBuilder(network string) *ProtocolVersionTableBuilder {
 	return &ProtocolVersionTableBuilder{
 		network:  network,
-		versions: []protocolVersion{},
+		versions: []ProtocolVersion{},
 	}
 }
 
@@ -61,7 +85,7 @@ func (pvtb *ProtocolVersionTableBuilder) Add(network string, version uint64, eff
 		return pvtb
 	}
 
-	protocolVersion := protocolVersion{
+	protocolVersion := ProtocolVersion{
 		Version:     version,
 		EffectiveAt: effectiveAt,
 	}
@@ -78,7 +102,7 @@ func (pvtb *ProtocolVersionTableBuilder) Build() (*ProtocolVersionTable, error)
 	sort.Sort(pvtb.versions)
 
 	// copy to insure an Add doesn't alter the table
-	versions := make([]protocolVersion, len(pvtb.versions))
+	versions := make([]ProtocolVersion, len(pvtb.versions))
 	copy(versions, pvtb.versions)
 
 	// enforce that the current network has an entry at block height zero
@@ -103,7 +127,7 @@ func (pvtb *ProtocolVersionTableBuilder) Build() (*ProtocolVersionTable, error)
 }
 
 // sort methods for protocolVersion slice
-type protocolVersionsByEffectiveAt []protocolVersion
+type protocolVersionsByEffectiveAt []ProtocolVersion
 
 func (a protocolVersionsByEffectiveAt) Len() int      { return len(a) }
 func (a protocolVersionsByEffectiveAt) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
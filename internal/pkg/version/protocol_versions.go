@@ -22,6 +22,15 @@ const Protocol0 = 0
 // Protocol1 is the weight upgrade
 const Protocol1 = 1
 
+// Protocol2 is the upgrade capping the number of messages per block
+const Protocol2 = 2
+
+// Protocol3 is the upgrade requiring each block's ticket to be derived from
+// its parents' min ticket, checking proofs of spacetime, rejecting tipsets
+// with two blocks from one miner and requiring a weight margin to switch
+// chains
+const Protocol3 = 3
+
 // ConfigureProtocolVersions configures all protocol upgrades for all known networks.
 // TODO: support arbitrary network names at "latest" protocol version so that only coordinated
 // network upgrades need to be represented here. See #3491.
@@ -31,7 +40,7 @@ func ConfigureProtocolVersions(network string) (*ProtocolVersionTable, error) {
 		Add(USER, Protocol1, types.NewBlockHeight(43000)).
 		Add(DEVNET4, Protocol0, types.NewBlockHeight(0)).
 		Add(DEVNET4, Protocol1, types.NewBlockHeight(300)).
-		Add(LOCALNET, Protocol1, types.NewBlockHeight(0)).
-		Add(TEST, Protocol1, types.NewBlockHeight(0)).
+		Add(LOCALNET, Protocol2, types.NewBlockHeight(0)).
+		Add(TEST, Protocol2, types.NewBlockHeight(0)).
 		Build()
 }
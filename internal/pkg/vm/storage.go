@@ -3,6 +3,7 @@ package vm
 import (
 	"bytes"
 	"errors"
+	"fmt"
 
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
@@ -74,6 +75,33 @@ func (s *storageMap) NewStorage(addr address.Address, actor *actor.Actor) Storag
 	return storage
 }
 
+// CopyStorageMap returns a storage map over the same blockstore holding copies
+// of the chunks staged in `vms`, so chunks staged in either map are not seen or
+// flushed by the other.
+func CopyStorageMap(vms StorageMap) (StorageMap, error) {
+	s, ok := vms.(*storageMap)
+	if !ok {
+		return nil, fmt.Errorf("cannot copy storage map of type %T", vms)
+	}
+
+	cp := &storageMap{
+		blockstore: s.blockstore,
+		storageMap: make(map[address.Address]Storage, len(s.storageMap)),
+	}
+	for addr, storage := range s.storageMap {
+		chunks := make(map[cid.Cid]ipld.Node, len(storage.chunks))
+		for c, nd := range storage.chunks {
+			chunks[c] = nd
+		}
+		cp.storageMap[addr] = Storage{
+			actor:      storage.actor,
+			chunks:     chunks,
+			blockstore: s.blockstore,
+		}
+	}
+	return cp, nil
+}
+
 // Flush saves all valid staged changes to the datastore
 func (s *storageMap) Flush() error {
 	for _, storage := range s.storageMap {
@@ -409,6 +409,11 @@ func (gbr *blockRewarder) GasReward(ctx context.Context, st state.Tree, minerAdd
 	return nil
 }
 
+// RewardAt returns zero, matching the rewards this rewarder pays
+func (gbr *blockRewarder) RewardAt(height uint64) types.AttoFIL {
+	return types.ZeroAttoFIL
+}
+
 // signer doesn't actually sign because it's not actually validated
 type signer struct{}
 